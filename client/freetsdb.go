@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -28,10 +29,27 @@ const (
 	DefaultTimeout = 0
 )
 
+// Formats in which query results can be requested.
+const (
+	// FormatJSON requests results as JSON. It is the default.
+	FormatJSON = "json"
+
+	// FormatMsgpack requests results as MessagePack, which is faster to
+	// decode for large results.
+	FormatMsgpack = "msgpack"
+
+	// FormatCSV requests results as CSV. CSV results can't be decoded into a
+	// Response so they must be requested with QueryCSV.
+	FormatCSV = "csv"
+)
+
 // Query is used to send a command to the server. Both Command and Database are required.
 type Query struct {
 	Command  string
 	Database string
+
+	// Format is the format the results are requested in. Defaults to JSON.
+	Format string
 }
 
 // ParseConnectionString will parse a string to create a valid connection URL
@@ -151,36 +169,35 @@ func (c *Client) SetPrecision(precision string) {
 
 // Query sends a command to the server and returns the Response
 func (c *Client) Query(q Query) (*Response, error) {
-	u := c.url
-
-	u.Path = "query"
-	values := u.Query()
-	values.Set("q", q.Command)
-	values.Set("db", q.Database)
-	if c.precision != "" {
-		values.Set("epoch", c.precision)
-	}
-	u.RawQuery = values.Encode()
-
-	req, err := http.NewRequest("GET", u.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("User-Agent", c.userAgent)
-	if c.username != "" {
-		req.SetBasicAuth(c.username, c.password)
+	var accept string
+	switch q.Format {
+	case "", FormatJSON:
+		accept = "application/json"
+	case FormatMsgpack:
+		accept = "application/x-msgpack"
+	case FormatCSV:
+		return nil, errors.New("CSV results must be requested with QueryCSV")
+	default:
+		return nil, fmt.Errorf("unknown query format %q", q.Format)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.query(q, accept)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	// Decode the format the server responded with, older servers always
+	// respond with JSON.
 	var response Response
-	dec := json.NewDecoder(resp.Body)
-	dec.UseNumber()
-	decErr := dec.Decode(&response)
+	var decErr error
+	if resp.Header.Get("Content-Type") == "application/x-msgpack" {
+		decErr = response.decodeMsgpack(resp.Body)
+	} else {
+		dec := json.NewDecoder(resp.Body)
+		dec.UseNumber()
+		decErr = dec.Decode(&response)
+	}
 
 	// ignore this error if we got an invalid status code
 	if decErr != nil && decErr.Error() == "EOF" && resp.StatusCode != http.StatusOK {
@@ -197,6 +214,63 @@ func (c *Client) Query(q Query) (*Response, error) {
 	return &response, nil
 }
 
+// QueryCSV sends a command to the server and copies the results to w as CSV.
+// Errors in the results of statements are written to w as error records.
+func (c *Client) QueryCSV(q Query, w io.Writer) error {
+	resp, err := c.query(q, "application/csv")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Errors that occur before the query is executed, and responses from
+	// servers that don't support CSV, are returned as JSON.
+	if resp.Header.Get("Content-Type") != "application/csv" {
+		var response Response
+		dec := json.NewDecoder(resp.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&response); err == nil && response.Error() != nil {
+			return response.Error()
+		}
+		return fmt.Errorf("unexpected response from server: status code %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d from server", resp.StatusCode)
+	}
+	return nil
+}
+
+// query sends a command to the server requesting the results in the accept
+// media type.
+func (c *Client) query(q Query, accept string) (*http.Response, error) {
+	u := c.url
+
+	u.Path = "query"
+	values := u.Query()
+	values.Set("q", q.Command)
+	values.Set("db", q.Database)
+	if c.precision != "" {
+		values.Set("epoch", c.precision)
+	}
+	u.RawQuery = values.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", accept)
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	return c.httpClient.Do(req)
+}
+
 // Write takes BatchPoints and allows for writing of multiple points with defaults
 // If successful, error is nil and Response is nil
 // If an error occurs, Response may contain additional information if populated.
@@ -353,18 +427,23 @@ func (c *Client) Ping() (time.Duration, string, error) {
 type Result struct {
 	Series []models.Row
 	Err    error
+
+	// Partial is set if the server truncated the series.
+	Partial bool
 }
 
 // MarshalJSON encodes the result into JSON.
 func (r *Result) MarshalJSON() ([]byte, error) {
 	// Define a struct that outputs "error" as a string.
 	var o struct {
-		Series []models.Row `json:"series,omitempty"`
-		Err    string       `json:"error,omitempty"`
+		Series  []models.Row `json:"series,omitempty"`
+		Err     string       `json:"error,omitempty"`
+		Partial bool         `json:"partial,omitempty"`
 	}
 
 	// Copy fields to output struct.
 	o.Series = r.Series
+	o.Partial = r.Partial
 	if r.Err != nil {
 		o.Err = r.Err.Error()
 	}
@@ -375,8 +454,9 @@ func (r *Result) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON decodes the data into the Result struct
 func (r *Result) UnmarshalJSON(b []byte) error {
 	var o struct {
-		Series []models.Row `json:"series,omitempty"`
-		Err    string       `json:"error,omitempty"`
+		Series  []models.Row `json:"series,omitempty"`
+		Err     string       `json:"error,omitempty"`
+		Partial bool         `json:"partial,omitempty"`
 	}
 
 	dec := json.NewDecoder(bytes.NewBuffer(b))
@@ -386,6 +466,7 @@ func (r *Result) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	r.Series = o.Series
+	r.Partial = o.Partial
 	if o.Err != "" {
 		r.Err = errors.New(o.Err)
 	}
@@ -1,6 +1,7 @@
 package client_test
 
 import (
+	"bytes"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -10,11 +11,14 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/freetsdb/freetsdb/client"
+	"github.com/freetsdb/freetsdb/models"
+	"github.com/freetsdb/freetsdb/pkg/msgpack"
 )
 
 func BenchmarkWrite(b *testing.B) {
@@ -169,6 +173,87 @@ func TestClient_Query(t *testing.T) {
 	}
 }
 
+func TestClient_Query_Msgpack(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get("Accept"); accept != "application/x-msgpack" {
+			t.Errorf("unexpected accept header: %s", accept)
+		}
+		w.Header().Set("Content-Type", "application/x-msgpack")
+		w.WriteHeader(http.StatusOK)
+
+		// Write a chunked response.
+		enc := msgpack.NewEncoder(w)
+		enc.Encode(map[string]interface{}{"results": []interface{}{
+			map[string]interface{}{"series": []interface{}{map[string]interface{}{
+				"name":    "cpu",
+				"tags":    map[string]string{"host": "a"},
+				"columns": []string{"time", "value"},
+				"values":  [][]interface{}{{int64(10), 1.5}},
+			}}},
+		}})
+		enc.Encode(map[string]interface{}{"results": []interface{}{
+			map[string]interface{}{"error": "measurement not found"},
+		}})
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	c, err := client.NewClient(client.Config{URL: *u})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.Query(client.Query{Command: "SELECT * FROM cpu", Format: client.FormatMsgpack})
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := []client.Result{
+		{Series: []models.Row{{
+			Name:    "cpu",
+			Tags:    map[string]string{"host": "a"},
+			Columns: []string{"time", "value"},
+			Values:  [][]interface{}{{int64(10), 1.5}},
+		}}},
+		{Err: errors.New("measurement not found")},
+	}; !reflect.DeepEqual(resp.Results, exp) {
+		t.Fatalf("unexpected results: %#v", resp.Results)
+	}
+}
+
+func TestClient_QueryCSV(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get("Accept"); accept != "application/csv" {
+			t.Errorf("unexpected accept header: %s", accept)
+		}
+		if r.URL.Query().Get("db") == "missing" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"authorization failed"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/csv")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("name,tags,time,value\ncpu,,10,1.5\n"))
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	c, err := client.NewClient(client.Config{URL: *u})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.QueryCSV(client.Query{Command: "SELECT * FROM cpu", Database: "db0"}, &buf); err != nil {
+		t.Fatal(err)
+	} else if buf.String() != "name,tags,time,value\ncpu,,10,1.5\n" {
+		t.Fatalf("unexpected csv: %q", buf.String())
+	}
+
+	if err := c.QueryCSV(client.Query{Command: "SELECT * FROM cpu", Database: "missing"}, &buf); err == nil || err.Error() != "authorization failed" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestClient_BasicAuth(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		u, p, ok := r.BasicAuth()
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/freetsdb/freetsdb/models"
+	"github.com/freetsdb/freetsdb/pkg/msgpack"
+)
+
+// decodeMsgpack decodes a MessagePack encoded response from rd. Chunked
+// responses are a sequence of responses whose results are appended to r.
+func (r *Response) decodeMsgpack(rd io.Reader) error {
+	dec := msgpack.NewDecoder(rd)
+	for n := 0; ; n++ {
+		v, err := dec.Decode()
+		if err == io.EOF {
+			if n == 0 {
+				return err
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected msgpack response: %T", v)
+		}
+		if s, ok := o["error"].(string); ok {
+			r.Err = errors.New(s)
+		}
+
+		results, _ := o["results"].([]interface{})
+		for _, v := range results {
+			result, err := decodeMsgpackResult(v)
+			if err != nil {
+				return err
+			}
+			r.Results = append(r.Results, result)
+		}
+	}
+}
+
+func decodeMsgpackResult(v interface{}) (Result, error) {
+	var result Result
+	o, ok := v.(map[string]interface{})
+	if !ok {
+		return result, fmt.Errorf("unexpected msgpack result: %T", v)
+	}
+	if s, ok := o["error"].(string); ok {
+		result.Err = errors.New(s)
+	}
+	result.Partial, _ = o["partial"].(bool)
+
+	series, _ := o["series"].([]interface{})
+	for _, v := range series {
+		s, ok := v.(map[string]interface{})
+		if !ok {
+			return result, fmt.Errorf("unexpected msgpack series: %T", v)
+		}
+
+		var row models.Row
+		row.Name, _ = s["name"].(string)
+		if tags, ok := s["tags"].(map[string]interface{}); ok {
+			row.Tags = make(map[string]string, len(tags))
+			for k, v := range tags {
+				row.Tags[k], _ = v.(string)
+			}
+		}
+		if columns, ok := s["columns"].([]interface{}); ok {
+			row.Columns = make([]string, len(columns))
+			for i, v := range columns {
+				row.Columns[i], _ = v.(string)
+			}
+		}
+		if values, ok := s["values"].([]interface{}); ok {
+			row.Values = make([][]interface{}, len(values))
+			for i, v := range values {
+				row.Values[i], _ = v.([]interface{})
+			}
+		}
+		result.Series = append(result.Series, row)
+	}
+	return result, nil
+}
@@ -464,9 +464,10 @@ func (c *client) Write(bp BatchPoints) error {
 
 // Query defines a query to send to the server
 type Query struct {
-	Command   string
-	Database  string
-	Precision string
+	Command    string
+	Database   string
+	Precision  string
+	Parameters map[string]interface{}
 }
 
 // NewQuery returns a query object
@@ -480,6 +481,17 @@ func NewQuery(command, database, precision string) Query {
 	}
 }
 
+// NewQueryWithParameters returns a query object with the values of the
+// parameters bound to the $name placeholders in the command.
+func NewQueryWithParameters(command, database, precision string, parameters map[string]interface{}) Query {
+	return Query{
+		Command:    command,
+		Database:   database,
+		Precision:  precision,
+		Parameters: parameters,
+	}
+}
+
 // Response represents a list of statement results.
 type Response struct {
 	Results []Result
@@ -502,8 +514,9 @@ func (r *Response) Error() error {
 
 // Result represents a resultset returned from a single statement.
 type Result struct {
-	Series []models.Row
-	Err    string `json:"error,omitempty"`
+	Series  []models.Row
+	Err     string `json:"error,omitempty"`
+	Partial bool   `json:"partial,omitempty"`
 }
 
 func (uc *udpclient) Query(q Query) (*Response, error) {
@@ -531,6 +544,13 @@ func (c *client) Query(q Query) (*Response, error) {
 	if q.Precision != "" {
 		params.Set("epoch", q.Precision)
 	}
+	if len(q.Parameters) > 0 {
+		b, err := json.Marshal(q.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		params.Set("params", string(b))
+	}
 	req.URL.RawQuery = params.Encode()
 
 	resp, err := c.httpClient.Do(req)
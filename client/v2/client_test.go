@@ -90,6 +90,31 @@ func TestClient_Query(t *testing.T) {
 	}
 }
 
+func TestClient_Query_Parameters(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("params"); got != `{"host":"serverA","limit":10}` {
+			t.Errorf("unexpected params: %s", got)
+		}
+		var data Response
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(data)
+	}))
+	defer ts.Close()
+
+	config := HTTPConfig{Addr: ts.URL}
+	c, _ := NewHTTPClient(config)
+	defer c.Close()
+
+	query := NewQueryWithParameters("SELECT * FROM cpu WHERE host = $host LIMIT $limit", "db", "", map[string]interface{}{
+		"host":  "serverA",
+		"limit": 10,
+	})
+	_, err := c.Query(query)
+	if err != nil {
+		t.Errorf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+}
+
 func TestClient_BasicAuth(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		u, p, ok := r.BasicAuth()
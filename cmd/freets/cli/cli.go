@@ -21,6 +21,7 @@ import (
 
 	"github.com/freetsdb/freetsdb/client"
 	"github.com/freetsdb/freetsdb/cluster"
+	"github.com/freetsdb/freetsdb/importer/v8"
 	"github.com/peterh/liner"
 )
 
@@ -51,6 +52,7 @@ type CommandLine struct {
 	WriteConsistency string
 	Execute          string
 	ShowVersion      bool
+	Import           bool
 	PPS              int // Controls how many points per second the import will allow via throttling
 	Path             string
 	Compressed       bool
@@ -109,6 +111,11 @@ func (c *CommandLine) Run() error {
 	// Modify precision.
 	c.SetPrecision(c.Precision)
 
+	if c.Import {
+		c.Line.Close()
+		return c.importFile()
+	}
+
 	if c.Execute == "" {
 		token, err := c.DatabaseToken()
 		if err != nil {
@@ -259,6 +266,30 @@ func (c *CommandLine) Connect(cmd string) error {
 	return nil
 }
 
+// importFile imports the file at Path into the connected server.
+func (c *CommandLine) importFile() error {
+	config := v8.NewConfig()
+	config.Username = c.Username
+	config.Password = c.Password
+	config.Precision = "ns"
+	config.WriteConsistency = c.WriteConsistency
+	config.Path = c.Path
+	config.Version = c.ClientVersion
+	config.Compressed = c.Compressed
+	config.PPS = c.PPS
+	u, err := client.ParseConnectionString(net.JoinHostPort(c.Host, strconv.Itoa(c.Port)), c.Ssl)
+	if err != nil {
+		return err
+	}
+	config.URL = u
+
+	i := v8.NewImporter(config)
+	if err := i.Import(); err != nil {
+		return fmt.Errorf("ERROR: %s", err)
+	}
+	return nil
+}
+
 // SetAuth sets client authentication credentials
 func (c *CommandLine) SetAuth(cmd string) {
 	// If they pass in the entire command, we should parse it
@@ -489,7 +520,22 @@ func (c *CommandLine) Insert(stmt string) error {
 
 // ExecuteQuery runs any query statement
 func (c *CommandLine) ExecuteQuery(query string) error {
-	response, err := c.Client.Query(client.Query{Command: query, Database: c.Database})
+	// CSV is written by the server as is.
+	if c.Format == "csv" {
+		if err := c.Client.QueryCSV(client.Query{Command: query, Database: c.Database}, os.Stdout); err != nil {
+			fmt.Printf("ERR: %s\n", err)
+			return err
+		}
+		return nil
+	}
+
+	// Columns are formatted from MessagePack, which decodes faster than JSON.
+	format := client.FormatJSON
+	if c.Format == "column" {
+		format = client.FormatMsgpack
+	}
+
+	response, err := c.Client.Query(client.Query{Command: query, Database: c.Database, Format: format})
 	if err != nil {
 		fmt.Printf("ERR: %s\n", err)
 		return err
@@ -672,7 +718,10 @@ func interfaceToString(v interface{}) string {
 		return fmt.Sprintf("%v", v)
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
 		return fmt.Sprintf("%d", t)
-	case float32, float64:
+	case float64:
+		// Format floats without an exponent.
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case float32:
 		return fmt.Sprintf("%v", t)
 	default:
 		return fmt.Sprintf("%v", t)
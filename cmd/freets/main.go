@@ -43,6 +43,7 @@ func main() {
 	fs.BoolVar(&c.Pretty, "pretty", false, "Turns on pretty print for the json format.")
 	fs.StringVar(&c.Execute, "execute", c.Execute, "Execute command and quit.")
 	fs.BoolVar(&c.ShowVersion, "version", false, "Displays the FreeTSDB version.")
+	fs.BoolVar(&c.Import, "import", false, "Import a file exported with freets_inspect export.")
 	fs.IntVar(&c.PPS, "pps", defaultPPS, "How many points per second the import will allow.  By default it is zero and will not throttle importing.")
 	fs.StringVar(&c.Path, "path", "", "path to the file to import")
 	fs.BoolVar(&c.Compressed, "compressed", false, "set to true if the import file is compressed")
@@ -76,6 +77,8 @@ func main() {
        Set write consistency level: any, one, quorum, or all
   -pretty
        Turns on pretty print for the json format.
+  -import
+       Import a file exported with freets_inspect export, resuming a previous import of it that failed.
   -pps
        How many points per second the import will allow.  By default it is zero and will not throttle importing.
   -path
@@ -88,6 +91,9 @@ Examples:
     # Use freets in a non-interactive mode to query the database "metrics" and pretty print json:
     $ freets -database 'metrics' -execute 'select * from cpu' -format 'json' -pretty
 
+    # Import a gzipped export, throttled to 10000 points per second:
+    $ freets -import -path 'export.gz' -compressed -pps 10000
+
     # Connect to a specific database on startup and set database context:
     $ freets -database 'metrics' -host 'localhost' -port '8086'
 `)
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/freetsdb/freetsdb/pkg/escape"
+	"github.com/freetsdb/freetsdb/tsdb/engine/tsm1"
+)
+
+// exportOpts are the options of the export command.
+type exportOpts struct {
+	dataDir         string
+	walDir          string
+	out             string
+	database        string
+	retentionPolicy string
+	start           string
+	end             string
+	compress        bool
+}
+
+// exporter writes the data in tsm1 shards as line protocol.
+type exporter struct {
+	opts *exportOpts
+
+	startTime int64
+	endTime   int64
+
+	// tsmFiles and walFiles are keyed by "database/retention policy".
+	tsmFiles map[string][]string
+	walFiles map[string][]string
+}
+
+// newExporter returns a new exporter with opts.
+func newExporter(opts *exportOpts) *exporter {
+	return &exporter{
+		opts:     opts,
+		tsmFiles: make(map[string][]string),
+		walFiles: make(map[string][]string),
+	}
+}
+
+func cmdExport(opts *exportOpts) {
+	e := newExporter(opts)
+	if err := e.run(); err != nil {
+		fmt.Fprintf(os.Stderr, "export: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func (e *exporter) run() error {
+	if err := e.parseTimes(); err != nil {
+		return err
+	}
+	if err := e.walk(); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if e.opts.out != "-" {
+		if err := os.MkdirAll(filepath.Dir(e.opts.out), 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(e.opts.out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	w = bw
+	if e.opts.compress {
+		gz := gzip.NewWriter(bw)
+		defer gz.Close()
+		w = gz
+	}
+
+	if err := e.writeDDL(w); err != nil {
+		return err
+	}
+	if err := e.writeDML(w); err != nil {
+		return err
+	}
+
+	if gz, ok := w.(*gzip.Writer); ok {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// parseTimes parses the RFC3339 start and end times of the export.
+func (e *exporter) parseTimes() error {
+	e.startTime, e.endTime = math.MinInt64, math.MaxInt64
+	if e.opts.start != "" {
+		t, err := time.Parse(time.RFC3339, e.opts.start)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %s", err)
+		}
+		e.startTime = t.UnixNano()
+	}
+	if e.opts.end != "" {
+		t, err := time.Parse(time.RFC3339, e.opts.end)
+		if err != nil {
+			return fmt.Errorf("invalid end time: %s", err)
+		}
+		e.endTime = t.UnixNano()
+	}
+	if e.startTime > e.endTime {
+		return fmt.Errorf("end time before start time")
+	}
+	return nil
+}
+
+// walk finds the TSM and WAL files of every shard being exported. Shards are
+// stored as <dir>/<database>/<retention policy>/<shard id>.
+func (e *exporter) walk() error {
+	if err := e.walkDir(e.opts.dataDir, "."+tsm1.TSMFileExtension, e.tsmFiles); err != nil {
+		return err
+	}
+	return e.walkDir(e.opts.walDir, "."+tsm1.WALFileExtension, e.walFiles)
+}
+
+func (e *exporter) walkDir(dir, ext string, files map[string][]string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if info.IsDir() || filepath.Ext(path) != ext {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		dirs := strings.Split(rel, string(os.PathSeparator))
+		if len(dirs) != 4 {
+			return nil
+		}
+		if e.opts.database != "" && dirs[0] != e.opts.database {
+			return nil
+		}
+		if e.opts.retentionPolicy != "" && dirs[1] != e.opts.retentionPolicy {
+			return nil
+		}
+
+		key := dirs[0] + "/" + dirs[1]
+		files[key] = append(files[key], path)
+		return nil
+	})
+}
+
+// keys returns the sorted "database/retention policy" keys of every shard
+// being exported.
+func (e *exporter) keys() []string {
+	set := make(map[string]struct{})
+	for k := range e.tsmFiles {
+		set[k] = struct{}{}
+	}
+	for k := range e.walFiles {
+		set[k] = struct{}{}
+	}
+
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeDDL writes the statements creating each exported database and
+// retention policy.
+func (e *exporter) writeDDL(w io.Writer) error {
+	fmt.Fprintln(w, "# DDL")
+	for _, key := range e.keys() {
+		db, rp := splitKey(key)
+		if _, err := fmt.Fprintf(w, "CREATE DATABASE %s WITH NAME %s\n", quoteIdent(db), quoteIdent(rp)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// writeDML writes the points of every exported shard as line protocol,
+// preceded by the database and retention policy they belong to.
+func (e *exporter) writeDML(w io.Writer) error {
+	fmt.Fprintln(w, "# DML")
+	for _, key := range e.keys() {
+		db, rp := splitKey(key)
+		fmt.Fprintf(w, "# CONTEXT-DATABASE:%s\n", db)
+		fmt.Fprintf(w, "# CONTEXT-RETENTION-POLICY:%s\n", rp)
+
+		for _, path := range e.tsmFiles[key] {
+			if err := e.exportTSMFile(path, w); err != nil {
+				return fmt.Errorf("%s: %s", path, err)
+			}
+		}
+
+		// A delete in the WAL only removes the writes before it in the same
+		// shard, so the segments of each shard are replayed together.
+		shards := make(map[string][]string)
+		for _, path := range e.walFiles[key] {
+			dir := filepath.Dir(path)
+			shards[dir] = append(shards[dir], path)
+		}
+		dirs := make([]string, 0, len(shards))
+		for dir := range shards {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+
+		for _, dir := range dirs {
+			if err := e.exportWALFiles(shards[dir], w); err != nil {
+				return fmt.Errorf("%s: %s", dir, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *exporter) exportTSMFile(path string, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	r, err := tsm1.NewTSMReaderWithOptions(tsm1.TSMReaderOptions{MMAPFile: f})
+	if err != nil {
+		f.Close()
+		return err
+	}
+	defer r.Close()
+
+	if min, max := r.TimeRange(); min > e.endTime || max < e.startTime {
+		return nil
+	}
+
+	var values []tsm1.Value
+	itr := r.BlockIterator()
+	for itr.Next() {
+		key, min, max, buf, err := itr.Read()
+		if err != nil {
+			return err
+		} else if min > e.endTime || max < e.startTime {
+			continue
+		}
+
+		values, err = tsm1.DecodeBlock(buf, values[:0])
+		if err != nil {
+			return err
+		}
+		if err := e.writeValues(w, key, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportWALFiles replays the WAL segments of a shard into a cache, applying
+// deletes to the writes before them, and writes the values left in it.
+func (e *exporter) exportWALFiles(paths []string, w io.Writer) error {
+	// Segments are replayed in order so later writes replace earlier ones.
+	sort.Strings(paths)
+
+	cache := tsm1.NewCache(0, "")
+	for _, path := range paths {
+		if err := e.readWALFile(path, cache); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+	}
+
+	for _, k := range cache.Keys() {
+		if err := e.writeValues(w, k, cache.Values(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readWALFile applies the entries of a WAL segment to cache.
+func (e *exporter) readWALFile(path string, cache *tsm1.Cache) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	r := tsm1.NewWALSegmentReader(f)
+	defer r.Close()
+
+	for r.Next() {
+		entry, err := r.Read()
+		if err != nil {
+			// A segment may end in a partial write if the server was stopped.
+			fmt.Fprintf(os.Stderr, "%s: skipping corrupt entry at offset %d: %s\n", path, r.Count(), err)
+			break
+		}
+
+		switch t := entry.(type) {
+		case *tsm1.WriteWALEntry:
+			if err := cache.WriteMulti(t.Values); err != nil {
+				return err
+			}
+		case *tsm1.DeleteWALEntry:
+			cache.Delete(t.Keys)
+		}
+	}
+	return nil
+}
+
+// writeValues writes the values of a series field as line protocol.
+func (e *exporter) writeValues(w io.Writer, key string, values []tsm1.Value) error {
+	series, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+	prefix := series + " " + escape.String(field) + "="
+
+	buf := make([]byte, 0, 256)
+	for _, v := range values {
+		ts := v.UnixNano()
+		if ts < e.startTime || ts > e.endTime {
+			continue
+		}
+
+		buf = append(buf[:0], prefix...)
+		switch v := v.Value().(type) {
+		case float64:
+			buf = strconv.AppendFloat(buf, v, 'g', -1, 64)
+		case int64:
+			buf = strconv.AppendInt(buf, v, 10)
+			buf = append(buf, 'i')
+		case bool:
+			buf = strconv.AppendBool(buf, v)
+		case string:
+			buf = append(buf, '"')
+			buf = append(buf, escapeStringField(v)...)
+			buf = append(buf, '"')
+		default:
+			return fmt.Errorf("%s: unknown value type %T", key, v)
+		}
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, ts, 10)
+		buf = append(buf, '\n')
+
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeStringField escapes backslashes and double quotes in a string field value.
+func escapeStringField(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// quoteIdent double quotes an identifier for use in a statement.
+func quoteIdent(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// splitKey returns the database and retention policy of a shard key.
+func splitKey(key string) (string, string) {
+	i := strings.Index(key, "/")
+	return key[:i], key[i+1:]
+}
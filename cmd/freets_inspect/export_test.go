@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/freetsdb/freetsdb/importer/v8"
+	"github.com/freetsdb/freetsdb/tsdb/engine/tsm1"
+	"github.com/golang/snappy"
+)
+
+// Ensure the TSM and WAL data of a shard is exported as line protocol.
+func TestExporter_Export(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	MustWriteTSMFile(filepath.Join(dir, "data", "db0", "rp0", "1"), map[string][]tsm1.Value{
+		"cpu,host=A#!~#value": {tsm1.NewValue(1000000000, 1.5), tsm1.NewValue(2000000000, 2.5)},
+		"cpu,host=A#!~#count": {tsm1.NewValue(1000000000, int64(10))},
+	})
+	MustWriteWALFile(filepath.Join(dir, "wal", "db0", "rp0", "1", "_00001.wal"),
+		&tsm1.WriteWALEntry{Values: map[string][]tsm1.Value{
+			"cpu,host=A#!~#value": {tsm1.NewValue(3000000000, 3.5)},
+			"mem,host=A#!~#ok":    {tsm1.NewValue(3000000000, true)},
+		}},
+	)
+	MustWriteWALFile(filepath.Join(dir, "wal", "db0", "rp0", "1", "_00002.wal"),
+		&tsm1.WriteWALEntry{Values: map[string][]tsm1.Value{
+			"cpu,host=A#!~#value": {tsm1.NewValue(4000000000, 4.5)},
+		}},
+	)
+
+	if out := MustExport(dir, &exportOpts{}); out != `# DDL
+CREATE DATABASE "db0" WITH NAME "rp0"
+
+# DML
+# CONTEXT-DATABASE:db0
+# CONTEXT-RETENTION-POLICY:rp0
+cpu,host=A count=10i 1000000000
+cpu,host=A value=1.5 1000000000
+cpu,host=A value=2.5 2000000000
+cpu,host=A value=3.5 3000000000
+cpu,host=A value=4.5 4000000000
+mem,host=A ok=true 3000000000
+` {
+		t.Fatalf("unexpected export:\n%s", out)
+	}
+}
+
+// Ensure the export is limited to the requested database, retention policy
+// and time range.
+func TestExporter_Export_Filter(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	for _, path := range []string{"db0/rp0/1", "db0/rp1/2", "db1/rp0/3"} {
+		MustWriteTSMFile(filepath.Join(dir, "data", path), map[string][]tsm1.Value{
+			"cpu#!~#value": {tsm1.NewValue(0, 1.0), tsm1.NewValue(60000000000, 2.0), tsm1.NewValue(120000000000, 3.0)},
+		})
+		MustWriteWALFile(filepath.Join(dir, "wal", path, "_00001.wal"),
+			&tsm1.WriteWALEntry{Values: map[string][]tsm1.Value{
+				"mem#!~#value": {tsm1.NewValue(0, 1.0), tsm1.NewValue(60000000000, 2.0), tsm1.NewValue(120000000000, 3.0)},
+			}},
+		)
+	}
+
+	if out := MustExport(dir, &exportOpts{
+		database:        "db0",
+		retentionPolicy: "rp1",
+		start:           "1970-01-01T00:01:00Z",
+		end:             "1970-01-01T00:01:00Z",
+	}); out != `# DDL
+CREATE DATABASE "db0" WITH NAME "rp1"
+
+# DML
+# CONTEXT-DATABASE:db0
+# CONTEXT-RETENTION-POLICY:rp1
+cpu value=2 60000000000
+mem value=2 60000000000
+` {
+		t.Fatalf("unexpected export:\n%s", out)
+	}
+
+	// Every retention policy of the database is exported without -retention.
+	if out := MustExport(dir, &exportOpts{database: "db0", start: "1970-01-01T00:02:00Z"}); out != `# DDL
+CREATE DATABASE "db0" WITH NAME "rp0"
+CREATE DATABASE "db0" WITH NAME "rp1"
+
+# DML
+# CONTEXT-DATABASE:db0
+# CONTEXT-RETENTION-POLICY:rp0
+cpu value=3 120000000000
+mem value=3 120000000000
+# CONTEXT-DATABASE:db0
+# CONTEXT-RETENTION-POLICY:rp1
+cpu value=3 120000000000
+mem value=3 120000000000
+` {
+		t.Fatalf("unexpected export:\n%s", out)
+	}
+}
+
+// Ensure a delete in the WAL removes the earlier writes of its shard only.
+func TestExporter_Export_WALDelete(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	MustWriteWALFile(filepath.Join(dir, "wal", "db0", "rp0", "1", "_00001.wal"),
+		&tsm1.WriteWALEntry{Values: map[string][]tsm1.Value{
+			"cpu,host=A#!~#value": {tsm1.NewValue(1, 1.0)},
+			"cpu,host=B#!~#value": {tsm1.NewValue(1, 2.0)},
+		}},
+	)
+	MustWriteWALFile(filepath.Join(dir, "wal", "db0", "rp0", "1", "_00002.wal"),
+		&tsm1.DeleteWALEntry{Keys: []string{"cpu,host=A#!~#value"}},
+		&tsm1.WriteWALEntry{Values: map[string][]tsm1.Value{
+			"cpu,host=A#!~#value": {tsm1.NewValue(3, 3.0)},
+		}},
+	)
+	MustWriteWALFile(filepath.Join(dir, "wal", "db0", "rp0", "2", "_00001.wal"),
+		&tsm1.WriteWALEntry{Values: map[string][]tsm1.Value{
+			"cpu,host=A#!~#value": {tsm1.NewValue(2, 4.0)},
+		}},
+	)
+
+	if out := MustExport(dir, &exportOpts{}); out != `# DDL
+CREATE DATABASE "db0" WITH NAME "rp0"
+
+# DML
+# CONTEXT-DATABASE:db0
+# CONTEXT-RETENTION-POLICY:rp0
+cpu,host=A value=3 3
+cpu,host=B value=2 1
+cpu,host=A value=4 2
+` {
+		t.Fatalf("unexpected export:\n%s", out)
+	}
+}
+
+// Ensure field keys and string field values are escaped.
+func TestExporter_Export_Escape(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	MustWriteTSMFile(filepath.Join(dir, "data", "db0", "rp0", "1"), map[string][]tsm1.Value{
+		`cpu,host=A#!~#idle time,pct=x`: {tsm1.NewValue(1, 1.0)},
+		`cpu,host=A#!~#msg`:             {tsm1.NewValue(1, `say "hi" \ bye`)},
+	})
+
+	if out := MustExport(dir, &exportOpts{}); out != `# DDL
+CREATE DATABASE "db0" WITH NAME "rp0"
+
+# DML
+# CONTEXT-DATABASE:db0
+# CONTEXT-RETENTION-POLICY:rp0
+cpu,host=A idle\ time\,pct\=x=1 1
+cpu,host=A msg="say \"hi\" \\ bye" 1
+` {
+		t.Fatalf("unexpected export:\n%s", out)
+	}
+}
+
+// Ensure an export can be imported into the databases and retention policies
+// it was exported from.
+func TestExporter_Export_Import(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	MustWriteTSMFile(filepath.Join(dir, "data", "db0", "rp0", "1"), map[string][]tsm1.Value{
+		"cpu#!~#value": {tsm1.NewValue(1, 1.0)},
+	})
+	MustWriteTSMFile(filepath.Join(dir, "data", "db1", "rp1", "2"), map[string][]tsm1.Value{
+		"mem#!~#value": {tsm1.NewValue(2, 2.0)},
+	})
+
+	opts := &exportOpts{
+		dataDir: filepath.Join(dir, "data"),
+		walDir:  filepath.Join(dir, "wal"),
+		out:     filepath.Join(dir, "export"),
+	}
+	MustMkdirAll(opts.walDir)
+	if err := newExporter(opts).run(); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var queries []string
+	writes := make(map[string]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/query":
+			queries = append(queries, r.URL.Query().Get("q"))
+			w.WriteHeader(http.StatusNoContent)
+		case "/ping":
+			w.WriteHeader(http.StatusNoContent)
+		case "/write":
+			b, _ := ioutil.ReadAll(r.Body)
+			writes[r.URL.Query().Get("db")+"/"+r.URL.Query().Get("rp")] += string(b)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	config := v8.NewConfig()
+	config.URL = *u
+	config.Path = opts.out
+	if err := v8.NewImporter(config).Import(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if exp := []string{`CREATE DATABASE "db0" WITH NAME "rp0"`, `CREATE DATABASE "db1" WITH NAME "rp1"`}; fmt.Sprint(queries) != fmt.Sprint(exp) {
+		t.Fatalf("unexpected queries: %q", queries)
+	} else if writes["db0/rp0"] != "cpu value=1 1" || writes["db1/rp1"] != "mem value=2 2" || len(writes) != 2 {
+		t.Fatalf("unexpected writes: %q", writes)
+	}
+}
+
+// MustExport exports the data and WAL directories in dir with opts and
+// returns the export. Panic on error.
+func MustExport(dir string, opts *exportOpts) string {
+	opts.dataDir = filepath.Join(dir, "data")
+	opts.walDir = filepath.Join(dir, "wal")
+	opts.out = filepath.Join(dir, "export")
+	MustMkdirAll(opts.dataDir, opts.walDir)
+
+	if err := newExporter(opts).run(); err != nil {
+		panic(err)
+	}
+	b, err := ioutil.ReadFile(opts.out)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// MustTempDir returns a new temporary directory. Panic on error.
+func MustTempDir() string {
+	dir, err := ioutil.TempDir("", "freets_inspect-")
+	if err != nil {
+		panic(err)
+	}
+	return dir
+}
+
+// MustMkdirAll creates the directories in paths. Panic on error.
+func MustMkdirAll(paths ...string) {
+	for _, path := range paths {
+		if err := os.MkdirAll(path, 0777); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// MustWriteTSMFile writes values to a TSM file in the shard directory dir.
+// Panic on error.
+func MustWriteTSMFile(dir string, values map[string][]tsm1.Value) {
+	MustMkdirAll(dir)
+	f, err := os.Create(filepath.Join(dir, fmt.Sprintf("%09d-%09d.%s", 1, 1, tsm1.TSMFileExtension)))
+	if err != nil {
+		panic(err)
+	}
+
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		panic(err)
+	}
+
+	// Keys must be written in order.
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := w.Write(k, values[k]); err != nil {
+			panic(err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		panic(err)
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+}
+
+// MustWriteWALFile writes entries to the WAL segment at path. Panic on error.
+func MustWriteWALFile(path string, entries ...tsm1.WALEntry) {
+	MustMkdirAll(filepath.Dir(path))
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+
+	w := tsm1.NewWALSegmentWriter(f)
+	for _, entry := range entries {
+		b, err := entry.Encode(make([]byte, 4096))
+		if err != nil {
+			panic(err)
+		}
+		if err := w.Write(entry.Type(), snappy.Encode(nil, b)); err != nil {
+			panic(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		panic(err)
+	}
+}
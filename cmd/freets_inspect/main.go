@@ -17,7 +17,8 @@ Displays detailed information about FreeTSDB data files.
 	println(`Commands:
   info - displays series meta-data for all shards.  Default location [$HOME/.freetsdb]
   dumptsm - dumps low-level details about tsm1 files.
-  dumptsmdev - dumps low-level details about tsm1dev files.`)
+  dumptsmdev - dumps low-level details about tsm1dev files.
+  export - exports tsm1 data as line protocol for import with freets.`)
 	println()
 }
 
@@ -113,6 +114,35 @@ func main() {
 		opts.dumpBlocks = opts.dumpBlocks || dumpAll || opts.filterKey != ""
 		opts.dumpIndex = opts.dumpIndex || dumpAll || opts.filterKey != ""
 		cmdDumpTsm1dev(opts)
+	case "export":
+		opts := &exportOpts{}
+		fs := flag.NewFlagSet("export", flag.ExitOnError)
+		fs.StringVar(&opts.dataDir, "datadir", os.Getenv("HOME")+"/.freetsdb/data", "Data storage path. [$HOME/.freetsdb/data]")
+		fs.StringVar(&opts.walDir, "waldir", os.Getenv("HOME")+"/.freetsdb/wal", "WAL storage path. [$HOME/.freetsdb/wal]")
+		fs.StringVar(&opts.out, "out", os.Getenv("HOME")+"/.freetsdb/export", "Destination file to export to, or - for stdout. [$HOME/.freetsdb/export]")
+		fs.StringVar(&opts.database, "database", "", "Optional: the database to export")
+		fs.StringVar(&opts.retentionPolicy, "retention", "", "Optional: the retention policy to export (requires -database)")
+		fs.StringVar(&opts.start, "start", "", "Optional: the start time to export (RFC3339 format)")
+		fs.StringVar(&opts.end, "end", "", "Optional: the end time to export (RFC3339 format)")
+		fs.BoolVar(&opts.compress, "compress", false, "Compress the output with gzip")
+
+		fs.Usage = func() {
+			println("Usage: freets_inspect export [options]\n\n   Exports tsm1 data as line protocol, for import with freets -import.")
+			println()
+			println("Options:")
+			fs.PrintDefaults()
+		}
+
+		if err := fs.Parse(flag.Args()[1:]); err != nil {
+			fmt.Printf("%v", err)
+			os.Exit(1)
+		}
+		if opts.retentionPolicy != "" && opts.database == "" {
+			fmt.Printf("must specify a database with -retention\n\n")
+			fs.Usage()
+			os.Exit(1)
+		}
+		cmdExport(opts)
 	default:
 		flag.Usage()
 		os.Exit(1)
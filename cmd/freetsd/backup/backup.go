@@ -1,7 +1,11 @@
 package backup
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -12,9 +16,11 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 
+	"github.com/freetsdb/freetsdb"
+	"github.com/freetsdb/freetsdb/services/meta"
 	"github.com/freetsdb/freetsdb/services/snapshotter"
 	"github.com/freetsdb/freetsdb/tcp"
 )
@@ -29,8 +35,18 @@ const (
 	// BackupFilePattern is the beginning of the pattern for a backup
 	// file. They follow the scheme <database>.<retention>.<shardID>.<increment>
 	BackupFilePattern = "%s.%s.%05d"
+
+	// PortableShardExt is the extension of shard files in a portable backup.
+	// Their names follow the scheme <time>.<database>.<retention>.<shardID>.tar.gz
+	PortableShardExt = ".tar.gz"
 )
 
+// DefaultConcurrency is the default number of shards streamed at once.
+const DefaultConcurrency = 4
+
+// nodeStatusTimeout is how long a data node has to report the shards it holds.
+const nodeStatusTimeout = 10 * time.Second
+
 // Command represents the program execution for "freetsd backup".
 type Command struct {
 	// The logger passed to the ticker during execution.
@@ -40,9 +56,15 @@ type Command struct {
 	Stderr io.Writer
 	Stdout io.Writer
 
-	host     string
-	path     string
-	database string
+	host        string
+	path        string
+	database    string
+	concurrency int
+
+	// Portable backups prefix every file with the backup's start time
+	// instead of numbering increments, and compress shards.
+	portable bool
+	prefix   string
 }
 
 // NewCommand returns a new instance of Command with default settings.
@@ -64,22 +86,7 @@ func (cmd *Command) Run(args ...string) error {
 		return err
 	}
 
-	// based on the arguments passed in we only backup the minimum
-	if shardID != "" {
-		// always backup the metastore
-		if err := cmd.backupMetastore(); err != nil {
-			return err
-		}
-		err = cmd.backupShard(retentionPolicy, shardID, since)
-	} else if retentionPolicy != "" {
-		err = cmd.backupRetentionPolicy(retentionPolicy, since)
-	} else if cmd.database != "" {
-		err = cmd.backupDatabase(since)
-	} else {
-		err = cmd.backupMetastore()
-	}
-
-	if err != nil {
+	if err := cmd.backup(retentionPolicy, shardID, since); err != nil {
 		cmd.Logger.Printf("backup failed: %v", err)
 		return err
 	}
@@ -97,6 +104,8 @@ func (cmd *Command) parseFlags(args []string) (retentionPolicy, shardID string,
 	fs.StringVar(&cmd.database, "database", "", "")
 	fs.StringVar(&retentionPolicy, "retention", "", "")
 	fs.StringVar(&shardID, "shard", "", "")
+	fs.IntVar(&cmd.concurrency, "concurrency", DefaultConcurrency, "")
+	fs.BoolVar(&cmd.portable, "portable", false, "")
 	var sinceArg string
 	fs.StringVar(&sinceArg, "since", "", "")
 
@@ -113,6 +122,9 @@ func (cmd *Command) parseFlags(args []string) (retentionPolicy, shardID string,
 			return
 		}
 	}
+	if cmd.concurrency < 1 {
+		return "", "", time.Unix(0, 0), errors.New("concurrency must be at least 1")
+	}
 
 	// Ensure that only one arg is specified.
 	if fs.NArg() == 0 {
@@ -127,98 +139,408 @@ func (cmd *Command) parseFlags(args []string) (retentionPolicy, shardID string,
 	return
 }
 
-// backupShard will write a tar archive of the passed in shard with any TSM files that have been
-// created since the time passed in
-func (cmd *Command) backupShard(retentionPolicy string, shardID string, since time.Time) error {
-	id, err := strconv.ParseUint(shardID, 10, 64)
+// backup backs up the metastore and then every shard in the cluster selected
+// by the database, retention policy and shard arguments. The shards are
+// selected using the metastore snapshot, so the backup is consistent with a
+// single meta index even if shards are created while it is running.
+func (cmd *Command) backup(retentionPolicy, shardID string, since time.Time) error {
+	m := &Manifest{
+		Version:   ManifestVersion,
+		Time:      time.Now().UTC(),
+		Since:     since,
+		Databases: []ManifestDatabase{},
+		Shards:    []ManifestShard{},
+	}
+
+	var prev *Manifest
+	if cmd.portable {
+		cmd.prefix = m.Time.Format(PortableTimeFormat)
+
+		var err error
+		if m.Previous, prev, err = cmd.previousManifest(since); err != nil {
+			return err
+		}
+	}
+
+	// always backup the metastore
+	data, metaFile, err := cmd.backupMetastore()
 	if err != nil {
 		return err
 	}
+	m.ClusterID, m.MetaIndex, m.Meta = data.ClusterID, data.Index, *metaFile
+
+	if prev != nil && prev.ClusterID != m.ClusterID {
+		return errors.New("previous backup is from a different cluster")
+	}
 
-	shardArchivePath, err := cmd.nextPath(filepath.Join(cmd.path, fmt.Sprintf(BackupFilePattern, cmd.database, retentionPolicy, id)))
+	if cmd.database != "" {
+		shards, err := cmd.selectShards(data, retentionPolicy, shardID)
+		if err != nil {
+			return err
+		}
+		m.Databases = append(m.Databases, newManifestDatabase(data.Database(cmd.database)))
+
+		cmd.Logger.Printf("backing up %d shards in db=%s from meta index %d since %s",
+			len(shards), cmd.database, data.Index, since)
+
+		if m.Shards, err = cmd.backupShards(data, shards, since); err != nil {
+			return err
+		}
+	}
+
+	manifestPath, err := cmd.archivePath(Manifestfile)
 	if err != nil {
 		return err
 	}
+	cmd.Logger.Printf("writing manifest to %s", manifestPath)
+	return writeManifest(m, manifestPath)
+}
+
+// previousManifest returns the manifest of the backup an incremental portable
+// backup is based on, which is the most recent backup in the path. Backups
+// not taken since a time are never incremental.
+func (cmd *Command) previousManifest(since time.Time) (*ManifestFile, *Manifest, error) {
+	if since.IsZero() {
+		return nil, nil, nil
+	}
 
-	cmd.Logger.Printf("backing up db=%v rp=%v shard=%v to %s since %s",
-		cmd.database, retentionPolicy, shardID, shardArchivePath, since)
+	path, err := LatestManifest(cmd.path)
+	if err == ErrManifestNotFound {
+		return nil, nil, errors.New("incremental portable backup requires a previous portable backup in the path")
+	} else if err != nil {
+		return nil, nil, err
+	}
 
-	req := &snapshotter.Request{
-		Type:            snapshotter.RequestShardBackup,
-		Database:        cmd.database,
-		RetentionPolicy: retentionPolicy,
-		ShardID:         id,
-		Since:           since,
+	prev, err := ReadManifest(path)
+	if err != nil {
+		return nil, nil, err
+	} else if since.After(prev.Time) {
+		return nil, nil, fmt.Errorf("since %s is after the previous backup at %s, data would be missed", since, prev.Time)
+	}
+
+	f, err := checksumFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, prev, nil
+}
+
+// selectShards returns the shards in the database matching the retention
+// policy and shard arguments, if specified.
+func (cmd *Command) selectShards(data *meta.Data, retentionPolicy, shardID string) ([]ManifestShard, error) {
+	var id uint64
+	if shardID != "" {
+		if retentionPolicy == "" {
+			return nil, errors.New("retention policy required to backup a shard")
+		}
+
+		var err error
+		if id, err = strconv.ParseUint(shardID, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	db := data.Database(cmd.database)
+	if db == nil {
+		return nil, freetsdb.ErrDatabaseNotFound(cmd.database)
+	} else if retentionPolicy != "" && db.RetentionPolicy(retentionPolicy) == nil {
+		return nil, freetsdb.ErrRetentionPolicyNotFound(retentionPolicy)
+	}
+
+	var shards []ManifestShard
+	for _, rp := range db.RetentionPolicies {
+		if retentionPolicy != "" && rp.Name != retentionPolicy {
+			continue
+		}
+		for _, sg := range rp.ShardGroups {
+			if sg.Deleted() {
+				continue
+			}
+			for _, sh := range sg.Shards {
+				if id != 0 && sh.ID != id {
+					continue
+				}
+				shards = append(shards, ManifestShard{
+					Database:        db.Name,
+					RetentionPolicy: rp.Name,
+					ShardID:         sh.ID,
+					owners:          sh.Owners,
+				})
+			}
+		}
 	}
 
-	// TODO: verify shard backup data
-	return cmd.downloadAndVerify(req, shardArchivePath, nil)
+	if id != 0 && len(shards) == 0 {
+		return nil, fmt.Errorf("shard not found: %d", id)
+	}
+	return shards, nil
 }
 
-// backupDatabase will request the database information from the server and then backup the metastore and
-// every shard in every retention policy in the database. Each shard will be written to a separate tar.
-func (cmd *Command) backupDatabase(since time.Time) error {
-	cmd.Logger.Printf("backing up db=%s since %s", cmd.database, since)
+// backupShards streams the shards from their healthy owners, up to the
+// configured number at once, and returns them as they should appear in the
+// manifest. Shards no owner holds have no data and are left out.
+func (cmd *Command) backupShards(data *meta.Data, shards []ManifestShard, since time.Time) ([]ManifestShard, error) {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   []error
+		ch     = make(chan int)
+		result = make([]*ManifestShard, len(shards))
+		nodes  = cmd.nodeShards(data, shards)
+	)
+
+	for i := 0; i < cmd.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ch {
+				sh, err := cmd.backupShard(data, nodes, shards[i], since)
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+					continue
+				}
+				result[i] = sh
+			}
+		}()
+	}
 
-	req := &snapshotter.Request{
+	for i := range shards {
+		ch <- i
+	}
+	close(ch)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	a := make([]ManifestShard, 0, len(result))
+	for _, sh := range result {
+		if sh != nil {
+			a = append(a, *sh)
+		}
+	}
+	return a, nil
+}
+
+// nodeShards returns the shards of the database held by each data node that
+// owns one of the shards. Nodes that can't be reached are left out, so shards
+// are only streamed from healthy owners.
+func (cmd *Command) nodeShards(data *meta.Data, shards []ManifestShard) map[uint64]map[uint64]struct{} {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		nodes = make(map[uint64]map[uint64]struct{})
+		seen  = make(map[uint64]struct{})
+	)
+
+	for _, sh := range shards {
+		for _, owner := range sh.owners {
+			if _, ok := seen[owner.NodeID]; ok {
+				continue
+			}
+			seen[owner.NodeID] = struct{}{}
+
+			n := data.DataNode(owner.NodeID)
+			if n == nil {
+				continue
+			}
+
+			wg.Add(1)
+			go func(n *meta.NodeInfo) {
+				defer wg.Done()
+				ids, err := cmd.nodeStatus(n.TCPHost)
+				if err != nil {
+					cmd.Logger.Printf("skipping unreachable node %d (%s): %v", n.ID, n.TCPHost, err)
+					return
+				}
+
+				mu.Lock()
+				nodes[n.ID] = ids
+				mu.Unlock()
+			}(n)
+		}
+	}
+	wg.Wait()
+
+	return nodes
+}
+
+// nodeStatus returns the IDs of the shards of the database held by the data
+// node at host.
+func (cmd *Command) nodeStatus(host string) (map[uint64]struct{}, error) {
+	conn, err := tcp.DialTimeout("tcp", host, snapshotter.MuxHeader, nodeStatusTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(nodeStatusTimeout))
+
+	if err := json.NewEncoder(conn).Encode(&snapshotter.Request{
 		Type:     snapshotter.RequestDatabaseInfo,
 		Database: cmd.database,
+	}); err != nil {
+		return nil, fmt.Errorf("encode database info request: %s", err)
 	}
 
-	response, err := cmd.requestInfo(req)
-	if err != nil {
-		return err
+	var resp snapshotter.Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("decode database info: %s", err)
 	}
 
-	return cmd.backupResponsePaths(response, since)
+	ids := make(map[uint64]struct{}, len(resp.Paths))
+	for _, path := range resp.Paths {
+		id, err := strconv.ParseUint(filepath.Base(path), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shard path: %s", path)
+		}
+		ids[id] = struct{}{}
+	}
+	return ids, nil
 }
 
-// backupRetentionPolicy will request the retention policy information from the server and then backup
-// the metastore and every shard in the retention policy. Each shard will be written to a separate tar.
-func (cmd *Command) backupRetentionPolicy(retentionPolicy string, since time.Time) error {
-	cmd.Logger.Printf("backing up rp=%s since %s", retentionPolicy, since)
+// backupShard will write a tar archive of the shard with any TSM files that have been
+// created since the time passed in. The shard is streamed from the first of its
+// healthy owners to send a complete archive. A nil shard is returned if every
+// owner is reachable and none holds the shard, since it was never written to.
+func (cmd *Command) backupShard(data *meta.Data, nodes map[uint64]map[uint64]struct{}, sh ManifestShard, since time.Time) (*ManifestShard, error) {
+	owners := rotateOwners(sh.ShardID, sh.owners, nodes)
+	if len(owners) == 0 {
+		for _, owner := range sh.owners {
+			if _, ok := nodes[owner.NodeID]; !ok {
+				return nil, fmt.Errorf("no healthy owner of shard %d", sh.ShardID)
+			}
+		}
+		cmd.Logger.Printf("skipping shard %d, no owner holds it", sh.ShardID)
+		return nil, nil
+	}
+
+	name := fmt.Sprintf(BackupFilePattern, sh.Database, sh.RetentionPolicy, sh.ShardID)
+	if cmd.portable {
+		name += PortableShardExt
+	}
+	shardArchivePath, err := cmd.archivePath(name)
+	if err != nil {
+		return nil, err
+	}
 
 	req := &snapshotter.Request{
-		Type:            snapshotter.RequestRetentionPolicyInfo,
-		Database:        cmd.database,
-		RetentionPolicy: retentionPolicy,
+		Type:            snapshotter.RequestShardBackup,
+		Database:        sh.Database,
+		RetentionPolicy: sh.RetentionPolicy,
+		ShardID:         sh.ShardID,
+		Since:           since,
 	}
 
-	response, err := cmd.requestInfo(req)
-	if err != nil {
-		return err
+	for _, owner := range owners {
+		n := data.DataNode(owner.NodeID)
+
+		cmd.Logger.Printf("backing up db=%v rp=%v shard=%v from node %d (%s) to %s since %s",
+			sh.Database, sh.RetentionPolicy, sh.ShardID, n.ID, n.TCPHost, shardArchivePath, since)
+
+		f, err := cmd.downloadAndVerify(n.TCPHost, req, shardArchivePath, cmd.portable, func(file string) error {
+			return verifyShardArchive(file, cmd.portable)
+		})
+		if err != nil {
+			cmd.Logger.Printf("failed to backup shard %d from node %d: %v", sh.ShardID, n.ID, err)
+			continue
+		}
+
+		sh.NodeID = n.ID
+		sh.ManifestFile = *f
+		sh.owners = nil
+		return &sh, nil
 	}
 
-	return cmd.backupResponsePaths(response, since)
+	return nil, fmt.Errorf("no owner of shard %d available", sh.ShardID)
+}
+
+// rotateOwners returns the healthy owners of a shard, the owners reachable
+// and holding it according to nodes. They start at a different owner for
+// each shard so the load is spread across replicas.
+func rotateOwners(id uint64, owners []meta.ShardOwner, nodes map[uint64]map[uint64]struct{}) []meta.ShardOwner {
+	var healthy []meta.ShardOwner
+	for _, owner := range owners {
+		if _, ok := nodes[owner.NodeID][id]; ok {
+			healthy = append(healthy, owner)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	i := int(id % uint64(len(healthy)))
+	return append(append([]meta.ShardOwner{}, healthy[i:]...), healthy[:i]...)
 }
 
-// backupResponsePaths will backup the metastore and all shard paths in the response struct
-func (cmd *Command) backupResponsePaths(response *snapshotter.Response, since time.Time) error {
-	if err := cmd.backupMetastore(); err != nil {
+// verifyShardArchive returns an error unless the file holds a complete tar
+// archive of a shard. The archive is cut short if the owner fails while
+// streaming it, which otherwise looks like a successful download.
+func verifyShardArchive(path string, compressed bool) error {
+	f, err := os.Open(path)
+	if err != nil {
 		return err
 	}
+	defer f.Close()
 
-	// loop through the returned paths and back up each shard
-	for _, path := range response.Paths {
-		rp, id, err := retentionAndShardFromPath(path)
+	var r io.Reader = f
+	if compressed {
+		gz, err := gzip.NewReader(f)
 		if err != nil {
-			return err
+			return fmt.Errorf("invalid shard archive: %s", err)
 		}
+		defer gz.Close()
+		r = gz
+	}
 
-		if err := cmd.backupShard(rp, id, since); err != nil {
-			return err
+	zr := &zeroTailReader{r: r}
+	tr := tar.NewReader(zr)
+	for {
+		if _, err := tr.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("invalid shard archive: %s", err)
 		}
+		if _, err := io.Copy(ioutil.Discard, tr); err != nil {
+			return fmt.Errorf("invalid shard archive: %s", err)
+		}
+	}
+	if _, err := io.Copy(ioutil.Discard, zr); err != nil {
+		return fmt.Errorf("invalid shard archive: %s", err)
 	}
 
+	// A complete archive ends with two zero blocks.
+	if zr.zeros < 2*512 {
+		return errors.New("incomplete shard archive")
+	}
 	return nil
 }
 
-// backupMetastore will backup the metastore on the host to the passed in path. Database and retention policy backups
-// will force a backup of the metastore as well as requesting a specific shard backup from the command line
-func (cmd *Command) backupMetastore() error {
-	metastoreArchivePath, err := cmd.nextPath(filepath.Join(cmd.path, Metafile))
+// zeroTailReader counts the zero bytes at the end of the data read from r.
+type zeroTailReader struct {
+	r     io.Reader
+	zeros int
+}
+
+func (z *zeroTailReader) Read(p []byte) (int, error) {
+	n, err := z.r.Read(p)
+	for _, b := range p[:n] {
+		if b == 0 {
+			z.zeros++
+		} else {
+			z.zeros = 0
+		}
+	}
+	return n, err
+}
+
+// backupMetastore will backup the metastore on the host to the backup path and
+// return the meta data it contains.
+func (cmd *Command) backupMetastore() (*meta.Data, *ManifestFile, error) {
+	metastoreArchivePath, err := cmd.archivePath(Metafile)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	cmd.Logger.Printf("backing up metastore to %s", metastoreArchivePath)
@@ -227,20 +549,40 @@ func (cmd *Command) backupMetastore() error {
 		Type: snapshotter.RequestMetastoreBackup,
 	}
 
-	return cmd.downloadAndVerify(req, metastoreArchivePath, func(file string) error {
+	var data *meta.Data
+	f, err := cmd.downloadAndVerify(cmd.host, req, metastoreArchivePath, false, func(file string) error {
 		binData, err := ioutil.ReadFile(file)
 		if err != nil {
 			return err
 		}
 
-		magic := binary.BigEndian.Uint64(binData[:8])
-		if magic != snapshotter.BackupMagicHeader {
+		if len(binData) < 8 || binary.BigEndian.Uint64(binData[:8]) != snapshotter.BackupMagicHeader {
 			cmd.Logger.Println("Invalid metadata blob, ensure the metadata service is running (default port 8088)")
 			return errors.New("invalid metadata received")
 		}
 
-		return nil
+		data, _, err = ReadMetastore(file)
+		return err
 	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, f, nil
+}
+
+// archivePath returns the path to write the named file of the backup to.
+func (cmd *Command) archivePath(name string) (string, error) {
+	if !cmd.portable {
+		return cmd.nextPath(filepath.Join(cmd.path, name))
+	}
+
+	path := filepath.Join(cmd.path, cmd.prefix+"."+name)
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("backup file already exists: %s", path)
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	return path, nil
 }
 
 // nextPath returns the next file to write to.
@@ -256,12 +598,14 @@ func (cmd *Command) nextPath(path string) (string, error) {
 	}
 }
 
-// downloadAndVerify will download either the metastore or shard to a temp file and then
+// downloadAndVerify will download either the metastore or shard from host to a temp file and then
 // rename it to a good backup file name after complete
-func (cmd *Command) downloadAndVerify(req *snapshotter.Request, path string, validator func(string) error) error {
+func (cmd *Command) downloadAndVerify(host string, req *snapshotter.Request, path string, compress bool, validator func(string) error) (*ManifestFile, error) {
 	tmppath := path + Suffix
-	if err := cmd.download(req, tmppath); err != nil {
-		return err
+	f, err := cmd.download(host, req, tmppath, compress)
+	if err != nil {
+		os.Remove(tmppath)
+		return nil, err
 	}
 
 	if validator != nil {
@@ -269,79 +613,78 @@ func (cmd *Command) downloadAndVerify(req *snapshotter.Request, path string, val
 			if rmErr := os.Remove(tmppath); rmErr != nil {
 				cmd.Logger.Printf("Error cleaning up temporary file: %v", rmErr)
 			}
-			return err
+			return nil, err
 		}
 	}
 
 	// Rename temporary file to final path.
 	if err := os.Rename(tmppath, path); err != nil {
-		return fmt.Errorf("rename: %s", err)
+		return nil, fmt.Errorf("rename: %s", err)
 	}
+	f.FileName = filepath.Base(path)
 
-	return nil
+	return f, nil
 }
 
-// download downloads a snapshot of either the metastore or a shard from a host to a given path.
-func (cmd *Command) download(req *snapshotter.Request, path string) error {
+// download downloads a snapshot of either the metastore or a shard from a host to a given path,
+// gzip compressing it if requested. It returns the size and SHA-256 checksum of the file written.
+func (cmd *Command) download(host string, req *snapshotter.Request, path string, compress bool) (*ManifestFile, error) {
 	// Create local file to write to.
 	f, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("open temp file: %s", err)
+		return nil, fmt.Errorf("open temp file: %s", err)
 	}
 	defer f.Close()
 
 	// Connect to snapshotter service.
-	conn, err := tcp.Dial("tcp", cmd.host, snapshotter.MuxHeader)
+	conn, err := tcp.Dial("tcp", host, snapshotter.MuxHeader)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer conn.Close()
 
 	// Write the request
 	if err := json.NewEncoder(conn).Encode(req); err != nil {
-		return fmt.Errorf("encode snapshot request: %s", err)
+		return nil, fmt.Errorf("encode snapshot request: %s", err)
 	}
 
 	// Read snapshot from the connection
-	if _, err := io.Copy(f, conn); err != nil {
-		return fmt.Errorf("copy backup to file: %s", err)
+	h := sha256.New()
+	w := io.MultiWriter(f, h)
+	if !compress {
+		n, err := io.Copy(w, conn)
+		if err != nil {
+			return nil, fmt.Errorf("copy backup to file: %s", err)
+		}
+		return &ManifestFile{Size: n, Checksum: hex.EncodeToString(h.Sum(nil))}, nil
 	}
 
-	return nil
-}
-
-// requestInfo will request the database or retention policy information from the host
-func (cmd *Command) requestInfo(request *snapshotter.Request) (*snapshotter.Response, error) {
-	// Connect to snapshotter service.
-	conn, err := tcp.Dial("tcp", cmd.host, snapshotter.MuxHeader)
-	if err != nil {
-		return nil, err
+	gz := gzip.NewWriter(w)
+	if _, err := io.Copy(gz, conn); err != nil {
+		return nil, fmt.Errorf("copy backup to file: %s", err)
 	}
-	defer conn.Close()
-
-	// Write the request
-	if err := json.NewEncoder(conn).Encode(request); err != nil {
-		return nil, fmt.Errorf("encode snapshot request: %s", err)
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("compress backup: %s", err)
 	}
 
-	// Read the response
-	var r snapshotter.Response
-	if err := json.NewDecoder(conn).Decode(&r); err != nil {
+	n, err := f.Seek(0, os.SEEK_CUR)
+	if err != nil {
 		return nil, err
 	}
-
-	return &r, nil
+	return &ManifestFile{Size: n, Checksum: hex.EncodeToString(h.Sum(nil)), Compression: CompressionGzip}, nil
 }
 
 // printUsage prints the usage message to STDERR.
 func (cmd *Command) printUsage() {
 	fmt.Fprintf(cmd.Stdout, `usage: freetsd backup [flags] PATH
 
-Backup downloads a snapshot of a data node and saves it to disk.
+Backup downloads a snapshot of the cluster and saves it to disk. The metastore
+is downloaded from the host and each shard is streamed from one of its owners.
+A manifest describing the backup is written once all shards are downloaded.
 
 Options:
   -host <host:port>
-        The host to connect to snapshot. Defaults to 127.0.0.1:8088.
+        The host to download the metastore from. Defaults to 127.0.0.1:8088.
   -database <name>
         The database to backup.
   -retention <name>
@@ -351,17 +694,12 @@ Options:
   -since <2015-12-24T08:12:23>
         Optional. Do an incremental backup since the passed in RFC3339
         formatted time.
+  -concurrency <n>
+        Optional. The number of shards to download at once. Defaults to 4.
+  -portable
+        Optional. Write a portable backup. Every file is prefixed with the
+        time the backup started and shards are gzip compressed. With -since,
+        the backup is an increment on the most recent portable backup in PATH.
 
 `)
 }
-
-// retentionAndShardFromPath will take the shard relative path and split it into the
-// retention policy name and shard ID. The first part of the path should be the database name.
-func retentionAndShardFromPath(path string) (retention, shard string, err error) {
-	a := strings.Split(path, string(filepath.Separator))
-	if len(a) != 3 {
-		return "", "", fmt.Errorf("expected database, retention policy, and shard id in path: %s", path)
-	}
-
-	return a[1], a[2], nil
-}
@@ -0,0 +1,351 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/freetsdb/freetsdb/services/meta"
+	"github.com/freetsdb/freetsdb/services/snapshotter"
+)
+
+// Ensure shards are only streamed from the reachable owners holding them.
+func TestRotateOwners(t *testing.T) {
+	owners := []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}, {NodeID: 3}}
+	nodes := map[uint64]map[uint64]struct{}{
+		1: {10: {}, 11: {}},
+		3: {10: {}, 11: {}},
+	}
+
+	if a := rotateOwners(10, owners, nodes); !reflect.DeepEqual(a, []meta.ShardOwner{{NodeID: 1}, {NodeID: 3}}) {
+		t.Fatalf("unexpected owners of shard 10: %v", a)
+	}
+	if a := rotateOwners(11, owners, nodes); !reflect.DeepEqual(a, []meta.ShardOwner{{NodeID: 3}, {NodeID: 1}}) {
+		t.Fatalf("unexpected owners of shard 11: %v", a)
+	}
+	if a := rotateOwners(12, owners, nodes); a != nil {
+		t.Fatalf("unexpected owners of shard 12: %v", a)
+	}
+}
+
+// Ensure shard archives cut short while streaming are rejected.
+func TestVerifyShardArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freetsd-backup-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := MustShardArchive("db/rp/1/000000001-000000001.tsm", []byte("tsm data"))
+
+	for _, tt := range []struct {
+		name  string
+		data  []byte
+		valid bool
+	}{
+		{name: "complete", data: archive, valid: true},
+		{name: "empty", data: nil},
+		{name: "truncated header", data: archive[:100]},
+		{name: "truncated data", data: archive[:516]},
+		{name: "missing trailer", data: archive[:1024]},
+	} {
+		path := filepath.Join(dir, tt.name)
+		if err := ioutil.WriteFile(path, tt.data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := verifyShardArchive(path, false); tt.valid && err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.name, err)
+		} else if !tt.valid && err == nil {
+			t.Fatalf("%s: expected error", tt.name)
+		}
+	}
+
+	// Portable backups compress the archive.
+	var gzbuf bytes.Buffer
+	gz := gzip.NewWriter(&gzbuf)
+	if _, err := gz.Write(archive); err != nil {
+		t.Fatal(err)
+	} else if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "compressed")
+	if err := ioutil.WriteFile(path, gzbuf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	} else if err := verifyShardArchive(path, true); err != nil {
+		t.Fatalf("compressed: unexpected error: %s", err)
+	}
+}
+
+// Ensure shards are backed up from the healthy owners holding them, falling
+// back to another owner when one fails to send a complete archive.
+func TestCommand_Backup_Owners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freetsd-backup-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Node 1 holds every shard, node 2 is down and node 3 cuts the archive
+	// of shard 2 short.
+	archive1 := MustShardArchive("db0/rp0/1/000000001-000000001.tsm", []byte("shard 1"))
+	archive2 := MustShardArchive("db0/rp0/2/000000001-000000001.tsm", []byte("shard 2"))
+
+	s1 := NewSnapshotter()
+	defer s1.Close()
+	s1.Shards[1], s1.Shards[2] = archive1, archive2
+
+	s3 := NewSnapshotter()
+	defer s3.Close()
+	s3.Shards[2] = archive2[:512]
+
+	data := NewData(s1.Addr(), MustClosedAddr(), s3.Addr())
+	s1.Meta = data
+
+	cmd := NewCommand()
+	cmd.Stderr, cmd.Stdout = ioutil.Discard, ioutil.Discard
+	if err := cmd.Run("-host", s1.Addr(), "-database", "db0", dir); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := ReadManifest(filepath.Join(dir, Manifestfile+".00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.MetaIndex != data.Index || m.ClusterID != data.ClusterID {
+		t.Fatalf("unexpected meta index %d of cluster %d", m.MetaIndex, m.ClusterID)
+	} else if m.Meta.Checksum != checksum(MustMarshalMetastore(data)) {
+		t.Fatalf("unexpected metastore checksum: %s", m.Meta.Checksum)
+	} else if len(m.Shards) != 2 {
+		t.Fatalf("unexpected shards: %+v", m.Shards)
+	}
+
+	for i, archive := range [][]byte{archive1, archive2} {
+		sh := m.Shards[i]
+		if sh.ShardID != uint64(i+1) || sh.NodeID != 1 {
+			t.Fatalf("unexpected shard %d from node %d", sh.ShardID, sh.NodeID)
+		} else if sh.Checksum != checksum(archive) || sh.Size != int64(len(archive)) {
+			t.Fatalf("unexpected checksum of shard %d: %s", sh.ShardID, sh.Checksum)
+		}
+
+		if b, err := ioutil.ReadFile(filepath.Join(dir, sh.FileName)); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(b, archive) {
+			t.Fatalf("unexpected archive of shard %d", sh.ShardID)
+		}
+	}
+
+	// Shard 2 was requested from node 3 before falling back to node 1.
+	if !s3.Requested(snapshotter.RequestShardBackup, 2) {
+		t.Fatal("expected shard 2 to be requested from node 3")
+	}
+}
+
+// Ensure a backup fails when every owner of a shard is unreachable.
+func TestCommand_Backup_NoHealthyOwner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freetsd-backup-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s1 := NewSnapshotter()
+	defer s1.Close()
+	s1.Shards[1] = MustShardArchive("db0/rp0/1/000000001-000000001.tsm", []byte("shard 1"))
+	s1.Meta = NewData(s1.Addr(), MustClosedAddr(), MustClosedAddr())
+
+	cmd := NewCommand()
+	cmd.Stderr, cmd.Stdout = ioutil.Discard, ioutil.Discard
+	if err := cmd.Run("-host", s1.Addr(), "-database", "db1", dir); err == nil || !strings.Contains(err.Error(), "no healthy owner of shard 3") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// NewData returns meta data for data nodes at hosts. Shards 1 and 2 of db0
+// are owned by nodes 2 and 1, and nodes 3 and 1. Shard 3 of db1 is owned by
+// nodes 2 and 3.
+func NewData(hosts ...string) *meta.Data {
+	data := &meta.Data{Index: 10, ClusterID: 100}
+	for i, host := range hosts {
+		data.DataNodes = append(data.DataNodes, meta.NodeInfo{ID: uint64(i + 1), TCPHost: host})
+	}
+
+	for _, db := range []struct {
+		name   string
+		shards []meta.ShardInfo
+	}{
+		{name: "db0", shards: []meta.ShardInfo{
+			{ID: 1, Owners: []meta.ShardOwner{{NodeID: 2}, {NodeID: 1}}},
+			{ID: 2, Owners: []meta.ShardOwner{{NodeID: 3}, {NodeID: 1}}},
+		}},
+		{name: "db1", shards: []meta.ShardInfo{
+			{ID: 3, Owners: []meta.ShardOwner{{NodeID: 2}, {NodeID: 3}}},
+		}},
+	} {
+		data.Databases = append(data.Databases, meta.DatabaseInfo{
+			Name:                   db.name,
+			DefaultRetentionPolicy: "rp0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name:        "rp0",
+				ReplicaN:    2,
+				ShardGroups: []meta.ShardGroupInfo{{ID: 1, Shards: db.shards}},
+			}},
+		})
+	}
+	return data
+}
+
+// Snapshotter is a fake snapshotter service of a data node.
+type Snapshotter struct {
+	ln net.Listener
+	wg sync.WaitGroup
+
+	// Meta is sent in response to metastore backup requests.
+	Meta *meta.Data
+
+	// Shards holds the archive sent for each shard held by the node.
+	Shards map[uint64][]byte
+
+	mu       sync.Mutex
+	requests []snapshotter.Request
+}
+
+// NewSnapshotter returns a new, listening instance of Snapshotter.
+func NewSnapshotter() *Snapshotter {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Snapshotter{ln: ln, Shards: make(map[uint64][]byte)}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Addr returns the address of the snapshotter.
+func (s *Snapshotter) Addr() string { return s.ln.Addr().String() }
+
+// Close closes the listener and waits for open connections to be served.
+func (s *Snapshotter) Close() {
+	s.ln.Close()
+	s.wg.Wait()
+}
+
+// Requested returns true if a request of type typ was made for a shard.
+func (s *Snapshotter) Requested(typ snapshotter.RequestType, shardID uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, req := range s.requests {
+		if req.Type == typ && req.ShardID == shardID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Snapshotter) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handleConn(conn)
+		}()
+	}
+}
+
+func (s *Snapshotter) handleConn(conn net.Conn) {
+	var header [1]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil || header[0] != snapshotter.MuxHeader {
+		return
+	}
+
+	var req snapshotter.Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	switch req.Type {
+	case snapshotter.RequestMetastoreBackup:
+		conn.Write(MustMarshalMetastore(s.Meta))
+	case snapshotter.RequestDatabaseInfo:
+		var resp snapshotter.Response
+		for id := range s.Shards {
+			resp.Paths = append(resp.Paths, filepath.Join(req.Database, "rp0", strconv.FormatUint(id, 10)))
+		}
+		json.NewEncoder(conn).Encode(&resp)
+	case snapshotter.RequestShardBackup:
+		conn.Write(s.Shards[req.ShardID])
+	}
+}
+
+// MustMarshalMetastore returns data as sent by the snapshotter service in
+// response to metastore backup requests. Panic on error.
+func MustMarshalMetastore(data *meta.Data) []byte {
+	b, err := data.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint64(snapshotter.BackupMagicHeader))
+	binary.Write(&buf, binary.BigEndian, uint64(len(b)))
+	buf.Write(b)
+	binary.Write(&buf, binary.BigEndian, uint64(0))
+	return buf.Bytes()
+}
+
+// MustShardArchive returns a tar archive of a single file. Panic on error.
+func MustShardArchive(name string, data []byte) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data))}); err != nil {
+		panic(err)
+	} else if _, err := tw.Write(data); err != nil {
+		panic(err)
+	} else if err := tw.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// MustClosedAddr returns the address of a closed listener, where a node is
+// unreachable. Panic on error.
+func MustClosedAddr() string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// checksum returns the hex encoded SHA-256 checksum of b.
+func checksum(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
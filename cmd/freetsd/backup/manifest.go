@@ -0,0 +1,333 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/freetsdb/freetsdb/services/meta"
+	"github.com/freetsdb/freetsdb/services/snapshotter"
+)
+
+const (
+	// Manifestfile is the base name given to backup manifests.
+	Manifestfile = "manifest"
+
+	// ManifestVersion is the version of the manifest format written.
+	ManifestVersion = 1
+
+	// CompressionGzip marks a file in a backup as gzip compressed.
+	CompressionGzip = "gzip"
+)
+
+// PortableTimeFormat is the format of the time prefixing the name of every
+// file in a portable backup. It sorts in time order.
+const PortableTimeFormat = "20060102T150405Z"
+
+var (
+	// ErrManifestNotFound is returned when a backup has no portable manifest.
+	ErrManifestNotFound = errors.New("no portable backup manifest found")
+
+	// ErrChecksumMismatch is returned when a file in a backup is corrupt.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// Manifest describes a backup of the cluster. Every shard in the manifest
+// was selected from the metastore snapshot in the same backup, so the
+// backup is consistent with the meta index that snapshot was taken at.
+type Manifest struct {
+	Version   int       `json:"version"`
+	ClusterID uint64    `json:"clusterID"`
+	MetaIndex uint64    `json:"metaIndex"`
+	Time      time.Time `json:"time"`
+	Since     time.Time `json:"since"`
+
+	// Previous is the manifest of the backup an incremental backup is based on.
+	Previous *ManifestFile `json:"previous,omitempty"`
+
+	Databases []ManifestDatabase `json:"databases"`
+
+	Meta   ManifestFile    `json:"meta"`
+	Shards []ManifestShard `json:"shards"`
+}
+
+// ManifestFile describes a single file in a backup.
+type ManifestFile struct {
+	FileName    string `json:"fileName"`
+	Size        int64  `json:"size"`
+	Checksum    string `json:"checksum"`
+	Compression string `json:"compression,omitempty"`
+}
+
+// ManifestDatabase describes a database in a backup.
+type ManifestDatabase struct {
+	Name              string                    `json:"name"`
+	RetentionPolicies []ManifestRetentionPolicy `json:"retentionPolicies"`
+}
+
+// ManifestRetentionPolicy describes a retention policy in a backup.
+type ManifestRetentionPolicy struct {
+	Name               string               `json:"name"`
+	Duration           time.Duration        `json:"duration"`
+	ShardGroupDuration time.Duration        `json:"shardGroupDuration"`
+	ReplicaN           int                  `json:"replicaN"`
+	ShardGroups        []ManifestShardGroup `json:"shardGroups"`
+}
+
+// ManifestShardGroup describes the time range of a shard group in a backup.
+type ManifestShardGroup struct {
+	ID        uint64    `json:"id"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Shards    []uint64  `json:"shards"`
+}
+
+// newManifestDatabase returns the description of a database in the meta data.
+func newManifestDatabase(db *meta.DatabaseInfo) ManifestDatabase {
+	d := ManifestDatabase{Name: db.Name, RetentionPolicies: []ManifestRetentionPolicy{}}
+	for _, rp := range db.RetentionPolicies {
+		r := ManifestRetentionPolicy{
+			Name:               rp.Name,
+			Duration:           rp.Duration,
+			ShardGroupDuration: rp.ShardGroupDuration,
+			ReplicaN:           rp.ReplicaN,
+			ShardGroups:        []ManifestShardGroup{},
+		}
+		for _, sg := range rp.ShardGroups {
+			if sg.Deleted() {
+				continue
+			}
+			g := ManifestShardGroup{ID: sg.ID, StartTime: sg.StartTime, EndTime: sg.EndTime, Shards: []uint64{}}
+			for _, sh := range sg.Shards {
+				g.Shards = append(g.Shards, sh.ID)
+			}
+			r.ShardGroups = append(r.ShardGroups, g)
+		}
+		d.RetentionPolicies = append(d.RetentionPolicies, r)
+	}
+	return d
+}
+
+// Verify checks the file in dir matches the size and checksum it was
+// backed up with.
+func (f *ManifestFile) Verify(dir string) error {
+	mf, err := checksumFile(filepath.Join(dir, f.FileName))
+	if err != nil {
+		return err
+	} else if mf.Size != f.Size || mf.Checksum != f.Checksum {
+		return fmt.Errorf("%s: %s", f.FileName, ErrChecksumMismatch)
+	}
+	return nil
+}
+
+// checksumFile returns the description of the file at path.
+func checksumFile(path string) (*ManifestFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	return &ManifestFile{FileName: filepath.Base(path), Size: n, Checksum: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// LatestManifest returns the path of the most recent portable backup
+// manifest in dir.
+func LatestManifest(dir string) (string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*."+Manifestfile))
+	if err != nil {
+		return "", err
+	} else if len(paths) == 0 {
+		return "", ErrManifestNotFound
+	}
+	sort.Strings(paths)
+	return paths[len(paths)-1], nil
+}
+
+// ReadChain reads the most recent portable backup manifest in dir and the
+// manifests of the backups it is incremental to. The chain is returned oldest
+// first, once every link and every file it describes has been verified.
+func ReadChain(dir string) ([]*Manifest, error) {
+	path, err := LatestManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var chain []*Manifest
+	for {
+		m, err := ReadManifest(path)
+		if err != nil {
+			return nil, err
+		} else if m.Version != ManifestVersion {
+			return nil, fmt.Errorf("%s: unsupported manifest version: %d", filepath.Base(path), m.Version)
+		}
+		chain = append([]*Manifest{m}, chain...)
+
+		if m.Previous == nil {
+			if !m.Since.IsZero() {
+				return nil, fmt.Errorf("%s: incremental backup has no previous backup", filepath.Base(path))
+			}
+			break
+		}
+
+		// The previous manifest must be intact and cover everything up to
+		// the time this backup was taken since.
+		if err := m.Previous.Verify(dir); err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, m.Previous.FileName)
+		prev, err := ReadManifest(path)
+		if err != nil {
+			return nil, err
+		} else if prev.ClusterID != m.ClusterID {
+			return nil, fmt.Errorf("%s: backup is from a different cluster", m.Previous.FileName)
+		} else if m.Since.After(prev.Time) {
+			return nil, fmt.Errorf("%s: gap between backups from %s to %s", m.Previous.FileName, prev.Time, m.Since)
+		}
+	}
+
+	for _, m := range chain {
+		if err := m.Meta.Verify(dir); err != nil {
+			return nil, err
+		}
+		for i := range m.Shards {
+			if err := m.Shards[i].Verify(dir); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return chain, nil
+}
+
+// OpenArchive opens a shard archive in a backup, decompressing it if needed.
+func OpenArchive(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &archiveReader{Reader: gz, f: f}, nil
+}
+
+// archiveReader closes a compressed archive and its file together.
+type archiveReader struct {
+	*gzip.Reader
+	f *os.File
+}
+
+// Close closes the decompressor and the file.
+func (r *archiveReader) Close() error {
+	r.Reader.Close()
+	return r.f.Close()
+}
+
+// ManifestShard describes a shard in a backup and the data node it was
+// streamed from.
+type ManifestShard struct {
+	Database        string `json:"database"`
+	RetentionPolicy string `json:"retentionPolicy"`
+	ShardID         uint64 `json:"shardID"`
+	NodeID          uint64 `json:"nodeID"`
+	ManifestFile
+
+	owners []meta.ShardOwner
+}
+
+// ReadManifest reads a manifest from path.
+func ReadManifest(path string) (*Manifest, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return nil, fmt.Errorf("unmarshal manifest: %s", err)
+	}
+	return &m, nil
+}
+
+// writeManifest writes m to path via a temporary file, so a manifest is
+// only ever present for a complete backup.
+func writeManifest(m *Manifest, path string) error {
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmppath := path + Suffix
+	if err := ioutil.WriteFile(tmppath, buf, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmppath, path)
+}
+
+// ReadMetastore reads a metastore backup file, returning the meta data and
+// the node.json contents of the node it was taken from.
+func ReadMetastore(path string) (*meta.Data, []byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Make sure the file is actually a meta store backup file
+	if len(b) < 16 || binary.BigEndian.Uint64(b[:8]) != snapshotter.BackupMagicHeader {
+		return nil, nil, errors.New("invalid metadata file")
+	}
+	r := bytes.NewReader(b[8:])
+
+	// Size of the meta store bytes, followed by the size of the node.json bytes.
+	metaBytes, err := readBlock(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	nodeBytes, err := readBlock(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var data meta.Data
+	if err := data.UnmarshalBinary(metaBytes); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal: %s", err)
+	}
+	return &data, nodeBytes, nil
+}
+
+// readBlock reads a length prefixed block from r.
+func readBlock(r *bytes.Reader) ([]byte, error) {
+	var n uint64
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, errors.New("invalid metadata file")
+	} else if n > uint64(r.Len()) {
+		return nil, errors.New("invalid metadata file")
+	}
+
+	b := make([]byte, n)
+	r.Read(b)
+	return b, nil
+}
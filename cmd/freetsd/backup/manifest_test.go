@@ -0,0 +1,129 @@
+package backup_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freetsdb/freetsdb/cmd/freetsd/backup"
+)
+
+// Ensure a chain of portable backups is read oldest first.
+func TestReadChain(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	full := MustWriteBackup(dir, time.Unix(100, 0), time.Time{}, nil)
+	MustWriteBackup(dir, time.Unix(200, 0), time.Unix(100, 0), full)
+
+	chain, err := backup.ReadChain(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(chain) != 2 {
+		t.Fatalf("unexpected chain length: %d", len(chain))
+	} else if !chain[0].Time.Equal(time.Unix(100, 0)) || !chain[1].Time.Equal(time.Unix(200, 0)) {
+		t.Fatalf("unexpected chain order: %s, %s", chain[0].Time, chain[1].Time)
+	}
+}
+
+// Ensure a corrupt file anywhere in the chain is detected.
+func TestReadChain_ChecksumMismatch(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	full := MustWriteBackup(dir, time.Unix(100, 0), time.Time{}, nil)
+	MustWriteBackup(dir, time.Unix(200, 0), time.Unix(100, 0), full)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, full.Shards[0].FileName), []byte("corrupt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backup.ReadChain(dir); err == nil || !strings.Contains(err.Error(), backup.ErrChecksumMismatch.Error()) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure an incremental backup taken since after the previous backup is rejected.
+func TestReadChain_Gap(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	full := MustWriteBackup(dir, time.Unix(100, 0), time.Time{}, nil)
+	MustWriteBackup(dir, time.Unix(300, 0), time.Unix(200, 0), full)
+
+	if _, err := backup.ReadChain(dir); err == nil || !strings.Contains(err.Error(), "gap between backups") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure an incremental backup without its previous backup is rejected.
+func TestReadChain_MissingPrevious(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	MustWriteBackup(dir, time.Unix(200, 0), time.Unix(100, 0), nil)
+
+	if _, err := backup.ReadChain(dir); err == nil || !strings.Contains(err.Error(), "no previous backup") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// MustTempDir returns a temporary directory. Panic on error.
+func MustTempDir() string {
+	dir, err := ioutil.TempDir("", "backup-")
+	if err != nil {
+		panic(err)
+	}
+	return dir
+}
+
+// MustWriteBackup writes a portable backup with a meta file and one shard
+// file to dir, and returns its manifest. Panic on error.
+func MustWriteBackup(dir string, now, since time.Time, prev *backup.Manifest) *backup.Manifest {
+	prefix := now.UTC().Format(backup.PortableTimeFormat)
+
+	m := &backup.Manifest{
+		Version: backup.ManifestVersion,
+		Time:    now,
+		Since:   since,
+		Meta:    MustWriteFile(dir, prefix+".meta", "meta"),
+		Shards: []backup.ManifestShard{{
+			Database:        "db0",
+			RetentionPolicy: "rp0",
+			ShardID:         1,
+			ManifestFile:    MustWriteFile(dir, prefix+".db0.rp0.00001.tar.gz", prefix),
+		}},
+	}
+	if prev != nil {
+		f := MustWriteFile(dir, prev.Time.UTC().Format(backup.PortableTimeFormat)+".manifest", MustMarshal(prev))
+		m.Previous = &f
+	}
+
+	MustWriteFile(dir, prefix+".manifest", MustMarshal(m))
+	return m
+}
+
+// MustWriteFile writes data to the named file in dir and returns its
+// description. Panic on error.
+func MustWriteFile(dir, name, data string) backup.ManifestFile {
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256([]byte(data))
+	return backup.ManifestFile{FileName: name, Size: int64(len(data)), Checksum: hex.EncodeToString(sum[:])}
+}
+
+// MustMarshal returns the JSON encoding of v. Panic on error.
+func MustMarshal(v interface{}) string {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(buf)
+}
@@ -0,0 +1,244 @@
+package restore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/freetsdb/freetsdb"
+	"github.com/freetsdb/freetsdb/cmd/freetsd/backup"
+	"github.com/freetsdb/freetsdb/services/meta"
+	"github.com/freetsdb/freetsdb/services/snapshotter"
+	"github.com/freetsdb/freetsdb/tcp"
+)
+
+// validateOnline validates the command line arguments for an online restore.
+func (cmd *Command) validateOnline() error {
+	if cmd.database == "" {
+		return fmt.Errorf("-db is required to restore online")
+	} else if cmd.metadir != "" || cmd.datadir != "" {
+		return fmt.Errorf("-metadir and -datadir can't be used to restore online")
+	} else if cmd.shard != "" {
+		return fmt.Errorf("-shard can't be used to restore online")
+	} else if cmd.newRetention != "" && cmd.retention == "" {
+		return fmt.Errorf("-rp is required to restore into -newrp")
+	}
+
+	if cmd.newDatabase == "" {
+		cmd.newDatabase = cmd.database
+	}
+	return nil
+}
+
+// restoreOnline restores a database from the backup into a running cluster,
+// using the most recent metastore backup to find its shards.
+func (cmd *Command) restoreOnline() error {
+	path, err := cmd.latestMetaFile()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.Stdout, "Using metastore snapshot: %v\n", path)
+	data, _, err := backup.ReadMetastore(path)
+	if err != nil {
+		return err
+	}
+
+	db := data.Database(cmd.database)
+	if db == nil {
+		return freetsdb.ErrDatabaseNotFound(cmd.database)
+	}
+
+	var found bool
+	for i := range db.RetentionPolicies {
+		rpi := &db.RetentionPolicies[i]
+		if cmd.retention != "" && rpi.Name != cmd.retention {
+			continue
+		}
+		found = true
+
+		name := rpi.Name
+		if cmd.newRetention != "" {
+			name = cmd.newRetention
+		}
+		if err := cmd.restoreRetentionPolicyOnline(rpi, name); err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return freetsdb.ErrRetentionPolicyNotFound(cmd.retention)
+	}
+	return nil
+}
+
+// restoreRetentionPolicyOnline restores a backed up retention policy into the
+// retention policy name in the new database. A shard group is created for each
+// backed up shard group, and each backed up shard is restored to a shard in it.
+func (cmd *Command) restoreRetentionPolicyOnline(rpi *meta.RetentionPolicyInfo, name string) error {
+	fmt.Fprintf(cmd.Stdout, "Restoring %s.%s into %s.%s\n", cmd.database, rpi.Name, cmd.newDatabase, name)
+
+	res, err := cmd.request(cmd.host, &snapshotter.Request{
+		Type:                snapshotter.RequestRetentionPolicyRestore,
+		Database:            cmd.newDatabase,
+		RetentionPolicy:     name,
+		RetentionPolicyInfo: rpi,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	nodes := make(map[uint64]meta.NodeInfo, len(res.Nodes))
+	for _, n := range res.Nodes {
+		nodes[n.ID] = n
+	}
+
+	for _, sg := range rpi.ShardGroups {
+		if sg.Deleted() {
+			continue
+		}
+
+		target := shardGroupCovering(res.ShardGroups, &sg)
+		if target == nil {
+			return fmt.Errorf("no shard group created for %s - %s", sg.StartTime, sg.EndTime)
+		} else if len(target.Shards) == 0 {
+			return fmt.Errorf("shard group %d has no shards", target.ID)
+		}
+
+		// Series are spread over the shards of a group by hash, so a backed
+		// up shard is restored to the shard at the same position when the
+		// groups have as many shards. Otherwise queries still read every
+		// shard in the group, so any shard can hold its data.
+		for i, sh := range sg.Shards {
+			dst := target.Shards[i%len(target.Shards)]
+			if err := cmd.restoreShardOnline(rpi.Name, name, sh.ID, dst, nodes); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// shardGroupCovering returns the shard group in groups covering the time
+// range of sg, or nil if there is none.
+func shardGroupCovering(groups []meta.ShardGroupInfo, sg *meta.ShardGroupInfo) *meta.ShardGroupInfo {
+	for i := range groups {
+		g := &groups[i]
+		if !g.StartTime.After(sg.StartTime) && !g.EndTime.Before(sg.EndTime) {
+			return g
+		}
+	}
+	return nil
+}
+
+// restoreShardOnline streams the backup files of a shard to every owner of dst.
+func (cmd *Command) restoreShardOnline(rp, newRP string, id uint64, dst meta.ShardInfo, nodes map[uint64]meta.NodeInfo) error {
+	files, err := cmd.shardArchives(rp, id)
+	if err != nil {
+		return err
+	}
+
+	// The shard may not have been included in the backup.
+	if len(files) == 0 {
+		return nil
+	}
+
+	for _, owner := range dst.Owners {
+		n, ok := nodes[owner.NodeID]
+		if !ok {
+			return fmt.Errorf("data node %d owning shard %d not found", owner.NodeID, dst.ID)
+		}
+
+		for _, fn := range files {
+			fmt.Fprintf(cmd.Stdout, "restoring %s to shard %d on node %d\n", fn, dst.ID, n.ID)
+			if err := cmd.streamShard(n.TCPHost, newRP, dst.ID, fn); err != nil {
+				return fmt.Errorf("restore %s to node %d: %s", fn, n.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// shardArchives returns the backup files of a shard in the order they must
+// be restored.
+func (cmd *Command) shardArchives(rp string, id uint64) ([]string, error) {
+	if cmd.portable {
+		return cmd.portableArchives(cmd.database, rp, id), nil
+	}
+
+	pat := filepath.Join(cmd.backupFilesPath, fmt.Sprintf(backup.BackupFilePattern, cmd.database, rp, id))
+	matches, err := filepath.Glob(pat + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, fn := range matches {
+		if !strings.HasSuffix(fn, backup.Suffix) {
+			files = append(files, fn)
+		}
+	}
+	return files, nil
+}
+
+// streamShard sends the shard backup archive at path to the snapshotter on
+// host to be added to a shard.
+func (cmd *Command) streamShard(host, rp string, id uint64, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	_, err = cmd.request(host, &snapshotter.Request{
+		Type:            snapshotter.RequestShardRestore,
+		Database:        cmd.newDatabase,
+		RetentionPolicy: rp,
+		ShardID:         id,
+		Size:            fi.Size(),
+		Compressed:      strings.HasSuffix(path, ".gz"),
+	}, f)
+	return err
+}
+
+// request sends a request, followed by body if not nil, to the snapshotter on
+// host and returns its response.
+func (cmd *Command) request(host string, req *snapshotter.Request, body io.Reader) (*snapshotter.Response, error) {
+	// Connect to snapshotter service.
+	conn, err := tcp.Dial("tcp", host, snapshotter.MuxHeader)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Write the request
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("encode snapshot request: %s", err)
+	}
+	if body != nil {
+		if _, err := io.Copy(conn, body); err != nil {
+			return nil, fmt.Errorf("write snapshot request: %s", err)
+		}
+	}
+
+	// Read the response
+	var res snapshotter.Response
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		return nil, err
+	}
+	if res.Err != "" {
+		return nil, errors.New(res.Err)
+	}
+	return &res, nil
+}
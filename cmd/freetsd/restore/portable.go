@@ -0,0 +1,21 @@
+package restore
+
+import "path/filepath"
+
+// portableArchives returns the shard archives in the backup chain for the
+// database, and the retention policy and shard if set, in the order they
+// must be restored.
+func (cmd *Command) portableArchives(database, retention string, id uint64) []string {
+	var files []string
+	for _, m := range cmd.chain {
+		for _, sh := range m.Shards {
+			if sh.Database != database ||
+				(retention != "" && sh.RetentionPolicy != retention) ||
+				(id != 0 && sh.ShardID != id) {
+				continue
+			}
+			files = append(files, filepath.Join(cmd.backupFilesPath, sh.FileName))
+		}
+	}
+	return files
+}
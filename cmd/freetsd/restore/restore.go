@@ -33,6 +33,17 @@ type Command struct {
 	retention       string
 	shard           string
 
+	// Online restores go through the snapshotter on a running data node.
+	online       bool
+	host         string
+	newDatabase  string
+	newRetention string
+
+	// Portable restores use the chain of manifests ending with the most
+	// recent portable backup in the path, oldest first.
+	portable bool
+	chain    []*backup.Manifest
+
 	// TODO: when the new meta stuff is done this should not be exported or be gone
 	MetaConfig *meta.Config
 }
@@ -52,6 +63,19 @@ func (cmd *Command) Run(args ...string) error {
 		return err
 	}
 
+	// Verify a portable backup completely before restoring any of it.
+	if cmd.portable {
+		chain, err := backup.ReadChain(cmd.backupFilesPath)
+		if err != nil {
+			return err
+		}
+		cmd.chain = chain
+	}
+
+	if cmd.online {
+		return cmd.restoreOnline()
+	}
+
 	if err := cmd.ensureStopped(); err != nil {
 		fmt.Fprintln(cmd.Stderr, "freetsd cannot be running during a restore.  Please stop any running instances and try again.")
 		return err
@@ -81,6 +105,13 @@ func (cmd *Command) parseFlags(args []string) error {
 	fs.StringVar(&cmd.database, "database", "", "")
 	fs.StringVar(&cmd.retention, "retention", "", "")
 	fs.StringVar(&cmd.shard, "shard", "", "")
+	fs.BoolVar(&cmd.online, "online", false, "")
+	fs.BoolVar(&cmd.portable, "portable", false, "")
+	fs.StringVar(&cmd.host, "host", "localhost:8088", "")
+	fs.StringVar(&cmd.database, "db", "", "")
+	fs.StringVar(&cmd.newDatabase, "newdb", "", "")
+	fs.StringVar(&cmd.retention, "rp", "", "")
+	fs.StringVar(&cmd.newRetention, "newrp", "", "")
 	fs.SetOutput(cmd.Stdout)
 	fs.Usage = cmd.printUsage
 	if err := fs.Parse(args); err != nil {
@@ -96,6 +127,10 @@ func (cmd *Command) parseFlags(args []string) error {
 		return fmt.Errorf("path with backup files required")
 	}
 
+	if cmd.online {
+		return cmd.validateOnline()
+	}
+
 	// validate the arguments
 	if cmd.metadir == "" && cmd.database == "" {
 		return fmt.Errorf("-metadir or -database are required to restore")
@@ -132,17 +167,11 @@ func (cmd *Command) ensureStopped() error {
 // cluster and replaces the root metadata.
 func (cmd *Command) unpackMeta() error {
 	// find the meta file
-	metaFiles, err := filepath.Glob(filepath.Join(cmd.backupFilesPath, backup.Metafile+".*"))
+	latest, err := cmd.latestMetaFile()
 	if err != nil {
 		return err
 	}
 
-	if len(metaFiles) == 0 {
-		return fmt.Errorf("no metastore backups in %s", cmd.backupFilesPath)
-	}
-
-	latest := metaFiles[len(metaFiles)-1]
-
 	fmt.Fprintf(cmd.Stdout, "Using metastore snapshot: %v\n", latest)
 	// Read the metastore backup
 	f, err := os.Open(latest)
@@ -230,6 +259,24 @@ func (cmd *Command) unpackMeta() error {
 	return nil
 }
 
+// latestMetaFile returns the path of the most recent metastore backup.
+func (cmd *Command) latestMetaFile() (string, error) {
+	if cmd.portable {
+		return filepath.Join(cmd.backupFilesPath, cmd.chain[len(cmd.chain)-1].Meta.FileName), nil
+	}
+
+	metaFiles, err := filepath.Glob(filepath.Join(cmd.backupFilesPath, backup.Metafile+".*"))
+	if err != nil {
+		return "", err
+	}
+
+	if len(metaFiles) == 0 {
+		return "", fmt.Errorf("no metastore backups in %s", cmd.backupFilesPath)
+	}
+
+	return metaFiles[len(metaFiles)-1], nil
+}
+
 // unpackShard will look for all backup files in the path matching this shard ID
 // and restore them to the data dir
 func (cmd *Command) unpackShard(shardID string) error {
@@ -244,6 +291,10 @@ func (cmd *Command) unpackShard(shardID string) error {
 		return err
 	}
 
+	if cmd.portable {
+		return cmd.unpackArchives(cmd.portableArchives(cmd.database, cmd.retention, id))
+	}
+
 	// find the shard backup files
 	pat := filepath.Join(cmd.backupFilesPath, fmt.Sprintf(backup.BackupFilePattern, cmd.database, cmd.retention, id))
 	return cmd.unpackFiles(pat + ".*")
@@ -258,6 +309,10 @@ func (cmd *Command) unpackDatabase() error {
 		return fmt.Errorf("database already present: %s", restorePath)
 	}
 
+	if cmd.portable {
+		return cmd.unpackArchives(cmd.portableArchives(cmd.database, "", 0))
+	}
+
 	// find the database backup files
 	pat := filepath.Join(cmd.backupFilesPath, cmd.database)
 	return cmd.unpackFiles(pat + ".*")
@@ -272,6 +327,10 @@ func (cmd *Command) unpackRetention() error {
 		return fmt.Errorf("retention already present: %s", restorePath)
 	}
 
+	if cmd.portable {
+		return cmd.unpackArchives(cmd.portableArchives(cmd.database, cmd.retention, 0))
+	}
+
 	// find the retention backup files
 	pat := filepath.Join(cmd.backupFilesPath, cmd.database)
 	return cmd.unpackFiles(fmt.Sprintf("%s.%s.*", pat, cmd.retention))
@@ -290,7 +349,16 @@ func (cmd *Command) unpackFiles(pat string) error {
 		return fmt.Errorf("no backup files for %s in %s", pat, cmd.backupFilesPath)
 	}
 
-	for _, fn := range backupFiles {
+	return cmd.unpackArchives(backupFiles)
+}
+
+// unpackArchives will restore the tar archives, in order, to the data dir
+func (cmd *Command) unpackArchives(files []string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no backup files in %s", cmd.backupFilesPath)
+	}
+
+	for _, fn := range files {
 		if err := cmd.unpackTar(fn); err != nil {
 			return err
 		}
@@ -299,9 +367,9 @@ func (cmd *Command) unpackFiles(pat string) error {
 	return nil
 }
 
-// unpackTar will restore a single tar archive to the data dir
+// unpackTar will restore a single tar archive, which may be compressed, to the data dir
 func (cmd *Command) unpackTar(tarFile string) error {
-	f, err := os.Open(tarFile)
+	f, err := backup.OpenArchive(tarFile)
 	if err != nil {
 		return err
 	}
@@ -351,9 +419,13 @@ func (cmd *Command) printUsage() {
 
 Restore uses backups from the PATH to restore the metastore, databases,
 retention policies, or specific shards. The FreeTSDB process must not be
-running during restore.
+running during restore, unless -online is given.
 
 Options:
+  -portable
+        Optional. Restore from the most recent portable backup in PATH and
+        the backups it is incremental to. The chain of backups and the
+        checksum of every file in it are verified before anything is restored.
   -metadir <path>
         Optional. If set the metastore will be recovered to the given path.
   -datadir <path>
@@ -369,6 +441,28 @@ Options:
     Optional. If given, database and retention are required. Will restore the shard's
     TSM files.
 
+Online options:
+  -online
+        Optional. Restore a database into a running cluster. The database,
+        retention policies and shard groups are created through the data node
+        at -host and the TSM files are streamed to the owners of each shard.
+        Restore requests aren't authenticated, so data nodes only accept
+        them from the local host. To restore into a cluster, set
+        remote-restore-enabled in the [snapshotter] section of every data
+        node for the duration of the restore.
+  -host <host:port>
+        The data node to connect to. Defaults to 127.0.0.1:8088.
+  -db <name>
+        The database to restore. Alias for -database.
+  -newdb <name>
+        Optional. The database to restore into. Defaults to -db.
+  -rp <name>
+        Optional. The retention policy to restore. Alias for -retention.
+        Defaults to all retention policies in the database.
+  -newrp <name>
+        Optional. If given, -rp is required. The retention policy to restore into.
+        Defaults to -rp.
+
 `)
 }
 
@@ -13,6 +13,7 @@ import (
 
 	"github.com/freetsdb/freetsdb/cluster"
 	"github.com/freetsdb/freetsdb/monitor"
+	"github.com/freetsdb/freetsdb/services/audit"
 	"github.com/freetsdb/freetsdb/services/collectd"
 	"github.com/freetsdb/freetsdb/services/continuous_querier"
 	"github.com/freetsdb/freetsdb/services/graphite"
@@ -22,6 +23,7 @@ import (
 	"github.com/freetsdb/freetsdb/services/opentsdb"
 	"github.com/freetsdb/freetsdb/services/precreator"
 	"github.com/freetsdb/freetsdb/services/retention"
+	"github.com/freetsdb/freetsdb/services/snapshotter"
 	"github.com/freetsdb/freetsdb/services/subscriber"
 	"github.com/freetsdb/freetsdb/services/udp"
 	"github.com/freetsdb/freetsdb/tsdb"
@@ -44,7 +46,10 @@ type Config struct {
 	Retention  retention.Config  `toml:"retention"`
 	Precreator precreator.Config `toml:"shard-precreation"`
 
+	Snapshotter snapshotter.Config `toml:"snapshotter"`
+
 	Monitor    monitor.Config    `toml:"monitor"`
+	Audit      audit.Config      `toml:"audit"`
 	Subscriber subscriber.Config `toml:"subscriber"`
 	HTTPD      httpd.Config      `toml:"http"`
 	Graphites  []graphite.Config `toml:"graphite"`
@@ -75,8 +80,10 @@ func NewConfig() *Config {
 	c.Data = tsdb.NewConfig()
 	c.Cluster = cluster.NewConfig()
 	c.Precreator = precreator.NewConfig()
+	c.Snapshotter = snapshotter.NewConfig()
 
 	c.Monitor = monitor.NewConfig()
+	c.Audit = audit.NewConfig()
 	c.Subscriber = subscriber.NewConfig()
 	c.HTTPD = httpd.NewConfig()
 	c.Collectd = collectd.NewConfig()
@@ -159,10 +166,27 @@ func (c *Config) Validate() error {
 		if err := c.HintedHandoff.Validate(); err != nil {
 			return err
 		}
+		// Each enabled graphite listener needs its own address. The tcp and
+		// pickle protocols both listen on TCP.
+		binds := make(map[string]bool)
 		for _, g := range c.Graphites {
 			if err := g.Validate(); err != nil {
 				return fmt.Errorf("invalid graphite config: %v", err)
 			}
+			if !g.Enabled {
+				continue
+			}
+
+			d := g.WithDefaults()
+			network := "tcp"
+			if strings.ToLower(d.Protocol) == "udp" {
+				network = "udp"
+			}
+			key := network + " " + d.BindAddress
+			if binds[key] {
+				return fmt.Errorf("invalid graphite config: %s address %s is used by more than one listener", network, d.BindAddress)
+			}
+			binds[key] = true
 		}
 	}
 
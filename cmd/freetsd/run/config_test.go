@@ -186,3 +186,44 @@ enabled = false
 		t.Fatalf("got nil, expected error")
 	}
 }
+
+func TestConfig_ValidateGraphiteDuplicateBind(t *testing.T) {
+	c := run.NewConfig()
+	if _, err := toml.Decode(`
+[meta]
+dir = "foo"
+
+[data]
+dir = "foo"
+wal-dir = "bar"
+
+[[graphite]]
+enabled = true
+protocol = "tcp"
+bind-address = ":2003"
+
+[[graphite]]
+enabled = true
+protocol = "udp"
+bind-address = ":2003"
+
+[[graphite]]
+enabled = true
+protocol = "pickle"
+bind-address = ":2004"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// A second TCP listener on the pickle address is rejected.
+	g := c.Graphites[0]
+	g.BindAddress = ":2004"
+	c.Graphites = append(c.Graphites, g)
+	if err := c.Validate(); err == nil {
+		t.Fatalf("got nil, expected error")
+	}
+}
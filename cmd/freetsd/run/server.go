@@ -14,6 +14,7 @@ import (
 	"github.com/freetsdb/freetsdb/cluster"
 	"github.com/freetsdb/freetsdb/models"
 	"github.com/freetsdb/freetsdb/monitor"
+	"github.com/freetsdb/freetsdb/services/audit"
 	"github.com/freetsdb/freetsdb/services/collectd"
 	"github.com/freetsdb/freetsdb/services/continuous_querier"
 	"github.com/freetsdb/freetsdb/services/copier"
@@ -81,6 +82,9 @@ type Server struct {
 
 	Monitor *monitor.Monitor
 
+	// Records DDL statements and authentication failures, if enabled.
+	AuditService *audit.Service
+
 	// Server reporting and registration
 	reportingDisabled bool
 
@@ -202,11 +206,18 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 		s.PointsWriter.HintedHandoff = s.HintedHandoff
 		s.PointsWriter.Subscriber = s.Subscriber
 		s.PointsWriter.Node = s.Node
+		s.PointsWriter.WriteLimiter = cluster.NewWriteLimiter(c.Cluster)
+		if c.Cluster.QueryCacheMaxSize > 0 {
+			s.PointsWriter.QueryCache = cluster.NewQueryCache(c.Cluster)
+		}
 
 		// Initialize meta executor.
 		metaExecutor := cluster.NewMetaExecutor()
 		metaExecutor.MetaClient = s.MetaClient
 		metaExecutor.Node = s.Node
+		if s.PointsWriter.QueryCache != nil {
+			s.PointsWriter.QueryCache.Broadcaster = metaExecutor
+		}
 
 		// Initialize query executor.
 		s.QueryExecutor = cluster.NewQueryExecutor()
@@ -215,6 +226,11 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 		s.QueryExecutor.Monitor = s.Monitor
 		s.QueryExecutor.PointsWriter = s.PointsWriter
 		s.QueryExecutor.MetaExecutor = metaExecutor
+		s.QueryExecutor.HintedHandoff = s.HintedHandoff
+		s.QueryExecutor.MaxSelectPointN = c.Cluster.MaxSelectPointN
+		s.QueryExecutor.MaxSelectSeriesN = c.Cluster.MaxSelectSeriesN
+		s.QueryExecutor.MaxSelectBucketsN = c.Cluster.MaxSelectBucketsN
+		s.QueryExecutor.QueryCache = s.PointsWriter.QueryCache
 		if c.Data.QueryLogEnabled {
 			s.QueryExecutor.LogOutput = os.Stderr
 		}
@@ -225,6 +241,14 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 		s.Monitor.Branch = s.buildInfo.Branch
 		s.Monitor.BuildTime = s.buildInfo.Time
 		s.Monitor.PointsWriter = (*monitorPointsWriter)(s.PointsWriter)
+
+		// Initialize the audit log.
+		if c.Audit.Enabled {
+			s.AuditService = audit.NewService(c.Audit)
+			s.AuditService.Node = s.Node
+			s.AuditService.PointsWriter = (*monitorPointsWriter)(s.PointsWriter)
+			s.QueryExecutor.Auditor = s.AuditService
+		}
 	}
 
 	return s, nil
@@ -234,12 +258,15 @@ func (s *Server) appendClusterService(c cluster.Config) {
 	srv := cluster.NewService(c)
 	srv.TSDBStore = s.TSDBStore
 	srv.MetaClient = s.MetaClient
+	srv.WriteLimiter = s.PointsWriter.WriteLimiter
+	srv.QueryCache = s.PointsWriter.QueryCache
 	s.Services = append(s.Services, srv)
 	s.ClusterService = srv
 }
 
-func (s *Server) appendSnapshotterService() {
+func (s *Server) appendSnapshotterService(c snapshotter.Config) {
 	srv := snapshotter.NewService()
+	srv.RemoteRestoreEnabled = c.RemoteRestoreEnabled
 	srv.TSDBStore = s.TSDBStore
 	srv.MetaClient = s.MetaClient
 	srv.Node = s.Node
@@ -274,6 +301,9 @@ func (s *Server) appendHTTPDService(c httpd.Config) {
 	srv.Handler.QueryExecutor = s.QueryExecutor
 	srv.Handler.PointsWriter = s.PointsWriter
 	srv.Handler.Version = s.buildInfo.Version
+	if s.AuditService != nil {
+		srv.Handler.Auditor = s.AuditService
+	}
 
 	// If a ContinuousQuerier service has been started, attach it.
 	for _, srvc := range s.Services {
@@ -305,6 +335,7 @@ func (s *Server) appendOpenTSDBService(c opentsdb.Config) error {
 	}
 	srv.PointsWriter = s.PointsWriter
 	srv.MetaClient = s.MetaClient
+	srv.QueryExecutor = s.QueryExecutor
 	s.Services = append(s.Services, srv)
 	return nil
 }
@@ -356,7 +387,11 @@ func (s *Server) appendContinuousQueryService(c continuous_querier.Config) {
 	srv := continuous_querier.NewService(c)
 	srv.MetaClient = s.MetaClient
 	srv.QueryExecutor = s.QueryExecutor
+	srv.FieldStore = s.QueryExecutor
 	s.Services = append(s.Services, srv)
+
+	// Allow RUN CONTINUOUS QUERY statements to backfill through the service.
+	s.QueryExecutor.ContinuousQuerier = srv
 }
 
 // Err returns an error channel that multiplexes all out of band errors received from all services.
@@ -396,7 +431,7 @@ func (s *Server) Open() error {
 		// Append services.
 		s.appendClusterService(s.config.Cluster)
 		s.appendPrecreatorService(s.config.Precreator)
-		s.appendSnapshotterService()
+		s.appendSnapshotterService(s.config.Snapshotter)
 		s.appendCopierService()
 		s.appendContinuousQueryService(s.config.ContinuousQuery)
 		s.appendHTTPDService(s.config.HTTPD)
@@ -422,6 +457,9 @@ func (s *Server) Open() error {
 		s.Subscriber.MetaClient = s.MetaClient
 		s.PointsWriter.MetaClient = s.MetaClient
 		s.Monitor.MetaClient = s.MetaClient
+		if s.AuditService != nil {
+			s.AuditService.MetaClient = s.MetaClient
+		}
 
 		s.ClusterService.Listener = mux.Listen(cluster.MuxHeader)
 		s.SnapshotterService.Listener = mux.Listen(snapshotter.MuxHeader)
@@ -457,6 +495,13 @@ func (s *Server) Open() error {
 			return fmt.Errorf("open monitor: %v", err)
 		}
 
+		// Open the audit log before any statements can be executed.
+		if s.AuditService != nil {
+			if err := s.AuditService.Open(); err != nil {
+				return fmt.Errorf("open audit log: %v", err)
+			}
+		}
+
 		for _, service := range s.Services {
 			if err := service.Open(); err != nil {
 				return fmt.Errorf("open service: %s", err)
@@ -491,6 +536,10 @@ func (s *Server) Close() error {
 		s.Monitor.Close()
 	}
 
+	if s.AuditService != nil {
+		s.AuditService.Close()
+	}
+
 	if s.PointsWriter != nil {
 		s.PointsWriter.Close()
 	}
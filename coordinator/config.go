@@ -19,6 +19,10 @@ const (
 	// DefaultMaxRemoteWriteConnections is the maximum number of open connections
 	// that will be available for remote writes to another host.
 	DefaultMaxRemoteWriteConnections = 3
+
+	// DefaultWriteThrottleRetryAfter is the default time a client whose write
+	// was throttled is asked to wait before retrying.
+	DefaultWriteThrottleRetryAfter = time.Second
 )
 
 // Config represents the configuration for the clustering service.
@@ -28,6 +32,27 @@ type Config struct {
 	ShardWriterTimeout        toml.Duration `toml:"shard-writer-timeout"`
 	MaxRemoteWriteConnections int           `toml:"max-remote-write-connections"`
 	ShardMapperTimeout        toml.Duration `toml:"shard-mapper-timeout"`
+
+	// Limits on the writes in flight on the node, and for each database.
+	// Writes over a limit are rejected. Zero is unlimited.
+	MaxInflightWriteBytes          int64         `toml:"max-inflight-write-bytes"`
+	MaxInflightWritePoints         int64         `toml:"max-inflight-write-points"`
+	MaxDatabaseInflightWriteBytes  int64         `toml:"max-database-inflight-write-bytes"`
+	MaxDatabaseInflightWritePoints int64         `toml:"max-database-inflight-write-points"`
+	WriteThrottleRetryAfter        toml.Duration `toml:"write-throttle-retry-after"`
+
+	// Limits on the points read from shards, the series and the GROUP BY
+	// time buckets of a SELECT statement. Zero is unlimited.
+	MaxSelectPointN   int `toml:"max-select-point"`
+	MaxSelectSeriesN  int `toml:"max-select-series"`
+	MaxSelectBucketsN int `toml:"max-select-buckets"`
+
+	// The approximate size in bytes of the results of repeated aggregate
+	// queries that are cached, and how long they are used. Zero disables
+	// the cache. Only nodes with a cache send their writes to the caches
+	// of the other nodes, so it should be enabled on all data nodes or none.
+	QueryCacheMaxSize int64         `toml:"query-cache-max-size"`
+	QueryCacheTTL     toml.Duration `toml:"query-cache-ttl"`
 }
 
 // NewConfig returns an instance of Config with defaults.
@@ -37,5 +62,7 @@ func NewConfig() Config {
 		ShardWriterTimeout:        toml.Duration(DefaultShardWriterTimeout),
 		ShardMapperTimeout:        toml.Duration(DefaultShardMapperTimeout),
 		MaxRemoteWriteConnections: DefaultMaxRemoteWriteConnections,
+		WriteThrottleRetryAfter:   toml.Duration(DefaultWriteThrottleRetryAfter),
+		QueryCacheTTL:             toml.Duration(DefaultQueryCacheTTL),
 	}
 }
@@ -14,6 +14,11 @@ func TestConfig_Parse(t *testing.T) {
 	if _, err := toml.Decode(`
 shard-writer-timeout = "10s"
 write-timeout = "20s"
+max-inflight-write-bytes = 1000
+max-database-inflight-write-points = 10
+write-throttle-retry-after = "5s"
+query-cache-max-size = 1048576
+query-cache-ttl = "1m"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -23,5 +28,15 @@ write-timeout = "20s"
 		t.Fatalf("unexpected shard-writer timeout: %s", c.ShardWriterTimeout)
 	} else if time.Duration(c.WriteTimeout) != 20*time.Second {
 		t.Fatalf("unexpected write timeout s: %s", c.WriteTimeout)
+	} else if c.MaxInflightWriteBytes != 1000 {
+		t.Fatalf("unexpected max inflight write bytes: %d", c.MaxInflightWriteBytes)
+	} else if c.MaxDatabaseInflightWritePoints != 10 {
+		t.Fatalf("unexpected max database inflight write points: %d", c.MaxDatabaseInflightWritePoints)
+	} else if time.Duration(c.WriteThrottleRetryAfter) != 5*time.Second {
+		t.Fatalf("unexpected write throttle retry after: %s", c.WriteThrottleRetryAfter)
+	} else if c.QueryCacheMaxSize != 1048576 {
+		t.Fatalf("unexpected query cache max size: %d", c.QueryCacheMaxSize)
+	} else if time.Duration(c.QueryCacheTTL) != time.Minute {
+		t.Fatalf("unexpected query cache ttl: %s", c.QueryCacheTTL)
 	}
 }
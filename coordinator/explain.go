@@ -0,0 +1,259 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/freetsdb/freetsdb/influxql"
+	"github.com/freetsdb/freetsdb/models"
+)
+
+// executeExplainStatement describes how the select statement of stmt is
+// executed: the shards it reads from on each node, the number of series in
+// each shard and the iterators created for each expression. If the statement
+// is analyzed, it is executed and its results are replaced by the time spent
+// and the data read by each iterator.
+func (e *QueryExecutor) executeExplainStatement(stmt *influxql.ExplainStatement) (models.Rows, error) {
+	start := time.Now()
+	sel := stmt.Statement
+
+	opt, err := e.prepareSelectStatement(sel, start.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	// Count the points read from shards if they are limited.
+	var limiter *influxql.PointLimiter
+	if e.MaxSelectPointN > 0 {
+		limiter = influxql.NewPointLimiter(e.MaxSelectPointN)
+	}
+
+	sics, err := e.shardIteratorCreators(sel, &opt, limiter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the iterators created for each local shard and remote node.
+	plan := &explainPlan{analyze: stmt.Analyze}
+	ics := make([]influxql.IteratorCreator, 0, len(sics))
+	for _, sic := range sics {
+		ics = append(ics, &explainShardIteratorCreator{IteratorCreator: sic.ic, plan: plan, name: sic.name(e.Node.ID)})
+	}
+	ic := &explainIteratorCreator{IteratorCreator: influxql.IteratorCreators(ics), plan: plan}
+
+	// Rewrite wildcards, if any exist.
+	if sel, err = sel.RewriteWildcards(ic); err != nil {
+		return nil, err
+	}
+
+	// Validate the number of series read.
+	if err := e.validateSelectSeries(sel, ic, &opt); err != nil {
+		return nil, err
+	}
+
+	shards, err := e.explainShards(sel, sics, ic, &opt)
+	if err != nil {
+		return nil, err
+	}
+
+	itrs, err := influxql.Select(sel, ic, &opt)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := append([]string{sel.String()}, shards...)
+	if !stmt.Analyze {
+		influxql.Iterators(itrs).Close()
+		return explainRows(append(lines, plan.lines()...)), nil
+	}
+
+	// Read the results of the statement and discard them.
+	planned := time.Now()
+	em := influxql.NewEmitter(itrs, sel.TimeAscending())
+	em.Columns = sel.ColumnNames()
+	em.OmitTime = sel.OmitTime
+	em.Location = sel.Location
+	defer em.Close()
+
+	var rowN int
+	for {
+		row := em.Emit()
+
+		// Return an error if the iterators were ended by the point limit.
+		if limiter != nil {
+			if err := limiter.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		if row == nil {
+			break
+		}
+		rowN++
+	}
+	executed := time.Now()
+
+	lines = append(lines, plan.lines()...)
+	lines = append(lines,
+		fmt.Sprintf("rows: %d", rowN),
+		fmt.Sprintf("planning time: %s", planned.Sub(start)),
+		fmt.Sprintf("execution time: %s", executed.Sub(planned)),
+	)
+	return explainRows(lines), nil
+}
+
+// explainShards returns the lines that describe the shards read on each node
+// and the number of series stmt reads from each shard.
+func (e *QueryExecutor) explainShards(stmt *influxql.SelectStatement, sics []shardIteratorCreator, ic influxql.IteratorCreator, opt *influxql.SelectOptions) ([]string, error) {
+	// Series can not be counted for system sources.
+	var seriesOpt influxql.IteratorOptions
+	countSeries := !stmt.Sources.HasSystemSource()
+	if countSeries {
+		var err error
+		if seriesOpt, err = seriesIteratorOptions(stmt, ic, opt); err != nil {
+			return nil, err
+		}
+	}
+
+	var lines []string
+	for i, sic := range sics {
+		if i == 0 || sics[i-1].nodeID != sic.nodeID {
+			if sic.nodeID == e.Node.ID {
+				lines = append(lines, fmt.Sprintf("node %d (local)", sic.nodeID))
+			} else {
+				lines = append(lines, fmt.Sprintf("node %d", sic.nodeID))
+			}
+		}
+
+		for _, shardID := range sic.shardIDs {
+			if !countSeries {
+				lines = append(lines, fmt.Sprintf("  shard %d", shardID))
+				continue
+			}
+
+			// Count the series of each shard of a remote node separately.
+			shardIC := sic.ic
+			if sic.nodeID != e.Node.ID {
+				dialer := &NodeDialer{MetaClient: e.MetaClient, Timeout: e.Timeout}
+				shardIC = newRemoteIteratorCreator(dialer, sic.nodeID, []uint64{shardID})
+			}
+
+			series, err := shardIC.SeriesKeys(seriesOpt)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, fmt.Sprintf("  shard %d: %d series", shardID, len(series)))
+		}
+	}
+	return lines, nil
+}
+
+// name returns the name of the iterators of sic in an explained plan.
+func (sic shardIteratorCreator) name(localID uint64) string {
+	if sic.nodeID == localID {
+		return fmt.Sprintf("shard %d (node %d)", sic.shardIDs[0], sic.nodeID)
+	}
+	return fmt.Sprintf("node %d (shards %s)", sic.nodeID, joinUint64(sic.shardIDs))
+}
+
+// explainRows returns the lines of an explained plan as rows.
+func explainRows(lines []string) models.Rows {
+	row := &models.Row{Columns: []string{"QUERY PLAN"}}
+	for _, line := range lines {
+		row.Values = append(row.Values, []interface{}{line})
+	}
+	return models.Rows{row}
+}
+
+// explainPlan records the iterators created for the expressions of a
+// statement and the iterators they read from.
+type explainPlan struct {
+	analyze bool
+	exprs   []*explainExpr
+}
+
+// explainExpr is the iterator created for an expression of a statement.
+type explainExpr struct {
+	expr   string
+	inputs []*explainInput
+}
+
+// explainInput is an iterator of a local shard or remote node that an
+// expression reads from.
+type explainInput struct {
+	name  string
+	stats influxql.IteratorStats
+}
+
+// lines returns the lines that describe the iterators of the plan.
+func (p *explainPlan) lines() []string {
+	var lines []string
+	for _, expr := range p.exprs {
+		lines = append(lines, fmt.Sprintf("expression: %s", expr.expr))
+		for _, in := range expr.inputs {
+			if !p.analyze {
+				lines = append(lines, "  "+in.name)
+				continue
+			}
+
+			s := in.stats
+			lines = append(lines, fmt.Sprintf("  %s: %d points in %s, %d series, %d blocks, %d bytes",
+				in.name, s.PointN, s.Duration, s.SeriesN, s.BlocksN, s.BlockBytes))
+		}
+	}
+	return lines
+}
+
+// explainIteratorCreator records the expressions of the iterators created.
+type explainIteratorCreator struct {
+	influxql.IteratorCreator
+	plan *explainPlan
+}
+
+// CreateIterator records the expression of an iterator before creating it.
+func (ic *explainIteratorCreator) CreateIterator(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+	expr := strings.Join(opt.Aux, ", ")
+	if opt.Expr != nil {
+		expr = opt.Expr.String()
+	}
+	ic.plan.exprs = append(ic.plan.exprs, &explainExpr{expr: expr})
+	return ic.IteratorCreator.CreateIterator(opt)
+}
+
+// explainShardIteratorCreator records the iterators created by a local shard
+// or a remote node. Iterators are only created if the plan is analyzed.
+type explainShardIteratorCreator struct {
+	influxql.IteratorCreator
+	plan *explainPlan
+	name string
+}
+
+// CreateIterator records an input of the last expression created. If the plan
+// is analyzed, the points read from the iterator and the data read to create
+// them are counted.
+func (ic *explainShardIteratorCreator) CreateIterator(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+	in := &explainInput{name: ic.name}
+	if n := len(ic.plan.exprs); n > 0 {
+		expr := ic.plan.exprs[n-1]
+		expr.inputs = append(expr.inputs, in)
+	}
+
+	if !ic.plan.analyze {
+		return &explainNilIterator{}, nil
+	}
+
+	opt.Stats = &in.stats
+	itr, err := ic.IteratorCreator.CreateIterator(opt)
+	if err != nil || itr == nil {
+		return itr, err
+	}
+	return influxql.NewStatsIterator(itr, &in.stats), nil
+}
+
+// explainNilIterator is an empty iterator that stands in for the iterators of
+// a plan that is not executed.
+type explainNilIterator struct{}
+
+func (*explainNilIterator) Close() error               { return nil }
+func (*explainNilIterator) Next() *influxql.FloatPoint { return nil }
@@ -19,6 +19,10 @@ It has these top-level messages:
 	FieldDimensionsResponse
 	SeriesKeysRequest
 	SeriesKeysResponse
+	NumericFieldsRequest
+	MeasurementFields
+	NumericFieldsResponse
+	InvalidateQueryCacheRequest
 */
 package internal
 
@@ -74,6 +78,7 @@ func (m *WriteShardRequest) GetRetentionPolicy() string {
 type WriteShardResponse struct {
 	Code             *int32  `protobuf:"varint,1,req,name=Code" json:"Code,omitempty"`
 	Message          *string `protobuf:"bytes,2,opt,name=Message" json:"Message,omitempty"`
+	RetryAfter       *int64  `protobuf:"varint,3,opt,name=RetryAfter" json:"RetryAfter,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -95,6 +100,13 @@ func (m *WriteShardResponse) GetMessage() string {
 	return ""
 }
 
+func (m *WriteShardResponse) GetRetryAfter() int64 {
+	if m != nil && m.RetryAfter != nil {
+		return *m.RetryAfter
+	}
+	return 0
+}
+
 type ExecuteStatementRequest struct {
 	Statement        *string `protobuf:"bytes,1,req,name=Statement" json:"Statement,omitempty"`
 	Database         *string `protobuf:"bytes,2,req,name=Database" json:"Database,omitempty"`
@@ -146,6 +158,8 @@ func (m *ExecuteStatementResponse) GetMessage() string {
 type CreateIteratorRequest struct {
 	ShardIDs         []uint64 `protobuf:"varint,1,rep,name=ShardIDs" json:"ShardIDs,omitempty"`
 	Opt              []byte   `protobuf:"bytes,2,req,name=Opt" json:"Opt,omitempty"`
+	MaxPointN        *int64   `protobuf:"varint,3,opt,name=MaxPointN" json:"MaxPointN,omitempty"`
+	Stats            *bool    `protobuf:"varint,4,opt,name=Stats" json:"Stats,omitempty"`
 	XXX_unrecognized []byte   `json:"-"`
 }
 
@@ -167,6 +181,20 @@ func (m *CreateIteratorRequest) GetOpt() []byte {
 	return nil
 }
 
+func (m *CreateIteratorRequest) GetMaxPointN() int64 {
+	if m != nil && m.MaxPointN != nil {
+		return *m.MaxPointN
+	}
+	return 0
+}
+
+func (m *CreateIteratorRequest) GetStats() bool {
+	if m != nil && m.Stats != nil {
+		return *m.Stats
+	}
+	return false
+}
+
 type CreateIteratorResponse struct {
 	Err              *string `protobuf:"bytes,1,opt,name=Err" json:"Err,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
@@ -287,6 +315,110 @@ func (m *SeriesKeysResponse) GetErr() string {
 	return ""
 }
 
+type NumericFieldsRequest struct {
+	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	RetentionPolicy  *string `protobuf:"bytes,2,req,name=RetentionPolicy" json:"RetentionPolicy,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *NumericFieldsRequest) Reset()         { *m = NumericFieldsRequest{} }
+func (m *NumericFieldsRequest) String() string { return proto.CompactTextString(m) }
+func (*NumericFieldsRequest) ProtoMessage()    {}
+
+func (m *NumericFieldsRequest) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *NumericFieldsRequest) GetRetentionPolicy() string {
+	if m != nil && m.RetentionPolicy != nil {
+		return *m.RetentionPolicy
+	}
+	return ""
+}
+
+type MeasurementFields struct {
+	Name             *string  `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	Fields           []string `protobuf:"bytes,2,rep,name=Fields" json:"Fields,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *MeasurementFields) Reset()         { *m = MeasurementFields{} }
+func (m *MeasurementFields) String() string { return proto.CompactTextString(m) }
+func (*MeasurementFields) ProtoMessage()    {}
+
+func (m *MeasurementFields) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *MeasurementFields) GetFields() []string {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+type NumericFieldsResponse struct {
+	Measurements     []*MeasurementFields `protobuf:"bytes,1,rep,name=Measurements" json:"Measurements,omitempty"`
+	Err              *string              `protobuf:"bytes,2,opt,name=Err" json:"Err,omitempty"`
+	XXX_unrecognized []byte               `json:"-"`
+}
+
+func (m *NumericFieldsResponse) Reset()         { *m = NumericFieldsResponse{} }
+func (m *NumericFieldsResponse) String() string { return proto.CompactTextString(m) }
+func (*NumericFieldsResponse) ProtoMessage()    {}
+
+func (m *NumericFieldsResponse) GetMeasurements() []*MeasurementFields {
+	if m != nil {
+		return m.Measurements
+	}
+	return nil
+}
+
+func (m *NumericFieldsResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type InvalidateQueryCacheRequest struct {
+	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	Time             *int64  `protobuf:"varint,2,req,name=Time" json:"Time,omitempty"`
+	Measurement      *string `protobuf:"bytes,3,opt,name=Measurement" json:"Measurement,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *InvalidateQueryCacheRequest) Reset()         { *m = InvalidateQueryCacheRequest{} }
+func (m *InvalidateQueryCacheRequest) String() string { return proto.CompactTextString(m) }
+func (*InvalidateQueryCacheRequest) ProtoMessage()    {}
+
+func (m *InvalidateQueryCacheRequest) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *InvalidateQueryCacheRequest) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+func (m *InvalidateQueryCacheRequest) GetMeasurement() string {
+	if m != nil && m.Measurement != nil {
+		return *m.Measurement
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*WriteShardRequest)(nil), "internal.WriteShardRequest")
 	proto.RegisterType((*WriteShardResponse)(nil), "internal.WriteShardResponse")
@@ -298,4 +430,8 @@ func init() {
 	proto.RegisterType((*FieldDimensionsResponse)(nil), "internal.FieldDimensionsResponse")
 	proto.RegisterType((*SeriesKeysRequest)(nil), "internal.SeriesKeysRequest")
 	proto.RegisterType((*SeriesKeysResponse)(nil), "internal.SeriesKeysResponse")
+	proto.RegisterType((*NumericFieldsRequest)(nil), "internal.NumericFieldsRequest")
+	proto.RegisterType((*MeasurementFields)(nil), "internal.MeasurementFields")
+	proto.RegisterType((*NumericFieldsResponse)(nil), "internal.NumericFieldsResponse")
+	proto.RegisterType((*InvalidateQueryCacheRequest)(nil), "internal.InvalidateQueryCacheRequest")
 }
@@ -12,8 +12,10 @@ type MetaClient interface {
 	CreateContinuousQuery(database, name, query string) error
 	CreateDatabase(name string) (*meta.DatabaseInfo, error)
 	CreateDatabaseWithRetentionPolicy(name string, rpi *meta.RetentionPolicyInfo) (*meta.DatabaseInfo, error)
+	CreateDownsamplePolicy(database, rp string, dpi *meta.DownsamplePolicyInfo) error
 	CreateRetentionPolicy(database string, rpi *meta.RetentionPolicyInfo) (*meta.RetentionPolicyInfo, error)
 	CreateSubscription(database, rp, name, mode string, destinations []string) error
+	CreateToken(name, username string, privileges map[string]influxql.Privilege) (string, error)
 	CreateUser(name, password string, admin bool) (*meta.UserInfo, error)
 	Database(name string) (*meta.DatabaseInfo, error)
 	Databases() ([]meta.DatabaseInfo, error)
@@ -23,17 +25,22 @@ type MetaClient interface {
 	DeleteMetaNode(id uint64) error
 	DropContinuousQuery(database, name string) error
 	DropDatabase(name string) error
+	DropDownsamplePolicy(database, rp, targetDatabase, targetRP string) error
 	DropRetentionPolicy(database, name string) error
 	DropSubscription(database, rp, name string) error
+	DropToken(name string) error
 	DropUser(name string) error
 	MetaNodes() ([]meta.NodeInfo, error)
 	RetentionPolicy(database, name string) (rpi *meta.RetentionPolicyInfo, err error)
 	SetAdminPrivilege(username string, admin bool) error
 	SetDefaultRetentionPolicy(database, name string) error
+	SetMeasurementPrivilege(username, database, measurement string, p influxql.Privilege, condition string) error
 	SetPrivilege(username, database string, p influxql.Privilege) error
 	ShardsByTimeRange(sources influxql.Sources, tmin, tmax time.Time) (a []meta.ShardInfo, err error)
+	Tokens() []meta.TokenInfo
 	UpdateRetentionPolicy(database, name string, rpu *meta.RetentionPolicyUpdate) error
 	UpdateUser(name, password string) error
+	UserMeasurementPrivilege(username, database, measurement string) (*meta.MeasurementPrivilege, error)
 	UserPrivilege(username, database string) (*influxql.Privilege, error)
 	UserPrivileges(username string) (map[string]influxql.Privilege, error)
 	Users() []meta.UserInfo
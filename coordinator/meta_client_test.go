@@ -12,8 +12,10 @@ type MetaClient struct {
 	CreateContinuousQueryFn             func(database, name, query string) error
 	CreateDatabaseFn                    func(name string) (*meta.DatabaseInfo, error)
 	CreateDatabaseWithRetentionPolicyFn func(name string, rpi *meta.RetentionPolicyInfo) (*meta.DatabaseInfo, error)
+	CreateDownsamplePolicyFn            func(database, rp string, dpi *meta.DownsamplePolicyInfo) error
 	CreateRetentionPolicyFn             func(database string, rpi *meta.RetentionPolicyInfo) (*meta.RetentionPolicyInfo, error)
 	CreateSubscriptionFn                func(database, rp, name, mode string, destinations []string) error
+	CreateTokenFn                       func(name, username string, privileges map[string]influxql.Privilege) (string, error)
 	CreateUserFn                        func(name, password string, admin bool) (*meta.UserInfo, error)
 	DatabaseFn                          func(name string) (*meta.DatabaseInfo, error)
 	DatabasesFn                         func() ([]meta.DatabaseInfo, error)
@@ -23,17 +25,22 @@ type MetaClient struct {
 	DeleteMetaNodeFn                    func(id uint64) error
 	DropContinuousQueryFn               func(database, name string) error
 	DropDatabaseFn                      func(name string) error
+	DropDownsamplePolicyFn              func(database, rp, targetDatabase, targetRP string) error
 	DropRetentionPolicyFn               func(database, name string) error
 	DropSubscriptionFn                  func(database, rp, name string) error
+	DropTokenFn                         func(name string) error
 	DropUserFn                          func(name string) error
 	MetaNodesFn                         func() ([]meta.NodeInfo, error)
 	RetentionPolicyFn                   func(database, name string) (rpi *meta.RetentionPolicyInfo, err error)
 	SetAdminPrivilegeFn                 func(username string, admin bool) error
 	SetDefaultRetentionPolicyFn         func(database, name string) error
+	SetMeasurementPrivilegeFn           func(username, database, measurement string, p influxql.Privilege, condition string) error
 	SetPrivilegeFn                      func(username, database string, p influxql.Privilege) error
 	ShardsByTimeRangeFn                 func(sources influxql.Sources, tmin, tmax time.Time) (a []meta.ShardInfo, err error)
+	TokensFn                            func() []meta.TokenInfo
 	UpdateRetentionPolicyFn             func(database, name string, rpu *meta.RetentionPolicyUpdate) error
 	UpdateUserFn                        func(name, password string) error
+	UserMeasurementPrivilegeFn          func(username, database, measurement string) (*meta.MeasurementPrivilege, error)
 	UserPrivilegeFn                     func(username, database string) (*influxql.Privilege, error)
 	UserPrivilegesFn                    func(username string) (map[string]influxql.Privilege, error)
 	UsersFn                             func() []meta.UserInfo
@@ -51,6 +58,10 @@ func (c *MetaClient) CreateDatabaseWithRetentionPolicy(name string, rpi *meta.Re
 	return c.CreateDatabaseWithRetentionPolicyFn(name, rpi)
 }
 
+func (c *MetaClient) CreateDownsamplePolicy(database, rp string, dpi *meta.DownsamplePolicyInfo) error {
+	return c.CreateDownsamplePolicyFn(database, rp, dpi)
+}
+
 func (c *MetaClient) CreateRetentionPolicy(database string, rpi *meta.RetentionPolicyInfo) (*meta.RetentionPolicyInfo, error) {
 	return c.CreateRetentionPolicyFn(database, rpi)
 }
@@ -59,6 +70,10 @@ func (c *MetaClient) CreateSubscription(database, rp, name, mode string, destina
 	return c.CreateSubscriptionFn(database, rp, name, mode, destinations)
 }
 
+func (c *MetaClient) CreateToken(name, username string, privileges map[string]influxql.Privilege) (string, error) {
+	return c.CreateTokenFn(name, username, privileges)
+}
+
 func (c *MetaClient) CreateUser(name, password string, admin bool) (*meta.UserInfo, error) {
 	return c.CreateUserFn(name, password, admin)
 }
@@ -95,6 +110,10 @@ func (c *MetaClient) DropDatabase(name string) error {
 	return c.DropDatabaseFn(name)
 }
 
+func (c *MetaClient) DropDownsamplePolicy(database, rp, targetDatabase, targetRP string) error {
+	return c.DropDownsamplePolicyFn(database, rp, targetDatabase, targetRP)
+}
+
 func (c *MetaClient) DropRetentionPolicy(database, name string) error {
 	return c.DropRetentionPolicyFn(database, name)
 }
@@ -103,6 +122,10 @@ func (c *MetaClient) DropSubscription(database, rp, name string) error {
 	return c.DropSubscriptionFn(database, rp, name)
 }
 
+func (c *MetaClient) DropToken(name string) error {
+	return c.DropTokenFn(name)
+}
+
 func (c *MetaClient) DropUser(name string) error {
 	return c.DropUserFn(name)
 }
@@ -123,6 +146,10 @@ func (c *MetaClient) SetDefaultRetentionPolicy(database, name string) error {
 	return c.SetDefaultRetentionPolicyFn(database, name)
 }
 
+func (c *MetaClient) SetMeasurementPrivilege(username, database, measurement string, p influxql.Privilege, condition string) error {
+	return c.SetMeasurementPrivilegeFn(username, database, measurement, p, condition)
+}
+
 func (c *MetaClient) SetPrivilege(username, database string, p influxql.Privilege) error {
 	return c.SetPrivilegeFn(username, database, p)
 }
@@ -131,6 +158,10 @@ func (c *MetaClient) ShardsByTimeRange(sources influxql.Sources, tmin, tmax time
 	return c.ShardsByTimeRangeFn(sources, tmin, tmax)
 }
 
+func (c *MetaClient) Tokens() []meta.TokenInfo {
+	return c.TokensFn()
+}
+
 func (c *MetaClient) UpdateRetentionPolicy(database, name string, rpu *meta.RetentionPolicyUpdate) error {
 	return c.UpdateRetentionPolicyFn(database, name, rpu)
 }
@@ -139,6 +170,10 @@ func (c *MetaClient) UpdateUser(name, password string) error {
 	return c.UpdateUserFn(name, password)
 }
 
+func (c *MetaClient) UserMeasurementPrivilege(username, database, measurement string) (*meta.MeasurementPrivilege, error) {
+	return c.UserMeasurementPrivilegeFn(username, database, measurement)
+}
+
 func (c *MetaClient) UserPrivilege(username, database string) (*influxql.Privilege, error) {
 	return c.UserPrivilegeFn(username, database)
 }
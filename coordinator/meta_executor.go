@@ -1,6 +1,7 @@
 package cluster
 
 import (
+	"encoding"
 	"fmt"
 	"log"
 	"net"
@@ -99,8 +100,55 @@ func (m *MetaExecutor) ExecuteStatement(stmt influxql.Statement, database string
 	}
 }
 
+// InvalidateQueryCache drops the cached query results of the windows of a
+// measurement at and after the window of t on all other nodes concurrently.
+func (m *MetaExecutor) InvalidateQueryCache(database, measurement string, t int64) error {
+	nodes, err := m.MetaClient.DataNodes()
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(nodes))
+	for _, node := range nodes {
+		if m.Node.ID == node.ID {
+			continue
+		}
+
+		wg.Add(1)
+		go func(node meta.NodeInfo) {
+			defer wg.Done()
+			if err := m.request(&node, invalidateQueryCacheRequestMessage, &InvalidateQueryCacheRequest{
+				Database:    database,
+				Measurement: measurement,
+				Time:        t,
+			}); err != nil {
+				errs <- remoteNodeError{id: node.ID, err: err}
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	select {
+	case err = <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
 // executeOnNode executes a single InfluxQL statement on a single node.
 func (m *MetaExecutor) executeOnNode(stmt influxql.Statement, database string, node *meta.NodeInfo) error {
+	// Build RPC request.
+	var request ExecuteStatementRequest
+	request.SetStatement(stmt.String())
+	request.SetDatabase(database)
+
+	return m.request(node, executeStatementRequestMessage, &request)
+}
+
+// request sends a request to a single node and reads its response.
+func (m *MetaExecutor) request(node *meta.NodeInfo, typ byte, request encoding.BinaryMarshaler) error {
 	// We're executing on a remote node so establish a connection.
 	c, err := m.dial(node.ID)
 	if err != nil {
@@ -114,11 +162,6 @@ func (m *MetaExecutor) executeOnNode(stmt influxql.Statement, database string, n
 	// Return connection to pool by "closing" it.
 	defer conn.Close()
 
-	// Build RPC request.
-	var request ExecuteStatementRequest
-	request.SetStatement(stmt.String())
-	request.SetDatabase(database)
-
 	// Marshal into protocol buffer.
 	buf, err := request.MarshalBinary()
 	if err != nil {
@@ -127,7 +170,7 @@ func (m *MetaExecutor) executeOnNode(stmt influxql.Statement, database string, n
 
 	// Send request.
 	conn.SetWriteDeadline(time.Now().Add(m.timeout))
-	if err := WriteTLV(conn, executeStatementRequestMessage, buf); err != nil {
+	if err := WriteTLV(conn, typ, buf); err != nil {
 		conn.MarkUnusable()
 		return err
 	}
@@ -31,6 +31,7 @@ const (
 	statWriteTimeout        = "writeTimeout"
 	statWriteErr            = "writeError"
 	statWritePointReqHH     = "pointReqHH"
+	statWriteThrottled      = "writeThrottled"
 	statSubWriteOK          = "subWriteOk"
 	statSubWriteDrop        = "subWriteDrop"
 )
@@ -115,6 +116,12 @@ type PointsWriter struct {
 	}
 	subPoints chan<- *WritePointsRequest
 
+	// WriteLimiter, if set, rejects writes while too many are in flight.
+	WriteLimiter *WriteLimiter
+
+	// QueryCache, if set, drops the cached query results that writes change.
+	QueryCache *QueryCache
+
 	statMap *expvar.Map
 }
 
@@ -235,6 +242,15 @@ func (w *PointsWriter) WritePoints(p *WritePointsRequest) error {
 	w.statMap.Add(statWriteReq, 1)
 	w.statMap.Add(statPointWriteReq, int64(len(p.Points)))
 
+	if w.WriteLimiter != nil {
+		n, size := int64(len(p.Points)), pointsSize(p.Points)
+		if err := w.WriteLimiter.Acquire(p.Database, n, size); err != nil {
+			w.statMap.Add(statWriteThrottled, 1)
+			return err
+		}
+		defer w.WriteLimiter.Release(p.Database, n, size)
+	}
+
 	if p.RetentionPolicy == "" {
 		db, err := w.MetaClient.Database(p.Database)
 		if err != nil {
@@ -250,6 +266,12 @@ func (w *PointsWriter) WritePoints(p *WritePointsRequest) error {
 		return err
 	}
 
+	// Drop the cached query results of the windows written to once the
+	// points are written, so queries running meanwhile don't cache them.
+	if w.QueryCache != nil {
+		defer w.QueryCache.Invalidate(p.Database, p.Points)
+	}
+
 	// Write each shard in it's own goroutine and return as soon
 	// as one fails.
 	ch := make(chan error, len(shardMappings.Points))
@@ -392,6 +414,10 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 	}
 
 	if writeError != nil {
+		// Let the client retry a write its owners throttled.
+		if _, ok := writeError.(*WriteThrottledError); ok {
+			return writeError
+		}
 		return fmt.Errorf("write failed: %v", writeError)
 	}
 
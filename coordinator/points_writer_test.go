@@ -221,6 +221,16 @@ func TestPointsWriter_WritePoints(t *testing.T) {
 			expErr:          fmt.Errorf("write failed: a failure"),
 		},
 
+		// Throttled by every owner
+		{
+			name:            "all writes throttled",
+			database:        "mydb",
+			retentionPolicy: "myrp",
+			consistency:     cluster.ConsistencyLevelOne,
+			err:             []error{&cluster.WriteThrottledError{Limit: "a limit"}, &cluster.WriteThrottledError{Limit: "a limit"}, &cluster.WriteThrottledError{Limit: "a limit"}},
+			expErr:          &cluster.WriteThrottledError{Limit: "a limit"},
+		},
+
 		// Hinted handoff w/ ANY
 		{
 			name:            "hinted handoff write succeed",
@@ -0,0 +1,522 @@
+package cluster
+
+import (
+	"container/list"
+	"expvar"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/freetsdb/freetsdb"
+	"github.com/freetsdb/freetsdb/influxql"
+	"github.com/freetsdb/freetsdb/models"
+)
+
+// Statistics for the QueryCache
+const (
+	statQueryCacheHit   = "hit"   // Number of queries that used cached windows
+	statQueryCacheMiss  = "miss"  // Number of cacheable queries without cached windows
+	statQueryCacheEvict = "evict" // Number of entries evicted to stay within the max size
+	statQueryCacheSize  = "size"  // Approximate size of the cached values in bytes
+)
+
+// DefaultQueryCacheTTL is the default time results stay in the query cache.
+const DefaultQueryCacheTTL = 10 * time.Minute
+
+// QueryCache caches the results of the complete GROUP BY time() windows of
+// aggregate queries. Dashboards that repeat a query over a sliding time range
+// only compute the windows that are not cached.
+//
+// The windows of an entry are dropped when points are written into them.
+// Writes into the shards of other nodes are sent to this node by their
+// Broadcaster, and the data dropped by statements is dropped from the cache
+// of every node executing them.
+type QueryCache struct {
+	mu      sync.Mutex
+	size    int64
+	entries map[string]*list.Element
+	lru     *list.List
+	lookups map[*queryCacheLookup]struct{}
+
+	// The keys of the entries reading each measurement of each database,
+	// so writes only visit the entries they invalidate.
+	index map[string]map[string]map[string]struct{}
+
+	// The earliest time written to each measurement that has not been
+	// broadcast yet. It is nil while no broadcast is in progress.
+	pending map[queryCacheSource]int64
+
+	// MaxSize is the approximate size of the cached values in bytes.
+	MaxSize int64
+
+	// TTL is how long an entry is used after it is stored.
+	TTL time.Duration
+
+	// Broadcaster, if set, drops the windows written to on this node from
+	// the query caches of the other data nodes.
+	Broadcaster interface {
+		InvalidateQueryCache(database, measurement string, t int64) error
+	}
+
+	Logger  *log.Logger
+	statMap *expvar.Map
+}
+
+// NewQueryCache returns a new instance of QueryCache with the limits in c.
+func NewQueryCache(c Config) *QueryCache {
+	return &QueryCache{
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		lookups: make(map[*queryCacheLookup]struct{}),
+		index:   make(map[string]map[string]map[string]struct{}),
+		MaxSize: c.QueryCacheMaxSize,
+		TTL:     time.Duration(c.QueryCacheTTL),
+		Logger:  log.New(os.Stderr, "[query-cache] ", log.LstdFlags),
+		statMap: freetsdb.NewStatistics("queryCache", "queryCache", nil),
+	}
+}
+
+// queryCacheSource is a measurement read by a cached query.
+type queryCacheSource struct {
+	database    string
+	measurement string
+}
+
+// queryCacheEntry holds the rows of the windows in [start, end) of a query.
+// Entries are not modified once stored so lookups can read them unlocked.
+type queryCacheEntry struct {
+	key      string
+	sources  []queryCacheSource
+	interval influxql.Interval
+	start    int64
+	end      int64
+	rows     []*models.Row
+	size     int64
+	expires  time.Time
+}
+
+// queryCacheLookup holds the cached windows of a query while the rest of
+// its windows are computed.
+type queryCacheLookup struct {
+	key      string
+	sources  []queryCacheSource
+	interval influxql.Interval
+	entry    *queryCacheEntry
+
+	// The earliest time written to one of the sources since the lookup.
+	written int64
+}
+
+// lookup returns the cached windows of the query identified by key. The
+// returned entry is nil if no window in [start, end) is cached. Every
+// lookup must be followed by release.
+func (c *QueryCache) lookup(key string, sources []queryCacheSource, interval influxql.Interval, start, end int64) (*queryCacheLookup, *queryCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l := &queryCacheLookup{key: key, sources: sources, interval: interval, written: math.MaxInt64}
+	c.lookups[l] = struct{}{}
+
+	if elem := c.entries[key]; elem != nil {
+		entry := elem.Value.(*queryCacheEntry)
+		if time.Now().After(entry.expires) {
+			c.remove(elem)
+		} else if entry.start < end && entry.end > start {
+			c.lru.MoveToFront(elem)
+			l.entry = entry
+		}
+	}
+
+	if l.entry != nil {
+		c.statMap.Add(statQueryCacheHit, 1)
+	} else {
+		c.statMap.Add(statQueryCacheMiss, 1)
+	}
+	return l, l.entry
+}
+
+// store caches the rows of the windows in [start, end) of a lookup. Windows
+// written to since the lookup are not stored.
+func (c *QueryCache) store(l *queryCacheLookup, start, end int64, rows []*models.Row) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if l.written < end {
+		end, _ = influxql.IteratorOptions{Interval: l.interval}.Window(l.written)
+	}
+	if start >= end {
+		return
+	}
+
+	entry := newQueryCacheEntry(l.key, l.sources, l.interval, start, end, rows)
+	entry.expires = time.Now().Add(c.TTL)
+	if entry.size > c.MaxSize {
+		return
+	}
+
+	if elem := c.entries[l.key]; elem != nil {
+		c.remove(elem)
+	}
+	c.insert(entry, c.lru.PushFront(entry))
+
+	// Evict the least recently used entries until the cache fits.
+	for c.size > c.MaxSize {
+		c.remove(c.lru.Back())
+		c.statMap.Add(statQueryCacheEvict, 1)
+	}
+}
+
+// release ends a lookup.
+func (c *QueryCache) release(l *queryCacheLookup) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.lookups, l)
+}
+
+// Invalidate drops the cached windows of the measurements of database that
+// points were written into, on this node and through the Broadcaster on the
+// other data nodes.
+func (c *QueryCache) Invalidate(database string, points []models.Point) {
+	if len(points) == 0 {
+		return
+	}
+
+	// Find the earliest time written to each measurement.
+	min := make(map[string]int64)
+	for _, p := range points {
+		if t, ok := min[p.Name()]; !ok || p.Time().UnixNano() < t {
+			min[p.Name()] = p.Time().UnixNano()
+		}
+	}
+
+	for name, t := range min {
+		c.invalidate(database, name, t)
+		if c.Broadcaster != nil {
+			c.broadcast(database, name, t)
+		}
+	}
+}
+
+// InvalidateDatabase drops all cached windows of database.
+func (c *QueryCache) InvalidateDatabase(database string) {
+	c.invalidate(database, "", math.MinInt64)
+}
+
+// invalidateStatement drops the cached windows of the data stmt removes.
+// Statements are executed on every node, so this isn't broadcast.
+func (c *QueryCache) invalidateStatement(stmt influxql.Statement, database string) {
+	switch stmt := stmt.(type) {
+	case *influxql.DropDatabaseStatement:
+		c.InvalidateDatabase(stmt.Name)
+	case *influxql.DropRetentionPolicyStatement:
+		c.InvalidateDatabase(stmt.Database)
+	case *influxql.DropMeasurementStatement:
+		c.invalidate(database, stmt.Name, math.MinInt64)
+	case *influxql.DropSeriesStatement, *influxql.DeleteStatement:
+		c.InvalidateDatabase(database)
+	}
+}
+
+// broadcast queues the invalidation of the windows of a measurement at and
+// after the window of t on the other data nodes. Invalidations queued while
+// others are sent are merged, so slow nodes don't hold up writes.
+func (c *QueryCache) broadcast(database, measurement string, t int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending == nil {
+		c.pending = make(map[queryCacheSource]int64)
+		go c.sendPending()
+	}
+	src := queryCacheSource{database: database, measurement: measurement}
+	if other, ok := c.pending[src]; !ok || t < other {
+		c.pending[src] = t
+	}
+}
+
+// sendPending broadcasts the queued invalidations until none are left.
+func (c *QueryCache) sendPending() {
+	for {
+		c.mu.Lock()
+		pending := c.pending
+		if len(pending) == 0 {
+			c.pending = nil
+			c.mu.Unlock()
+			return
+		}
+		c.pending = make(map[queryCacheSource]int64)
+		c.mu.Unlock()
+
+		for src, t := range pending {
+			if err := c.Broadcaster.InvalidateQueryCache(src.database, src.measurement, t); err != nil {
+				c.Logger.Printf("failed to invalidate query cache of %s.%s: %s", src.database, src.measurement, err)
+			}
+		}
+	}
+}
+
+// invalidate drops the cached windows of a measurement at and after the
+// window of t on this node. An empty measurement matches every measurement
+// of database.
+func (c *QueryCache) invalidate(database, measurement string, t int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for l := range c.lookups {
+		if matchQueryCacheSources(l.sources, database, measurement) && t < l.written {
+			l.written = t
+		}
+	}
+
+	// Collect the keys first since invalidating an entry updates the index.
+	var keys []string
+	for name, set := range c.index[database] {
+		if measurement == "" || name == "" || name == measurement {
+			for key := range set {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		elem := c.entries[key]
+		if elem == nil {
+			continue
+		}
+		entry := elem.Value.(*queryCacheEntry)
+		if t >= entry.end {
+			continue
+		}
+
+		// Keep the windows before the first window written to.
+		end := int64(math.MinInt64)
+		if t != math.MinInt64 {
+			end, _ = influxql.IteratorOptions{Interval: entry.interval}.Window(t)
+		}
+
+		next := elem.Next()
+		c.remove(elem)
+		if end > entry.start {
+			other := newQueryCacheEntry(entry.key, entry.sources, entry.interval, entry.start, end, entry.rows)
+			other.expires = entry.expires
+			if next != nil {
+				c.insert(other, c.lru.InsertBefore(other, next))
+			} else {
+				c.insert(other, c.lru.PushBack(other))
+			}
+		}
+	}
+}
+
+// insert adds the element of entry to the entries and the index.
+func (c *QueryCache) insert(entry *queryCacheEntry, elem *list.Element) {
+	c.entries[entry.key] = elem
+	for _, src := range entry.sources {
+		measurements := c.index[src.database]
+		if measurements == nil {
+			measurements = make(map[string]map[string]struct{})
+			c.index[src.database] = measurements
+		}
+		keys := measurements[src.measurement]
+		if keys == nil {
+			keys = make(map[string]struct{})
+			measurements[src.measurement] = keys
+		}
+		keys[entry.key] = struct{}{}
+	}
+	c.add(entry.size)
+}
+
+func (c *QueryCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*queryCacheEntry)
+	delete(c.entries, entry.key)
+	for _, src := range entry.sources {
+		keys := c.index[src.database][src.measurement]
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(c.index[src.database], src.measurement)
+			if len(c.index[src.database]) == 0 {
+				delete(c.index, src.database)
+			}
+		}
+	}
+	c.add(-entry.size)
+}
+
+func (c *QueryCache) add(size int64) {
+	c.size += size
+	c.statMap.Add(statQueryCacheSize, size)
+}
+
+// newQueryCacheEntry returns an entry with the values of rows in [start, end).
+func newQueryCacheEntry(key string, sources []queryCacheSource, interval influxql.Interval, start, end int64, rows []*models.Row) *queryCacheEntry {
+	entry := &queryCacheEntry{
+		key:      key,
+		sources:  sources,
+		interval: interval,
+		start:    start,
+		end:      end,
+		size:     int64(len(key)),
+	}
+	for _, row := range filterRows(rows, start, end) {
+		entry.rows = append(entry.rows, row)
+		entry.size += rowSize(row)
+	}
+	return entry
+}
+
+// filterRows returns copies of the rows with their values in [start, end).
+// Rows without values in the range are left out. The values are copied since
+// results may be modified once they are sent.
+func filterRows(rows []*models.Row, start, end int64) []*models.Row {
+	var a []*models.Row
+	for _, row := range rows {
+		var values [][]interface{}
+		for _, v := range row.Values {
+			if t := v[0].(time.Time).UnixNano(); t >= start && t < end {
+				values = append(values, append([]interface{}{}, v...))
+			}
+		}
+		if len(values) > 0 {
+			a = append(a, &models.Row{Name: row.Name, Tags: row.Tags, Columns: row.Columns, Values: values})
+		}
+	}
+	return a
+}
+
+// rowSize returns the approximate size of a row in bytes.
+func rowSize(row *models.Row) int64 {
+	size := int64(len(row.Name))
+	for k, v := range row.Tags {
+		size += int64(len(k) + len(v))
+	}
+	for _, values := range row.Values {
+		for _, v := range values {
+			size += 16
+			if s, ok := v.(string); ok {
+				size += int64(len(s))
+			}
+		}
+	}
+	return size
+}
+
+// queryCacheFunctions are the functions whose result in a window only
+// depends on the points in the window.
+var queryCacheFunctions = map[string]struct{}{
+	"count": {}, "distinct": {}, "sum": {}, "mean": {}, "median": {}, "mode": {},
+	"spread": {}, "stddev": {}, "min": {}, "max": {}, "first": {}, "last": {},
+	"percentile": {}, "percentile_approx": {}, "tdigest": {}, "histogram": {},
+	"top": {}, "bottom": {},
+}
+
+// isQueryCacheable returns true if the results of stmt can be cached. The
+// result of each GROUP BY time() window must only depend on the points in
+// the window.
+func isQueryCacheable(stmt *influxql.SelectStatement) bool {
+	if stmt.IsRawQuery || stmt.Target != nil || stmt.OmitTime || stmt.Location != nil {
+		return false
+	} else if stmt.Limit > 0 || stmt.Offset > 0 || stmt.SLimit > 0 || stmt.SOffset > 0 {
+		return false
+	} else if stmt.Fill == influxql.PreviousFill || stmt.Fill == influxql.LinearFill {
+		return false
+	} else if interval, err := stmt.GroupByInterval(); err != nil || interval <= 0 {
+		return false
+	}
+
+	cacheable := true
+	influxql.WalkFunc(stmt.Fields, func(n influxql.Node) {
+		if call, ok := n.(*influxql.Call); ok {
+			if _, ok := queryCacheFunctions[call.Name]; !ok {
+				cacheable = false
+			}
+		}
+	})
+	return cacheable
+}
+
+// queryCacheKey returns the key of the results of stmt in the query cache and
+// the measurements it reads from. The time range of stmt is not part of the
+// key. The sources and condition of stmt have already been restricted to the
+// series the user may read, so users with different grants get different keys.
+func queryCacheKey(stmt *influxql.SelectStatement) (string, []queryCacheSource, error) {
+	other := stmt.Clone()
+	if err := other.SetTimeRange(time.Unix(0, 0), time.Unix(0, 0)); err != nil {
+		return "", nil, err
+	}
+
+	var sources []queryCacheSource
+	for _, src := range stmt.Sources {
+		m, ok := src.(*influxql.Measurement)
+		if !ok {
+			continue
+		}
+
+		// Regex sources are expanded before the statement is executed, so
+		// one that is left did not match any measurement yet.
+		name := m.Name
+		if m.Regex != nil {
+			name = ""
+		}
+		sources = append(sources, queryCacheSource{database: m.Database, measurement: name})
+	}
+	return other.String(), sources, nil
+}
+
+// mergeRows merges the rows of the same series and sorts their values by time.
+// The series are sorted in the order the Emitter returns them.
+func mergeRows(ascending bool, a ...[]*models.Row) []*models.Row {
+	var rows []*models.Row
+	for _, other := range a {
+	OTHER:
+		for _, row := range other {
+			for _, r := range rows {
+				if r.SameSeries(row) {
+					r.Values = append(r.Values, row.Values...)
+					continue OTHER
+				}
+			}
+			rows = append(rows, &models.Row{Name: row.Name, Tags: row.Tags, Columns: row.Columns, Values: append([][]interface{}{}, row.Values...)})
+		}
+	}
+
+	for _, row := range rows {
+		values := row.Values
+		sort.SliceStable(values, func(i, j int) bool {
+			ti, tj := values[i][0].(time.Time), values[j][0].(time.Time)
+			if ascending {
+				return ti.Before(tj)
+			}
+			return ti.After(tj)
+		})
+	}
+
+	ids := make(map[*models.Row]string, len(rows))
+	for _, row := range rows {
+		ids[row] = influxql.NewTags(row.Tags).ID()
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		x, y := rows[i], rows[j]
+		if x.Name != y.Name {
+			return (x.Name < y.Name) == ascending
+		}
+		return (ids[x] < ids[y]) == ascending
+	})
+	return rows
+}
+
+// matchQueryCacheSources returns true if one of sources is a measurement of
+// database. An empty measurement matches every measurement.
+func matchQueryCacheSources(sources []queryCacheSource, database, measurement string) bool {
+	for _, src := range sources {
+		if src.database != database {
+			continue
+		} else if measurement == "" || src.measurement == "" || src.measurement == measurement {
+			return true
+		}
+	}
+	return false
+}
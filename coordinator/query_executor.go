@@ -12,12 +12,15 @@ import (
 	"net"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/freetsdb/freetsdb"
 	"github.com/freetsdb/freetsdb/influxql"
 	"github.com/freetsdb/freetsdb/models"
 	"github.com/freetsdb/freetsdb/monitor"
+	"github.com/freetsdb/freetsdb/services/audit"
+	"github.com/freetsdb/freetsdb/services/hh"
 	"github.com/freetsdb/freetsdb/services/meta"
 )
 
@@ -41,9 +44,35 @@ type QueryExecutor struct {
 	// Used for executing meta statements on all data nodes.
 	MetaExecutor *MetaExecutor
 
+	// Records non-SELECT statements in the audit log.
+	Auditor interface {
+		Audit(r *audit.Record)
+	}
+
+	// Used for backfilling continuous queries.
+	ContinuousQuerier interface {
+		Backfill(database, name string, start, end time.Time, progress func(start, end time.Time, chunk, chunks int), closing <-chan struct{}) error
+	}
+
+	// Used for showing and controlling the hinted handoff queues.
+	HintedHandoff interface {
+		NodeStatuses() ([]*hh.NodeStatus, error)
+		PurgeNode(nodeID uint64) error
+		PauseNode(nodeID uint64) error
+		ResumeNode(nodeID uint64) error
+	}
+
 	// Remote execution timeout
 	Timeout time.Duration
 
+	// Limits on the resources used by a SELECT statement. Zero is unlimited.
+	MaxSelectPointN   int
+	MaxSelectSeriesN  int
+	MaxSelectBucketsN int
+
+	// Caches the results of repeated aggregate queries, if set.
+	QueryCache *QueryCache
+
 	// Output of all logging.
 	// Defaults to discarding all log output.
 	LogOutput io.Writer
@@ -68,13 +97,13 @@ func NewQueryExecutor() *QueryExecutor {
 }
 
 // ExecuteQuery executes each statement within a query.
-func (e *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chunkSize int, closing chan struct{}) <-chan *influxql.Result {
+func (e *QueryExecutor) ExecuteQuery(query *influxql.Query, opt influxql.ExecutionOptions, closing chan struct{}) <-chan *influxql.Result {
 	results := make(chan *influxql.Result)
-	go e.executeQuery(query, database, chunkSize, closing, results)
+	go e.executeQuery(query, opt, closing, results)
 	return results
 }
 
-func (e *QueryExecutor) executeQuery(query *influxql.Query, database string, chunkSize int, closing chan struct{}, results chan *influxql.Result) {
+func (e *QueryExecutor) executeQuery(query *influxql.Query, opt influxql.ExecutionOptions, closing chan struct{}, results chan *influxql.Result) {
 	defer close(results)
 
 	e.statMap.Add(statQueriesActive, 1)
@@ -85,6 +114,8 @@ func (e *QueryExecutor) executeQuery(query *influxql.Query, database string, chu
 
 	logger := e.logger()
 
+	database, chunkSize := opt.Database, opt.ChunkSize
+
 	var i int
 	for ; i < len(query.Statements); i++ {
 		stmt := query.Statements[i]
@@ -101,13 +132,22 @@ func (e *QueryExecutor) executeQuery(query *influxql.Query, database string, chu
 		// This can occur on meta read statements which convert to SELECT statements.
 		newStmt, err := influxql.RewriteStatement(stmt)
 		if err != nil {
+			e.audit(opt, query.Statements[i], defaultDB, err)
 			results <- &influxql.Result{Err: err}
 			break
 		}
 		stmt = newStmt
 
+		// Bind any parameters passed with the query.
+		if err := influxql.BindParameters(stmt, opt.Params); err != nil {
+			e.audit(opt, query.Statements[i], defaultDB, err)
+			results <- &influxql.Result{Err: err}
+			break
+		}
+
 		// Normalize each statement.
 		if err := e.normalizeStatement(stmt, defaultDB); err != nil {
+			e.audit(opt, query.Statements[i], defaultDB, err)
 			results <- &influxql.Result{Err: err}
 			break
 		}
@@ -117,7 +157,20 @@ func (e *QueryExecutor) executeQuery(query *influxql.Query, database string, chu
 
 		// Select statements are handled separately so that they can be streamed.
 		if stmt, ok := stmt.(*influxql.SelectStatement); ok {
-			if err := e.executeSelectStatement(stmt, chunkSize, i, results, closing); err != nil {
+			err := e.executeSelectStatement(stmt, chunkSize, i, results, closing)
+			e.audit(opt, query.Statements[i], defaultDB, err)
+			if err != nil {
+				results <- &influxql.Result{StatementID: i, Err: err}
+				break
+			}
+			continue
+		}
+
+		// Backfills report their progress as they run so they are also handled separately.
+		if stmt, ok := stmt.(*influxql.RunContinuousQueryStatement); ok {
+			err := e.executeRunContinuousQueryStatement(stmt, i, results, closing)
+			e.audit(opt, query.Statements[i], defaultDB, err)
+			if err != nil {
 				results <- &influxql.Result{StatementID: i, Err: err}
 				break
 			}
@@ -132,16 +185,22 @@ func (e *QueryExecutor) executeQuery(query *influxql.Query, database string, chu
 			err = e.executeCreateContinuousQueryStatement(stmt)
 		case *influxql.CreateDatabaseStatement:
 			err = e.executeCreateDatabaseStatement(stmt)
+		case *influxql.CreateDownsampleStatement:
+			err = e.executeCreateDownsampleStatement(stmt)
 		case *influxql.CreateRetentionPolicyStatement:
 			err = e.executeCreateRetentionPolicyStatement(stmt)
 		case *influxql.CreateSubscriptionStatement:
 			err = e.executeCreateSubscriptionStatement(stmt)
+		case *influxql.CreateTokenStatement:
+			rows, err = e.executeCreateTokenStatement(stmt)
 		case *influxql.CreateUserStatement:
 			err = e.executeCreateUserStatement(stmt)
 		case *influxql.DropContinuousQueryStatement:
 			err = e.executeDropContinuousQueryStatement(stmt)
 		case *influxql.DropDatabaseStatement:
 			err = e.executeDropDatabaseStatement(stmt)
+		case *influxql.DropDownsampleStatement:
+			err = e.executeDropDownsampleStatement(stmt)
 		case *influxql.DropMeasurementStatement:
 			err = e.executeDropMeasurementStatement(stmt, database)
 		case *influxql.DropSeriesStatement:
@@ -152,12 +211,22 @@ func (e *QueryExecutor) executeQuery(query *influxql.Query, database string, chu
 			err = e.executeDropServerStatement(stmt)
 		case *influxql.DropSubscriptionStatement:
 			err = e.executeDropSubscriptionStatement(stmt)
+		case *influxql.DropTokenStatement:
+			err = e.executeDropTokenStatement(stmt)
 		case *influxql.DropUserStatement:
 			err = e.executeDropUserStatement(stmt)
+		case *influxql.ExplainStatement:
+			rows, err = e.executeExplainStatement(stmt)
 		case *influxql.GrantStatement:
 			err = e.executeGrantStatement(stmt)
 		case *influxql.GrantAdminStatement:
 			err = e.executeGrantAdminStatement(stmt)
+		case *influxql.PauseHintedHandoffStatement:
+			err = e.executePauseHintedHandoffStatement(stmt)
+		case *influxql.PurgeHintedHandoffStatement:
+			err = e.executePurgeHintedHandoffStatement(stmt)
+		case *influxql.ResumeHintedHandoffStatement:
+			err = e.executeResumeHintedHandoffStatement(stmt)
 		case *influxql.RevokeStatement:
 			err = e.executeRevokeStatement(stmt)
 		case *influxql.RevokeAdminStatement:
@@ -168,8 +237,12 @@ func (e *QueryExecutor) executeQuery(query *influxql.Query, database string, chu
 			rows, err = e.executeShowDatabasesStatement(stmt)
 		case *influxql.ShowDiagnosticsStatement:
 			rows, err = e.executeShowDiagnosticsStatement(stmt)
+		case *influxql.ShowDownsamplesStatement:
+			rows, err = e.executeShowDownsamplesStatement(stmt)
 		case *influxql.ShowGrantsForUserStatement:
 			rows, err = e.executeShowGrantsForUserStatement(stmt)
+		case *influxql.ShowHintedHandoffStatement:
+			rows, err = e.executeShowHintedHandoffStatement(stmt)
 		case *influxql.ShowRetentionPoliciesStatement:
 			rows, err = e.executeShowRetentionPoliciesStatement(stmt)
 		case *influxql.ShowServersStatement:
@@ -184,6 +257,8 @@ func (e *QueryExecutor) executeQuery(query *influxql.Query, database string, chu
 			rows, err = e.executeShowSubscriptionsStatement(stmt)
 		case *influxql.ShowTagValuesStatement:
 			rows, err = e.executeShowTagValuesStatement(stmt, database)
+		case *influxql.ShowTokensStatement:
+			rows, err = e.executeShowTokensStatement(stmt)
 		case *influxql.ShowUsersStatement:
 			rows, err = e.executeShowUsersStatement(stmt)
 		case *influxql.SetPasswordUserStatement:
@@ -191,6 +266,13 @@ func (e *QueryExecutor) executeQuery(query *influxql.Query, database string, chu
 		default:
 			err = influxql.ErrInvalidQuery
 		}
+		e.audit(opt, query.Statements[i], defaultDB, err)
+
+		// Drop the cached results of the data that was removed. Data may
+		// be removed even if the statement failed on other nodes.
+		if e.QueryCache != nil {
+			e.QueryCache.invalidateStatement(stmt, database)
+		}
 
 		// Send results for each statement.
 		results <- &influxql.Result{
@@ -252,6 +334,15 @@ func (e *QueryExecutor) executeCreateDatabaseStatement(stmt *influxql.CreateData
 	return err
 }
 
+func (e *QueryExecutor) executeCreateDownsampleStatement(stmt *influxql.CreateDownsampleStatement) error {
+	return e.MetaClient.CreateDownsamplePolicy(stmt.Database, stmt.RetentionPolicy, &meta.DownsamplePolicyInfo{
+		Database:        stmt.TargetDatabase,
+		RetentionPolicy: stmt.TargetRetentionPolicy,
+		Interval:        stmt.Interval,
+		Aggregates:      stmt.Aggregates,
+	})
+}
+
 func (e *QueryExecutor) executeCreateRetentionPolicyStatement(stmt *influxql.CreateRetentionPolicyStatement) error {
 	rpi := meta.NewRetentionPolicyInfo(stmt.Name)
 	rpi.Duration = stmt.Duration
@@ -307,6 +398,10 @@ func (e *QueryExecutor) executeDropDatabaseStatement(stmt *influxql.DropDatabase
 	return e.MetaExecutor.ExecuteStatement(stmt, "")
 }
 
+func (e *QueryExecutor) executeDropDownsampleStatement(stmt *influxql.DropDownsampleStatement) error {
+	return e.MetaClient.DropDownsamplePolicy(stmt.Database, stmt.RetentionPolicy, stmt.TargetDatabase, stmt.TargetRetentionPolicy)
+}
+
 func (e *QueryExecutor) executeDropMeasurementStatement(stmt *influxql.DropMeasurementStatement, database string) error {
 	if dbi, err := e.MetaClient.Database(database); err != nil {
 		return err
@@ -369,11 +464,43 @@ func (e *QueryExecutor) executeDropSubscriptionStatement(q *influxql.DropSubscri
 	return e.MetaClient.DropSubscription(q.Database, q.RetentionPolicy, q.Name)
 }
 
+func (e *QueryExecutor) executeCreateTokenStatement(q *influxql.CreateTokenStatement) (models.Rows, error) {
+	var privileges map[string]influxql.Privilege
+	if len(q.Privileges) > 0 {
+		privileges = make(map[string]influxql.Privilege)
+		for _, p := range q.Privileges {
+			privileges[p.Database] |= p.Privilege
+		}
+	}
+
+	token, err := e.MetaClient.CreateToken(q.Name, q.User, privileges)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token is only ever returned here; the meta store keeps a hash.
+	return []*models.Row{{
+		Columns: []string{"name", "token"},
+		Values:  [][]interface{}{{q.Name, token}},
+	}}, nil
+}
+
+func (e *QueryExecutor) executeDropTokenStatement(q *influxql.DropTokenStatement) error {
+	return e.MetaClient.DropToken(q.Name)
+}
+
 func (e *QueryExecutor) executeDropUserStatement(q *influxql.DropUserStatement) error {
 	return e.MetaClient.DropUser(q.Name)
 }
 
 func (e *QueryExecutor) executeGrantStatement(stmt *influxql.GrantStatement) error {
+	if stmt.Measurement != "" {
+		var cond string
+		if stmt.Condition != nil {
+			cond = stmt.Condition.String()
+		}
+		return e.MetaClient.SetMeasurementPrivilege(stmt.User, stmt.On, stmt.Measurement, stmt.Privilege, cond)
+	}
 	return e.MetaClient.SetPrivilege(stmt.User, stmt.On, stmt.Privilege)
 }
 
@@ -382,6 +509,10 @@ func (e *QueryExecutor) executeGrantAdminStatement(stmt *influxql.GrantAdminStat
 }
 
 func (e *QueryExecutor) executeRevokeStatement(stmt *influxql.RevokeStatement) error {
+	if stmt.Measurement != "" {
+		return e.executeRevokeMeasurementStatement(stmt)
+	}
+
 	priv := influxql.NoPrivileges
 
 	// Revoking all privileges means there's no need to look at existing user privileges.
@@ -397,6 +528,23 @@ func (e *QueryExecutor) executeRevokeStatement(stmt *influxql.RevokeStatement) e
 	return e.MetaClient.SetPrivilege(stmt.User, stmt.On, priv)
 }
 
+func (e *QueryExecutor) executeRevokeMeasurementStatement(stmt *influxql.RevokeStatement) error {
+	mp, err := e.MetaClient.UserMeasurementPrivilege(stmt.User, stmt.On, stmt.Measurement)
+	if err != nil {
+		return err
+	} else if mp == nil {
+		return nil
+	}
+
+	// Bit clear (AND NOT) the user's privilege with the revoked privilege.
+	// The grant's condition is kept when only part of the privilege is revoked.
+	priv := influxql.NoPrivileges
+	if stmt.Privilege != influxql.AllPrivileges {
+		priv = mp.Privilege &^ stmt.Privilege
+	}
+	return e.MetaClient.SetMeasurementPrivilege(stmt.User, stmt.On, stmt.Measurement, priv, mp.Condition)
+}
+
 func (e *QueryExecutor) executeRevokeAdminStatement(stmt *influxql.RevokeAdminStatement) error {
 	return e.MetaClient.SetAdminPrivilege(stmt.User, false)
 }
@@ -408,33 +556,19 @@ func (e *QueryExecutor) executeSetPasswordUserStatement(q *influxql.SetPasswordU
 func (e *QueryExecutor) executeSelectStatement(stmt *influxql.SelectStatement, chunkSize, statementID int, results chan *influxql.Result, closing <-chan struct{}) error {
 	// It is important to "stamp" this time so that everywhere we evaluate `now()` in the statement is EXACTLY the same `now`
 	now := time.Now().UTC()
-	opt := influxql.SelectOptions{}
-
-	// Replace instances of "now()" with the current time, and check the resultant times.
-	stmt.Condition = influxql.Reduce(stmt.Condition, &influxql.NowValuer{Now: now})
-	opt.MinTime, opt.MaxTime = influxql.TimeRange(stmt.Condition)
-	if opt.MaxTime.IsZero() {
-		opt.MaxTime = now
-	}
-	if opt.MinTime.IsZero() {
-		opt.MinTime = time.Unix(0, 0)
-	}
-
-	// Expand regex sources to their actual source names.
-	sources, err := e.TSDBStore.ExpandSources(stmt.Sources)
+	opt, err := e.prepareSelectStatement(stmt, now)
 	if err != nil {
 		return err
 	}
-	stmt.Sources = sources
-
-	// Convert DISTINCT into a call.
-	stmt.RewriteDistinct()
 
-	// Remove "time" from fields list.
-	stmt.RewriteTimeFields()
+	// Count the points read from shards if they are limited.
+	var limiter *influxql.PointLimiter
+	if e.MaxSelectPointN > 0 {
+		limiter = influxql.NewPointLimiter(e.MaxSelectPointN)
+	}
 
 	// Create an iterator creator based on the shards in the cluster.
-	ic, err := e.iteratorCreator(stmt, &opt)
+	ic, err := e.iteratorCreator(stmt, &opt, limiter)
 	if err != nil {
 		return err
 	}
@@ -446,6 +580,16 @@ func (e *QueryExecutor) executeSelectStatement(stmt *influxql.SelectStatement, c
 	}
 	stmt = tmp
 
+	// Validate the number of series read.
+	if err := e.validateSelectSeries(stmt, ic, &opt); err != nil {
+		return err
+	}
+
+	// Only compute the windows of repeated aggregate queries that aren't cached.
+	if e.QueryCache != nil && isQueryCacheable(stmt) {
+		return e.executeCachedSelectStatement(stmt, ic, &opt, limiter, now, chunkSize, statementID, results, closing)
+	}
+
 	// Create a set of iterators from a selection.
 	itrs, err := influxql.Select(stmt, ic, &opt)
 	if err != nil {
@@ -456,6 +600,7 @@ func (e *QueryExecutor) executeSelectStatement(stmt *influxql.SelectStatement, c
 	em := influxql.NewEmitter(itrs, stmt.TimeAscending())
 	em.Columns = stmt.ColumnNames()
 	em.OmitTime = stmt.OmitTime
+	em.Location = stmt.Location
 	defer em.Close()
 
 	// Emit rows to the results channel.
@@ -463,13 +608,16 @@ func (e *QueryExecutor) executeSelectStatement(stmt *influxql.SelectStatement, c
 	var emitted bool
 	for {
 		row := em.Emit()
-		if row == nil {
-			break
+
+		// Return an error if the iterators were ended by the point limit.
+		if limiter != nil {
+			if err := limiter.Err(); err != nil {
+				return err
+			}
 		}
 
-		result := &influxql.Result{
-			StatementID: statementID,
-			Series:      []*models.Row{row},
+		if row == nil {
+			break
 		}
 
 		// Write points back into system for INTO statements.
@@ -482,10 +630,8 @@ func (e *QueryExecutor) executeSelectStatement(stmt *influxql.SelectStatement, c
 		}
 
 		// Send results or exit if closing.
-		select {
-		case <-closing:
+		if !sendRow(row, chunkSize, statementID, results, closing) {
 			return nil
-		case results <- result:
 		}
 
 		emitted = true
@@ -515,8 +661,304 @@ func (e *QueryExecutor) executeSelectStatement(stmt *influxql.SelectStatement, c
 	return nil
 }
 
+// prepareSelectStatement rewrites stmt for execution at now and returns the
+// options it is selected with.
+func (e *QueryExecutor) prepareSelectStatement(stmt *influxql.SelectStatement, now time.Time) (influxql.SelectOptions, error) {
+	opt := influxql.SelectOptions{}
+
+	// Replace instances of "now()" with the current time, and check the resultant times.
+	stmt.Condition = influxql.Reduce(stmt.Condition, &influxql.NowValuer{Now: now})
+	opt.MinTime, opt.MaxTime = influxql.TimeRange(stmt.Condition)
+	if opt.MaxTime.IsZero() {
+		opt.MaxTime = now
+	}
+	if opt.MinTime.IsZero() {
+		opt.MinTime = time.Unix(0, 0)
+	}
+
+	// Expand regex sources to their actual source names.
+	sources, err := e.TSDBStore.ExpandSources(stmt.Sources)
+	if err != nil {
+		return opt, err
+	}
+	stmt.Sources = sources
+
+	// Restrict the statement to the measurements and series the user may read.
+	if stmt.SeriesAuthorizer != nil {
+		if err := authorizeSeriesRead(stmt); err != nil {
+			return opt, err
+		}
+	}
+
+	// Convert DISTINCT into a call.
+	stmt.RewriteDistinct()
+
+	// Remove "time" from fields list.
+	stmt.RewriteTimeFields()
+
+	// Validate the number of GROUP BY time buckets.
+	if err := e.validateSelectBuckets(stmt, &opt); err != nil {
+		return opt, err
+	}
+	return opt, nil
+}
+
+// executeCachedSelectStatement executes an aggregate query whose complete
+// GROUP BY time() windows can be cached. Cached windows are read from the
+// query cache and the windows around them are computed. The complete windows
+// are then cached for the next time the query is executed.
+func (e *QueryExecutor) executeCachedSelectStatement(stmt *influxql.SelectStatement, ic influxql.IteratorCreator, opt *influxql.SelectOptions, limiter *influxql.PointLimiter, now time.Time, chunkSize, statementID int, results chan *influxql.Result, closing <-chan struct{}) error {
+	interval, err := stmt.GroupByInterval()
+	if err != nil {
+		return err
+	}
+	offset, err := stmt.GroupByOffset()
+	if err != nil {
+		return err
+	}
+	window := influxql.IteratorOptions{Interval: influxql.Interval{Duration: interval, Offset: offset}}
+
+	// Determine the complete windows of the time range. Windows that end
+	// after now can still change.
+	start, end := opt.MinTime.UnixNano(), opt.MaxTime.UnixNano()+1
+	first, _ := window.Window(start)
+	if first < start {
+		first += int64(interval)
+	}
+	last := end
+	if now.UnixNano() < last {
+		last = now.UnixNano()
+	}
+	last, _ = window.Window(last)
+
+	var rows []*models.Row
+	if first < last {
+		key, sources, err := queryCacheKey(stmt)
+		if err != nil {
+			return err
+		}
+		l, entry := e.QueryCache.lookup(key, sources, window.Interval, first, last)
+		defer e.QueryCache.release(l)
+
+		if entry == nil {
+			if rows, err = e.selectRows(stmt, ic, opt, limiter, start, end); err != nil {
+				return err
+			}
+		} else {
+			// Compute the windows before and after the cached windows.
+			from, to := entry.start, entry.end
+			if from < first {
+				from = first
+			}
+			if to > last {
+				to = last
+			}
+
+			var head, tail []*models.Row
+			if start < from {
+				if head, err = e.selectRows(stmt, ic, opt, limiter, start, from); err != nil {
+					return err
+				}
+			}
+			if to < end {
+				if tail, err = e.selectRows(stmt, ic, opt, limiter, to, end); err != nil {
+					return err
+				}
+			}
+			rows = mergeRows(stmt.TimeAscending(), head, filterRows(entry.rows, from, to), tail)
+		}
+		e.QueryCache.store(l, first, last, rows)
+	} else {
+		if rows, err = e.selectRows(stmt, ic, opt, limiter, start, end); err != nil {
+			return err
+		}
+	}
+
+	// Always emit at least one result.
+	if len(rows) == 0 {
+		results <- &influxql.Result{
+			StatementID: statementID,
+			Series:      make([]*models.Row, 0),
+		}
+		return nil
+	}
+
+	for _, row := range rows {
+		if !sendRow(row, chunkSize, statementID, results, closing) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// sendRow sends row to results in chunks of at most chunkSize values. A
+// chunkSize of zero sends the row as a single result. Returns false if the
+// query was closed.
+func sendRow(row *models.Row, chunkSize, statementID int, results chan *influxql.Result, closing <-chan struct{}) bool {
+	for {
+		chunk := row
+		if chunkSize > 0 && len(row.Values) > chunkSize {
+			chunk = &models.Row{Name: row.Name, Tags: row.Tags, Columns: row.Columns, Values: row.Values[:chunkSize]}
+			row = &models.Row{Name: row.Name, Tags: row.Tags, Columns: row.Columns, Values: row.Values[chunkSize:]}
+		}
+
+		select {
+		case <-closing:
+			return false
+		case results <- &influxql.Result{StatementID: statementID, Series: []*models.Row{chunk}}:
+		}
+
+		if chunk == row {
+			return true
+		}
+	}
+}
+
+// selectRows returns the rows of stmt in the time range [start, end).
+func (e *QueryExecutor) selectRows(stmt *influxql.SelectStatement, ic influxql.IteratorCreator, opt *influxql.SelectOptions, limiter *influxql.PointLimiter, start, end int64) ([]*models.Row, error) {
+	other := stmt.Clone()
+	if err := other.SetTimeRange(time.Unix(0, start), time.Unix(0, end)); err != nil {
+		return nil, err
+	}
+
+	itrs, err := influxql.Select(other, ic, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	em := influxql.NewEmitter(itrs, stmt.TimeAscending())
+	em.Columns = stmt.ColumnNames()
+	defer em.Close()
+
+	var rows []*models.Row
+	for {
+		row := em.Emit()
+
+		// Return an error if the iterators were ended by the point limit.
+		if limiter != nil {
+			if err := limiter.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		if row == nil {
+			return rows, nil
+		}
+		rows = append(rows, row)
+	}
+}
+
+// validateSelectBuckets returns an error if stmt groups by time into more
+// buckets than MaxSelectBucketsN.
+func (e *QueryExecutor) validateSelectBuckets(stmt *influxql.SelectStatement, opt *influxql.SelectOptions) error {
+	if e.MaxSelectBucketsN <= 0 || stmt.IsRawQuery {
+		return nil
+	}
+
+	interval, err := stmt.GroupByInterval()
+	if err != nil {
+		return err
+	} else if interval <= 0 {
+		return nil
+	}
+
+	offset, err := stmt.GroupByOffset()
+	if err != nil {
+		return err
+	}
+
+	// Count the buckets between the start of the first and the end of the last.
+	min := opt.MinTime.Add(-offset).Truncate(interval)
+	max := opt.MaxTime.Add(-offset).Truncate(interval).Add(interval)
+	if n := int64(max.Sub(min) / interval); n > int64(e.MaxSelectBucketsN) {
+		return ErrMaxSelectBucketsLimitExceeded(n, e.MaxSelectBucketsN)
+	}
+	return nil
+}
+
+// validateSelectSeries returns an error if stmt reads from more series than
+// MaxSelectSeriesN.
+func (e *QueryExecutor) validateSelectSeries(stmt *influxql.SelectStatement, ic influxql.IteratorCreator, opt *influxql.SelectOptions) error {
+	if e.MaxSelectSeriesN <= 0 || stmt.Sources.HasSystemSource() {
+		return nil
+	}
+
+	seriesOpt, err := seriesIteratorOptions(stmt, ic, opt)
+	if err != nil {
+		return err
+	}
+
+	series, err := ic.SeriesKeys(seriesOpt)
+	if err != nil {
+		return err
+	} else if len(series) > e.MaxSelectSeriesN {
+		return ErrMaxSelectSeriesLimitExceeded(len(series), e.MaxSelectSeriesN)
+	}
+	return nil
+}
+
+// seriesIteratorOptions returns the options to list every series stmt reads
+// from with SeriesKeys.
+func seriesIteratorOptions(stmt *influxql.SelectStatement, ic influxql.IteratorCreator, opt *influxql.SelectOptions) (influxql.IteratorOptions, error) {
+	// Group by every tag key so that each series is counted.
+	_, dimensions, err := ic.FieldDimensions(stmt.Sources)
+	if err != nil {
+		return influxql.IteratorOptions{}, err
+	}
+	dims := make([]string, 0, len(dimensions))
+	for k := range dimensions {
+		dims = append(dims, k)
+	}
+	sort.Strings(dims)
+
+	return influxql.IteratorOptions{
+		Sources:    stmt.Sources,
+		Dimensions: dims,
+		Condition:  stmt.Condition,
+		StartTime:  opt.MinTime.UnixNano(),
+		EndTime:    opt.MaxTime.UnixNano(),
+	}, nil
+}
+
+// ErrMaxSelectBucketsLimitExceeded is an error when a query groups by time
+// into more buckets than the limit.
+func ErrMaxSelectBucketsLimitExceeded(n int64, limit int) error {
+	return fmt.Errorf("max-select-buckets limit exceeded: (%d/%d)", n, limit)
+}
+
+// ErrMaxSelectSeriesLimitExceeded is an error when a query reads from more
+// series than the limit.
+func ErrMaxSelectSeriesLimitExceeded(n, limit int) error {
+	return fmt.Errorf("max-select-series limit exceeded: (%d/%d)", n, limit)
+}
+
 // iteratorCreator returns a new instance of IteratorCreator based on stmt.
-func (e *QueryExecutor) iteratorCreator(stmt *influxql.SelectStatement, opt *influxql.SelectOptions) (influxql.IteratorCreator, error) {
+// If limiter is not nil, the iterators count the points read against it.
+func (e *QueryExecutor) iteratorCreator(stmt *influxql.SelectStatement, opt *influxql.SelectOptions, limiter *influxql.PointLimiter) (influxql.IteratorCreator, error) {
+	sics, err := e.shardIteratorCreators(stmt, opt, limiter)
+	if err != nil {
+		return nil, err
+	}
+
+	ics := make([]influxql.IteratorCreator, 0, len(sics))
+	for _, sic := range sics {
+		ics = append(ics, sic.ic)
+	}
+	return influxql.IteratorCreators(ics), nil
+}
+
+// shardIteratorCreator is the iterator creator of a local shard or of the
+// shards read from a remote node.
+type shardIteratorCreator struct {
+	nodeID   uint64
+	shardIDs []uint64
+	ic       influxql.IteratorCreator
+}
+
+// shardIteratorCreators returns the iterator creators of the shards stmt
+// reads from. If limiter is not nil, the iterators count the points read
+// against it.
+func (e *QueryExecutor) shardIteratorCreators(stmt *influxql.SelectStatement, opt *influxql.SelectOptions, limiter *influxql.PointLimiter) ([]shardIteratorCreator, error) {
 	// Retrieve a list of shard IDs.
 	shards, err := e.MetaClient.ShardsByTimeRange(stmt.Sources, opt.MinTime, opt.MaxTime)
 	if err != nil {
@@ -543,40 +985,127 @@ func (e *QueryExecutor) iteratorCreator(stmt *influxql.SelectStatement, opt *inf
 		shardIDsByNodeID[nodeID] = append(shardIDsByNodeID[nodeID], si.ID)
 	}
 
+	// Sort node IDs so we get more predicable execution.
+	nodeIDs := make([]uint64, 0, len(shardIDsByNodeID))
+	for nodeID := range shardIDsByNodeID {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Sort(uint64Slice(nodeIDs))
+
 	// Generate iterators for each node.
-	ics := make([]influxql.IteratorCreator, 0)
-	if err := func() error {
-		for nodeID, shardIDs := range shardIDsByNodeID {
-			// Sort shard IDs so we get more predicable execution.
-			sort.Sort(uint64Slice(shardIDs))
-
-			// Create iterator creators from TSDB if local.
-			if nodeID == e.Node.ID {
-				for _, shardID := range shardIDs {
-					ic := e.TSDBStore.ShardIteratorCreator(shardID)
-					if ic == nil {
-						continue
-					}
-					ics = append(ics, ic)
+	var sics []shardIteratorCreator
+	for _, nodeID := range nodeIDs {
+		shardIDs := shardIDsByNodeID[nodeID]
+
+		// Sort shard IDs so we get more predicable execution.
+		sort.Sort(uint64Slice(shardIDs))
+
+		// Create iterator creators from TSDB if local.
+		if nodeID == e.Node.ID {
+			for _, shardID := range shardIDs {
+				ic := e.TSDBStore.ShardIteratorCreator(shardID)
+				if ic == nil {
+					continue
 				}
-				continue
+				if limiter != nil {
+					ic = limiter.IteratorCreator(ic)
+				}
+				sics = append(sics, shardIteratorCreator{nodeID: nodeID, shardIDs: []uint64{shardID}, ic: ic})
 			}
+			continue
+		}
 
-			// Otherwise create iterator creator remotely.
-			dialer := &NodeDialer{
-				MetaClient: e.MetaClient,
-				Timeout:    e.Timeout,
-			}
-			ics = append(ics, newRemoteIteratorCreator(dialer, nodeID, shardIDs))
+		// Otherwise create iterator creator remotely.
+		dialer := &NodeDialer{
+			MetaClient: e.MetaClient,
+			Timeout:    e.Timeout,
+		}
+		ric := newRemoteIteratorCreator(dialer, nodeID, shardIDs)
+		if limiter != nil {
+			ric.maxPointN = e.MaxSelectPointN
+			sics = append(sics, shardIteratorCreator{nodeID: nodeID, shardIDs: shardIDs, ic: limiter.IteratorCreator(ric)})
+			continue
 		}
+		sics = append(sics, shardIteratorCreator{nodeID: nodeID, shardIDs: shardIDs, ic: ric})
+	}
+	return sics, nil
+}
 
-		return nil
-	}(); err != nil {
-		influxql.IteratorCreators(ics).Close()
-		return nil, err
+func (e *QueryExecutor) executeRunContinuousQueryStatement(stmt *influxql.RunContinuousQueryStatement, statementID int, results chan *influxql.Result, closing <-chan struct{}) error {
+	if e.ContinuousQuerier == nil {
+		return errors.New("continuous query service is not enabled")
 	}
 
-	return influxql.IteratorCreators(ics), nil
+	// Resolve the time range to recompute. Both bounds are required.
+	cond := influxql.Reduce(stmt.Condition, &influxql.NowValuer{Now: time.Now().UTC()})
+	if !influxql.OnlyTimeExpr(cond) {
+		return errors.New("continuous query backfill condition may only reference time")
+	}
+	tmin, tmax := influxql.TimeRange(cond)
+	if tmin.IsZero() || tmax.IsZero() {
+		return errors.New("continuous query backfill requires a lower and upper time bound")
+	}
+
+	// The upper bound returned by TimeRange is inclusive.
+	tmax = tmax.Add(time.Nanosecond)
+
+	// Report each completed chunk as a separate result.
+	progress := func(start, end time.Time, chunk, chunks int) {
+		results <- &influxql.Result{
+			StatementID: statementID,
+			Series: []*models.Row{{
+				Name:    stmt.Name,
+				Columns: []string{"time", "end", "chunk", "chunks"},
+				Values:  [][]interface{}{{start.UTC(), end.UTC(), chunk, chunks}},
+			}},
+		}
+	}
+	return e.ContinuousQuerier.Backfill(stmt.Database, stmt.Name, tmin, tmax, progress, closing)
+}
+
+// authorizeSeriesRead removes the sources of stmt that may not be read and
+// adds the series restrictions of the remaining sources to its condition.
+func authorizeSeriesRead(stmt *influxql.SelectStatement) error {
+	var sources influxql.Sources
+	var cond influxql.Expr
+	for _, src := range stmt.Sources {
+		mm, ok := src.(*influxql.Measurement)
+		if !ok {
+			return fmt.Errorf("unsupported source: %s", src)
+		}
+
+		ok, c := stmt.SeriesAuthorizer.AuthorizeSeriesRead(mm.Database, mm.Name)
+		if !ok {
+			continue
+		}
+
+		// Sources are queried with a single condition, so they must share
+		// the same series restriction.
+		if len(sources) == 0 {
+			cond = c
+		} else if (cond == nil) != (c == nil) || (cond != nil && cond.String() != c.String()) {
+			return fmt.Errorf("measurements %s and %s have different series restrictions and must be queried separately", sources[0], mm)
+		}
+		sources = append(sources, mm)
+	}
+
+	if len(sources) == 0 {
+		return errors.New("not authorized to read any of the requested measurements")
+	}
+	stmt.Sources = sources
+
+	if cond != nil {
+		if stmt.Condition == nil {
+			stmt.Condition = &influxql.ParenExpr{Expr: cond}
+		} else {
+			stmt.Condition = &influxql.BinaryExpr{
+				Op:  influxql.AND,
+				LHS: &influxql.ParenExpr{Expr: stmt.Condition},
+				RHS: &influxql.ParenExpr{Expr: cond},
+			}
+		}
+	}
+	return nil
 }
 
 func (e *QueryExecutor) executeShowContinuousQueriesStatement(stmt *influxql.ShowContinuousQueriesStatement) (models.Rows, error) {
@@ -637,6 +1166,27 @@ func (e *QueryExecutor) executeShowDiagnosticsStatement(stmt *influxql.ShowDiagn
 	return rows, nil
 }
 
+func (e *QueryExecutor) executeShowDownsamplesStatement(stmt *influxql.ShowDownsamplesStatement) (models.Rows, error) {
+	dis, err := e.MetaClient.Databases()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []*models.Row{}
+	for _, di := range dis {
+		row := &models.Row{Columns: []string{"retention_policy", "target_database", "target_retention_policy", "every", "aggregates"}, Name: di.Name}
+		for _, rpi := range di.RetentionPolicies {
+			for _, dpi := range rpi.DownsamplePolicies {
+				row.Values = append(row.Values, []interface{}{rpi.Name, dpi.Database, dpi.RetentionPolicy, influxql.FormatDuration(dpi.Interval), dpi.Aggregates})
+			}
+		}
+		if len(row.Values) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
 func (e *QueryExecutor) executeShowFieldKeysStatement(stmt *influxql.ShowFieldKeysStatement, database string) (models.Rows, error) {
 	// FIXME(benbjohnson): Rewrite to use new query engine.
 	return e.TSDBStore.ExecuteShowFieldKeysStatement(stmt, database)
@@ -670,6 +1220,48 @@ func (e *QueryExecutor) executeShowRetentionPoliciesStatement(q *influxql.ShowRe
 	return []*models.Row{row}, nil
 }
 
+func (e *QueryExecutor) executeShowHintedHandoffStatement(stmt *influxql.ShowHintedHandoffStatement) (models.Rows, error) {
+	if e.HintedHandoff == nil {
+		return nil, hh.ErrHintedHandoffDisabled
+	}
+
+	statuses, err := e.HintedHandoff.NodeStatuses()
+	if err != nil {
+		return nil, err
+	}
+
+	row := &models.Row{Name: "hinted_handoff", Columns: []string{"node", "active", "paused", "segments", "queue_bytes", "oldest_age", "last_modified", "head", "tail", "replay_bytes_per_sec"}}
+	for _, st := range statuses {
+		var age string
+		if st.Size > 0 {
+			age = time.Since(st.Oldest).String()
+		}
+		row.Values = append(row.Values, []interface{}{st.NodeID, st.Active, st.Paused, st.Segments, st.Size, age, st.LastModified, st.Head, st.Tail, st.ReplayRate})
+	}
+	return []*models.Row{row}, nil
+}
+
+func (e *QueryExecutor) executePurgeHintedHandoffStatement(stmt *influxql.PurgeHintedHandoffStatement) error {
+	if e.HintedHandoff == nil {
+		return hh.ErrHintedHandoffDisabled
+	}
+	return e.HintedHandoff.PurgeNode(stmt.NodeID)
+}
+
+func (e *QueryExecutor) executePauseHintedHandoffStatement(stmt *influxql.PauseHintedHandoffStatement) error {
+	if e.HintedHandoff == nil {
+		return hh.ErrHintedHandoffDisabled
+	}
+	return e.HintedHandoff.PauseNode(stmt.NodeID)
+}
+
+func (e *QueryExecutor) executeResumeHintedHandoffStatement(stmt *influxql.ResumeHintedHandoffStatement) error {
+	if e.HintedHandoff == nil {
+		return hh.ErrHintedHandoffDisabled
+	}
+	return e.HintedHandoff.ResumeNode(stmt.NodeID)
+}
+
 func (e *QueryExecutor) executeShowServersStatement(q *influxql.ShowServersStatement) (models.Rows, error) {
 	nis, err := e.MetaClient.DataNodes()
 	if err != nil {
@@ -817,6 +1409,29 @@ func (e *QueryExecutor) executeShowTagValuesStatement(stmt *influxql.ShowTagValu
 	return e.TSDBStore.ExecuteShowTagValuesStatement(stmt, database)
 }
 
+func (e *QueryExecutor) executeShowTokensStatement(q *influxql.ShowTokensStatement) (models.Rows, error) {
+	row := &models.Row{Columns: []string{"name", "user", "privileges"}}
+	for _, ti := range e.MetaClient.Tokens() {
+		// Unscoped tokens carry all of the user's privileges.
+		privileges := "ALL USER PRIVILEGES"
+		if ti.Scoped {
+			databases := make([]string, 0, len(ti.Privileges))
+			for database := range ti.Privileges {
+				databases = append(databases, database)
+			}
+			sort.Strings(databases)
+
+			a := make([]string, len(databases))
+			for i, database := range databases {
+				a[i] = fmt.Sprintf("%s ON %s", ti.Privileges[database], influxql.QuoteIdent(database))
+			}
+			privileges = strings.Join(a, ", ")
+		}
+		row.Values = append(row.Values, []interface{}{ti.Name, ti.Username, privileges})
+	}
+	return []*models.Row{row}, nil
+}
+
 func (e *QueryExecutor) executeShowUsersStatement(q *influxql.ShowUsersStatement) (models.Rows, error) {
 	row := &models.Row{Columns: []string{"user", "admin"}}
 	for _, ui := range e.MetaClient.Users() {
@@ -825,6 +1440,25 @@ func (e *QueryExecutor) executeShowUsersStatement(q *influxql.ShowUsersStatement
 	return []*models.Row{row}, nil
 }
 
+// audit records the execution of a statement in the audit log. SELECT and
+// EXPLAIN statements are not recorded.
+func (e *QueryExecutor) audit(opt influxql.ExecutionOptions, stmt influxql.Statement, database string, err error) {
+	if e.Auditor == nil {
+		return
+	}
+	switch stmt.(type) {
+	case *influxql.SelectStatement, *influxql.ExplainStatement:
+		return
+	}
+
+	r := audit.NewRecord(audit.EventStatement, err)
+	r.User = opt.User
+	r.Addr = opt.Addr
+	r.Database = database
+	r.Statement = stmt.String()
+	e.Auditor.Audit(r)
+}
+
 func (e *QueryExecutor) logger() *log.Logger {
 	return log.New(e.LogOutput, "[query] ", log.LstdFlags)
 }
@@ -966,9 +1600,10 @@ type IntoWriteRequest struct {
 
 // remoteIteratorCreator creates iterators for remote shards.
 type remoteIteratorCreator struct {
-	dialer   *NodeDialer
-	nodeID   uint64
-	shardIDs []uint64
+	dialer    *NodeDialer
+	nodeID    uint64
+	shardIDs  []uint64
+	maxPointN int
 }
 
 // newRemoteIteratorCreator returns a new instance of remoteIteratorCreator for a remote shard.
@@ -990,8 +1625,10 @@ func (ic *remoteIteratorCreator) CreateIterator(opt influxql.IteratorOptions) (i
 	if err := func() error {
 		// Write request.
 		if err := EncodeTLV(conn, createIteratorRequestMessage, &CreateIteratorRequest{
-			ShardIDs: ic.shardIDs,
-			Opt:      opt,
+			ShardIDs:  ic.shardIDs,
+			Opt:       opt,
+			MaxPointN: ic.maxPointN,
+			Stats:     opt.Stats != nil,
 		}); err != nil {
 			return err
 		}
@@ -1001,7 +1638,7 @@ func (ic *remoteIteratorCreator) CreateIterator(opt influxql.IteratorOptions) (i
 		if _, err := DecodeTLV(conn, &resp); err != nil {
 			return err
 		} else if resp.Err != nil {
-			return err
+			return resp.Err
 		}
 
 		return nil
@@ -1010,7 +1647,7 @@ func (ic *remoteIteratorCreator) CreateIterator(opt influxql.IteratorOptions) (i
 		return nil, err
 	}
 
-	return influxql.NewReaderIterator(conn)
+	return influxql.NewReaderIterator(conn, opt.Stats)
 }
 
 // FieldDimensions returns the unique fields and dimensions across a list of sources.
@@ -1061,6 +1698,94 @@ func (ic *remoteIteratorCreator) SeriesKeys(opt influxql.IteratorOptions) (influ
 	return resp.SeriesList, resp.Err
 }
 
+// NumericFields returns the sorted float and integer field names of every
+// measurement in a retention policy, read from every node that owns one of its
+// shards. If a node can't be read, the fields of the other nodes are returned
+// along with the error.
+func (e *QueryExecutor) NumericFields(database, retentionPolicy string) (map[string][]string, error) {
+	rpi, err := e.MetaClient.RetentionPolicy(database, retentionPolicy)
+	if err != nil {
+		return nil, err
+	} else if rpi == nil {
+		return nil, freetsdb.ErrRetentionPolicyNotFound(retentionPolicy)
+	}
+
+	// Find the nodes that own the shards of the retention policy.
+	nodeIDs := make(map[uint64]struct{})
+	for _, sgi := range rpi.ShardGroups {
+		if sgi.Deleted() {
+			continue
+		}
+		for _, sh := range sgi.Shards {
+			for _, owner := range sh.Owners {
+				nodeIDs[owner.NodeID] = struct{}{}
+			}
+		}
+	}
+
+	sets := make(map[string]map[string]struct{})
+	var firstErr error
+	for nodeID := range nodeIDs {
+		var fields map[string][]string
+		if nodeID == e.Node.ID {
+			fields = e.TSDBStore.NumericFields(database, retentionPolicy)
+		} else {
+			dialer := &NodeDialer{MetaClient: e.MetaClient, Timeout: e.Timeout}
+			if fields, err = remoteNumericFields(dialer, nodeID, database, retentionPolicy); err != nil {
+				if firstErr == nil {
+					firstErr = remoteNodeError{id: nodeID, err: err}
+				}
+				continue
+			}
+		}
+
+		for name, a := range fields {
+			if sets[name] == nil {
+				sets[name] = make(map[string]struct{})
+			}
+			for _, f := range a {
+				sets[name][f] = struct{}{}
+			}
+		}
+	}
+
+	fields := make(map[string][]string, len(sets))
+	for name, set := range sets {
+		a := make([]string, 0, len(set))
+		for f := range set {
+			a = append(a, f)
+		}
+		sort.Strings(a)
+		fields[name] = a
+	}
+	return fields, firstErr
+}
+
+// remoteNumericFields returns the numeric fields of the local shards of a
+// retention policy on a remote node.
+func remoteNumericFields(dialer *NodeDialer, nodeID uint64, database, retentionPolicy string) (map[string][]string, error) {
+	conn, err := dialer.DialNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Write request.
+	if err := EncodeTLV(conn, numericFieldsRequestMessage, &NumericFieldsRequest{
+		Database:        database,
+		RetentionPolicy: retentionPolicy,
+	}); err != nil {
+		return nil, err
+	}
+
+	// Read the response.
+	var resp NumericFieldsResponse
+	if _, err := DecodeTLV(conn, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Fields, resp.Err
+}
+
 // NodeDialer dials connections to a given node.
 type NodeDialer struct {
 	MetaClient MetaClient
@@ -1101,6 +1826,7 @@ type TSDBStore interface {
 	ExecuteShowFieldKeysStatement(stmt *influxql.ShowFieldKeysStatement, database string) (models.Rows, error)
 	ExecuteShowTagValuesStatement(stmt *influxql.ShowTagValuesStatement, database string) (models.Rows, error)
 	ExpandSources(sources influxql.Sources) (influxql.Sources, error)
+	NumericFields(database, retentionPolicy string) map[string][]string
 	ShardIteratorCreator(id uint64) influxql.IteratorCreator
 }
 
@@ -2,9 +2,12 @@ package cluster_test
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"os"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,7 +16,10 @@ import (
 	"github.com/freetsdb/freetsdb/cluster"
 	"github.com/freetsdb/freetsdb/influxql"
 	"github.com/freetsdb/freetsdb/models"
+	"github.com/freetsdb/freetsdb/services/audit"
+	"github.com/freetsdb/freetsdb/services/hh"
 	"github.com/freetsdb/freetsdb/services/meta"
+	"github.com/freetsdb/freetsdb/toml"
 )
 
 const (
@@ -145,6 +151,629 @@ func TestQueryExecutor_ExecuteQuery_SelectStatement_Remote(t *testing.T) {
 	}
 }
 
+// Ensure query executor can describe and analyze a distributed SELECT statement.
+func TestQueryExecutor_ExecuteQuery_ExplainStatement(t *testing.T) {
+	e := DefaultQueryExecutor()
+
+	// Start a second service.
+	s := MustOpenService()
+	defer s.Close()
+
+	// The shards report the data read from storage if it is requested.
+	newIteratorCreator := func(seriesN int) influxql.IteratorCreator {
+		var ic IteratorCreator
+		ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			if opt.Stats == nil {
+				t.Fatal("expected iterator statistics")
+			}
+			opt.Stats.Add(influxql.IteratorStats{SeriesN: 1})
+			opt.Stats.AddBlock(128)
+			return &FloatIterator{Points: []influxql.FloatPoint{
+				{Name: "cpu", Time: int64(0 * time.Second), Value: 10},
+			}}, nil
+		}
+		ic.FieldDimensionsFn = func(sources influxql.Sources) (fields, dimensions map[string]struct{}, err error) {
+			return map[string]struct{}{"value": struct{}{}}, map[string]struct{}{"host": struct{}{}}, nil
+		}
+		ic.SeriesKeysFn = func(opt influxql.IteratorOptions) (influxql.SeriesList, error) {
+			if !reflect.DeepEqual(opt.Dimensions, []string{"host"}) {
+				t.Fatalf("unexpected dimensions: %v", opt.Dimensions)
+			}
+			var a influxql.SeriesList
+			for i := 0; i < seriesN; i++ {
+				a = append(a, influxql.Series{Name: "cpu", Tags: influxql.NewTags(map[string]string{"host": fmt.Sprint(i)})})
+			}
+			return a, nil
+		}
+		return &ic
+	}
+	s.TSDBStore.ShardIteratorCreatorFn = func(shardID uint64) influxql.IteratorCreator {
+		return newIteratorCreator(3)
+	}
+	e.TSDBStore.ShardIteratorCreatorFn = func(shardID uint64) influxql.IteratorCreator {
+		return newIteratorCreator(2)
+	}
+
+	// Two shards are returned. One local and one remote.
+	e.MetaClient.ShardsByTimeRangeFn = func(sources influxql.Sources, tmin, tmax time.Time) (a []meta.ShardInfo, err error) {
+		return []meta.ShardInfo{
+			{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}},
+			{ID: 200, Owners: []meta.ShardOwner{{NodeID: 1}}},
+		}, nil
+	}
+	e.MetaClient.DataNodeFn = func(id uint64) (*meta.NodeInfo, error) {
+		return &meta.NodeInfo{ID: 1, TCPHost: s.Addr().String()}, nil
+	}
+
+	// Describe the statement without executing it.
+	a := ReadAllResults(e.ExecuteQuery(`EXPLAIN SELECT count(value) FROM cpu`, "db0", 0))
+	if len(a) != 1 || a[0].Err != nil || len(a[0].Series) != 1 {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	} else if lines := explainLines(a[0].Series[0]); !reflect.DeepEqual(lines, []string{
+		`SELECT count(value) FROM db0.rp0.cpu`,
+		`node 0 (local)`,
+		`  shard 100: 2 series`,
+		`node 1`,
+		`  shard 200: 3 series`,
+		`expression: count(value)`,
+		`  shard 100 (node 0)`,
+		`  node 1 (shards 200)`,
+	}) {
+		t.Fatalf("unexpected plan:\n%s", strings.Join(lines, "\n"))
+	}
+
+	// Execute the statement and report the data read.
+	a = ReadAllResults(e.ExecuteQuery(`EXPLAIN ANALYZE SELECT count(value) FROM cpu`, "db0", 0))
+	if len(a) != 1 || a[0].Err != nil || len(a[0].Series) != 1 {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+	lines := explainLines(a[0].Series[0])
+	if len(lines) != 11 {
+		t.Fatalf("unexpected plan:\n%s", strings.Join(lines, "\n"))
+	}
+	for i, re := range []string{
+		`^expression: count\(value\)$`,
+		`^  shard 100 \(node 0\): 1 points in .+, 1 series, 1 blocks, 128 bytes$`,
+		`^  node 1 \(shards 200\): 1 points in .+, 1 series, 1 blocks, 128 bytes$`,
+		`^rows: 1$`,
+		`^planning time: .+$`,
+		`^execution time: .+$`,
+	} {
+		if line := lines[i+5]; !regexp.MustCompile(re).MatchString(line) {
+			t.Fatalf("unexpected line %d: %s", i+5, line)
+		}
+	}
+}
+
+// explainLines returns the lines of an explained plan.
+func explainLines(row *models.Row) []string {
+	var lines []string
+	for _, v := range row.Values {
+		lines = append(lines, v[0].(string))
+	}
+	return lines
+}
+
+// Ensure query executor returns an error when a SELECT reads too many points.
+func TestQueryExecutor_ExecuteQuery_MaxSelectPointN(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.MaxSelectPointN = 2
+
+	e.MetaClient.ShardsByTimeRangeFn = func(sources influxql.Sources, tmin, tmax time.Time) (a []meta.ShardInfo, err error) {
+		return []meta.ShardInfo{{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}}}, nil
+	}
+	e.TSDBStore.ShardIteratorCreatorFn = func(id uint64) influxql.IteratorCreator {
+		var ic IteratorCreator
+		ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			return &FloatIterator{Points: []influxql.FloatPoint{
+				{Name: "cpu", Time: int64(0 * time.Second), Value: 1},
+				{Name: "cpu", Time: int64(1 * time.Second), Value: 2},
+				{Name: "cpu", Time: int64(2 * time.Second), Value: 3},
+			}}, nil
+		}
+		return &ic
+	}
+
+	a := ReadAllResults(e.ExecuteQuery(`SELECT count(value) FROM cpu`, "db0", 0))
+	if len(a) != 1 {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	} else if a[0].Err == nil || a[0].Err.Error() != "max-select-point limit exceeded: (3/2)" {
+		t.Fatalf("unexpected error: %v", a[0].Err)
+	}
+}
+
+// Ensure query executor returns an error when a SELECT reads too many series.
+func TestQueryExecutor_ExecuteQuery_MaxSelectSeriesN(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.MaxSelectSeriesN = 2
+
+	e.MetaClient.ShardsByTimeRangeFn = func(sources influxql.Sources, tmin, tmax time.Time) (a []meta.ShardInfo, err error) {
+		return []meta.ShardInfo{{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}}}, nil
+	}
+	e.TSDBStore.ShardIteratorCreatorFn = func(id uint64) influxql.IteratorCreator {
+		var ic IteratorCreator
+		ic.FieldDimensionsFn = func(sources influxql.Sources) (fields, dimensions map[string]struct{}, err error) {
+			return map[string]struct{}{"value": struct{}{}}, map[string]struct{}{"region": struct{}{}, "host": struct{}{}}, nil
+		}
+		ic.SeriesKeysFn = func(opt influxql.IteratorOptions) (influxql.SeriesList, error) {
+			if !reflect.DeepEqual(opt.Dimensions, []string{"host", "region"}) {
+				t.Fatalf("unexpected dimensions: %v", opt.Dimensions)
+			}
+			return influxql.SeriesList{
+				{Name: "cpu", Tags: influxql.NewTags(map[string]string{"host": "a"})},
+				{Name: "cpu", Tags: influxql.NewTags(map[string]string{"host": "b"})},
+				{Name: "cpu", Tags: influxql.NewTags(map[string]string{"host": "c"})},
+			}, nil
+		}
+		return &ic
+	}
+
+	a := ReadAllResults(e.ExecuteQuery(`SELECT value FROM cpu`, "db0", 0))
+	if len(a) != 1 {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	} else if a[0].Err == nil || a[0].Err.Error() != "max-select-series limit exceeded: (3/2)" {
+		t.Fatalf("unexpected error: %v", a[0].Err)
+	}
+}
+
+// Ensure query executor returns an error when a SELECT groups by too many buckets.
+func TestQueryExecutor_ExecuteQuery_MaxSelectBucketsN(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.MaxSelectBucketsN = 30
+
+	a := ReadAllResults(e.ExecuteQuery(`SELECT count(value) FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T01:00:00Z' GROUP BY time(1m)`, "db0", 0))
+	if len(a) != 1 {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	} else if a[0].Err == nil || a[0].Err.Error() != "max-select-buckets limit exceeded: (60/30)" {
+		t.Fatalf("unexpected error: %v", a[0].Err)
+	}
+}
+
+// Ensure query executor only computes the windows of a repeated query that aren't cached.
+func TestQueryExecutor_ExecuteQuery_QueryCache(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.QueryCache = cluster.NewQueryCache(cluster.Config{QueryCacheMaxSize: 1 << 20, QueryCacheTTL: toml.Duration(time.Minute)})
+
+	e.MetaClient.ShardsByTimeRangeFn = func(sources influxql.Sources, tmin, tmax time.Time) (a []meta.ShardInfo, err error) {
+		return []meta.ShardInfo{{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}}}, nil
+	}
+
+	// Record the time ranges read from the shard.
+	var ranges [][2]time.Time
+	e.TSDBStore.ShardIteratorCreatorFn = func(id uint64) influxql.IteratorCreator {
+		var ic IteratorCreator
+		ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			ranges = append(ranges, [2]time.Time{time.Unix(0, opt.StartTime).UTC(), time.Unix(0, opt.EndTime+1).UTC()})
+
+			var points []influxql.FloatPoint
+			for i := int64(0); i < 10; i++ {
+				if t := mustParseTime("2000-01-01T00:00:00Z").Add(time.Duration(i) * 30 * time.Second).UnixNano(); t >= opt.StartTime && t <= opt.EndTime {
+					points = append(points, influxql.FloatPoint{Name: "cpu", Time: t, Value: float64(i)})
+				}
+			}
+			return &FloatIterator{Points: points}, nil
+		}
+		return &ic
+	}
+
+	// The first query computes and caches all of its windows.
+	if a := ReadAllResults(e.ExecuteQuery(`SELECT sum(value) FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:04:00Z' GROUP BY time(1m)`, "db0", 0)); !reflect.DeepEqual(a, []*influxql.Result{
+		{
+			StatementID: 0,
+			Series: []*models.Row{{
+				Name:    "cpu",
+				Columns: []string{"time", "sum"},
+				Values: [][]interface{}{
+					{mustParseTime("2000-01-01T00:00:00Z"), float64(1)},
+					{mustParseTime("2000-01-01T00:01:00Z"), float64(5)},
+					{mustParseTime("2000-01-01T00:02:00Z"), float64(9)},
+					{mustParseTime("2000-01-01T00:03:00Z"), float64(13)},
+				},
+			}},
+		},
+	}) {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	} else if len(ranges) != 1 {
+		t.Fatalf("unexpected ranges: %v", ranges)
+	}
+
+	// A query over a later time range only computes the windows around the cached windows.
+	ranges = nil
+	if a := ReadAllResults(e.ExecuteQuery(`SELECT sum(value) FROM cpu WHERE time >= '2000-01-01T00:01:30Z' AND time < '2000-01-01T00:05:00Z' GROUP BY time(1m)`, "db0", 0)); !reflect.DeepEqual(a, []*influxql.Result{
+		{
+			StatementID: 0,
+			Series: []*models.Row{{
+				Name:    "cpu",
+				Columns: []string{"time", "sum"},
+				Values: [][]interface{}{
+					{mustParseTime("2000-01-01T00:01:00Z"), float64(3)},
+					{mustParseTime("2000-01-01T00:02:00Z"), float64(9)},
+					{mustParseTime("2000-01-01T00:03:00Z"), float64(13)},
+					{mustParseTime("2000-01-01T00:04:00Z"), float64(17)},
+				},
+			}},
+		},
+	}) {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	} else if !reflect.DeepEqual(ranges, [][2]time.Time{
+		{mustParseTime("2000-01-01T00:01:30Z"), mustParseTime("2000-01-01T00:02:00Z")},
+		{mustParseTime("2000-01-01T00:04:00Z"), mustParseTime("2000-01-01T00:05:00Z")},
+	}) {
+		t.Fatalf("unexpected ranges: %v", ranges)
+	}
+
+	// A write into a cached window drops the windows from it onwards.
+	e.QueryCache.Invalidate("db0", []models.Point{models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, mustParseTime("2000-01-01T00:03:10Z"))})
+	ranges = nil
+	ReadAllResults(e.ExecuteQuery(`SELECT sum(value) FROM cpu WHERE time >= '2000-01-01T00:02:00Z' AND time < '2000-01-01T00:05:00Z' GROUP BY time(1m)`, "db0", 0))
+	if !reflect.DeepEqual(ranges, [][2]time.Time{
+		{mustParseTime("2000-01-01T00:03:00Z"), mustParseTime("2000-01-01T00:05:00Z")},
+	}) {
+		t.Fatalf("unexpected ranges: %v", ranges)
+	}
+}
+
+// Ensure the query cache drops the windows written to and the data dropped through other nodes.
+func TestQueryExecutor_ExecuteQuery_QueryCache_Remote(t *testing.T) {
+	// Start the service of the node with the cache.
+	s := MustOpenService()
+	defer s.Close()
+	s.QueryCache = cluster.NewQueryCache(cluster.Config{QueryCacheMaxSize: 1 << 20, QueryCacheTTL: toml.Duration(time.Minute)})
+	s.TSDBStore.DeleteMeasurementFn = func(database, name string) error { return nil }
+
+	e := DefaultQueryExecutor()
+	e.QueryCache = s.QueryCache
+	e.MetaClient.ShardsByTimeRangeFn = func(sources influxql.Sources, tmin, tmax time.Time) (a []meta.ShardInfo, err error) {
+		return []meta.ShardInfo{{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}}}, nil
+	}
+
+	// Record the time ranges read from the shard.
+	var ranges [][2]time.Time
+	e.TSDBStore.ShardIteratorCreatorFn = func(id uint64) influxql.IteratorCreator {
+		var ic IteratorCreator
+		ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			ranges = append(ranges, [2]time.Time{time.Unix(0, opt.StartTime).UTC(), time.Unix(0, opt.EndTime+1).UTC()})
+			return &FloatIterator{Points: []influxql.FloatPoint{{Name: "cpu", Time: mustParseTime("2000-01-01T00:00:00Z").UnixNano(), Value: 1}}}, nil
+		}
+		return &ic
+	}
+
+	// The other node sends its writes and statements to the service.
+	other := cluster.NewMetaExecutor()
+	other.Node = &freetsdb.Node{ID: 2}
+	other.MetaClient = &MetaClient{
+		DataNodeFn: func(id uint64) (*meta.NodeInfo, error) {
+			return &meta.NodeInfo{ID: 1, TCPHost: s.Addr().String()}, nil
+		},
+		DataNodesFn: func() ([]meta.NodeInfo, error) {
+			return []meta.NodeInfo{{ID: 1, TCPHost: s.Addr().String()}, {ID: 2}}, nil
+		},
+	}
+
+	query := `SELECT sum(value) FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:04:00Z' GROUP BY time(1m)`
+	ReadAllResults(e.ExecuteQuery(query, "db0", 0))
+
+	// A write through the other node drops the windows from the one written to onwards.
+	if err := other.InvalidateQueryCache("db0", "cpu", mustParseTime("2000-01-01T00:02:10Z").UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	ranges = nil
+	ReadAllResults(e.ExecuteQuery(query, "db0", 0))
+	if !reflect.DeepEqual(ranges, [][2]time.Time{
+		{mustParseTime("2000-01-01T00:02:00Z"), mustParseTime("2000-01-01T00:04:00Z")},
+	}) {
+		t.Fatalf("unexpected ranges: %v", ranges)
+	}
+
+	// Dropping data through the other node drops all windows of the database.
+	if err := other.ExecuteStatement(influxql.MustParseStatement("DROP MEASUREMENT cpu"), "db0"); err != nil {
+		t.Fatal(err)
+	}
+	ranges = nil
+	ReadAllResults(e.ExecuteQuery(query, "db0", 0))
+	if !reflect.DeepEqual(ranges, [][2]time.Time{
+		{mustParseTime("2000-01-01T00:00:00Z"), mustParseTime("2000-01-01T00:04:00Z")},
+	}) {
+		t.Fatalf("unexpected ranges: %v", ranges)
+	}
+}
+
+// Ensure results read from the query cache are chunked and ordered like computed results.
+func TestQueryExecutor_ExecuteQuery_QueryCache_Chunked(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.MetaClient.ShardsByTimeRangeFn = func(sources influxql.Sources, tmin, tmax time.Time) (a []meta.ShardInfo, err error) {
+		return []meta.ShardInfo{{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}}}, nil
+	}
+
+	// Host "a" is only written to from the third window onwards.
+	e.TSDBStore.ShardIteratorCreatorFn = func(id uint64) influxql.IteratorCreator {
+		var ic IteratorCreator
+		ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			var points []influxql.FloatPoint
+			for _, host := range []string{"a", "b"} {
+				for i := int64(0); i < 10; i++ {
+					t := mustParseTime("2000-01-01T00:00:00Z").Add(time.Duration(i) * 30 * time.Second).UnixNano()
+					if (host == "a" && i < 4) || t < opt.StartTime || t > opt.EndTime {
+						continue
+					}
+					points = append(points, influxql.FloatPoint{Name: "cpu", Tags: influxql.NewTags(map[string]string{"host": host}), Time: t, Value: float64(i)})
+				}
+			}
+			return &FloatIterator{Points: points}, nil
+		}
+		return &ic
+	}
+
+	first := `SELECT sum(value) FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:04:00Z' GROUP BY time(1m), host`
+	second := `SELECT sum(value) FROM cpu WHERE time >= '2000-01-01T00:01:30Z' AND time < '2000-01-01T00:05:00Z' GROUP BY time(1m), host`
+
+	// Compute the expected results without the cache.
+	expFirst := ReadAllResults(e.ExecuteQuery(first, "db0", 2))
+	expSecond := ReadAllResults(e.ExecuteQuery(second, "db0", 2))
+	if len(expSecond) != 4 {
+		t.Fatalf("unexpected results: %s", spew.Sdump(expSecond))
+	}
+
+	e.QueryCache = cluster.NewQueryCache(cluster.Config{QueryCacheMaxSize: 1 << 20, QueryCacheTTL: toml.Duration(time.Minute)})
+	if a := ReadAllResults(e.ExecuteQuery(first, "db0", 2)); !reflect.DeepEqual(a, expFirst) {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+
+	// The second query only computes the earlier windows of host "b", so
+	// host "a" must still be returned first.
+	if a := ReadAllResults(e.ExecuteQuery(second, "db0", 2)); !reflect.DeepEqual(a, expSecond) {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure queries restricted to the series a user may read are cached per restriction.
+func TestQueryExecutor_ExecuteQuery_QueryCache_SeriesAuthorizer(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.QueryCache = cluster.NewQueryCache(cluster.Config{QueryCacheMaxSize: 1 << 20, QueryCacheTTL: toml.Duration(time.Minute)})
+	e.MetaClient.ShardsByTimeRangeFn = func(sources influxql.Sources, tmin, tmax time.Time) (a []meta.ShardInfo, err error) {
+		return []meta.ShardInfo{{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}}}, nil
+	}
+
+	// Record the time ranges read from the shard.
+	var ranges [][2]time.Time
+	e.TSDBStore.ShardIteratorCreatorFn = func(id uint64) influxql.IteratorCreator {
+		var ic IteratorCreator
+		ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			ranges = append(ranges, [2]time.Time{time.Unix(0, opt.StartTime).UTC(), time.Unix(0, opt.EndTime+1).UTC()})
+			return &FloatIterator{Points: []influxql.FloatPoint{{Name: "cpu", Time: mustParseTime("2000-01-01T00:00:00Z").UnixNano(), Value: 1}}}, nil
+		}
+		return &ic
+	}
+
+	execute := func(cond string) {
+		q := MustParseQuery(`SELECT sum(value) FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:04:00Z' GROUP BY time(1m)`)
+		q.Statements[0].(*influxql.SelectStatement).SeriesAuthorizer = &SeriesAuthorizer{
+			AuthorizeSeriesReadFn: func(database, measurement string) (bool, influxql.Expr) {
+				return true, influxql.MustParseExpr(cond)
+			},
+		}
+		ranges = nil
+		ReadAllResults(e.QueryExecutor.ExecuteQuery(q, influxql.ExecutionOptions{Database: "db0"}, make(chan struct{})))
+	}
+
+	// A repeated query of the same user is read from the cache.
+	execute(`host = 'a'`)
+	if len(ranges) != 1 {
+		t.Fatalf("unexpected ranges: %v", ranges)
+	}
+	execute(`host = 'a'`)
+	if len(ranges) != 0 {
+		t.Fatalf("unexpected ranges: %v", ranges)
+	}
+
+	// A user with another restriction doesn't read the cached results.
+	execute(`host = 'b'`)
+	if len(ranges) != 1 {
+		t.Fatalf("unexpected ranges: %v", ranges)
+	}
+}
+
+// SeriesAuthorizer is a mockable implementation of influxql.SeriesAuthorizer.
+type SeriesAuthorizer struct {
+	AuthorizeSeriesReadFn func(database, measurement string) (bool, influxql.Expr)
+}
+
+func (a *SeriesAuthorizer) AuthorizeSeriesRead(database, measurement string) (bool, influxql.Expr) {
+	return a.AuthorizeSeriesReadFn(database, measurement)
+}
+
+// Ensure a write only drops the cached windows of the measurement written to.
+func TestQueryCache_Invalidate_Measurement(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.QueryCache = cluster.NewQueryCache(cluster.Config{QueryCacheMaxSize: 1 << 20, QueryCacheTTL: toml.Duration(time.Minute)})
+	e.MetaClient.ShardsByTimeRangeFn = func(sources influxql.Sources, tmin, tmax time.Time) (a []meta.ShardInfo, err error) {
+		return []meta.ShardInfo{{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}}}, nil
+	}
+
+	var n int
+	e.TSDBStore.ShardIteratorCreatorFn = func(id uint64) influxql.IteratorCreator {
+		var ic IteratorCreator
+		ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			n++
+			return &FloatIterator{Points: []influxql.FloatPoint{{Name: "cpu", Time: mustParseTime("2000-01-01T00:00:00Z").UnixNano(), Value: 1}}}, nil
+		}
+		return &ic
+	}
+
+	query := `SELECT sum(value) FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:04:00Z' GROUP BY time(1m)`
+	ReadAllResults(e.ExecuteQuery(query, "db0", 0))
+
+	// A write into another measurement keeps the cached windows.
+	e.QueryCache.Invalidate("db0", []models.Point{models.MustNewPoint("mem", nil, models.Fields{"value": 1.0}, mustParseTime("2000-01-01T00:00:00Z"))})
+	n = 0
+	ReadAllResults(e.ExecuteQuery(query, "db0", 0))
+	if n != 0 {
+		t.Fatalf("unexpected iterators created: %d", n)
+	}
+
+	// A write into the measurement drops them.
+	e.QueryCache.Invalidate("db0", []models.Point{models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, mustParseTime("2000-01-01T00:00:00Z"))})
+	n = 0
+	ReadAllResults(e.ExecuteQuery(query, "db0", 0))
+	if n != 1 {
+		t.Fatalf("unexpected iterators created: %d", n)
+	}
+}
+
+// Ensure the query cache sends the earliest time written to each measurement to the other nodes.
+func TestQueryCache_Invalidate_Broadcast(t *testing.T) {
+	c := cluster.NewQueryCache(cluster.Config{QueryCacheMaxSize: 1 << 20, QueryCacheTTL: toml.Duration(time.Minute)})
+
+	sent := make(chan string, 2)
+	c.Broadcaster = &QueryCacheBroadcaster{
+		InvalidateQueryCacheFn: func(database, measurement string, t int64) error {
+			sent <- fmt.Sprintf("%s.%s %s", database, measurement, time.Unix(0, t).UTC().Format(time.RFC3339))
+			return nil
+		},
+	}
+
+	c.Invalidate("db0", []models.Point{
+		models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, mustParseTime("2000-01-01T00:03:00Z")),
+		models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, mustParseTime("2000-01-01T00:01:00Z")),
+	})
+
+	select {
+	case s := <-sent:
+		if s != "db0.cpu 2000-01-01T00:01:00Z" {
+			t.Fatalf("unexpected invalidation: %s", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("invalidation not sent")
+	}
+}
+
+// QueryCacheBroadcaster is a mockable implementation of QueryCache.Broadcaster.
+type QueryCacheBroadcaster struct {
+	InvalidateQueryCacheFn func(database, measurement string, t int64) error
+}
+
+func (b *QueryCacheBroadcaster) InvalidateQueryCache(database, measurement string, t int64) error {
+	return b.InvalidateQueryCacheFn(database, measurement, t)
+}
+
+// Ensure query executor records non-SELECT statements in the audit log.
+func TestQueryExecutor_ExecuteQuery_Audit(t *testing.T) {
+	e := NewQueryExecutor()
+	e.MetaClient.DropUserFn = func(name string) error {
+		if name == "jdoe" {
+			return nil
+		}
+		return meta.ErrUserNotFound
+	}
+
+	var records []*audit.Record
+	e.Auditor = AuditorFunc(func(r *audit.Record) { records = append(records, r) })
+
+	ReadAllResults(e.QueryExecutor.ExecuteQuery(MustParseQuery(`DROP USER jdoe; DROP USER fred`), influxql.ExecutionOptions{
+		User: "admin",
+		Addr: "10.0.0.1",
+	}, make(chan struct{})))
+
+	if len(records) != 2 {
+		t.Fatalf("unexpected records: %s", spew.Sdump(records))
+	} else if r := records[0]; r.Event != audit.EventStatement || r.Result != audit.ResultSuccess || r.User != "admin" || r.Addr != "10.0.0.1" || r.Statement != `DROP USER jdoe` {
+		t.Fatalf("unexpected record: %s", spew.Sdump(r))
+	} else if r := records[1]; r.Result != audit.ResultFailure || r.Error != meta.ErrUserNotFound.Error() || r.Statement != `DROP USER fred` {
+		t.Fatalf("unexpected record: %s", spew.Sdump(r))
+	}
+}
+
+// Ensure hinted handoff statements are executed against the hinted handoff service.
+func TestQueryExecutor_ExecuteQuery_HintedHandoff(t *testing.T) {
+	e := NewQueryExecutor()
+
+	var paused []uint64
+	e.HintedHandoff = &HintedHandoff{
+		NodeStatusesFn: func() ([]*hh.NodeStatus, error) {
+			return []*hh.NodeStatus{{NodeID: 2, Active: true, Paused: true, Segments: 1, Size: 10, Oldest: time.Now()}}, nil
+		},
+		PauseNodeFn: func(nodeID uint64) error {
+			paused = append(paused, nodeID)
+			return nil
+		},
+		PurgeNodeFn: func(nodeID uint64) error { return hh.ErrNodeNotFound },
+	}
+
+	results := ReadAllResults(e.QueryExecutor.ExecuteQuery(MustParseQuery(`PAUSE HINTED HANDOFF FOR NODE 2; SHOW HINTED HANDOFF; PURGE HINTED HANDOFF FOR NODE 3`), influxql.ExecutionOptions{}, make(chan struct{})))
+	if len(results) != 3 {
+		t.Fatalf("unexpected results: %s", spew.Sdump(results))
+	} else if results[0].Err != nil || !reflect.DeepEqual(paused, []uint64{2}) {
+		t.Fatalf("unexpected pause: %v, %v", results[0].Err, paused)
+	} else if row := results[1].Series[0]; len(row.Values) != 1 || row.Values[0][0] != uint64(2) || row.Values[0][2] != true || row.Values[0][4] != int64(10) {
+		t.Fatalf("unexpected row: %s", spew.Sdump(row))
+	} else if results[2].Err != hh.ErrNodeNotFound {
+		t.Fatalf("unexpected error: %v", results[2].Err)
+	}
+}
+
+// Ensure the numeric fields of a retention policy are read from every node that owns its shards.
+func TestQueryExecutor_NumericFields(t *testing.T) {
+	e := DefaultQueryExecutor()
+
+	// Start a second service.
+	s := MustOpenService()
+	defer s.Close()
+
+	// The retention policy has one local and one remote shard.
+	e.MetaClient.RetentionPolicyFn = func(database, name string) (*meta.RetentionPolicyInfo, error) {
+		return &meta.RetentionPolicyInfo{
+			Name: name,
+			ShardGroups: []meta.ShardGroupInfo{{
+				ID: 1,
+				Shards: []meta.ShardInfo{
+					{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}},
+					{ID: 200, Owners: []meta.ShardOwner{{NodeID: 1}}},
+				},
+			}},
+		}, nil
+	}
+	e.MetaClient.DataNodeFn = func(id uint64) (*meta.NodeInfo, error) {
+		return &meta.NodeInfo{ID: 1, TCPHost: s.Addr().String()}, nil
+	}
+
+	e.TSDBStore.NumericFieldsFn = func(database, rp string) map[string][]string {
+		return map[string][]string{"cpu": {"idle", "user"}}
+	}
+	s.TSDBStore.NumericFieldsFn = func(database, rp string) map[string][]string {
+		if database != "db0" || rp != "rp0" {
+			t.Fatalf("unexpected retention policy: %s.%s", database, rp)
+		}
+		return map[string][]string{"cpu": {"system", "user"}, "mem": {"free"}}
+	}
+
+	if fields, err := e.NumericFields("db0", "rp0"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(fields, map[string][]string{
+		"cpu": {"idle", "system", "user"},
+		"mem": {"free"},
+	}) {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+}
+
+// HintedHandoff is a mockable implementation of cluster.QueryExecutor.HintedHandoff.
+type HintedHandoff struct {
+	NodeStatusesFn func() ([]*hh.NodeStatus, error)
+	PurgeNodeFn    func(nodeID uint64) error
+	PauseNodeFn    func(nodeID uint64) error
+	ResumeNodeFn   func(nodeID uint64) error
+}
+
+func (h *HintedHandoff) NodeStatuses() ([]*hh.NodeStatus, error) { return h.NodeStatusesFn() }
+func (h *HintedHandoff) PurgeNode(nodeID uint64) error           { return h.PurgeNodeFn(nodeID) }
+func (h *HintedHandoff) PauseNode(nodeID uint64) error           { return h.PauseNodeFn(nodeID) }
+func (h *HintedHandoff) ResumeNode(nodeID uint64) error          { return h.ResumeNodeFn(nodeID) }
+
+// AuditorFunc is a function that can be used as cluster.QueryExecutor.Auditor.
+type AuditorFunc func(r *audit.Record)
+
+func (fn AuditorFunc) Audit(r *audit.Record) { fn(r) }
+
 // QueryExecutor is a test wrapper for cluster.QueryExecutor.
 type QueryExecutor struct {
 	*cluster.QueryExecutor
@@ -182,7 +811,10 @@ func DefaultQueryExecutor() *QueryExecutor {
 
 // ExecuteQuery parses query and executes against the database.
 func (e *QueryExecutor) ExecuteQuery(query, database string, chunkSize int) <-chan *influxql.Result {
-	return e.QueryExecutor.ExecuteQuery(MustParseQuery(query), database, chunkSize, make(chan struct{}))
+	return e.QueryExecutor.ExecuteQuery(MustParseQuery(query), influxql.ExecutionOptions{
+		Database:  database,
+		ChunkSize: chunkSize,
+	}, make(chan struct{}))
 }
 
 // TSDBStore is a mockable implementation of cluster.TSDBStore.
@@ -197,6 +829,7 @@ type TSDBStore struct {
 	ExecuteShowFieldKeysStatementFn func(stmt *influxql.ShowFieldKeysStatement, database string) (models.Rows, error)
 	ExecuteShowTagValuesStatementFn func(stmt *influxql.ShowTagValuesStatement, database string) (models.Rows, error)
 	ExpandSourcesFn                 func(sources influxql.Sources) (influxql.Sources, error)
+	NumericFieldsFn                 func(database, retentionPolicy string) map[string][]string
 	ShardIteratorCreatorFn          func(id uint64) influxql.IteratorCreator
 }
 
@@ -239,6 +872,10 @@ func (s *TSDBStore) ExpandSources(sources influxql.Sources) (influxql.Sources, e
 	return s.ExpandSourcesFn(sources)
 }
 
+func (s *TSDBStore) NumericFields(database, retentionPolicy string) map[string][]string {
+	return s.NumericFieldsFn(database, retentionPolicy)
+}
+
 func (s *TSDBStore) ShardIteratorCreator(id uint64) influxql.IteratorCreator {
 	return s.ShardIteratorCreatorFn(id)
 }
@@ -261,6 +898,15 @@ func MustParseQuery(s string) *influxql.Query {
 	return q
 }
 
+// mustParseTime parses an IS0-8601 string. Panic on error.
+func mustParseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err.Error())
+	}
+	return t
+}
+
 // ReadAllResults reads all results from c and returns as a slice.
 func ReadAllResults(c <-chan *influxql.Result) []*influxql.Result {
 	var a []*influxql.Result
@@ -125,6 +125,12 @@ func (w *WriteShardResponse) Code() int { return int(w.pb.GetCode()) }
 // Message returns the Message
 func (w *WriteShardResponse) Message() string { return w.pb.GetMessage() }
 
+// SetRetryAfter sets how long a throttled writer should wait before retrying.
+func (w *WriteShardResponse) SetRetryAfter(d time.Duration) { w.pb.RetryAfter = proto.Int64(int64(d)) }
+
+// RetryAfter returns how long a throttled writer should wait before retrying.
+func (w *WriteShardResponse) RetryAfter() time.Duration { return time.Duration(w.pb.GetRetryAfter()) }
+
 // MarshalBinary encodes the object to a binary format.
 func (w *WriteShardResponse) MarshalBinary() ([]byte, error) {
 	return proto.Marshal(&w.pb)
@@ -204,6 +210,12 @@ func (w *ExecuteStatementResponse) UnmarshalBinary(buf []byte) error {
 type CreateIteratorRequest struct {
 	ShardIDs []uint64
 	Opt      influxql.IteratorOptions
+
+	// The maximum number of points to read from the shards, zero is unlimited.
+	MaxPointN int
+
+	// Sends the statistics of the data read from the shards after the points.
+	Stats bool
 }
 
 // MarshalBinary encodes r to a binary format.
@@ -212,10 +224,18 @@ func (r *CreateIteratorRequest) MarshalBinary() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	return proto.Marshal(&internal.CreateIteratorRequest{
+
+	pb := &internal.CreateIteratorRequest{
 		ShardIDs: r.ShardIDs,
 		Opt:      buf,
-	})
+	}
+	if r.MaxPointN > 0 {
+		pb.MaxPointN = proto.Int64(int64(r.MaxPointN))
+	}
+	if r.Stats {
+		pb.Stats = proto.Bool(true)
+	}
+	return proto.Marshal(pb)
 }
 
 // UnmarshalBinary decodes data into r.
@@ -229,6 +249,8 @@ func (r *CreateIteratorRequest) UnmarshalBinary(data []byte) error {
 	if err := r.Opt.UnmarshalBinary(pb.GetOpt()); err != nil {
 		return err
 	}
+	r.MaxPointN = int(pb.GetMaxPointN())
+	r.Stats = pb.GetStats()
 	return nil
 }
 
@@ -411,3 +433,103 @@ func (r *SeriesKeysResponse) UnmarshalBinary(data []byte) error {
 
 	return nil
 }
+
+// NumericFieldsRequest represents a request to retrieve the numeric fields of
+// the measurements in a retention policy.
+type NumericFieldsRequest struct {
+	Database        string
+	RetentionPolicy string
+}
+
+// MarshalBinary encodes r to a binary format.
+func (r *NumericFieldsRequest) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(&internal.NumericFieldsRequest{
+		Database:        proto.String(r.Database),
+		RetentionPolicy: proto.String(r.RetentionPolicy),
+	})
+}
+
+// UnmarshalBinary decodes data into r.
+func (r *NumericFieldsRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.NumericFieldsRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	r.Database = pb.GetDatabase()
+	r.RetentionPolicy = pb.GetRetentionPolicy()
+	return nil
+}
+
+// NumericFieldsResponse represents a response from retrieving numeric fields.
+type NumericFieldsResponse struct {
+	Fields map[string][]string
+	Err    error
+}
+
+// MarshalBinary encodes r to a binary format.
+func (r *NumericFieldsResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.NumericFieldsResponse
+
+	pb.Measurements = make([]*internal.MeasurementFields, 0, len(r.Fields))
+	for name, fields := range r.Fields {
+		pb.Measurements = append(pb.Measurements, &internal.MeasurementFields{
+			Name:   proto.String(name),
+			Fields: fields,
+		})
+	}
+
+	if r.Err != nil {
+		pb.Err = proto.String(r.Err.Error())
+	}
+	return proto.Marshal(&pb)
+}
+
+// UnmarshalBinary decodes data into r.
+func (r *NumericFieldsResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.NumericFieldsResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	r.Fields = make(map[string][]string, len(pb.GetMeasurements()))
+	for _, m := range pb.GetMeasurements() {
+		r.Fields[m.GetName()] = m.GetFields()
+	}
+
+	if pb.Err != nil {
+		r.Err = errors.New(pb.GetErr())
+	}
+	return nil
+}
+
+// InvalidateQueryCacheRequest represents a request to drop the cached query
+// results of the windows of a measurement at and after a time. An empty
+// measurement drops the windows of every measurement of the database.
+type InvalidateQueryCacheRequest struct {
+	Database    string
+	Measurement string
+	Time        int64
+}
+
+// MarshalBinary encodes r to a binary format.
+func (r *InvalidateQueryCacheRequest) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(&internal.InvalidateQueryCacheRequest{
+		Database:    proto.String(r.Database),
+		Time:        proto.Int64(r.Time),
+		Measurement: proto.String(r.Measurement),
+	})
+}
+
+// UnmarshalBinary decodes data into r.
+func (r *InvalidateQueryCacheRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.InvalidateQueryCacheRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	r.Database = pb.GetDatabase()
+	r.Measurement = pb.GetMeasurement()
+	r.Time = pb.GetTime()
+	return nil
+}
@@ -137,3 +137,28 @@ func TestSeriesKeysResponse_MarshalBinary(t *testing.T) {
 		t.Fatalf("unexpected response: %s", spew.Sdump(other))
 	}
 }
+
+func TestCreateIteratorRequest_MarshalBinary(t *testing.T) {
+	req := &CreateIteratorRequest{
+		ShardIDs:  []uint64{1, 2},
+		Opt:       influxql.IteratorOptions{StartTime: 10, EndTime: 20, Ascending: true},
+		MaxPointN: 100,
+		Stats:     true,
+	}
+
+	// Marshal to binary.
+	buf, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unmarshal back to an object.
+	var other CreateIteratorRequest
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(other.ShardIDs, req.ShardIDs) || other.MaxPointN != req.MaxPointN || !other.Stats {
+		t.Fatalf("unexpected request: %s", spew.Sdump(other))
+	} else if other.Opt.StartTime != 10 || other.Opt.EndTime != 20 || !other.Opt.Ascending {
+		t.Fatalf("unexpected options: %s", spew.Sdump(other.Opt))
+	}
+}
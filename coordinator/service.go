@@ -29,6 +29,7 @@ const (
 	writeShardReq       = "writeShardReq"
 	writeShardPointsReq = "writeShardPointsReq"
 	writeShardFail      = "writeShardFail"
+	writeShardThrottled = "writeShardThrottled"
 
 	createIteratorReq  = "createIteratorReq"
 	createIteratorResp = "createIteratorResp"
@@ -38,6 +39,10 @@ const (
 
 	seriesKeysReq  = "seriesKeysReq"
 	seriesKeysResp = "seriesKeysResp"
+
+	numericFieldsReq = "numericFieldsReq"
+
+	invalidateQueryCacheReq = "invalidateQueryCacheReq"
 )
 
 // Service processes data received over raw TCP connections.
@@ -55,6 +60,13 @@ type Service struct {
 
 	TSDBStore TSDBStore
 
+	// WriteLimiter, if set, rejects shard writes while too many are in flight.
+	WriteLimiter *WriteLimiter
+
+	// QueryCache, if set, drops the cached query results that writes and
+	// statements change, including the writes broadcast by other nodes.
+	QueryCache *QueryCache
+
 	Logger  *log.Logger
 	statMap *expvar.Map
 }
@@ -196,6 +208,23 @@ func (s *Service) handleConn(conn net.Conn) {
 			s.statMap.Add(seriesKeysReq, 1)
 			s.processSeriesKeysRequest(conn)
 			return
+		case numericFieldsRequestMessage:
+			s.statMap.Add(numericFieldsReq, 1)
+			s.processNumericFieldsRequest(conn)
+			return
+		case invalidateQueryCacheRequestMessage:
+			buf, err := ReadLV(conn)
+			if err != nil {
+				s.Logger.Printf("unable to read length-value: %s", err)
+				return
+			}
+
+			s.statMap.Add(invalidateQueryCacheReq, 1)
+			err = s.processInvalidateQueryCacheRequest(buf)
+			if err != nil {
+				s.Logger.Printf("process invalidate query cache error: %s", err)
+			}
+			s.writeShardResponse(conn, err)
 		default:
 			s.Logger.Printf("cluster service message type not found: %d", typ)
 		}
@@ -215,6 +244,11 @@ func (s *Service) processExecuteStatementRequest(buf []byte) error {
 		return err
 	}
 
+	// Drop the cached query results of the data that may have been removed.
+	if s.QueryCache != nil {
+		defer s.QueryCache.invalidateStatement(stmt, req.Database())
+	}
+
 	return s.executeStatement(stmt, req.Database())
 }
 
@@ -233,6 +267,19 @@ func (s *Service) executeStatement(stmt influxql.Statement, database string) err
 	}
 }
 
+func (s *Service) processInvalidateQueryCacheRequest(buf []byte) error {
+	var req InvalidateQueryCacheRequest
+	if err := req.UnmarshalBinary(buf); err != nil {
+		return err
+	}
+
+	// The sending node broadcasts the write, so only drop the local windows.
+	if s.QueryCache != nil {
+		s.QueryCache.invalidate(req.Database, req.Measurement, req.Time)
+	}
+	return nil
+}
+
 func (s *Service) processWriteShardRequest(buf []byte) error {
 	// Build request
 	var req WriteShardRequest
@@ -242,6 +289,21 @@ func (s *Service) processWriteShardRequest(buf []byte) error {
 
 	points := req.Points()
 	s.statMap.Add(writeShardPointsReq, int64(len(points)))
+
+	if s.WriteLimiter != nil {
+		n, size := int64(len(points)), int64(len(buf))
+		if err := s.WriteLimiter.Acquire(req.Database(), n, size); err != nil {
+			s.statMap.Add(writeShardThrottled, 1)
+			return err
+		}
+		defer s.WriteLimiter.Release(req.Database(), n, size)
+	}
+
+	// Drop the cached query results of the windows written to.
+	if s.QueryCache != nil {
+		defer s.QueryCache.Invalidate(req.Database(), points)
+	}
+
 	err := s.TSDBStore.WriteToShard(req.ShardID(), points)
 
 	// We may have received a write for a shard that we don't have locally because the
@@ -280,7 +342,11 @@ func (s *Service) processWriteShardRequest(buf []byte) error {
 func (s *Service) writeShardResponse(w io.Writer, e error) {
 	// Build response.
 	var resp WriteShardResponse
-	if e != nil {
+	if te, ok := e.(*WriteThrottledError); ok {
+		resp.SetCode(writeShardThrottledCode)
+		resp.SetMessage(te.Limit)
+		resp.SetRetryAfter(te.RetryAfter)
+	} else if e != nil {
 		resp.SetCode(1)
 		resp.SetMessage(e.Error())
 	} else {
@@ -304,6 +370,7 @@ func (s *Service) processCreateIteratorRequest(conn net.Conn) {
 	defer conn.Close()
 
 	var itr influxql.Iterator
+	var stats *influxql.IteratorStats
 	if err := func() error {
 		// Parse request.
 		var req CreateIteratorRequest
@@ -321,6 +388,12 @@ func (s *Service) processCreateIteratorRequest(conn net.Conn) {
 			ics = append(ics, ic)
 		}
 
+		// Collect the statistics of the data read if requested.
+		if req.Stats {
+			stats = &influxql.IteratorStats{}
+			req.Opt.Stats = stats
+		}
+
 		// Generate a single iterator from all shards.
 		i, err := influxql.IteratorCreators(ics).CreateIterator(req.Opt)
 		if err != nil {
@@ -328,9 +401,18 @@ func (s *Service) processCreateIteratorRequest(conn net.Conn) {
 		}
 		itr = i
 
+		// Stop streaming once the requesting node's point limit is exceeded.
+		// The point over the limit is sent so that the requesting node
+		// returns the error.
+		if req.MaxPointN > 0 && itr != nil {
+			itr = influxql.NewPointLimiter(req.MaxPointN + 1).Iterator(itr)
+		}
+
 		return nil
 	}(); err != nil {
-		itr.Close()
+		if itr != nil {
+			itr.Close()
+		}
 		s.Logger.Printf("error reading CreateIterator request: %s", err)
 		EncodeTLV(conn, createIteratorResponseMessage, &CreateIteratorResponse{Err: err})
 		return
@@ -348,10 +430,19 @@ func (s *Service) processCreateIteratorRequest(conn net.Conn) {
 	}
 
 	// Stream iterator to connection.
-	if err := influxql.NewIteratorEncoder(conn).EncodeIterator(itr); err != nil {
+	enc := influxql.NewIteratorEncoder(conn)
+	if err := enc.EncodeIterator(itr); err != nil {
 		s.Logger.Printf("error encoding CreateIterator iterator: %s", err)
 		return
 	}
+
+	// Send the statistics of the data read after the points.
+	if stats != nil {
+		if err := enc.EncodeStats(*stats); err != nil {
+			s.Logger.Printf("error encoding CreateIterator stats: %s", err)
+			return
+		}
+	}
 }
 
 func (s *Service) processFieldDimensionsRequest(conn net.Conn) {
@@ -439,6 +530,24 @@ func (s *Service) processSeriesKeysRequest(conn net.Conn) {
 	}
 }
 
+func (s *Service) processNumericFieldsRequest(conn net.Conn) {
+	// Parse request.
+	var req NumericFieldsRequest
+	if err := DecodeLV(conn, &req); err != nil {
+		s.Logger.Printf("error reading NumericFields request: %s", err)
+		EncodeTLV(conn, numericFieldsResponseMessage, &NumericFieldsResponse{Err: err})
+		return
+	}
+
+	// Encode success response.
+	if err := EncodeTLV(conn, numericFieldsResponseMessage, &NumericFieldsResponse{
+		Fields: s.TSDBStore.NumericFields(req.Database, req.RetentionPolicy),
+	}); err != nil {
+		s.Logger.Printf("error writing NumericFields response: %s", err)
+		return
+	}
+}
+
 // ReadTLV reads a type-length-value record from r.
 func ReadTLV(r io.Reader) (byte, []byte, error) {
 	typ, err := ReadType(r)
@@ -24,8 +24,18 @@ const (
 
 	seriesKeysRequestMessage
 	seriesKeysResponseMessage
+
+	numericFieldsRequestMessage
+	numericFieldsResponseMessage
+
+	invalidateQueryCacheRequestMessage
+	invalidateQueryCacheResponseMessage
 )
 
+// writeShardThrottledCode is the code of a WriteShardResponse for a write
+// rejected by the write limits of the owner.
+const writeShardThrottledCode = 2
+
 // ShardWriter writes a set of points to a shard.
 type ShardWriter struct {
 	pool           *clientPool
@@ -105,7 +115,9 @@ func (w *ShardWriter) WriteShard(shardID, ownerID uint64, points []models.Point)
 		return err
 	}
 
-	if response.Code() != 0 {
+	if response.Code() == writeShardThrottledCode {
+		return &WriteThrottledError{Limit: response.Message(), RetryAfter: response.RetryAfter()}
+	} else if response.Code() != 0 {
 		return fmt.Errorf("error code %d: %s", response.Code(), response.Message())
 	}
 
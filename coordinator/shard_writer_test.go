@@ -137,6 +137,44 @@ func TestShardWriter_WriteShard_Error(t *testing.T) {
 	}
 }
 
+// Ensure the shard writer returns a throttled error when the owner throttles the write.
+func TestShardWriter_WriteShard_Throttled(t *testing.T) {
+	ts := newTestWriteService(nil)
+	ts.TSDBStore.WriteToShardFn = ts.writeShardSuccess
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = &ts.TSDBStore
+
+	// Fill the database's in-flight limit so the next write is throttled.
+	s.WriteLimiter = cluster.NewWriteLimiter(cluster.Config{
+		MaxDatabaseInflightWritePoints: 1,
+		WriteThrottleRetryAfter:        toml.Duration(2 * time.Second),
+	})
+	if err := s.WriteLimiter.Acquire("db", 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute, 1)
+	w.MetaClient = &metaClient{host: ts.ln.Addr().String()}
+	points := []models.Point{models.MustNewPoint(
+		"cpu", models.Tags{"host": "server01"}, map[string]interface{}{"value": int64(100)}, time.Now(),
+	)}
+
+	err := w.WriteShard(1, 2, points)
+	if e, ok := err.(*cluster.WriteThrottledError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e.RetryAfter != 2*time.Second {
+		t.Fatalf("unexpected retry after: %s", e.RetryAfter)
+	} else if !strings.Contains(e.Limit, `database "db"`) {
+		t.Fatalf("unexpected limit: %s", e.Limit)
+	}
+}
+
 // Ensure the shard writer returns an error when dialing times out.
 func TestShardWriter_Write_ErrDialTimeout(t *testing.T) {
 	ts := newTestWriteService(nil)
@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/freetsdb/freetsdb/models"
+)
+
+// WriteThrottledError is returned when accepting a write would exceed the
+// limits on the writes in flight on a node or for a database.
+type WriteThrottledError struct {
+	// Limit describes the limit that was hit.
+	Limit string
+
+	// RetryAfter is how long the client should wait before retrying.
+	RetryAfter time.Duration
+}
+
+// Error returns the string representation of the error.
+func (e *WriteThrottledError) Error() string {
+	return fmt.Sprintf("write throttled: %s", e.Limit)
+}
+
+// writeUsage is the size of the writes in flight.
+type writeUsage struct {
+	bytes  int64
+	points int64
+}
+
+// WriteLimiter admits writes while the bytes and points in flight on the node,
+// and for each database, are within their limits. A limit of zero is unlimited.
+type WriteLimiter struct {
+	mu        sync.Mutex
+	node      writeUsage
+	databases map[string]*writeUsage
+
+	MaxBytes          int64
+	MaxPoints         int64
+	MaxDatabaseBytes  int64
+	MaxDatabasePoints int64
+
+	// RetryAfter is returned to clients whose writes are throttled.
+	RetryAfter time.Duration
+}
+
+// NewWriteLimiter returns a new instance of WriteLimiter with the limits in c.
+func NewWriteLimiter(c Config) *WriteLimiter {
+	return &WriteLimiter{
+		databases:         make(map[string]*writeUsage),
+		MaxBytes:          c.MaxInflightWriteBytes,
+		MaxPoints:         c.MaxInflightWritePoints,
+		MaxDatabaseBytes:  c.MaxDatabaseInflightWriteBytes,
+		MaxDatabasePoints: c.MaxDatabaseInflightWritePoints,
+		RetryAfter:        time.Duration(c.WriteThrottleRetryAfter),
+	}
+}
+
+// Acquire reserves room for a write of points to database. It returns a
+// *WriteThrottledError if the write would exceed a limit. A write is always
+// admitted when nothing else is in flight, so a write larger than a limit is
+// not rejected forever. Every successful Acquire must be followed by Release.
+func (l *WriteLimiter) Acquire(database string, points, bytes int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	db := l.databases[database]
+	if db == nil {
+		db = &writeUsage{}
+	}
+
+	if l.node.points > 0 {
+		if exceeds(l.node.bytes, bytes, l.MaxBytes) {
+			return l.throttled(fmt.Sprintf("node has %d bytes in flight, max %d", l.node.bytes, l.MaxBytes))
+		} else if exceeds(l.node.points, points, l.MaxPoints) {
+			return l.throttled(fmt.Sprintf("node has %d points in flight, max %d", l.node.points, l.MaxPoints))
+		}
+	}
+	if db.points > 0 {
+		if exceeds(db.bytes, bytes, l.MaxDatabaseBytes) {
+			return l.throttled(fmt.Sprintf("database %q has %d bytes in flight, max %d", database, db.bytes, l.MaxDatabaseBytes))
+		} else if exceeds(db.points, points, l.MaxDatabasePoints) {
+			return l.throttled(fmt.Sprintf("database %q has %d points in flight, max %d", database, db.points, l.MaxDatabasePoints))
+		}
+	}
+
+	l.node.bytes += bytes
+	l.node.points += points
+	db.bytes += bytes
+	db.points += points
+	l.databases[database] = db
+	return nil
+}
+
+// Release frees the room reserved for a write by Acquire.
+func (l *WriteLimiter) Release(database string, points, bytes int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.node.bytes -= bytes
+	l.node.points -= points
+
+	if db := l.databases[database]; db != nil {
+		db.bytes -= bytes
+		db.points -= points
+		if db.points <= 0 {
+			delete(l.databases, database)
+		}
+	}
+}
+
+func (l *WriteLimiter) throttled(limit string) error {
+	return &WriteThrottledError{Limit: limit, RetryAfter: l.RetryAfter}
+}
+
+// exceeds returns true if adding n to used goes over max. A max of zero is unlimited.
+func exceeds(used, n, max int64) bool {
+	return max > 0 && used+n > max
+}
+
+// pointsSize returns the approximate number of bytes used by points.
+func pointsSize(points []models.Point) int64 {
+	var n int64
+	for _, p := range points {
+		n += int64(len(p.Key()) + len(p.Data()) + 8)
+	}
+	return n
+}
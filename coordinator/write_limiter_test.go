@@ -0,0 +1,54 @@
+package cluster_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/freetsdb/freetsdb/cluster"
+)
+
+// Ensure writes are throttled once the node's in-flight limits are reached.
+func TestWriteLimiter_Node(t *testing.T) {
+	l := cluster.NewWriteLimiter(cluster.Config{MaxInflightWritePoints: 10})
+	l.RetryAfter = time.Second
+
+	if err := l.Acquire("db0", 8, 100); err != nil {
+		t.Fatal(err)
+	}
+	err := l.Acquire("db1", 5, 100)
+	if e, ok := err.(*cluster.WriteThrottledError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e.RetryAfter != time.Second {
+		t.Fatalf("unexpected retry after: %s", e.RetryAfter)
+	}
+
+	// Releasing the first write makes room for the second.
+	l.Release("db0", 8, 100)
+	if err := l.Acquire("db1", 5, 100); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure one database reaching its limit doesn't throttle the others.
+func TestWriteLimiter_Database(t *testing.T) {
+	l := cluster.NewWriteLimiter(cluster.Config{MaxDatabaseInflightWriteBytes: 1000})
+
+	if err := l.Acquire("db0", 1, 800); err != nil {
+		t.Fatal(err)
+	} else if err := l.Acquire("db0", 1, 800); err == nil {
+		t.Fatal("expected db0 write to be throttled")
+	} else if err := l.Acquire("db1", 1, 800); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure a write larger than a limit is admitted when nothing else is in flight.
+func TestWriteLimiter_Oversized(t *testing.T) {
+	l := cluster.NewWriteLimiter(cluster.Config{MaxInflightWriteBytes: 100})
+
+	if err := l.Acquire("db0", 1, 1000); err != nil {
+		t.Fatal(err)
+	} else if err := l.Acquire("db0", 1, 10); err == nil {
+		t.Fatal("expected write to be throttled")
+	}
+}
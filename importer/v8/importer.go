@@ -5,9 +5,11 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +18,19 @@ import (
 
 const batchSize = 5000
 
+const (
+	// DefaultRetries is the default number of times a failed batch is retried.
+	DefaultRetries = 3
+
+	// DefaultRetryInterval is the default time waited before the first retry
+	// of a failed batch. It doubles with every retry.
+	DefaultRetryInterval = time.Second
+
+	// ResumeExt is appended to the path of the file being imported to name the
+	// file recording how far the import got.
+	ResumeExt = ".resume"
+)
+
 // Config is the config used to initialize a Importer importer
 type Config struct {
 	Username         string
@@ -27,11 +42,16 @@ type Config struct {
 	Version          string
 	Compressed       bool
 	PPS              int
+	Retries          int
+	RetryInterval    time.Duration
 }
 
 // NewConfig returns an initialized *Config
 func NewConfig() *Config {
-	return &Config{}
+	return &Config{
+		Retries:       DefaultRetries,
+		RetryInterval: DefaultRetryInterval,
+	}
 }
 
 // Importer is the importer used for importing 0.8 data
@@ -47,6 +67,13 @@ type Importer struct {
 	throttlePointsWritten int
 	lastWrite             time.Time
 	throttle              *time.Ticker
+
+	// line is the number of the last line read from the file, and batchLine
+	// the number of the last line in the batch. resumeLine is the line a
+	// previous, failed import had written everything up to.
+	line       int
+	batchLine  int
+	resumeLine int
 }
 
 // NewImporter will return an intialized Importer struct
@@ -57,7 +84,9 @@ func NewImporter(config *Config) *Importer {
 	}
 }
 
-// Import processes the specified file in the Config and writes the data to the databases in chunks specified by batchSize
+// Import processes the specified file in the Config and writes the data to the
+// databases in chunks specified by batchSize. If a batch can't be written the
+// import stops, and running it again resumes from that batch.
 func (i *Importer) Import() error {
 	// Create a client and try to connect
 	config := client.NewConfig()
@@ -87,6 +116,13 @@ func (i *Importer) Import() error {
 		}
 	}()
 
+	// Pick up where a previous import of the file stopped.
+	if i.resumeLine, err = readResumeLine(i.resumePath()); err != nil {
+		return err
+	} else if i.resumeLine > 0 {
+		log.Printf("Resuming import after line %d\n", i.resumeLine)
+	}
+
 	// Open the file
 	f, err := os.Open(i.config.Path)
 	if err != nil {
@@ -125,18 +161,25 @@ func (i *Importer) Import() error {
 	i.lastWrite = time.Now()
 
 	// Process the DML
-	i.processDML(scanner)
+	if err := i.processDML(scanner); err != nil {
+		return err
+	}
 
 	// Check if we had any errors scanning the file
 	if err := scanner.Err(); err != nil {
 		return fmt.Errorf("reading standard input: %s", err)
 	}
 
+	// The whole file was imported, so there is nothing left to resume.
+	if err := os.Remove(i.resumePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return nil
 }
 
 func (i *Importer) processDDL(scanner *bufio.Scanner) {
 	for scanner.Scan() {
+		i.line++
 		line := scanner.Text()
 		// If we find the DML token, we are done with DDL
 		if strings.HasPrefix(line, "# DML") {
@@ -153,14 +196,22 @@ func (i *Importer) processDDL(scanner *bufio.Scanner) {
 	}
 }
 
-func (i *Importer) processDML(scanner *bufio.Scanner) {
+func (i *Importer) processDML(scanner *bufio.Scanner) error {
 	start := time.Now()
 	for scanner.Scan() {
+		i.line++
 		line := scanner.Text()
 		if strings.HasPrefix(line, "# CONTEXT-DATABASE:") {
+			// Points are written to the current context, so flush them first.
+			if err := i.batchWrite(); err != nil {
+				return err
+			}
 			i.database = strings.TrimSpace(strings.Split(line, ":")[1])
 		}
 		if strings.HasPrefix(line, "# CONTEXT-RETENTION-POLICY:") {
+			if err := i.batchWrite(); err != nil {
+				return err
+			}
 			i.retentionPolicy = strings.TrimSpace(strings.Split(line, ":")[1])
 		}
 		if strings.HasPrefix(line, "#") {
@@ -170,10 +221,16 @@ func (i *Importer) processDML(scanner *bufio.Scanner) {
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
-		i.batchAccumulator(line, start)
+		// Skip lines written by a previous import
+		if i.line <= i.resumeLine {
+			continue
+		}
+		if err := i.batchAccumulator(line, start); err != nil {
+			return err
+		}
 	}
 	// Call batchWrite one last time to flush anything out in the batch
-	i.batchWrite()
+	return i.batchWrite()
 }
 
 func (i *Importer) execute(command string) {
@@ -192,11 +249,13 @@ func (i *Importer) queryExecutor(command string) {
 	i.execute(command)
 }
 
-func (i *Importer) batchAccumulator(line string, start time.Time) {
+func (i *Importer) batchAccumulator(line string, start time.Time) error {
 	i.batch = append(i.batch, line)
+	i.batchLine = i.line
 	if len(i.batch) == batchSize {
-		i.batchWrite()
-		i.batch = i.batch[:0]
+		if err := i.batchWrite(); err != nil {
+			return err
+		}
 		// Give some status feedback every 100000 lines processed
 		processed := i.totalInserts + i.failedInserts
 		if processed%100000 == 0 {
@@ -205,44 +264,84 @@ func (i *Importer) batchAccumulator(line string, start time.Time) {
 			log.Printf("Processed %d lines.  Time elapsed: %s.  Points per second (PPS): %d", processed, since.String(), int64(pps))
 		}
 	}
+	return nil
 }
 
-func (i *Importer) batchWrite() {
+// batchWrite writes the batch, retrying it if it fails. Once it is written
+// the resume file is updated to the last line in the batch.
+func (i *Importer) batchWrite() error {
+	if len(i.batch) == 0 {
+		return nil
+	}
+
+	i.throttleBatch()
+
+	data := strings.Join(i.batch, "\n")
+	interval := i.config.RetryInterval
+	_, e := i.client.WriteLineProtocol(data, i.database, i.retentionPolicy, i.config.Precision, i.config.WriteConsistency)
+	for n := 0; e != nil && n < i.config.Retries; n++ {
+		log.Printf("error writing batch, retrying in %s: %s", interval, e)
+		time.Sleep(interval)
+		interval *= 2
+		_, e = i.client.WriteLineProtocol(data, i.database, i.retentionPolicy, i.config.Precision, i.config.WriteConsistency)
+	}
+	i.throttlePointsWritten = 0
+	i.lastWrite = time.Now()
+
+	if e != nil {
+		i.failedInserts += len(i.batch)
+		return fmt.Errorf("error writing batch ending at line %d: %s (run the import again to resume)", i.batchLine, e)
+	}
+	i.totalInserts += len(i.batch)
+	i.batch = i.batch[:0]
+
+	return ioutil.WriteFile(i.resumePath(), []byte(strconv.Itoa(i.batchLine)), 0600)
+}
+
+// throttleBatch waits until writing the batch won't exceed the points per
+// second allowed.
+func (i *Importer) throttleBatch() {
 	// Accumulate the batch size to see how many points we have written this second
 	i.throttlePointsWritten += len(i.batch)
 
-	// Find out when we last wrote data
-	since := time.Since(i.lastWrite)
+	for i.config.PPS != 0 {
+		// Find out when we last wrote data
+		since := time.Since(i.lastWrite)
 
-	// Check to see if we've exceeded our points per second for the current timeframe
-	var currentPPS int
-	if since.Seconds() > 0 {
-		currentPPS = int(float64(i.throttlePointsWritten) / since.Seconds())
-	} else {
-		currentPPS = i.throttlePointsWritten
-	}
+		// Check to see if we've exceeded our points per second for the current timeframe
+		var currentPPS int
+		if since.Seconds() > 0 {
+			currentPPS = int(float64(i.throttlePointsWritten) / since.Seconds())
+		} else {
+			currentPPS = i.throttlePointsWritten
+		}
+		if currentPPS <= i.config.PPS {
+			return
+		}
 
-	// If our currentPPS is greater than the PPS specified, then we wait and retry
-	if int(currentPPS) > i.config.PPS && i.config.PPS != 0 {
 		// Wait for the next tick
 		<-i.throttle.C
+	}
+}
 
-		// Decrement the batch size back out as it is going to get called again
-		i.throttlePointsWritten -= len(i.batch)
-		i.batchWrite()
-		return
+// resumePath returns the path of the file recording how far the import got.
+func (i *Importer) resumePath() string {
+	return i.config.Path + ResumeExt
+}
+
+// readResumeLine returns the line recorded in the resume file at path, or
+// zero if there is no resume file.
+func readResumeLine(path string) (int, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
 	}
 
-	_, e := i.client.WriteLineProtocol(strings.Join(i.batch, "\n"), i.database, i.retentionPolicy, i.config.Precision, i.config.WriteConsistency)
-	if e != nil {
-		log.Println("error writing batch: ", e)
-		// Output failed lines to STDOUT so users can capture lines that failed to import
-		fmt.Println(strings.Join(i.batch, "\n"))
-		i.failedInserts += len(i.batch)
-	} else {
-		i.totalInserts += len(i.batch)
+	n, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid resume file %s: %s", path, err)
 	}
-	i.throttlePointsWritten = 0
-	i.lastWrite = time.Now()
-	return
+	return n, nil
 }
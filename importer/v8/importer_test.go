@@ -0,0 +1,102 @@
+package v8_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/freetsdb/freetsdb/importer/v8"
+)
+
+// Ensure an import that fails part way resumes after the last written batch.
+func TestImporter_Resume(t *testing.T) {
+	f, err := ioutil.TempFile("", "importer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer os.Remove(f.Name() + v8.ResumeExt)
+
+	f.WriteString(`# DDL
+CREATE DATABASE db0
+CREATE DATABASE db1
+# DML
+# CONTEXT-DATABASE:db0
+cpu value=1 1
+cpu value=2 2
+# CONTEXT-DATABASE:db1
+cpu value=3 3
+`)
+	f.Close()
+
+	var mu sync.Mutex
+	var fail bool
+	writes := make(map[string][]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/query", "/ping":
+			w.WriteHeader(http.StatusNoContent)
+		case "/write":
+			db := r.URL.Query().Get("db")
+			if fail && db == "db1" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			b, _ := ioutil.ReadAll(r.Body)
+			writes[db] = append(writes[db], string(b))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	config := v8.NewConfig()
+	config.URL = *u
+	config.Path = f.Name()
+	config.Retries = 0
+
+	// The write to db1 fails, so only db0 is written.
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	if err := v8.NewImporter(config).Import(); err == nil || !strings.Contains(err.Error(), "ending at line 9") {
+		t.Fatalf("unexpected error: %v", err)
+	} else if b, err := ioutil.ReadFile(f.Name() + v8.ResumeExt); err != nil || string(b) != "7" {
+		t.Fatalf("unexpected resume file: %q, %v", b, err)
+	}
+
+	// Running the import again only writes db1.
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	if err := v8.NewImporter(config).Import(); err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"cpu value=1 1\ncpu value=2 2"}; !equal(writes["db0"], exp) {
+		t.Fatalf("unexpected db0 writes: %q", writes["db0"])
+	} else if exp := []string{"cpu value=3 3"}; !equal(writes["db1"], exp) {
+		t.Fatalf("unexpected db1 writes: %q", writes["db1"])
+	} else if _, err := os.Stat(f.Name() + v8.ResumeExt); !os.IsNotExist(err) {
+		t.Fatalf("resume file not removed: %v", err)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
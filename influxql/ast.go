@@ -92,29 +92,40 @@ func (Statements) node() {}
 func (*AlterRetentionPolicyStatement) node()  {}
 func (*CreateContinuousQueryStatement) node() {}
 func (*CreateDatabaseStatement) node()        {}
+func (*CreateDownsampleStatement) node()      {}
 func (*CreateRetentionPolicyStatement) node() {}
 func (*CreateSubscriptionStatement) node()    {}
+func (*CreateTokenStatement) node()           {}
 func (*CreateUserStatement) node()            {}
 func (*Distinct) node()                       {}
 func (*DeleteStatement) node()                {}
 func (*DropContinuousQueryStatement) node()   {}
 func (*DropDatabaseStatement) node()          {}
+func (*DropDownsampleStatement) node()        {}
 func (*DropMeasurementStatement) node()       {}
 func (*DropRetentionPolicyStatement) node()   {}
 func (*DropSeriesStatement) node()            {}
 func (*DropServerStatement) node()            {}
 func (*DropSubscriptionStatement) node()      {}
+func (*DropTokenStatement) node()             {}
 func (*DropUserStatement) node()              {}
+func (*ExplainStatement) node()               {}
 func (*GrantStatement) node()                 {}
+func (*PauseHintedHandoffStatement) node()    {}
+func (*PurgeHintedHandoffStatement) node()    {}
+func (*ResumeHintedHandoffStatement) node()   {}
 func (*GrantAdminStatement) node()            {}
 func (*RevokeStatement) node()                {}
 func (*RevokeAdminStatement) node()           {}
+func (*RunContinuousQueryStatement) node()    {}
 func (*SelectStatement) node()                {}
 func (*SetPasswordUserStatement) node()       {}
 func (*ShowContinuousQueriesStatement) node() {}
 func (*ShowGrantsForUserStatement) node()     {}
+func (*ShowHintedHandoffStatement) node()     {}
 func (*ShowServersStatement) node()           {}
 func (*ShowDatabasesStatement) node()         {}
+func (*ShowDownsamplesStatement) node()       {}
 func (*ShowFieldKeysStatement) node()         {}
 func (*ShowRetentionPoliciesStatement) node() {}
 func (*ShowMeasurementsStatement) node()      {}
@@ -126,10 +137,12 @@ func (*ShowSubscriptionsStatement) node()     {}
 func (*ShowDiagnosticsStatement) node()       {}
 func (*ShowTagKeysStatement) node()           {}
 func (*ShowTagValuesStatement) node()         {}
+func (*ShowTokensStatement) node()            {}
 func (*ShowUsersStatement) node()             {}
 
 func (*BinaryExpr) node()      {}
 func (*BooleanLiteral) node()  {}
+func (*BoundParameter) node()  {}
 func (*Call) node()            {}
 func (*Dimension) node()       {}
 func (Dimensions) node()       {}
@@ -204,24 +217,34 @@ type ExecutionPrivileges []ExecutionPrivilege
 func (*AlterRetentionPolicyStatement) stmt()  {}
 func (*CreateContinuousQueryStatement) stmt() {}
 func (*CreateDatabaseStatement) stmt()        {}
+func (*CreateDownsampleStatement) stmt()      {}
 func (*CreateRetentionPolicyStatement) stmt() {}
 func (*CreateSubscriptionStatement) stmt()    {}
+func (*CreateTokenStatement) stmt()           {}
 func (*CreateUserStatement) stmt()            {}
 func (*DeleteStatement) stmt()                {}
 func (*DropContinuousQueryStatement) stmt()   {}
 func (*DropDatabaseStatement) stmt()          {}
+func (*DropDownsampleStatement) stmt()        {}
 func (*DropMeasurementStatement) stmt()       {}
 func (*DropRetentionPolicyStatement) stmt()   {}
 func (*DropSeriesStatement) stmt()            {}
 func (*DropServerStatement) stmt()            {}
 func (*DropSubscriptionStatement) stmt()      {}
+func (*DropTokenStatement) stmt()             {}
 func (*DropUserStatement) stmt()              {}
+func (*ExplainStatement) stmt()               {}
 func (*GrantStatement) stmt()                 {}
 func (*GrantAdminStatement) stmt()            {}
+func (*PauseHintedHandoffStatement) stmt()    {}
+func (*PurgeHintedHandoffStatement) stmt()    {}
+func (*ResumeHintedHandoffStatement) stmt()   {}
 func (*ShowContinuousQueriesStatement) stmt() {}
 func (*ShowGrantsForUserStatement) stmt()     {}
+func (*ShowHintedHandoffStatement) stmt()     {}
 func (*ShowServersStatement) stmt()           {}
 func (*ShowDatabasesStatement) stmt()         {}
+func (*ShowDownsamplesStatement) stmt()       {}
 func (*ShowFieldKeysStatement) stmt()         {}
 func (*ShowMeasurementsStatement) stmt()      {}
 func (*ShowRetentionPoliciesStatement) stmt() {}
@@ -233,9 +256,11 @@ func (*ShowSubscriptionsStatement) stmt()     {}
 func (*ShowDiagnosticsStatement) stmt()       {}
 func (*ShowTagKeysStatement) stmt()           {}
 func (*ShowTagValuesStatement) stmt()         {}
+func (*ShowTokensStatement) stmt()            {}
 func (*ShowUsersStatement) stmt()             {}
 func (*RevokeStatement) stmt()                {}
 func (*RevokeAdminStatement) stmt()           {}
+func (*RunContinuousQueryStatement) stmt()    {}
 func (*SelectStatement) stmt()                {}
 func (*SetPasswordUserStatement) stmt()       {}
 
@@ -247,6 +272,7 @@ type Expr interface {
 
 func (*BinaryExpr) expr()      {}
 func (*BooleanLiteral) expr()  {}
+func (*BoundParameter) expr()  {}
 func (*Call) expr()            {}
 func (*Distinct) expr()        {}
 func (*DurationLiteral) expr() {}
@@ -548,6 +574,69 @@ func (s *DropUserStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+// CreateTokenStatement represents a command for creating an API token.
+type CreateTokenStatement struct {
+	// Name of the token to be created.
+	Name string
+
+	// User the token authenticates as.
+	User string
+
+	// Privileges the token is scoped to. If empty, the token carries all
+	// of the user's privileges.
+	Privileges []TokenPrivilege
+}
+
+// TokenPrivilege represents a privilege on a database that a token is scoped to.
+type TokenPrivilege struct {
+	Database  string
+	Privilege Privilege
+}
+
+// String returns a string representation of the create token statement.
+func (s *CreateTokenStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("CREATE TOKEN ")
+	_, _ = buf.WriteString(QuoteIdent(s.Name))
+	_, _ = buf.WriteString(" FOR ")
+	_, _ = buf.WriteString(QuoteIdent(s.User))
+	for i, p := range s.Privileges {
+		if i == 0 {
+			_, _ = buf.WriteString(" WITH ")
+		} else {
+			_, _ = buf.WriteString(", ")
+		}
+		_, _ = buf.WriteString(p.Privilege.String())
+		_, _ = buf.WriteString(" ON ")
+		_, _ = buf.WriteString(QuoteIdent(p.Database))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a CreateTokenStatement.
+func (s *CreateTokenStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// DropTokenStatement represents a command for dropping an API token.
+type DropTokenStatement struct {
+	// Name of the token to drop.
+	Name string
+}
+
+// String returns a string representation of the drop token statement.
+func (s *DropTokenStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("DROP TOKEN ")
+	_, _ = buf.WriteString(QuoteIdent(s.Name))
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a DropTokenStatement.
+func (s *DropTokenStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // Privilege is a type of action a user can be granted the right to use.
 type Privilege int
 
@@ -588,6 +677,12 @@ type GrantStatement struct {
 	// Database to grant the privilege to.
 	On string
 
+	// Measurement to grant the privilege to. Blank for the whole database.
+	Measurement string
+
+	// Tag expression restricting the series of the measurement covered by the grant.
+	Condition Expr
+
 	// Who to grant the privilege to.
 	User string
 }
@@ -599,6 +694,14 @@ func (s *GrantStatement) String() string {
 	_, _ = buf.WriteString(s.Privilege.String())
 	_, _ = buf.WriteString(" ON ")
 	_, _ = buf.WriteString(QuoteIdent(s.On))
+	if s.Measurement != "" {
+		_, _ = buf.WriteString(".")
+		_, _ = buf.WriteString(QuoteIdent(s.Measurement))
+	}
+	if s.Condition != nil {
+		_, _ = buf.WriteString(" WHERE ")
+		_, _ = buf.WriteString(s.Condition.String())
+	}
 	_, _ = buf.WriteString(" TO ")
 	_, _ = buf.WriteString(QuoteIdent(s.User))
 	return buf.String()
@@ -660,6 +763,9 @@ type RevokeStatement struct {
 	// Database to revoke the privilege from.
 	On string
 
+	// Measurement to revoke the privilege from. Blank for the whole database.
+	Measurement string
+
 	// Who to revoke privilege from.
 	User string
 }
@@ -671,6 +777,10 @@ func (s *RevokeStatement) String() string {
 	_, _ = buf.WriteString(s.Privilege.String())
 	_, _ = buf.WriteString(" ON ")
 	_, _ = buf.WriteString(QuoteIdent(s.On))
+	if s.Measurement != "" {
+		_, _ = buf.WriteString(".")
+		_, _ = buf.WriteString(QuoteIdent(s.Measurement))
+	}
 	_, _ = buf.WriteString(" FROM ")
 	_, _ = buf.WriteString(QuoteIdent(s.User))
 	return buf.String()
@@ -800,6 +910,8 @@ const (
 	NumberFill
 	// PreviousFill means that empty aggregate windows will be filled with whatever the previous aggregate window had
 	PreviousFill
+	// LinearFill means that empty aggregate windows will be filled with a value interpolated between the windows around them
+	LinearFill
 )
 
 // SelectStatement represents a command for extracting data from the database.
@@ -816,6 +928,10 @@ type SelectStatement struct {
 	// Data sources that fields are extracted from.
 	Sources Sources
 
+	// The tags two joined sources are matched on. Sources contains exactly
+	// two measurements if set.
+	JoinOn []string
+
 	// An expression evaluated on data point.
 	Condition Expr
 
@@ -834,6 +950,10 @@ type SelectStatement struct {
 	// Returns series starting at an offset from the first one.
 	SOffset int
 
+	// The time zone of the GROUP BY time() windows and of the times in the
+	// results. UTC if nil.
+	Location *time.Location
+
 	// memoize the group by interval
 	groupByInterval time.Duration
 
@@ -851,6 +971,16 @@ type SelectStatement struct {
 
 	// Removes duplicate rows from raw queries.
 	Dedupe bool
+
+	// Restricts the measurements and series that may be read, if set.
+	SeriesAuthorizer SeriesAuthorizer
+}
+
+// SeriesAuthorizer determines which measurements and series a statement may read.
+type SeriesAuthorizer interface {
+	// AuthorizeSeriesRead returns true if the measurement may be read. The
+	// returned condition, if not nil, restricts which series may be read.
+	AuthorizeSeriesRead(database, measurement string) (bool, Expr)
 }
 
 // HasDerivative returns true if one of the function calls in the statement is a
@@ -917,15 +1047,19 @@ func (s *SelectStatement) Clone() *SelectStatement {
 		Fields:     make(Fields, 0, len(s.Fields)),
 		Dimensions: make(Dimensions, 0, len(s.Dimensions)),
 		Sources:    cloneSources(s.Sources),
+		JoinOn:     s.JoinOn,
 		SortFields: make(SortFields, 0, len(s.SortFields)),
 		Condition:  CloneExpr(s.Condition),
 		Limit:      s.Limit,
 		Offset:     s.Offset,
 		SLimit:     s.SLimit,
 		SOffset:    s.SOffset,
+		Location:   s.Location,
 		Fill:       s.Fill,
 		FillValue:  s.FillValue,
 		IsRawQuery: s.IsRawQuery,
+
+		SeriesAuthorizer: s.SeriesAuthorizer,
 	}
 	if s.Target != nil {
 		clone.Target = &Target{
@@ -1173,7 +1307,13 @@ func (s *SelectStatement) String() string {
 		_, _ = buf.WriteString(" ")
 		_, _ = buf.WriteString(s.Target.String())
 	}
-	if len(s.Sources) > 0 {
+	if len(s.JoinOn) > 0 {
+		on := make([]string, len(s.JoinOn))
+		for i, tag := range s.JoinOn {
+			on[i] = QuoteIdent(tag)
+		}
+		_, _ = fmt.Fprintf(&buf, " FROM %s JOIN %s ON %s", s.Sources[0], s.Sources[1], strings.Join(on, ", "))
+	} else if len(s.Sources) > 0 {
 		_, _ = buf.WriteString(" FROM ")
 		_, _ = buf.WriteString(s.Sources.String())
 	}
@@ -1192,6 +1332,8 @@ func (s *SelectStatement) String() string {
 		_, _ = buf.WriteString(fmt.Sprintf(" fill(%v)", s.FillValue))
 	case PreviousFill:
 		_, _ = buf.WriteString(" fill(previous)")
+	case LinearFill:
+		_, _ = buf.WriteString(" fill(linear)")
 	}
 	if len(s.SortFields) > 0 {
 		_, _ = buf.WriteString(" ORDER BY ")
@@ -1210,6 +1352,9 @@ func (s *SelectStatement) String() string {
 	if s.SOffset > 0 {
 		_, _ = fmt.Fprintf(&buf, " SOFFSET %d", s.SOffset)
 	}
+	if s.Location != nil {
+		_, _ = fmt.Fprintf(&buf, " tz(%s)", QuoteString(s.Location.String()))
+	}
 	return buf.String()
 }
 
@@ -1279,6 +1424,10 @@ func (s *SelectStatement) validate(tr targetRequirement) error {
 		return err
 	}
 
+	if err := s.validateJoin(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -1304,14 +1453,16 @@ func (s *SelectStatement) validateDimensions() error {
 	for _, dim := range s.Dimensions {
 		switch expr := dim.Expr.(type) {
 		case *Call:
-			// Ensure the call is time() and it only has one duration argument.
-			// If we already have a duration
+			// Ensure the call is time() and it has a duration argument and
+			// an optional duration offset. If we already have a duration
 			if expr.Name != "time" {
 				return errors.New("only time() calls allowed in dimensions")
-			} else if len(expr.Args) != 1 {
-				return errors.New("time dimension expected one argument")
+			} else if len(expr.Args) < 1 || len(expr.Args) > 2 {
+				return errors.New("time dimension expected 1 or 2 arguments")
 			} else if lit, ok := expr.Args[0].(*DurationLiteral); !ok {
 				return errors.New("time dimension must have one duration argument")
+			} else if _, ok := expr.Args[len(expr.Args)-1].(*DurationLiteral); !ok {
+				return errors.New("time dimension offset must be a duration")
 			} else if dur != 0 {
 				return errors.New("multiple time dimensions not allowed")
 			} else {
@@ -1406,9 +1557,37 @@ func (s *SelectStatement) validPercentileAggr(expr *Call) error {
 	if exp, got := 2, len(expr.Args); got != exp {
 		return fmt.Errorf("invalid number of arguments for %s, expected %d, got %d", expr.Name, exp, got)
 	}
+	if _, ok := expr.Args[0].(*VarRef); !ok {
+		return fmt.Errorf("expected field argument in %s()", expr.Name)
+	}
 	_, ok := expr.Args[1].(*NumberLiteral)
 	if !ok {
-		return fmt.Errorf("expected float argument in percentile()")
+		return fmt.Errorf("expected float argument in %s()", expr.Name)
+	}
+	return nil
+}
+
+// validHistogramAggr determines if HISTOGRAM has valid arguments.
+func (s *SelectStatement) validHistogramAggr(expr *Call) error {
+	if err := s.validSelectWithAggregate(); err != nil {
+		return err
+	}
+	if len(s.Fields) > 1 {
+		return fmt.Errorf("aggregate function %s() can not be combined with other functions or fields", expr.Name)
+	}
+	if exp, got := 3, len(expr.Args); got < exp {
+		return fmt.Errorf("invalid number of arguments for %s, expected at least %d, got %d", expr.Name, exp, got)
+	}
+	if _, ok := expr.Args[0].(*VarRef); !ok {
+		return fmt.Errorf("expected field argument in %s()", expr.Name)
+	}
+	for i, arg := range expr.Args[1:] {
+		lit, ok := arg.(*NumberLiteral)
+		if !ok {
+			return fmt.Errorf("expected number as bucket boundary in %s(), found %s", expr.Name, arg)
+		} else if i > 0 && lit.Val <= expr.Args[i].(*NumberLiteral).Val {
+			return fmt.Errorf("bucket boundaries in %s() must be in ascending order", expr.Name)
+		}
 	}
 	return nil
 }
@@ -1440,7 +1619,7 @@ func (s *SelectStatement) validateAggregates(tr targetRequirement) error {
 						if err := s.validTopBottomAggr(c); err != nil {
 							return err
 						}
-					case "percentile":
+					case "percentile", "percentile_approx":
 						if err := s.validPercentileAggr(c); err != nil {
 							return err
 						}
@@ -1469,10 +1648,14 @@ func (s *SelectStatement) validateAggregates(tr targetRequirement) error {
 				if err := s.validTopBottomAggr(expr); err != nil {
 					return err
 				}
-			case "percentile":
+			case "percentile", "percentile_approx":
 				if err := s.validPercentileAggr(expr); err != nil {
 					return err
 				}
+			case "histogram":
+				if err := s.validHistogramAggr(expr); err != nil {
+					return err
+				}
 			default:
 				if err := s.validSelectWithAggregate(); err != nil {
 					return err
@@ -1652,6 +1835,66 @@ func (s *SelectStatement) validateDerivative() error {
 	return nil
 }
 
+func (s *SelectStatement) validateJoin() error {
+	if len(s.JoinOn) == 0 {
+		return nil
+	}
+
+	// Joined points are matched on the windows of a GROUP BY time().
+	if interval, err := s.GroupByInterval(); err != nil {
+		return err
+	} else if interval == 0 {
+		return fmt.Errorf("JOIN requires GROUP BY time()")
+	}
+	if s.HasWildcard() {
+		return fmt.Errorf("wildcards can not be used with JOIN")
+	}
+
+	// Every field must be qualified by the measurement it is read from.
+	var err error
+	WalkFunc(s.Fields, func(n Node) {
+		if ref, ok := n.(*VarRef); ok && err == nil {
+			if m, _ := joinFieldRef(s.Sources, ref.Val); m == nil {
+				err = fmt.Errorf("field %s must be qualified by a joined measurement", ref.Val)
+			}
+		}
+	})
+	return err
+}
+
+// rewriteJoinFields wraps the fields of a join that aren't aggregated in a
+// call to mean() so they are averaged over each GROUP BY time() window.
+// Rewritten fields keep their original name.
+func (s *SelectStatement) rewriteJoinFields() {
+	for _, f := range s.Fields {
+		if ref, ok := f.Expr.(*VarRef); ok && ref.Val == "time" {
+			continue
+		}
+
+		expr := rewriteJoinExpr(f.Expr)
+		if expr.String() == f.Expr.String() {
+			continue
+		}
+		if f.Alias == "" {
+			f.Alias = f.Name()
+		}
+		f.Expr = expr
+	}
+}
+
+func rewriteJoinExpr(expr Expr) Expr {
+	switch expr := expr.(type) {
+	case *VarRef:
+		return &Call{Name: "mean", Args: []Expr{expr}}
+	case *BinaryExpr:
+		return &BinaryExpr{Op: expr.Op, LHS: rewriteJoinExpr(expr.LHS), RHS: rewriteJoinExpr(expr.RHS)}
+	case *ParenExpr:
+		return &ParenExpr{Expr: rewriteJoinExpr(expr.Expr)}
+	default:
+		return expr
+	}
+}
+
 // GroupByInterval extracts the time interval, if specified.
 func (s *SelectStatement) GroupByInterval() (time.Duration, error) {
 	// return if we've already pulled it out
@@ -1666,9 +1909,9 @@ func (s *SelectStatement) GroupByInterval() (time.Duration, error) {
 
 	for _, d := range s.Dimensions {
 		if call, ok := d.Expr.(*Call); ok && call.Name == "time" {
-			// Make sure there is exactly one argument.
-			if len(call.Args) != 1 {
-				return 0, errors.New("time dimension expected one argument")
+			// Make sure there is a duration and an optional offset.
+			if len(call.Args) < 1 || len(call.Args) > 2 {
+				return 0, errors.New("time dimension expected 1 or 2 arguments")
 			}
 
 			// Ensure the argument is a duration.
@@ -1683,6 +1926,30 @@ func (s *SelectStatement) GroupByInterval() (time.Duration, error) {
 	return 0, nil
 }
 
+// GroupByOffset extracts the offset of the time dimension, if specified.
+// The offset is returned modulo the interval so it is always within one.
+func (s *SelectStatement) GroupByOffset() (time.Duration, error) {
+	interval, err := s.GroupByInterval()
+	if err != nil || interval <= 0 {
+		return 0, err
+	}
+
+	for _, d := range s.Dimensions {
+		if call, ok := d.Expr.(*Call); ok && call.Name == "time" {
+			if len(call.Args) != 2 {
+				return 0, nil
+			}
+
+			lit, ok := call.Args[1].(*DurationLiteral)
+			if !ok {
+				return 0, errors.New("time dimension offset must be a duration")
+			}
+			return lit.Val % interval, nil
+		}
+	}
+	return 0, nil
+}
+
 // SetTimeRange sets the start and end time of the select statement to [start, end). i.e. start inclusive, end exclusive.
 // This is used commonly for continuous queries so the start and end are in buckets.
 func (s *SelectStatement) SetTimeRange(start, end time.Time) error {
@@ -2132,6 +2399,69 @@ func (s *DropServerStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Name: "", Privilege: AllPrivileges}}
 }
 
+// ShowHintedHandoffStatement represents a command for listing the hinted
+// handoff queue of every node.
+type ShowHintedHandoffStatement struct{}
+
+// String returns a string representation of the show hinted handoff statement.
+func (s *ShowHintedHandoffStatement) String() string { return "SHOW HINTED HANDOFF" }
+
+// RequiredPrivileges returns the privilege required to execute a ShowHintedHandoffStatement.
+func (s *ShowHintedHandoffStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// PurgeHintedHandoffStatement represents a command for deleting the hinted
+// handoff data queued for a node.
+type PurgeHintedHandoffStatement struct {
+	// ID of the node whose queue is purged.
+	NodeID uint64
+}
+
+// String returns a string representation of the purge hinted handoff statement.
+func (s *PurgeHintedHandoffStatement) String() string {
+	return "PURGE HINTED HANDOFF FOR NODE " + strconv.FormatUint(s.NodeID, 10)
+}
+
+// RequiredPrivileges returns the privilege required to execute a PurgeHintedHandoffStatement.
+func (s *PurgeHintedHandoffStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// PauseHintedHandoffStatement represents a command for stopping hinted
+// handoff data being sent to a node.
+type PauseHintedHandoffStatement struct {
+	// ID of the node to stop sending data to.
+	NodeID uint64
+}
+
+// String returns a string representation of the pause hinted handoff statement.
+func (s *PauseHintedHandoffStatement) String() string {
+	return "PAUSE HINTED HANDOFF FOR NODE " + strconv.FormatUint(s.NodeID, 10)
+}
+
+// RequiredPrivileges returns the privilege required to execute a PauseHintedHandoffStatement.
+func (s *PauseHintedHandoffStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// ResumeHintedHandoffStatement represents a command for sending hinted
+// handoff data to a paused node again.
+type ResumeHintedHandoffStatement struct {
+	// ID of the node to send data to.
+	NodeID uint64
+}
+
+// String returns a string representation of the resume hinted handoff statement.
+func (s *ResumeHintedHandoffStatement) String() string {
+	return "RESUME HINTED HANDOFF FOR NODE " + strconv.FormatUint(s.NodeID, 10)
+}
+
+// RequiredPrivileges returns the privilege required to execute a ResumeHintedHandoffStatement.
+func (s *ResumeHintedHandoffStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // ShowContinuousQueriesStatement represents a command for listing continuous queries.
 type ShowContinuousQueriesStatement struct{}
 
@@ -2280,6 +2610,52 @@ func (s *DropContinuousQueryStatement) RequiredPrivileges() ExecutionPrivileges
 	return ExecutionPrivileges{{Admin: false, Name: "", Privilege: WritePrivilege}}
 }
 
+// ExplainStatement represents a command for describing how a select
+// statement is executed.
+type ExplainStatement struct {
+	// Statement to describe.
+	Statement *SelectStatement
+
+	// Executes the statement and reports the time spent and data read.
+	Analyze bool
+}
+
+// String returns a string representation of the explain statement.
+func (s *ExplainStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("EXPLAIN ")
+	if s.Analyze {
+		_, _ = buf.WriteString("ANALYZE ")
+	}
+	_, _ = buf.WriteString(s.Statement.String())
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute an ExplainStatement.
+func (s *ExplainStatement) RequiredPrivileges() ExecutionPrivileges {
+	return s.Statement.RequiredPrivileges()
+}
+
+// RunContinuousQueryStatement represents a command for recomputing a continuous
+// query over a historic time range.
+type RunContinuousQueryStatement struct {
+	Name     string
+	Database string
+
+	// Time range to recompute.
+	Condition Expr
+}
+
+// String returns a string representation of the statement.
+func (s *RunContinuousQueryStatement) String() string {
+	return fmt.Sprintf("RUN CONTINUOUS QUERY %s ON %s FOR %s", QuoteIdent(s.Name), QuoteIdent(s.Database), s.Condition.String())
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a RunContinuousQueryStatement
+func (s *RunContinuousQueryStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: false, Name: s.Database, Privilege: WritePrivilege}}
+}
+
 // ShowMeasurementsStatement represents a command for listing measurements.
 type ShowMeasurementsStatement struct {
 	// Measurement name or regex.
@@ -2508,6 +2884,83 @@ func (s *ShowSubscriptionsStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+// CreateDownsampleStatement represents a command to aggregate every numeric
+// field of a retention policy into another retention policy at a fixed interval.
+type CreateDownsampleStatement struct {
+	// Source database and retention policy.
+	Database        string
+	RetentionPolicy string
+
+	// Destination database and retention policy.
+	TargetDatabase        string
+	TargetRetentionPolicy string
+
+	// Width of the GROUP BY time() buckets.
+	Interval time.Duration
+
+	// Names of the aggregate functions applied to each field.
+	Aggregates []string
+}
+
+// String returns a string representation of the CreateDownsampleStatement.
+func (s *CreateDownsampleStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("CREATE DOWNSAMPLE ON ")
+	_, _ = buf.WriteString(QuoteIdent(s.Database))
+	_, _ = buf.WriteString(".")
+	_, _ = buf.WriteString(QuoteIdent(s.RetentionPolicy))
+	_, _ = buf.WriteString(" TO ")
+	_, _ = buf.WriteString(QuoteIdent(s.TargetDatabase))
+	_, _ = buf.WriteString(".")
+	_, _ = buf.WriteString(QuoteIdent(s.TargetRetentionPolicy))
+	_, _ = buf.WriteString(" EVERY ")
+	_, _ = buf.WriteString(FormatDuration(s.Interval))
+	_, _ = buf.WriteString(" AGGREGATE ")
+	_, _ = buf.WriteString(strings.Join(s.Aggregates, ", "))
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a CreateDownsampleStatement.
+func (s *CreateDownsampleStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// DropDownsampleStatement represents a command to remove a downsample policy.
+type DropDownsampleStatement struct {
+	// Source database and retention policy.
+	Database        string
+	RetentionPolicy string
+
+	// Destination database and retention policy.
+	TargetDatabase        string
+	TargetRetentionPolicy string
+}
+
+// String returns a string representation of the DropDownsampleStatement.
+func (s *DropDownsampleStatement) String() string {
+	return fmt.Sprintf("DROP DOWNSAMPLE ON %s.%s TO %s.%s",
+		QuoteIdent(s.Database), QuoteIdent(s.RetentionPolicy),
+		QuoteIdent(s.TargetDatabase), QuoteIdent(s.TargetRetentionPolicy))
+}
+
+// RequiredPrivileges returns the privilege required to execute a DropDownsampleStatement.
+func (s *DropDownsampleStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// ShowDownsamplesStatement represents a command to show a list of downsample policies.
+type ShowDownsamplesStatement struct{}
+
+// String returns a string representation of the ShowDownsamplesStatement.
+func (s *ShowDownsamplesStatement) String() string {
+	return "SHOW DOWNSAMPLES"
+}
+
+// RequiredPrivileges returns the privilege required to execute a ShowDownsamplesStatement.
+func (s *ShowDownsamplesStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // ShowTagKeysStatement represents a command for listing tag keys.
 type ShowTagKeysStatement struct {
 	// Data sources that fields are extracted from.
@@ -2649,6 +3102,19 @@ func (s *ShowUsersStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+// ShowTokensStatement represents a command for listing API tokens.
+type ShowTokensStatement struct{}
+
+// String returns a string representation of the ShowTokensStatement.
+func (s *ShowTokensStatement) String() string {
+	return "SHOW TOKENS"
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a ShowTokensStatement
+func (s *ShowTokensStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // ShowFieldKeysStatement represents a command for listing field keys.
 type ShowFieldKeysStatement struct {
 	// Data sources that fields are extracted from.
@@ -3144,6 +3610,17 @@ type ParenExpr struct {
 // String returns a string representation of the parenthesized expression.
 func (e *ParenExpr) String() string { return fmt.Sprintf("(%s)", e.Expr.String()) }
 
+// BoundParameter represents a placeholder for a literal whose value is bound
+// when the statement is executed.
+type BoundParameter struct {
+	Name string
+}
+
+// String returns a string representation of the bound parameter.
+func (bp *BoundParameter) String() string {
+	return "$" + QuoteIdent(bp.Name)
+}
+
 // RegexLiteral represents a regular expression.
 type RegexLiteral struct {
 	Val *regexp.Regexp
@@ -3187,6 +3664,8 @@ func CloneExpr(expr Expr) Expr {
 		return &BinaryExpr{Op: expr.Op, LHS: CloneExpr(expr.LHS), RHS: CloneExpr(expr.RHS)}
 	case *BooleanLiteral:
 		return &BooleanLiteral{Val: expr.Val}
+	case *BoundParameter:
+		return &BoundParameter{Name: expr.Name}
 	case *Call:
 		args := make([]Expr, len(expr.Args))
 		for i, arg := range expr.Args {
@@ -3386,6 +3865,9 @@ func Walk(v Visitor, node Node) {
 		Walk(v, n.Sources)
 		Walk(v, n.Condition)
 
+	case *ExplainStatement:
+		Walk(v, n.Statement)
+
 	case *Field:
 		Walk(v, n.Expr)
 
@@ -3400,6 +3882,9 @@ func Walk(v Visitor, node Node) {
 	case *Query:
 		Walk(v, n.Statements)
 
+	case *RunContinuousQueryStatement:
+		Walk(v, n.Condition)
+
 	case *SelectStatement:
 		Walk(v, n.Fields)
 		Walk(v, n.Target)
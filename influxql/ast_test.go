@@ -141,6 +141,30 @@ func TestSelectStatement_GroupByInterval(t *testing.T) {
 	}
 }
 
+// Ensure the SELECT statement can extract the GROUP BY interval offset.
+func TestSelectStatement_GroupByOffset(t *testing.T) {
+	for _, tt := range []struct {
+		q      string
+		offset time.Duration
+	}{
+		{q: `SELECT sum(value) FROM foo WHERE time < now() GROUP BY time(10m)`, offset: 0},
+		{q: `SELECT sum(value) FROM foo WHERE time < now() GROUP BY time(1h, 15m)`, offset: 15 * time.Minute},
+		{q: `SELECT sum(value) FROM foo WHERE time < now() GROUP BY time(1h, 75m)`, offset: 15 * time.Minute},
+		{q: `SELECT value FROM foo`, offset: 0},
+	} {
+		stmt, err := influxql.NewParser(strings.NewReader(tt.q)).ParseStatement()
+		if err != nil {
+			t.Fatalf("invalid statement: %q: %s", tt.q, err)
+		}
+
+		if offset, err := stmt.(*influxql.SelectStatement).GroupByOffset(); err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.q, err)
+		} else if offset != tt.offset {
+			t.Errorf("%s: group by offset not equal:\nexp=%s\ngot=%s", tt.q, tt.offset, offset)
+		}
+	}
+}
+
 // Ensure the SELECT statement can have its start and end time set
 func TestSelectStatement_SetTimeRange(t *testing.T) {
 	q := "SELECT sum(value) from foo where time < now() GROUP BY time(10m)"
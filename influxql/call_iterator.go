@@ -51,6 +51,10 @@ func NewCallIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
 		return newLastIterator(input, opt)
 	case "mean":
 		return newMeanIterator(input, opt)
+	case "percentile_approx", "tdigest":
+		return newTDigestIterator(input, opt)
+	case "histogram":
+		return newHistogramIterator(input, opt, histogramBounds(opt.Expr.(*Call)))
 	default:
 		return nil, fmt.Errorf("unsupported function call: %s", name)
 	}
@@ -1009,20 +1013,187 @@ func NewIntegerPercentileReduceSliceFunc(percentile float64) IntegerReduceSliceF
 	}
 }
 
+// newTDigestIterator returns an iterator for operating on a percentile_approx()
+// or tdigest() call. It emits the encoded t-digest of each window, so the
+// results of shards and nodes can be merged by another call to it.
+func newTDigestIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
+	switch input := input.(type) {
+	case FloatIterator:
+		createFn := func() (FloatPointAggregator, StringPointEmitter) {
+			fn := NewTDigestReducer()
+			return fn, fn
+		}
+		return &floatReduceStringIterator{input: newBufFloatIterator(input), opt: opt, create: createFn}, nil
+	case IntegerIterator:
+		createFn := func() (IntegerPointAggregator, StringPointEmitter) {
+			fn := NewTDigestReducer()
+			return fn, fn
+		}
+		return &integerReduceStringIterator{input: newBufIntegerIterator(input), opt: opt, create: createFn}, nil
+	case StringIterator:
+		createFn := func() (StringPointAggregator, StringPointEmitter) {
+			fn := NewTDigestReducer()
+			return fn, fn
+		}
+		return &stringReduceStringIterator{input: newBufStringIterator(input), opt: opt, create: createFn}, nil
+	default:
+		return nil, fmt.Errorf("unsupported %s iterator type: %T", opt.Expr.(*Call).Name, input)
+	}
+}
+
+// percentileApproxIterator returns the approximate percentile of each
+// t-digest emitted by a percentile_approx() call iterator.
+type percentileApproxIterator struct {
+	input      StringIterator
+	percentile float64
+}
+
+// newPercentileApproxIterator returns an iterator for the final result of a
+// percentile_approx() call.
+func newPercentileApproxIterator(input Iterator, percentile float64) (Iterator, error) {
+	switch input := input.(type) {
+	case StringIterator:
+		return &percentileApproxIterator{input: input, percentile: percentile}, nil
+	case *nilFloatIterator:
+		return input, nil
+	default:
+		return nil, fmt.Errorf("unsupported percentile_approx iterator type: %T", input)
+	}
+}
+
+// Close closes the iterator and all child iterators.
+func (itr *percentileApproxIterator) Close() error { return itr.input.Close() }
+
+// Next returns the percentile of the next digest.
+func (itr *percentileApproxIterator) Next() *FloatPoint {
+	for {
+		p := itr.input.Next()
+		if p == nil {
+			return nil
+		} else if p.Nil {
+			continue
+		}
+
+		d, err := decodeTDigest(p.Value)
+		if err != nil || d.Count() == 0 {
+			continue
+		}
+		return &FloatPoint{
+			Name:       p.Name,
+			Tags:       p.Tags,
+			Time:       p.Time,
+			Value:      d.Quantile(itr.percentile / 100),
+			Aux:        p.Aux,
+			Aggregated: p.Aggregated,
+		}
+	}
+}
+
+// histogramBounds returns the bucket boundaries of a histogram() call.
+func histogramBounds(call *Call) []float64 {
+	bounds := make([]float64, 0, len(call.Args)-1)
+	for _, arg := range call.Args[1:] {
+		bounds = append(bounds, arg.(*NumberLiteral).Val)
+	}
+	return bounds
+}
+
+// newHistogramIterator returns an iterator for operating on a histogram()
+// call. It emits the encoded bucket counts of each window, so the results
+// of shards and nodes can be merged by another call to it.
+func newHistogramIterator(input Iterator, opt IteratorOptions, bounds []float64) (Iterator, error) {
+	switch input := input.(type) {
+	case FloatIterator:
+		createFn := func() (FloatPointAggregator, StringPointEmitter) {
+			fn := NewHistogramReducer(bounds)
+			return fn, fn
+		}
+		return &floatReduceStringIterator{input: newBufFloatIterator(input), opt: opt, create: createFn}, nil
+	case IntegerIterator:
+		createFn := func() (IntegerPointAggregator, StringPointEmitter) {
+			fn := NewHistogramReducer(bounds)
+			return fn, fn
+		}
+		return &integerReduceStringIterator{input: newBufIntegerIterator(input), opt: opt, create: createFn}, nil
+	case StringIterator:
+		createFn := func() (StringPointAggregator, StringPointEmitter) {
+			fn := NewHistogramReducer(bounds)
+			return fn, fn
+		}
+		return &stringReduceStringIterator{input: newBufStringIterator(input), opt: opt, create: createFn}, nil
+	default:
+		return nil, fmt.Errorf("unsupported histogram iterator type: %T", input)
+	}
+}
+
+// histogramCountIterator returns the count of each bucket of the histograms
+// emitted by a histogram() call iterator. Every bucket of a window is
+// returned, in order, as a point with the time of the window.
+type histogramCountIterator struct {
+	input  StringIterator
+	points []IntegerPoint
+}
+
+// newHistogramCountIterator returns an iterator for the final result of a
+// histogram() call.
+func newHistogramCountIterator(input Iterator) (Iterator, error) {
+	switch input := input.(type) {
+	case StringIterator:
+		return &histogramCountIterator{input: input}, nil
+	case *nilFloatIterator:
+		return input, nil
+	default:
+		return nil, fmt.Errorf("unsupported histogram iterator type: %T", input)
+	}
+}
+
+// Close closes the iterator and all child iterators.
+func (itr *histogramCountIterator) Close() error { return itr.input.Close() }
+
+// Next returns the count of the next bucket.
+func (itr *histogramCountIterator) Next() *IntegerPoint {
+	for len(itr.points) == 0 {
+		p := itr.input.Next()
+		if p == nil {
+			return nil
+		} else if p.Nil {
+			continue
+		}
+
+		h, err := decodeHistogram(p.Value)
+		if err != nil {
+			continue
+		}
+		for _, n := range h.counts {
+			itr.points = append(itr.points, IntegerPoint{
+				Name:  p.Name,
+				Tags:  p.Tags,
+				Time:  p.Time,
+				Value: int64(n),
+				Aux:   p.Aux,
+			})
+		}
+	}
+
+	p := &itr.points[0]
+	itr.points = itr.points[1:]
+	return p
+}
+
 // newDerivativeIterator returns an iterator for operating on a derivative() call.
 func newDerivativeIterator(input Iterator, opt IteratorOptions, interval Interval, isNonNegative bool) (Iterator, error) {
 	switch input := input.(type) {
 	case FloatIterator:
-		floatDerivativeReduceSlice := NewFloatDerivativeReduceSliceFunc(interval, isNonNegative)
 		createFn := func() (FloatPointAggregator, FloatPointEmitter) {
-			fn := NewFloatSliceFuncReducer(floatDerivativeReduceSlice)
+			// Each series needs its own function since it keeps the previous point.
+			fn := NewFloatSliceFuncReducer(NewFloatDerivativeReduceSliceFunc(interval, isNonNegative, opt.Ascending))
 			return fn, fn
 		}
 		return &floatReduceFloatIterator{input: newBufFloatIterator(input), opt: opt, create: createFn}, nil
 	case IntegerIterator:
-		integerDerivativeReduceSlice := NewIntegerDerivativeReduceSliceFunc(interval, isNonNegative)
 		createFn := func() (IntegerPointAggregator, FloatPointEmitter) {
-			fn := NewIntegerSliceFuncFloatReducer(integerDerivativeReduceSlice)
+			// Each series needs its own function since it keeps the previous point.
+			fn := NewIntegerSliceFuncFloatReducer(NewIntegerDerivativeReduceSliceFunc(interval, isNonNegative, opt.Ascending))
 			return fn, fn
 		}
 		return &integerReduceFloatIterator{input: newBufIntegerIterator(input), opt: opt, create: createFn}, nil
@@ -1032,7 +1203,8 @@ func newDerivativeIterator(input Iterator, opt IteratorOptions, interval Interva
 }
 
 // NewFloatDerivativeReduceSliceFunc returns the derivative value within a window.
-func NewFloatDerivativeReduceSliceFunc(interval Interval, isNonNegative bool) FloatReduceSliceFunc {
+// The points are in descending time order if ascending is false.
+func NewFloatDerivativeReduceSliceFunc(interval Interval, isNonNegative, ascending bool) FloatReduceSliceFunc {
 	prev := FloatPoint{Nil: true}
 
 	return func(a []FloatPoint) []FloatPoint {
@@ -1050,10 +1222,17 @@ func NewFloatDerivativeReduceSliceFunc(interval Interval, isNonNegative bool) Fl
 		for i := 1; i < len(a); i++ {
 			p := &a[i]
 
+			// The derivative of successive points is set at the later point
+			// so descending results are the reverse of ascending ones.
+			earlier, later := prev, *p
+			if !ascending {
+				earlier, later = *p, prev
+			}
+
 			// Calculate the derivative of successive points by dividing the
 			// difference of each value by the elapsed time normalized to the interval.
-			diff := p.Value - prev.Value
-			elapsed := p.Time - prev.Time
+			diff := later.Value - earlier.Value
+			elapsed := later.Time - earlier.Time
 
 			value := 0.0
 			if elapsed > 0 {
@@ -1067,14 +1246,15 @@ func NewFloatDerivativeReduceSliceFunc(interval Interval, isNonNegative bool) Fl
 				continue
 			}
 
-			output = append(output, FloatPoint{Time: p.Time, Value: value})
+			output = append(output, FloatPoint{Time: later.Time, Value: value})
 		}
 		return output
 	}
 }
 
 // NewIntegerDerivativeReduceSliceFunc returns the derivative value within a window.
-func NewIntegerDerivativeReduceSliceFunc(interval Interval, isNonNegative bool) IntegerReduceFloatSliceFunc {
+// The points are in descending time order if ascending is false.
+func NewIntegerDerivativeReduceSliceFunc(interval Interval, isNonNegative, ascending bool) IntegerReduceFloatSliceFunc {
 	prev := IntegerPoint{Nil: true}
 
 	return func(a []IntegerPoint) []FloatPoint {
@@ -1092,10 +1272,17 @@ func NewIntegerDerivativeReduceSliceFunc(interval Interval, isNonNegative bool)
 		for i := 1; i < len(a); i++ {
 			p := &a[i]
 
+			// The derivative of successive points is set at the later point
+			// so descending results are the reverse of ascending ones.
+			earlier, later := prev, *p
+			if !ascending {
+				earlier, later = *p, prev
+			}
+
 			// Calculate the derivative of successive points by dividing the
 			// difference of each value by the elapsed time normalized to the interval.
-			diff := float64(p.Value - prev.Value)
-			elapsed := p.Time - prev.Time
+			diff := float64(later.Value - earlier.Value)
+			elapsed := later.Time - earlier.Time
 
 			value := 0.0
 			if elapsed > 0 {
@@ -1109,7 +1296,7 @@ func NewIntegerDerivativeReduceSliceFunc(interval Interval, isNonNegative bool)
 				continue
 			}
 
-			output = append(output, FloatPoint{Time: p.Time, Value: value})
+			output = append(output, FloatPoint{Time: later.Time, Value: value})
 		}
 		return output
 	}
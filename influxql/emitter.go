@@ -22,6 +22,9 @@ type Emitter struct {
 	// Removes the "time" column from output.
 	// Used for meta queries where time does not apply.
 	OmitTime bool
+
+	// The time zone of the times in the output. UTC if nil.
+	Location *time.Location
 }
 
 // NewEmitter returns a new instance of Emitter that pulls from itrs.
@@ -142,7 +145,11 @@ func (e *Emitter) readAt(t int64, name string, tags Tags) []interface{} {
 
 	values := make([]interface{}, len(e.itrs)+offset)
 	if !e.OmitTime {
-		values[0] = time.Unix(0, t).UTC()
+		if e.Location != nil {
+			values[0] = time.Unix(0, t).In(e.Location)
+		} else {
+			values[0] = time.Unix(0, t).UTC()
+		}
 	}
 
 	for i, p := range e.buf {
@@ -67,3 +67,31 @@ func TestEmitter_Emit(t *testing.T) {
 		t.Fatalf("unexpected eof: %s", spew.Sdump(row))
 	}
 }
+
+// Ensure the emitter renders timestamps in its location.
+func TestEmitter_Emit_Location(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := influxql.NewEmitter([]influxql.Iterator{
+		&FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Time: 0, Value: 1},
+		}},
+	}, true)
+	e.Columns = []string{"time", "value"}
+	e.Location = loc
+
+	if row := e.Emit(); !deep.Equal(row, &models.Row{
+		Name:    "cpu",
+		Columns: []string{"time", "value"},
+		Values: [][]interface{}{
+			{time.Unix(0, 0).In(loc), float64(1)},
+		},
+	}) {
+		t.Fatalf("unexpected row: %s", spew.Sdump(row))
+	} else if s := row.Values[0][0].(time.Time).Format(time.RFC3339); s != "1970-01-01T08:00:00+08:00" {
+		t.Fatalf("unexpected time: %s", s)
+	}
+}
@@ -1,5 +1,7 @@
 package influxql
 
+import "math"
+
 type FloatMeanReducer struct {
 	sum   float64
 	count uint32
@@ -53,3 +55,78 @@ func (r *IntegerMeanReducer) Emit() []FloatPoint {
 		Aggregated: r.count,
 	}}
 }
+
+// TDigestReducer builds the t-digest of the values in a window. Values that
+// are already encoded digests, from another shard or node, are merged.
+type TDigestReducer struct {
+	digest *TDigest
+}
+
+func NewTDigestReducer() *TDigestReducer {
+	return &TDigestReducer{digest: NewTDigest()}
+}
+
+func (r *TDigestReducer) AggregateFloat(p *FloatPoint) {
+	r.digest.Add(p.Value, 1)
+}
+
+func (r *TDigestReducer) AggregateInteger(p *IntegerPoint) {
+	r.digest.Add(float64(p.Value), 1)
+}
+
+func (r *TDigestReducer) AggregateString(p *StringPoint) {
+	if d, err := decodeTDigest(p.Value); err == nil {
+		r.digest.Merge(d)
+	}
+}
+
+func (r *TDigestReducer) Emit() []StringPoint {
+	if r.digest.Count() == 0 {
+		return nil
+	}
+	return []StringPoint{{
+		Time:       ZeroTime,
+		Value:      encodeTDigest(r.digest),
+		Aggregated: uint32(r.digest.Count()),
+	}}
+}
+
+// HistogramReducer counts the values in a window into buckets. Values that
+// are already encoded histograms, from another shard or node, are merged.
+type HistogramReducer struct {
+	histogram *histogram
+	count     uint32
+}
+
+func NewHistogramReducer(bounds []float64) *HistogramReducer {
+	return &HistogramReducer{histogram: newHistogram(bounds)}
+}
+
+func (r *HistogramReducer) AggregateFloat(p *FloatPoint) {
+	if !math.IsNaN(p.Value) {
+		r.histogram.add(p.Value, 1)
+	}
+	r.count++
+}
+
+func (r *HistogramReducer) AggregateInteger(p *IntegerPoint) {
+	r.histogram.add(float64(p.Value), 1)
+	r.count++
+}
+
+func (r *HistogramReducer) AggregateString(p *StringPoint) {
+	if h, err := decodeHistogram(p.Value); err == nil && r.histogram.merge(h) == nil {
+		r.count += p.Aggregated
+	}
+}
+
+func (r *HistogramReducer) Emit() []StringPoint {
+	if r.count == 0 {
+		return nil
+	}
+	return []StringPoint{{
+		Time:       ZeroTime,
+		Value:      encodeHistogram(r.histogram),
+		Aggregated: r.count,
+	}}
+}
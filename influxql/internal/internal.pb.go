@@ -17,6 +17,7 @@ It has these top-level messages:
 	Interval
 	Series
 	SeriesList
+	IteratorStats
 */
 package internal
 
@@ -30,17 +31,18 @@ var _ = fmt.Errorf
 var _ = math.Inf
 
 type Point struct {
-	Name             *string  `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
-	Tags             *string  `protobuf:"bytes,2,req,name=Tags" json:"Tags,omitempty"`
-	Time             *int64   `protobuf:"varint,3,req,name=Time" json:"Time,omitempty"`
-	Nil              *bool    `protobuf:"varint,4,req,name=Nil" json:"Nil,omitempty"`
-	Aux              []*Aux   `protobuf:"bytes,5,rep,name=Aux" json:"Aux,omitempty"`
-	Aggregated       *uint32  `protobuf:"varint,6,opt,name=Aggregated" json:"Aggregated,omitempty"`
-	FloatValue       *float64 `protobuf:"fixed64,7,opt,name=FloatValue" json:"FloatValue,omitempty"`
-	IntegerValue     *int64   `protobuf:"varint,8,opt,name=IntegerValue" json:"IntegerValue,omitempty"`
-	StringValue      *string  `protobuf:"bytes,9,opt,name=StringValue" json:"StringValue,omitempty"`
-	BooleanValue     *bool    `protobuf:"varint,10,opt,name=BooleanValue" json:"BooleanValue,omitempty"`
-	XXX_unrecognized []byte   `json:"-"`
+	Name             *string        `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	Tags             *string        `protobuf:"bytes,2,req,name=Tags" json:"Tags,omitempty"`
+	Time             *int64         `protobuf:"varint,3,req,name=Time" json:"Time,omitempty"`
+	Nil              *bool          `protobuf:"varint,4,req,name=Nil" json:"Nil,omitempty"`
+	Aux              []*Aux         `protobuf:"bytes,5,rep,name=Aux" json:"Aux,omitempty"`
+	Aggregated       *uint32        `protobuf:"varint,6,opt,name=Aggregated" json:"Aggregated,omitempty"`
+	FloatValue       *float64       `protobuf:"fixed64,7,opt,name=FloatValue" json:"FloatValue,omitempty"`
+	IntegerValue     *int64         `protobuf:"varint,8,opt,name=IntegerValue" json:"IntegerValue,omitempty"`
+	StringValue      *string        `protobuf:"bytes,9,opt,name=StringValue" json:"StringValue,omitempty"`
+	BooleanValue     *bool          `protobuf:"varint,10,opt,name=BooleanValue" json:"BooleanValue,omitempty"`
+	Stats            *IteratorStats `protobuf:"bytes,11,opt,name=Stats" json:"Stats,omitempty"`
+	XXX_unrecognized []byte         `json:"-"`
 }
 
 func (m *Point) Reset()         { *m = Point{} }
@@ -117,6 +119,13 @@ func (m *Point) GetBooleanValue() bool {
 	return false
 }
 
+func (m *Point) GetStats() *IteratorStats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
 type Aux struct {
 	DataType         *int32   `protobuf:"varint,1,req,name=DataType" json:"DataType,omitempty"`
 	FloatValue       *float64 `protobuf:"fixed64,2,opt,name=FloatValue" json:"FloatValue,omitempty"`
@@ -182,6 +191,7 @@ type IteratorOptions struct {
 	SLimit           *int64         `protobuf:"varint,14,opt,name=SLimit" json:"SLimit,omitempty"`
 	SOffset          *int64         `protobuf:"varint,15,opt,name=SOffset" json:"SOffset,omitempty"`
 	Dedupe           *bool          `protobuf:"varint,16,opt,name=Dedupe" json:"Dedupe,omitempty"`
+	Location         *string        `protobuf:"bytes,17,opt,name=Location" json:"Location,omitempty"`
 	XXX_unrecognized []byte         `json:"-"`
 }
 
@@ -301,6 +311,13 @@ func (m *IteratorOptions) GetDedupe() bool {
 	return false
 }
 
+func (m *IteratorOptions) GetLocation() string {
+	if m != nil && m.Location != nil {
+		return *m.Location
+	}
+	return ""
+}
+
 type Measurements struct {
 	Items            []*Measurement `protobuf:"bytes,1,rep,name=Items" json:"Items,omitempty"`
 	XXX_unrecognized []byte         `json:"-"`
@@ -437,6 +454,38 @@ func (m *SeriesList) GetItems() []*Series {
 	return nil
 }
 
+type IteratorStats struct {
+	SeriesN          *int64 `protobuf:"varint,1,opt,name=SeriesN" json:"SeriesN,omitempty"`
+	BlocksN          *int64 `protobuf:"varint,2,opt,name=BlocksN" json:"BlocksN,omitempty"`
+	BlockBytes       *int64 `protobuf:"varint,3,opt,name=BlockBytes" json:"BlockBytes,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *IteratorStats) Reset()         { *m = IteratorStats{} }
+func (m *IteratorStats) String() string { return proto.CompactTextString(m) }
+func (*IteratorStats) ProtoMessage()    {}
+
+func (m *IteratorStats) GetSeriesN() int64 {
+	if m != nil && m.SeriesN != nil {
+		return *m.SeriesN
+	}
+	return 0
+}
+
+func (m *IteratorStats) GetBlocksN() int64 {
+	if m != nil && m.BlocksN != nil {
+		return *m.BlocksN
+	}
+	return 0
+}
+
+func (m *IteratorStats) GetBlockBytes() int64 {
+	if m != nil && m.BlockBytes != nil {
+		return *m.BlockBytes
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Point)(nil), "internal.Point")
 	proto.RegisterType((*Aux)(nil), "internal.Aux")
@@ -446,4 +495,5 @@ func init() {
 	proto.RegisterType((*Interval)(nil), "internal.Interval")
 	proto.RegisterType((*Series)(nil), "internal.Series")
 	proto.RegisterType((*SeriesList)(nil), "internal.SeriesList")
+	proto.RegisterType((*IteratorStats)(nil), "internal.IteratorStats")
 }
@@ -14,6 +14,7 @@ import (
 	"log"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/gogo/protobuf/proto"
 )
@@ -416,6 +417,59 @@ func (itr *floatLimitIterator) Next() *FloatPoint {
 	}
 }
 
+// floatPointLimitIterator represents an iterator that ends once its
+// limiter has read more points than its limit.
+type floatPointLimitIterator struct {
+	input   FloatIterator
+	limiter *PointLimiter
+}
+
+// newFloatPointLimitIterator returns a new instance of floatPointLimitIterator.
+func newFloatPointLimitIterator(input FloatIterator, limiter *PointLimiter) *floatPointLimitIterator {
+	return &floatPointLimitIterator{
+		input:   input,
+		limiter: limiter,
+	}
+}
+
+// Close closes the underlying iterators.
+func (itr *floatPointLimitIterator) Close() error { return itr.input.Close() }
+
+// Next returns the next point from the iterator.
+func (itr *floatPointLimitIterator) Next() *FloatPoint {
+	p := itr.input.Next()
+	if p == nil || !itr.limiter.inc() {
+		return nil
+	}
+	return p
+}
+
+// floatStatsIterator represents an iterator that counts the points read
+// from its input and the time spent reading them.
+type floatStatsIterator struct {
+	input FloatIterator
+	stats *IteratorStats
+}
+
+// newFloatStatsIterator returns a new instance of floatStatsIterator.
+func newFloatStatsIterator(input FloatIterator, stats *IteratorStats) *floatStatsIterator {
+	return &floatStatsIterator{
+		input: input,
+		stats: stats,
+	}
+}
+
+// Close closes the underlying iterators.
+func (itr *floatStatsIterator) Close() error { return itr.input.Close() }
+
+// Next returns the next point from the iterator.
+func (itr *floatStatsIterator) Next() *FloatPoint {
+	start := time.Now()
+	p := itr.input.Next()
+	itr.stats.read(p != nil, time.Since(start))
+	return p
+}
+
 type floatFillIterator struct {
 	input     *bufFloatIterator
 	prev      *FloatPoint
@@ -445,7 +499,7 @@ func newFloatFillIterator(input FloatIterator, expr Expr, opt IteratorOptions) *
 		startTime, _ = opt.Window(opt.StartTime)
 		_, endTime = opt.Window(opt.EndTime)
 	} else {
-		_, startTime = opt.Window(opt.EndTime)
+		startTime, _ = opt.Window(opt.EndTime)
 		endTime, _ = opt.Window(opt.StartTime)
 	}
 
@@ -509,7 +563,7 @@ func (itr *floatFillIterator) Next() *FloatPoint {
 	}
 
 	// Check if the point is our next expected point.
-	if p == nil || p.Time > itr.window.time {
+	if p == nil || (itr.opt.Ascending && p.Time > itr.window.time) || (!itr.opt.Ascending && p.Time < itr.window.time) {
 		if p != nil {
 			itr.input.unread(p)
 		}
@@ -527,9 +581,26 @@ func (itr *floatFillIterator) Next() *FloatPoint {
 		case NumberFill:
 			p.Value = castToFloat(itr.opt.FillValue)
 		case PreviousFill:
-			if itr.prev != nil {
-				p.Value = itr.prev.Value
-				p.Nil = itr.prev.Nil
+			// The previous point in time is the next point of the series
+			// when the points are in descending order.
+			prev := itr.prev
+			if !itr.opt.Ascending {
+				prev = nil
+				if next := itr.input.peek(); next != nil && next.Name == p.Name && next.Tags.ID() == p.Tags.ID() {
+					prev = next
+				}
+			}
+
+			if prev != nil {
+				p.Value = prev.Value
+				p.Nil = prev.Nil
+			} else {
+				p.Nil = true
+			}
+		case LinearFill:
+			// Interpolate between the previous and next points of the series.
+			if next := itr.input.peek(); itr.prev != nil && !itr.prev.Nil && next != nil && !next.Nil && next.Name == p.Name && next.Tags.ID() == p.Tags.ID() {
+				p.Value = linearFloat(p.Time, itr.prev.Time, next.Time, itr.prev.Value, next.Value)
 			} else {
 				p.Nil = true
 			}
@@ -540,8 +611,15 @@ func (itr *floatFillIterator) Next() *FloatPoint {
 
 	// Advance the expected time. Do not advance to a new window here
 	// as there may be lingering points with the same timestamp in the previous
-	// window.
-	if itr.opt.Ascending {
+	// window. Windows in a time zone are found by their start as they may
+	// be shorter or longer than the interval.
+	if itr.opt.Location != nil {
+		if itr.opt.Ascending {
+			_, itr.window.time = itr.opt.Window(p.Time)
+		} else {
+			itr.window.time, _ = itr.opt.Window(p.Time - 1)
+		}
+	} else if itr.opt.Ascending {
 		itr.window.time = p.Time + int64(itr.opt.Interval.Duration)
 	} else {
 		itr.window.time = p.Time - int64(itr.opt.Interval.Duration)
@@ -1303,10 +1381,13 @@ type floatReaderIterator struct {
 }
 
 // newFloatReaderIterator returns a new instance of floatReaderIterator.
-func newFloatReaderIterator(r io.Reader, first *FloatPoint) *floatReaderIterator {
+// Statistics sent by the writer are added to stats, if not nil.
+func newFloatReaderIterator(r io.Reader, first *FloatPoint, stats *IteratorStats) *floatReaderIterator {
+	dec := NewFloatPointDecoder(r)
+	dec.stats = stats
 	return &floatReaderIterator{
 		r:     r,
-		dec:   NewFloatPointDecoder(r),
+		dec:   dec,
 		first: first,
 	}
 }
@@ -1736,6 +1817,59 @@ func (itr *integerLimitIterator) Next() *IntegerPoint {
 	}
 }
 
+// integerPointLimitIterator represents an iterator that ends once its
+// limiter has read more points than its limit.
+type integerPointLimitIterator struct {
+	input   IntegerIterator
+	limiter *PointLimiter
+}
+
+// newIntegerPointLimitIterator returns a new instance of integerPointLimitIterator.
+func newIntegerPointLimitIterator(input IntegerIterator, limiter *PointLimiter) *integerPointLimitIterator {
+	return &integerPointLimitIterator{
+		input:   input,
+		limiter: limiter,
+	}
+}
+
+// Close closes the underlying iterators.
+func (itr *integerPointLimitIterator) Close() error { return itr.input.Close() }
+
+// Next returns the next point from the iterator.
+func (itr *integerPointLimitIterator) Next() *IntegerPoint {
+	p := itr.input.Next()
+	if p == nil || !itr.limiter.inc() {
+		return nil
+	}
+	return p
+}
+
+// integerStatsIterator represents an iterator that counts the points read
+// from its input and the time spent reading them.
+type integerStatsIterator struct {
+	input IntegerIterator
+	stats *IteratorStats
+}
+
+// newIntegerStatsIterator returns a new instance of integerStatsIterator.
+func newIntegerStatsIterator(input IntegerIterator, stats *IteratorStats) *integerStatsIterator {
+	return &integerStatsIterator{
+		input: input,
+		stats: stats,
+	}
+}
+
+// Close closes the underlying iterators.
+func (itr *integerStatsIterator) Close() error { return itr.input.Close() }
+
+// Next returns the next point from the iterator.
+func (itr *integerStatsIterator) Next() *IntegerPoint {
+	start := time.Now()
+	p := itr.input.Next()
+	itr.stats.read(p != nil, time.Since(start))
+	return p
+}
+
 type integerFillIterator struct {
 	input     *bufIntegerIterator
 	prev      *IntegerPoint
@@ -1765,7 +1899,7 @@ func newIntegerFillIterator(input IntegerIterator, expr Expr, opt IteratorOption
 		startTime, _ = opt.Window(opt.StartTime)
 		_, endTime = opt.Window(opt.EndTime)
 	} else {
-		_, startTime = opt.Window(opt.EndTime)
+		startTime, _ = opt.Window(opt.EndTime)
 		endTime, _ = opt.Window(opt.StartTime)
 	}
 
@@ -1829,7 +1963,7 @@ func (itr *integerFillIterator) Next() *IntegerPoint {
 	}
 
 	// Check if the point is our next expected point.
-	if p == nil || p.Time > itr.window.time {
+	if p == nil || (itr.opt.Ascending && p.Time > itr.window.time) || (!itr.opt.Ascending && p.Time < itr.window.time) {
 		if p != nil {
 			itr.input.unread(p)
 		}
@@ -1847,9 +1981,26 @@ func (itr *integerFillIterator) Next() *IntegerPoint {
 		case NumberFill:
 			p.Value = castToInteger(itr.opt.FillValue)
 		case PreviousFill:
-			if itr.prev != nil {
-				p.Value = itr.prev.Value
-				p.Nil = itr.prev.Nil
+			// The previous point in time is the next point of the series
+			// when the points are in descending order.
+			prev := itr.prev
+			if !itr.opt.Ascending {
+				prev = nil
+				if next := itr.input.peek(); next != nil && next.Name == p.Name && next.Tags.ID() == p.Tags.ID() {
+					prev = next
+				}
+			}
+
+			if prev != nil {
+				p.Value = prev.Value
+				p.Nil = prev.Nil
+			} else {
+				p.Nil = true
+			}
+		case LinearFill:
+			// Interpolate between the previous and next points of the series.
+			if next := itr.input.peek(); itr.prev != nil && !itr.prev.Nil && next != nil && !next.Nil && next.Name == p.Name && next.Tags.ID() == p.Tags.ID() {
+				p.Value = linearInteger(p.Time, itr.prev.Time, next.Time, itr.prev.Value, next.Value)
 			} else {
 				p.Nil = true
 			}
@@ -1860,8 +2011,15 @@ func (itr *integerFillIterator) Next() *IntegerPoint {
 
 	// Advance the expected time. Do not advance to a new window here
 	// as there may be lingering points with the same timestamp in the previous
-	// window.
-	if itr.opt.Ascending {
+	// window. Windows in a time zone are found by their start as they may
+	// be shorter or longer than the interval.
+	if itr.opt.Location != nil {
+		if itr.opt.Ascending {
+			_, itr.window.time = itr.opt.Window(p.Time)
+		} else {
+			itr.window.time, _ = itr.opt.Window(p.Time - 1)
+		}
+	} else if itr.opt.Ascending {
 		itr.window.time = p.Time + int64(itr.opt.Interval.Duration)
 	} else {
 		itr.window.time = p.Time - int64(itr.opt.Interval.Duration)
@@ -2620,10 +2778,13 @@ type integerReaderIterator struct {
 }
 
 // newIntegerReaderIterator returns a new instance of integerReaderIterator.
-func newIntegerReaderIterator(r io.Reader, first *IntegerPoint) *integerReaderIterator {
+// Statistics sent by the writer are added to stats, if not nil.
+func newIntegerReaderIterator(r io.Reader, first *IntegerPoint, stats *IteratorStats) *integerReaderIterator {
+	dec := NewIntegerPointDecoder(r)
+	dec.stats = stats
 	return &integerReaderIterator{
 		r:     r,
-		dec:   NewIntegerPointDecoder(r),
+		dec:   dec,
 		first: first,
 	}
 }
@@ -3053,6 +3214,59 @@ func (itr *stringLimitIterator) Next() *StringPoint {
 	}
 }
 
+// stringPointLimitIterator represents an iterator that ends once its
+// limiter has read more points than its limit.
+type stringPointLimitIterator struct {
+	input   StringIterator
+	limiter *PointLimiter
+}
+
+// newStringPointLimitIterator returns a new instance of stringPointLimitIterator.
+func newStringPointLimitIterator(input StringIterator, limiter *PointLimiter) *stringPointLimitIterator {
+	return &stringPointLimitIterator{
+		input:   input,
+		limiter: limiter,
+	}
+}
+
+// Close closes the underlying iterators.
+func (itr *stringPointLimitIterator) Close() error { return itr.input.Close() }
+
+// Next returns the next point from the iterator.
+func (itr *stringPointLimitIterator) Next() *StringPoint {
+	p := itr.input.Next()
+	if p == nil || !itr.limiter.inc() {
+		return nil
+	}
+	return p
+}
+
+// stringStatsIterator represents an iterator that counts the points read
+// from its input and the time spent reading them.
+type stringStatsIterator struct {
+	input StringIterator
+	stats *IteratorStats
+}
+
+// newStringStatsIterator returns a new instance of stringStatsIterator.
+func newStringStatsIterator(input StringIterator, stats *IteratorStats) *stringStatsIterator {
+	return &stringStatsIterator{
+		input: input,
+		stats: stats,
+	}
+}
+
+// Close closes the underlying iterators.
+func (itr *stringStatsIterator) Close() error { return itr.input.Close() }
+
+// Next returns the next point from the iterator.
+func (itr *stringStatsIterator) Next() *StringPoint {
+	start := time.Now()
+	p := itr.input.Next()
+	itr.stats.read(p != nil, time.Since(start))
+	return p
+}
+
 type stringFillIterator struct {
 	input     *bufStringIterator
 	prev      *StringPoint
@@ -3082,7 +3296,7 @@ func newStringFillIterator(input StringIterator, expr Expr, opt IteratorOptions)
 		startTime, _ = opt.Window(opt.StartTime)
 		_, endTime = opt.Window(opt.EndTime)
 	} else {
-		_, startTime = opt.Window(opt.EndTime)
+		startTime, _ = opt.Window(opt.EndTime)
 		endTime, _ = opt.Window(opt.StartTime)
 	}
 
@@ -3146,7 +3360,7 @@ func (itr *stringFillIterator) Next() *StringPoint {
 	}
 
 	// Check if the point is our next expected point.
-	if p == nil || p.Time > itr.window.time {
+	if p == nil || (itr.opt.Ascending && p.Time > itr.window.time) || (!itr.opt.Ascending && p.Time < itr.window.time) {
 		if p != nil {
 			itr.input.unread(p)
 		}
@@ -3164,12 +3378,25 @@ func (itr *stringFillIterator) Next() *StringPoint {
 		case NumberFill:
 			p.Value = castToString(itr.opt.FillValue)
 		case PreviousFill:
-			if itr.prev != nil {
-				p.Value = itr.prev.Value
-				p.Nil = itr.prev.Nil
+			// The previous point in time is the next point of the series
+			// when the points are in descending order.
+			prev := itr.prev
+			if !itr.opt.Ascending {
+				prev = nil
+				if next := itr.input.peek(); next != nil && next.Name == p.Name && next.Tags.ID() == p.Tags.ID() {
+					prev = next
+				}
+			}
+
+			if prev != nil {
+				p.Value = prev.Value
+				p.Nil = prev.Nil
 			} else {
 				p.Nil = true
 			}
+		case LinearFill:
+			// Only numbers can be interpolated.
+			p.Nil = true
 		}
 	} else {
 		itr.prev = p
@@ -3177,8 +3404,15 @@ func (itr *stringFillIterator) Next() *StringPoint {
 
 	// Advance the expected time. Do not advance to a new window here
 	// as there may be lingering points with the same timestamp in the previous
-	// window.
-	if itr.opt.Ascending {
+	// window. Windows in a time zone are found by their start as they may
+	// be shorter or longer than the interval.
+	if itr.opt.Location != nil {
+		if itr.opt.Ascending {
+			_, itr.window.time = itr.opt.Window(p.Time)
+		} else {
+			itr.window.time, _ = itr.opt.Window(p.Time - 1)
+		}
+	} else if itr.opt.Ascending {
 		itr.window.time = p.Time + int64(itr.opt.Interval.Duration)
 	} else {
 		itr.window.time = p.Time - int64(itr.opt.Interval.Duration)
@@ -3937,10 +4171,13 @@ type stringReaderIterator struct {
 }
 
 // newStringReaderIterator returns a new instance of stringReaderIterator.
-func newStringReaderIterator(r io.Reader, first *StringPoint) *stringReaderIterator {
+// Statistics sent by the writer are added to stats, if not nil.
+func newStringReaderIterator(r io.Reader, first *StringPoint, stats *IteratorStats) *stringReaderIterator {
+	dec := NewStringPointDecoder(r)
+	dec.stats = stats
 	return &stringReaderIterator{
 		r:     r,
-		dec:   NewStringPointDecoder(r),
+		dec:   dec,
 		first: first,
 	}
 }
@@ -4370,6 +4607,59 @@ func (itr *booleanLimitIterator) Next() *BooleanPoint {
 	}
 }
 
+// booleanPointLimitIterator represents an iterator that ends once its
+// limiter has read more points than its limit.
+type booleanPointLimitIterator struct {
+	input   BooleanIterator
+	limiter *PointLimiter
+}
+
+// newBooleanPointLimitIterator returns a new instance of booleanPointLimitIterator.
+func newBooleanPointLimitIterator(input BooleanIterator, limiter *PointLimiter) *booleanPointLimitIterator {
+	return &booleanPointLimitIterator{
+		input:   input,
+		limiter: limiter,
+	}
+}
+
+// Close closes the underlying iterators.
+func (itr *booleanPointLimitIterator) Close() error { return itr.input.Close() }
+
+// Next returns the next point from the iterator.
+func (itr *booleanPointLimitIterator) Next() *BooleanPoint {
+	p := itr.input.Next()
+	if p == nil || !itr.limiter.inc() {
+		return nil
+	}
+	return p
+}
+
+// booleanStatsIterator represents an iterator that counts the points read
+// from its input and the time spent reading them.
+type booleanStatsIterator struct {
+	input BooleanIterator
+	stats *IteratorStats
+}
+
+// newBooleanStatsIterator returns a new instance of booleanStatsIterator.
+func newBooleanStatsIterator(input BooleanIterator, stats *IteratorStats) *booleanStatsIterator {
+	return &booleanStatsIterator{
+		input: input,
+		stats: stats,
+	}
+}
+
+// Close closes the underlying iterators.
+func (itr *booleanStatsIterator) Close() error { return itr.input.Close() }
+
+// Next returns the next point from the iterator.
+func (itr *booleanStatsIterator) Next() *BooleanPoint {
+	start := time.Now()
+	p := itr.input.Next()
+	itr.stats.read(p != nil, time.Since(start))
+	return p
+}
+
 type booleanFillIterator struct {
 	input     *bufBooleanIterator
 	prev      *BooleanPoint
@@ -4399,7 +4689,7 @@ func newBooleanFillIterator(input BooleanIterator, expr Expr, opt IteratorOption
 		startTime, _ = opt.Window(opt.StartTime)
 		_, endTime = opt.Window(opt.EndTime)
 	} else {
-		_, startTime = opt.Window(opt.EndTime)
+		startTime, _ = opt.Window(opt.EndTime)
 		endTime, _ = opt.Window(opt.StartTime)
 	}
 
@@ -4463,7 +4753,7 @@ func (itr *booleanFillIterator) Next() *BooleanPoint {
 	}
 
 	// Check if the point is our next expected point.
-	if p == nil || p.Time > itr.window.time {
+	if p == nil || (itr.opt.Ascending && p.Time > itr.window.time) || (!itr.opt.Ascending && p.Time < itr.window.time) {
 		if p != nil {
 			itr.input.unread(p)
 		}
@@ -4481,12 +4771,25 @@ func (itr *booleanFillIterator) Next() *BooleanPoint {
 		case NumberFill:
 			p.Value = castToBoolean(itr.opt.FillValue)
 		case PreviousFill:
-			if itr.prev != nil {
-				p.Value = itr.prev.Value
-				p.Nil = itr.prev.Nil
+			// The previous point in time is the next point of the series
+			// when the points are in descending order.
+			prev := itr.prev
+			if !itr.opt.Ascending {
+				prev = nil
+				if next := itr.input.peek(); next != nil && next.Name == p.Name && next.Tags.ID() == p.Tags.ID() {
+					prev = next
+				}
+			}
+
+			if prev != nil {
+				p.Value = prev.Value
+				p.Nil = prev.Nil
 			} else {
 				p.Nil = true
 			}
+		case LinearFill:
+			// Only numbers can be interpolated.
+			p.Nil = true
 		}
 	} else {
 		itr.prev = p
@@ -4494,8 +4797,15 @@ func (itr *booleanFillIterator) Next() *BooleanPoint {
 
 	// Advance the expected time. Do not advance to a new window here
 	// as there may be lingering points with the same timestamp in the previous
-	// window.
-	if itr.opt.Ascending {
+	// window. Windows in a time zone are found by their start as they may
+	// be shorter or longer than the interval.
+	if itr.opt.Location != nil {
+		if itr.opt.Ascending {
+			_, itr.window.time = itr.opt.Window(p.Time)
+		} else {
+			itr.window.time, _ = itr.opt.Window(p.Time - 1)
+		}
+	} else if itr.opt.Ascending {
 		itr.window.time = p.Time + int64(itr.opt.Interval.Duration)
 	} else {
 		itr.window.time = p.Time - int64(itr.opt.Interval.Duration)
@@ -5254,10 +5564,13 @@ type booleanReaderIterator struct {
 }
 
 // newBooleanReaderIterator returns a new instance of booleanReaderIterator.
-func newBooleanReaderIterator(r io.Reader, first *BooleanPoint) *booleanReaderIterator {
+// Statistics sent by the writer are added to stats, if not nil.
+func newBooleanReaderIterator(r io.Reader, first *BooleanPoint, stats *IteratorStats) *booleanReaderIterator {
+	dec := NewBooleanPointDecoder(r)
+	dec.stats = stats
 	return &booleanReaderIterator{
 		r:     r,
-		dec:   NewBooleanPointDecoder(r),
+		dec:   dec,
 		first: first,
 	}
 }
@@ -1,11 +1,13 @@
 package influxql
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -15,6 +17,12 @@ import (
 // ErrUnknownCall is returned when operating on an unknown function call.
 var ErrUnknownCall = errors.New("unknown call")
 
+// ErrMaxSelectPointsLimitExceeded is an error when a query reads more points
+// than the limit.
+func ErrMaxSelectPointsLimitExceeded(n, limit int) error {
+	return fmt.Errorf("max-select-point limit exceeded: (%d/%d)", n, limit)
+}
+
 const (
 	// MinTime is used as the minimum time value when computing an unbounded range.
 	MinTime = int64(0)
@@ -61,9 +69,13 @@ func (a Iterators) castType() DataType {
 		return Unknown
 	}
 
-	typ := DataType(Boolean)
+	typ, found := DataType(Boolean), false
 	for _, input := range a {
 		switch input.(type) {
+		case *nilFloatIterator:
+			// Empty iterators, such as an empty remote stream, don't
+			// decide the type.
+			continue
 		case FloatIterator:
 			// Once a float iterator is found, short circuit the end.
 			return Float
@@ -78,6 +90,10 @@ func (a Iterators) castType() DataType {
 		case BooleanIterator:
 			// Boolean is the lowest type.
 		}
+		found = true
+	}
+	if !found {
+		return Float
 	}
 	return typ
 }
@@ -395,9 +411,12 @@ func drainIterator(itr Iterator) {
 }
 
 // NewReaderIterator returns an iterator that streams from a reader.
-func NewReaderIterator(r io.Reader) (Iterator, error) {
+// Statistics sent by the writer are added to stats, if not nil.
+func NewReaderIterator(r io.Reader, stats *IteratorStats) (Iterator, error) {
 	var p Point
-	if err := NewPointDecoder(r).DecodePoint(&p); err == io.EOF {
+	dec := NewPointDecoder(r)
+	dec.stats = stats
+	if err := dec.DecodePoint(&p); err == io.EOF {
 		return &nilFloatIterator{}, nil
 	} else if err != nil {
 		return nil, err
@@ -405,13 +424,13 @@ func NewReaderIterator(r io.Reader) (Iterator, error) {
 
 	switch p := p.(type) {
 	case *FloatPoint:
-		return newFloatReaderIterator(r, p), nil
+		return newFloatReaderIterator(r, p, stats), nil
 	case *IntegerPoint:
-		return newIntegerReaderIterator(r, p), nil
+		return newIntegerReaderIterator(r, p, stats), nil
 	case *StringPoint:
-		return newStringReaderIterator(r, p), nil
+		return newStringReaderIterator(r, p, stats), nil
 	case *BooleanPoint:
-		return newBooleanReaderIterator(r, p), nil
+		return newBooleanReaderIterator(r, p, stats), nil
 	default:
 		panic(fmt.Sprintf("unsupported point for reader iterator: %T", p))
 	}
@@ -527,6 +546,165 @@ func (a IteratorCreators) SeriesKeys(opt IteratorOptions) (SeriesList, error) {
 	return SeriesList(seriesList), nil
 }
 
+// PointLimiter counts the points read by a set of iterators. The iterators
+// end once more than the limit of points has been read.
+type PointLimiter struct {
+	n     int64 // atomic
+	limit int64
+}
+
+// NewPointLimiter returns a new instance of PointLimiter.
+func NewPointLimiter(limit int) *PointLimiter {
+	return &PointLimiter{limit: int64(limit)}
+}
+
+// inc counts a point and returns false if the limit has been exceeded.
+func (l *PointLimiter) inc() bool {
+	return atomic.AddInt64(&l.n, 1) <= l.limit
+}
+
+// Err returns an error if more points than the limit were read.
+func (l *PointLimiter) Err() error {
+	if n := atomic.LoadInt64(&l.n); n > l.limit {
+		return ErrMaxSelectPointsLimitExceeded(int(n), int(l.limit))
+	}
+	return nil
+}
+
+// Iterator returns an iterator that counts the points read from itr.
+func (l *PointLimiter) Iterator(itr Iterator) Iterator {
+	switch input := itr.(type) {
+	case FloatIterator:
+		return newFloatPointLimitIterator(input, l)
+	case IntegerIterator:
+		return newIntegerPointLimitIterator(input, l)
+	case StringIterator:
+		return newStringPointLimitIterator(input, l)
+	case BooleanIterator:
+		return newBooleanPointLimitIterator(input, l)
+	default:
+		panic(fmt.Sprintf("unsupported point limit iterator type: %T", itr))
+	}
+}
+
+// IteratorCreator returns an iterator creator whose iterators count the
+// points read from the iterators created by ic.
+func (l *PointLimiter) IteratorCreator(ic IteratorCreator) IteratorCreator {
+	return &pointLimitIteratorCreator{IteratorCreator: ic, limiter: l}
+}
+
+// pointLimitIteratorCreator wraps the iterators of an IteratorCreator with a PointLimiter.
+type pointLimitIteratorCreator struct {
+	IteratorCreator
+	limiter *PointLimiter
+}
+
+// CreateIterator creates an iterator limited by the point limiter.
+func (ic *pointLimitIteratorCreator) CreateIterator(opt IteratorOptions) (Iterator, error) {
+	itr, err := ic.IteratorCreator.CreateIterator(opt)
+	if err != nil || itr == nil {
+		return itr, err
+	}
+	return ic.limiter.Iterator(itr), nil
+}
+
+// Close closes the underlying iterator creator if it implements io.Closer.
+func (ic *pointLimitIteratorCreator) Close() error {
+	if c, ok := ic.IteratorCreator.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// IteratorStats represents statistics about the points returned by an
+// iterator and the data read from storage to return them. The counters are
+// updated atomically since iterators may be read concurrently.
+type IteratorStats struct {
+	PointN   int64         // points returned
+	Duration time.Duration // time spent reading points
+
+	SeriesN    int64 // series read from storage
+	BlocksN    int64 // blocks decoded
+	BlockBytes int64 // size of the decoded blocks in bytes
+}
+
+// Add adds the counters of other to s.
+func (s *IteratorStats) Add(other IteratorStats) {
+	atomic.AddInt64(&s.PointN, other.PointN)
+	atomic.AddInt64((*int64)(&s.Duration), int64(other.Duration))
+	atomic.AddInt64(&s.SeriesN, other.SeriesN)
+	atomic.AddInt64(&s.BlocksN, other.BlocksN)
+	atomic.AddInt64(&s.BlockBytes, other.BlockBytes)
+}
+
+// AddBlock counts a decoded block of size bytes.
+func (s *IteratorStats) AddBlock(size int64) {
+	atomic.AddInt64(&s.BlocksN, 1)
+	atomic.AddInt64(&s.BlockBytes, size)
+}
+
+// read counts a read of a point that took d.
+func (s *IteratorStats) read(ok bool, d time.Duration) {
+	if ok {
+		atomic.AddInt64(&s.PointN, 1)
+	}
+	atomic.AddInt64((*int64)(&s.Duration), int64(d))
+}
+
+// NewStatsIterator returns an iterator that counts the points read from itr
+// and the time spent reading them in stats.
+func NewStatsIterator(itr Iterator, stats *IteratorStats) Iterator {
+	switch input := itr.(type) {
+	case FloatIterator:
+		return newFloatStatsIterator(input, stats)
+	case IntegerIterator:
+		return newIntegerStatsIterator(input, stats)
+	case StringIterator:
+		return newStringStatsIterator(input, stats)
+	case BooleanIterator:
+		return newBooleanStatsIterator(input, stats)
+	default:
+		panic(fmt.Sprintf("unsupported stats iterator type: %T", itr))
+	}
+}
+
+// EncodeStats writes the storage statistics of an iterator after its points.
+// The reader adds them to its statistics.
+func (enc *IteratorEncoder) EncodeStats(stats IteratorStats) error {
+	buf, err := proto.Marshal(&internal.Point{
+		Name:  proto.String(""),
+		Tags:  proto.String(""),
+		Time:  proto.Int64(0),
+		Nil:   proto.Bool(true),
+		Stats: encodeIteratorStats(stats),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := binary.Write(enc.w, binary.BigEndian, uint32(len(buf))); err != nil {
+		return err
+	}
+	_, err = enc.w.Write(buf)
+	return err
+}
+
+func encodeIteratorStats(stats IteratorStats) *internal.IteratorStats {
+	return &internal.IteratorStats{
+		SeriesN:    proto.Int64(stats.SeriesN),
+		BlocksN:    proto.Int64(stats.BlocksN),
+		BlockBytes: proto.Int64(stats.BlockBytes),
+	}
+}
+
+func decodeIteratorStats(pb *internal.IteratorStats) IteratorStats {
+	return IteratorStats{
+		SeriesN:    pb.GetSeriesN(),
+		BlocksN:    pb.GetBlocksN(),
+		BlockBytes: pb.GetBlockBytes(),
+	}
+}
+
 // IteratorOptions is an object passed to CreateIterator to specify creation options.
 type IteratorOptions struct {
 	// Expression to iterate for.
@@ -565,6 +743,13 @@ type IteratorOptions struct {
 
 	// Removes duplicate rows from raw queries.
 	Dedupe bool
+
+	// The time zone of the interval windows. UTC if nil.
+	Location *time.Location
+
+	// Collects statistics about the data read from storage, if not nil.
+	// Statistics are not encoded with the options.
+	Stats *IteratorStats
 }
 
 // newIteratorOptionsStmt creates the iterator options from stmt.
@@ -601,6 +786,11 @@ func newIteratorOptionsStmt(stmt *SelectStatement, sopt *SelectOptions) (opt Ite
 	}
 	opt.Interval.Duration = interval
 
+	// Determine the offset of the group by interval.
+	if opt.Interval.Offset, err = stmt.GroupByOffset(); err != nil {
+		return opt, err
+	}
+
 	// Determine dimensions.
 	for _, d := range stmt.Dimensions {
 		if d, ok := d.Expr.(*VarRef); ok {
@@ -608,10 +798,22 @@ func newIteratorOptionsStmt(stmt *SelectStatement, sopt *SelectOptions) (opt Ite
 		}
 	}
 
+	// Joined points are grouped by the tags they are joined on.
+	dimensions := make(map[string]struct{}, len(opt.Dimensions))
+	for _, d := range opt.Dimensions {
+		dimensions[d] = struct{}{}
+	}
+	for _, tag := range stmt.JoinOn {
+		if _, ok := dimensions[tag]; !ok {
+			opt.Dimensions = append(opt.Dimensions, tag)
+		}
+	}
+
 	opt.Sources = stmt.Sources
 	opt.Condition = stmt.Condition
 	opt.Ascending = stmt.TimeAscending()
 	opt.Dedupe = stmt.Dedupe
+	opt.Location = stmt.Location
 
 	opt.Fill, opt.FillValue = stmt.Fill, stmt.FillValue
 	opt.Limit, opt.Offset = stmt.Limit, stmt.Offset
@@ -648,15 +850,57 @@ func (opt IteratorOptions) Window(t int64) (start, end int64) {
 	// Subtract the offset to the time so we calculate the correct base interval.
 	t -= int64(opt.Interval.Offset)
 
-	// Truncate time by duration.
-	t -= t % int64(opt.Interval.Duration)
+	// Truncate the local time in the location by duration.
+	var zone int64
+	if opt.Location != nil {
+		zone = opt.zoneOffset(t)
+	}
+	duration := int64(opt.Interval.Duration)
+	dt := (t + zone) % duration
+	if dt < 0 {
+		dt += duration
+	}
+	start = t - dt
+	end = start + duration
+
+	// The zone offset at the start or end of the window differs from the
+	// offset at t if the window contains a daylight saving time change.
+	// Move them to the local boundaries unless the change is as large as
+	// the interval.
+	if opt.Location != nil {
+		if o := zone - opt.zoneOffset(start); o != 0 && abs(o) < duration {
+			start += o
+		}
+
+		// Skipped time comes after the change, so only move the end back
+		// if the change happens before the end of the window.
+		endOffset := opt.zoneOffset(end)
+		if o := zone - endOffset; o != 0 && abs(o) < duration {
+			if o > 0 || opt.zoneOffset(end+o) == endOffset {
+				end += o
+			}
+		}
+	}
 
 	// Apply the offset.
-	start = t + int64(opt.Interval.Offset)
-	end = start + int64(opt.Interval.Duration)
+	start += int64(opt.Interval.Offset)
+	end += int64(opt.Interval.Offset)
 	return
 }
 
+// zoneOffset returns the offset of the location's time zone at t in nanoseconds.
+func (opt IteratorOptions) zoneOffset(t int64) int64 {
+	_, offset := time.Unix(0, t).In(opt.Location).Zone()
+	return int64(offset) * int64(time.Second)
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
 // DerivativeInterval returns the time interval for the derivative function.
 func (opt IteratorOptions) DerivativeInterval() Interval {
 	// Use the interval on the derivative() call, if specified.
@@ -709,6 +953,11 @@ func encodeIteratorOptions(opt *IteratorOptions) *internal.IteratorOptions {
 		Dedupe:     proto.Bool(opt.Dedupe),
 	}
 
+	// Set location, if set.
+	if opt.Location != nil {
+		pb.Location = proto.String(opt.Location.String())
+	}
+
 	// Set expression, if set.
 	if opt.Expr != nil {
 		pb.Expr = proto.String(opt.Expr.String())
@@ -752,6 +1001,15 @@ func decodeIteratorOptions(pb *internal.IteratorOptions) (*IteratorOptions, erro
 		Dedupe:     pb.GetDedupe(),
 	}
 
+	// Set location, if set.
+	if pb.Location != nil {
+		loc, err := time.LoadLocation(pb.GetLocation())
+		if err != nil {
+			return nil, err
+		}
+		opt.Location = loc
+	}
+
 	// Set expression, if set.
 	if pb.Expr != nil {
 		expr, err := ParseExpr(pb.GetExpr())
@@ -605,6 +605,38 @@ func TestLimitIterator_Boolean(t *testing.T) {
 	}
 }
 
+// Ensure point limit iterators end once the limit is exceeded across iterators.
+func TestPointLimiter(t *testing.T) {
+	limiter := influxql.NewPointLimiter(3)
+	itr0 := limiter.Iterator(&FloatIterator{Points: []influxql.FloatPoint{
+		{Name: "cpu", Time: 0, Value: 1},
+		{Name: "cpu", Time: 5, Value: 3},
+	}})
+	itr1 := limiter.Iterator(&IntegerIterator{Points: []influxql.IntegerPoint{
+		{Name: "mem", Time: 0, Value: 2},
+		{Name: "mem", Time: 5, Value: 4},
+	}})
+
+	if a := (Iterators{itr0}).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "cpu", Time: 0, Value: 1}},
+		{&influxql.FloatPoint{Name: "cpu", Time: 5, Value: 3}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	} else if err := limiter.Err(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if a := (Iterators{itr1}).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.IntegerPoint{Name: "mem", Time: 0, Value: 2}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+
+	if err := limiter.Err(); err == nil || err.Error() != "max-select-point limit exceeded: (4/3)" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 // Ensure auxilary iterators can be created for auxilary fields.
 func TestFloatAuxIterator(t *testing.T) {
 	itr := influxql.NewAuxIterator(
@@ -761,6 +793,36 @@ func TestIteratorOptions_Window_Offset(t *testing.T) {
 	}
 }
 
+func TestIteratorOptions_Window_Location(t *testing.T) {
+	for _, tt := range []struct {
+		loc      string
+		interval time.Duration
+		time     string
+		start    string
+		end      string
+	}{
+		{loc: "Asia/Shanghai", interval: 24 * time.Hour, time: "2020-06-15T03:00:00+08:00", start: "2020-06-15T00:00:00+08:00", end: "2020-06-16T00:00:00+08:00"},
+		{loc: "America/New_York", interval: 24 * time.Hour, time: "2020-03-08T12:00:00-04:00", start: "2020-03-08T00:00:00-05:00", end: "2020-03-09T00:00:00-04:00"},
+		{loc: "America/New_York", interval: 24 * time.Hour, time: "2020-03-08T01:00:00-05:00", start: "2020-03-08T00:00:00-05:00", end: "2020-03-09T00:00:00-04:00"},
+		{loc: "America/New_York", interval: 24 * time.Hour, time: "2020-11-01T12:00:00-05:00", start: "2020-11-01T00:00:00-04:00", end: "2020-11-02T00:00:00-05:00"},
+		{loc: "America/New_York", interval: 2 * time.Hour, time: "2020-03-08T01:30:00-05:00", start: "2020-03-08T00:00:00-05:00", end: "2020-03-08T03:00:00-04:00"},
+		{loc: "America/New_York", interval: time.Hour, time: "2020-11-01T01:30:00-05:00", start: "2020-11-01T01:00:00-05:00", end: "2020-11-01T02:00:00-05:00"},
+	} {
+		opt := influxql.IteratorOptions{
+			Interval: influxql.Interval{Duration: tt.interval},
+			Location: LoadLocation(tt.loc),
+		}
+
+		start, end := opt.Window(mustParseTime(tt.time).UnixNano())
+		if exp := mustParseTime(tt.start).UnixNano(); start != exp {
+			t.Errorf("%s %s: unexpected start: %s", tt.loc, tt.time, time.Unix(0, start).In(opt.Location))
+		}
+		if exp := mustParseTime(tt.end).UnixNano(); end != exp {
+			t.Errorf("%s %s: unexpected end: %s", tt.loc, tt.time, time.Unix(0, end).In(opt.Location))
+		}
+	}
+}
+
 func TestIteratorOptions_Window_Default(t *testing.T) {
 	opt := influxql.IteratorOptions{
 		StartTime: 0,
@@ -890,6 +952,7 @@ func TestIteratorOptions_MarshalBinary(t *testing.T) {
 		SLimit:     300,
 		SOffset:    400,
 		Dedupe:     true,
+		Location:   LoadLocation("America/New_York"),
 	}
 
 	// Marshal to binary.
@@ -0,0 +1,256 @@
+package influxql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// joinFieldRef returns the joined measurement a qualified field reference
+// such as "cpu.value" reads from and the name of the field in it.
+// Returns a nil measurement if the reference isn't qualified.
+func joinFieldRef(sources Sources, ref string) (*Measurement, string) {
+	for _, src := range sources {
+		m, ok := src.(*Measurement)
+		if !ok || m.Name == "" {
+			continue
+		}
+		if strings.HasPrefix(ref, m.Name+".") && len(ref) > len(m.Name)+1 {
+			return m, ref[len(m.Name)+1:]
+		}
+	}
+	return nil, ""
+}
+
+// joinIteratorCreator creates the iterators of the fields of a JOIN. Each
+// field is read from the measurement that qualifies it and its points are
+// named after the join so the points of both measurements can be combined.
+type joinIteratorCreator struct {
+	ic      IteratorCreator
+	sources Sources
+	name    string
+}
+
+// newJoinIteratorCreator returns an iterator creator for the fields of the
+// joined sources.
+func newJoinIteratorCreator(ic IteratorCreator, sources Sources) *joinIteratorCreator {
+	names := make([]string, 0, len(sources))
+	for _, src := range sources {
+		if m, ok := src.(*Measurement); ok {
+			names = append(names, m.Name)
+		}
+	}
+	return &joinIteratorCreator{ic: ic, sources: sources, name: strings.Join(names, "_")}
+}
+
+// CreateIterator reads the qualified fields of opt.Expr from their measurement.
+func (ic *joinIteratorCreator) CreateIterator(opt IteratorOptions) (Iterator, error) {
+	var m *Measurement
+	var err error
+	opt.Expr = RewriteExpr(CloneExpr(opt.Expr), func(expr Expr) Expr {
+		ref, ok := expr.(*VarRef)
+		if !ok || err != nil {
+			return expr
+		}
+
+		src, name := joinFieldRef(ic.sources, ref.Val)
+		if src == nil {
+			err = fmt.Errorf("field %s must be qualified by a joined measurement", ref.Val)
+			return expr
+		} else if m != nil && m != src {
+			err = fmt.Errorf("can not combine fields of joined measurements in %s", opt.Expr)
+			return expr
+		}
+		m = src
+		return &VarRef{Val: name}
+	})
+	if err != nil {
+		return nil, err
+	} else if m == nil {
+		return nil, fmt.Errorf("expected a field of a joined measurement in %s", opt.Expr)
+	}
+
+	// Read only from the measurement of the field.
+	opt.Sources = Sources{m}
+	aux := make([]string, 0, len(opt.Aux))
+	for _, ref := range opt.Aux {
+		if src, name := joinFieldRef(ic.sources, ref); src == m {
+			ref = name
+		}
+		aux = append(aux, ref)
+	}
+	opt.Aux = aux
+
+	itr, err := ic.ic.CreateIterator(opt)
+	if err != nil {
+		return nil, err
+	}
+	return newRenameIterator(itr, ic.name)
+}
+
+// FieldDimensions returns the unique fields and dimensions across a list of sources.
+func (ic *joinIteratorCreator) FieldDimensions(sources Sources) (fields, dimensions map[string]struct{}, err error) {
+	return ic.ic.FieldDimensions(sources)
+}
+
+// SeriesKeys returns the series keys that will be returned by this iterator.
+func (ic *joinIteratorCreator) SeriesKeys(opt IteratorOptions) (SeriesList, error) {
+	return ic.ic.SeriesKeys(opt)
+}
+
+// newRenameIterator returns an iterator that sets the name of every point to name.
+func newRenameIterator(input Iterator, name string) (Iterator, error) {
+	switch input := input.(type) {
+	case FloatIterator:
+		return &floatTransformIterator{input: input, fn: func(p *FloatPoint) *FloatPoint {
+			p.Name = name
+			return p
+		}}, nil
+	case IntegerIterator:
+		return &integerTransformIterator{input: input, fn: func(p *IntegerPoint) *IntegerPoint {
+			p.Name = name
+			return p
+		}}, nil
+	case StringIterator:
+		return &stringTransformIterator{input: input, fn: func(p *StringPoint) *StringPoint {
+			p.Name = name
+			return p
+		}}, nil
+	case BooleanIterator:
+		return &booleanTransformIterator{input: input, fn: func(p *BooleanPoint) *BooleanPoint {
+			p.Name = name
+			return p
+		}}, nil
+	default:
+		input.Close()
+		return nil, fmt.Errorf("unsupported rename iterator type: %T", input)
+	}
+}
+
+// joinKey identifies the window of a series joined points are matched on.
+type joinKey struct {
+	tags string
+	time int64
+}
+
+// newJoinIterators matches the points of two iterators on their tags and
+// time. Only the points that exist in both iterators are returned, in the
+// order of lhs. Integer points are converted to floats.
+func newJoinIterators(lhs, rhs Iterator, opt IteratorOptions) (Iterator, Iterator, error) {
+	left, err := joinFloatIterator(lhs)
+	if err != nil {
+		lhs.Close()
+		rhs.Close()
+		return nil, nil, err
+	}
+	right, err := joinFloatIterator(rhs)
+	if err != nil {
+		lhs.Close()
+		rhs.Close()
+		return nil, nil, err
+	}
+
+	j := &floatJoin{lhs: newBufFloatIterator(left), rhs: newBufFloatIterator(right), opt: opt}
+	return &floatJoinIterator{join: j}, &floatJoinIterator{join: j, rhs: true}, nil
+}
+
+func joinFloatIterator(itr Iterator) (FloatIterator, error) {
+	switch itr := itr.(type) {
+	case FloatIterator:
+		return itr, nil
+	case IntegerIterator:
+		return &integerFloatCastIterator{input: itr}, nil
+	default:
+		return nil, fmt.Errorf("unsupported join iterator type: %T", itr)
+	}
+}
+
+// floatJoin matches the points of two iterators one GROUP BY time() window
+// at a time. Both iterators return their points window by window, so only
+// the points of the current window are held in memory.
+type floatJoin struct {
+	lhs, rhs *bufFloatIterator
+	opt      IteratorOptions
+	left     []FloatPoint
+	right    []FloatPoint
+	closed   bool
+}
+
+// next appends the matched points of the next window with matches to the
+// points not yet read. Returns false once either iterator is exhausted.
+func (j *floatJoin) next() bool {
+	for {
+		lp, rp := j.lhs.peek(), j.rhs.peek()
+		if lp == nil || rp == nil {
+			return false
+		}
+
+		// A window only one side has points in has no matches.
+		lstart, lend := j.opt.Window(lp.Time)
+		rstart, rend := j.opt.Window(rp.Time)
+		if lstart != rstart {
+			if (lstart < rstart) == j.opt.Ascending {
+				skipFloatWindow(j.lhs, lstart, lend)
+			} else {
+				skipFloatWindow(j.rhs, rstart, rend)
+			}
+			continue
+		}
+
+		points := make(map[joinKey]FloatPoint)
+		for p := j.rhs.NextInWindow(rstart, rend); p != nil; p = j.rhs.NextInWindow(rstart, rend) {
+			points[joinKey{tags: p.Tags.ID(), time: p.Time}] = *p.Clone()
+		}
+
+		n := len(j.left)
+		for p := j.lhs.NextInWindow(lstart, lend); p != nil; p = j.lhs.NextInWindow(lstart, lend) {
+			other, ok := points[joinKey{tags: p.Tags.ID(), time: p.Time}]
+			if !ok {
+				continue
+			}
+			j.left = append(j.left, *p.Clone())
+			j.right = append(j.right, other)
+		}
+		if len(j.left) > n {
+			return true
+		}
+	}
+}
+
+// skipFloatWindow discards the points of itr in [startTime, endTime).
+func skipFloatWindow(itr *bufFloatIterator, startTime, endTime int64) {
+	for itr.NextInWindow(startTime, endTime) != nil {
+	}
+}
+
+func (j *floatJoin) Close() error {
+	if j.closed {
+		return nil
+	}
+	j.closed = true
+	j.lhs.Close()
+	return j.rhs.Close()
+}
+
+// floatJoinIterator returns the matched points of one side of a join.
+type floatJoinIterator struct {
+	join *floatJoin
+	rhs  bool
+}
+
+// Close closes both sides of the join.
+func (itr *floatJoinIterator) Close() error { return itr.join.Close() }
+
+// Next returns the next matched point.
+func (itr *floatJoinIterator) Next() *FloatPoint {
+	points := &itr.join.left
+	if itr.rhs {
+		points = &itr.join.right
+	}
+	if len(*points) == 0 && !itr.join.next() {
+		return nil
+	}
+
+	p := (*points)[0]
+	*points = (*points)[1:]
+	return &p
+}
@@ -0,0 +1,17 @@
+package influxql
+
+// linearFloat returns the value at windowTime on the line between the points
+// (previousTime, previousValue) and (nextTime, nextValue).
+func linearFloat(windowTime, previousTime, nextTime int64, previousValue, nextValue float64) float64 {
+	m := (nextValue - previousValue) / float64(nextTime-previousTime) // the slope of the line
+	x := float64(windowTime - previousTime)                           // how far into the interval we are
+	return m*x + previousValue
+}
+
+// linearInteger returns the value at windowTime on the line between the points
+// (previousTime, previousValue) and (nextTime, nextValue).
+func linearInteger(windowTime, previousTime, nextTime int64, previousValue, nextValue int64) int64 {
+	m := float64(nextValue-previousValue) / float64(nextTime-previousTime) // the slope of the line
+	x := float64(windowTime - previousTime)                                // how far into the interval we are
+	return int64(m*x + float64(previousValue))
+}
@@ -0,0 +1,165 @@
+package influxql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"time"
+)
+
+// BindParameters replaces the bound parameters in the expressions of stmt
+// with literals of their values in params. Strings that look like times are
+// bound as times, as they are when parsed. A value can also be given a type
+// with an object such as {"duration": "1h"} or {"regex": "^cpu"}.
+//
+// Returns an error if a parameter has no value or can't be bound.
+func BindParameters(stmt Statement, params map[string]interface{}) error {
+	var err error
+	bind := func(expr Expr) Expr {
+		bp, ok := expr.(*BoundParameter)
+		if !ok || err != nil {
+			return expr
+		}
+
+		lit, e := boundLiteral(params, bp.Name)
+		if e != nil {
+			err = e
+			return expr
+		}
+		return lit
+	}
+
+	WalkFunc(stmt, func(n Node) {
+		switch n := n.(type) {
+		case *BinaryExpr:
+			bp, _ := n.RHS.(*BoundParameter)
+			n.LHS, n.RHS = bind(n.LHS), bind(n.RHS)
+			if _, ok := n.RHS.(*RegexLiteral); bp != nil && err == nil && IsRegexOp(n.Op) && !ok {
+				err = fmt.Errorf("parameter %s must be a regex", bp.Name)
+			}
+		case *ParenExpr:
+			n.Expr = bind(n.Expr)
+		case *Call:
+			for i := range n.Args {
+				n.Args[i] = bind(n.Args[i])
+			}
+		case *Field:
+			n.Expr = bind(n.Expr)
+		case *Dimension:
+			n.Expr = bind(n.Expr)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bound parameters anywhere else, such as on their own in a WHERE
+	// clause, are not supported.
+	WalkFunc(stmt, func(n Node) {
+		if bp, ok := n.(*BoundParameter); ok && err == nil {
+			err = fmt.Errorf("unable to bind parameter %s", bp)
+		}
+	})
+	return err
+}
+
+// boundLiteral returns the literal for the value of the parameter name.
+func boundLiteral(params map[string]interface{}, name string) (Expr, error) {
+	v, ok := params[name]
+	if !ok {
+		return nil, fmt.Errorf("missing parameter: %s", name)
+	}
+
+	switch v := v.(type) {
+	case string:
+		if isDateTimeString(v) {
+			t, err := time.Parse(DateTimeFormat, v)
+			if err != nil {
+				if t, err = time.Parse(time.RFC3339Nano, v); err != nil {
+					return nil, fmt.Errorf("unable to parse datetime for parameter %s", name)
+				}
+			}
+			return &TimeLiteral{Val: t}, nil
+		} else if isDateString(v) {
+			t, err := time.Parse(DateFormat, v)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse date for parameter %s", name)
+			}
+			return &TimeLiteral{Val: t}, nil
+		}
+		return &StringLiteral{Val: v}, nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse number for parameter %s", name)
+		}
+		return &NumberLiteral{Val: f}, nil
+	case float64:
+		return &NumberLiteral{Val: v}, nil
+	case int:
+		return &NumberLiteral{Val: float64(v)}, nil
+	case int64:
+		return &NumberLiteral{Val: float64(v)}, nil
+	case bool:
+		return &BooleanLiteral{Val: v}, nil
+	case time.Time:
+		return &TimeLiteral{Val: v}, nil
+	case time.Duration:
+		return &DurationLiteral{Val: v}, nil
+	case map[string]interface{}:
+		if len(v) != 1 {
+			return nil, fmt.Errorf("typed parameter %s must have exactly one type", name)
+		}
+		for typ, val := range v {
+			s, ok := val.(string)
+			if !ok {
+				return nil, fmt.Errorf("typed parameter %s must have a string value", name)
+			}
+
+			switch typ {
+			case "duration":
+				d, err := ParseDuration(s)
+				if err != nil {
+					return nil, fmt.Errorf("unable to parse duration for parameter %s", name)
+				}
+				return &DurationLiteral{Val: d}, nil
+			case "regex":
+				re, err := regexp.Compile(s)
+				if err != nil {
+					return nil, fmt.Errorf("unable to parse regex for parameter %s: %s", name, err)
+				}
+				return &RegexLiteral{Val: re}, nil
+			case "string":
+				return &StringLiteral{Val: s}, nil
+			default:
+				return nil, fmt.Errorf("unknown type %q for parameter %s", typ, name)
+			}
+		}
+	}
+	return nil, fmt.Errorf("unsupported value %v for parameter %s", v, name)
+}
+
+// intParam returns the value of the parameter name, which must be an integer.
+func intParam(params map[string]interface{}, name string) (int64, error) {
+	v, ok := params[name]
+	if !ok {
+		return 0, fmt.Errorf("missing parameter: %s", name)
+	}
+
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+	case float64:
+		if v == math.Trunc(v) {
+			return int64(v), nil
+		}
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	}
+	return 0, fmt.Errorf("parameter %s must be an integer", name)
+}
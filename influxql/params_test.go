@@ -0,0 +1,142 @@
+package influxql_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/freetsdb/freetsdb/influxql"
+)
+
+// Ensure bound parameters are replaced with the literals of their values.
+func TestBindParameters(t *testing.T) {
+	var tests = []struct {
+		s      string
+		params string
+		stmt   string
+		err    string
+	}{
+		{
+			s:      `SELECT value FROM cpu WHERE host = $host`,
+			params: `{"host": "serverA"}`,
+			stmt:   `SELECT value FROM cpu WHERE host = 'serverA'`,
+		},
+		{
+			s:      `SELECT value FROM cpu WHERE value > $"min value" AND enabled = $enabled`,
+			params: `{"min value": 10, "enabled": true}`,
+			stmt:   `SELECT value FROM cpu WHERE value > 10.000 AND enabled = true`,
+		},
+		{
+			s:      `SELECT value FROM cpu WHERE time > $start`,
+			params: `{"start": "2000-01-01T00:00:00Z"}`,
+			stmt:   `SELECT value FROM cpu WHERE time > '2000-01-01T00:00:00Z'`,
+		},
+		{
+			s:      `SELECT mean(value) FROM cpu WHERE time > now() - $ago GROUP BY time(10m)`,
+			params: `{"ago": {"duration": "1h"}}`,
+			stmt:   `SELECT mean(value) FROM cpu WHERE time > now() - 1h GROUP BY time(10m)`,
+		},
+		{
+			s:      `SELECT value FROM cpu WHERE host =~ $host`,
+			params: `{"host": {"regex": "^server"}}`,
+			stmt:   `SELECT value FROM cpu WHERE host =~ /^server/`,
+		},
+		{
+			s:      `SELECT value FROM cpu WHERE region = $region`,
+			params: `{"region": {"string": "2000-01-01"}}`,
+			stmt:   `SELECT value FROM cpu WHERE region = '2000-01-01'`,
+		},
+		{
+			s:      `SELECT value FROM cpu WHERE host = $host`,
+			params: `{}`,
+			err:    `missing parameter: host`,
+		},
+		{
+			s:      `SELECT value FROM cpu WHERE host = $host`,
+			params: `{"host": [1, 2]}`,
+			err:    `unsupported value [1 2] for parameter host`,
+		},
+		{
+			s:      `SELECT value FROM cpu WHERE host = $host`,
+			params: `{"host": {"date": "2000-01-01"}}`,
+			err:    `unknown type "date" for parameter host`,
+		},
+		{
+			s:      `SELECT value FROM cpu WHERE host !~ $host`,
+			params: `{"host": "serverA"}`,
+			err:    `parameter host must be a regex`,
+		},
+		{
+			s:      `SELECT value FROM cpu WHERE $cond`,
+			params: `{"cond": true}`,
+			err:    `unable to bind parameter $cond`,
+		},
+	}
+
+	for i, tt := range tests {
+		var params map[string]interface{}
+		dec := json.NewDecoder(strings.NewReader(tt.params))
+		dec.UseNumber()
+		if err := dec.Decode(&params); err != nil {
+			t.Fatalf("%d. unexpected error: %s", i, err)
+		}
+
+		stmt, err := influxql.ParseStatement(tt.s)
+		if err != nil {
+			t.Fatalf("%d. %q: unexpected parse error: %s", i, tt.s, err)
+		}
+
+		err = influxql.BindParameters(stmt, params)
+		if errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.s, tt.err, err)
+		} else if tt.err == "" && stmt.String() != tt.stmt {
+			t.Errorf("%d. %q: statement mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.s, tt.stmt, stmt)
+		}
+	}
+}
+
+// Ensure integer bound parameters are bound by the parser.
+func TestParser_SetParams(t *testing.T) {
+	var tests = []struct {
+		s      string
+		params map[string]interface{}
+		stmt   string
+		err    string
+	}{
+		{
+			s:      `SELECT value FROM cpu LIMIT $limit OFFSET $offset`,
+			params: map[string]interface{}{"limit": json.Number("10"), "offset": float64(20)},
+			stmt:   `SELECT value FROM cpu LIMIT 10 OFFSET 20`,
+		},
+		{
+			s:      `SELECT value FROM cpu SLIMIT $n`,
+			params: map[string]interface{}{"n": 5},
+			stmt:   `SELECT value FROM cpu SLIMIT 5`,
+		},
+		{
+			s:   `SELECT value FROM cpu LIMIT $limit`,
+			err: `missing parameter: limit at line 1, char 29`,
+		},
+		{
+			s:      `SELECT value FROM cpu LIMIT $limit`,
+			params: map[string]interface{}{"limit": 1.5},
+			err:    `parameter limit must be an integer at line 1, char 29`,
+		},
+		{
+			s:      `SELECT value FROM cpu LIMIT $limit`,
+			params: map[string]interface{}{"limit": -1},
+			err:    `LIMIT must be >= 0 at line 1, char 29`,
+		},
+	}
+
+	for i, tt := range tests {
+		p := influxql.NewParser(strings.NewReader(tt.s))
+		p.SetParams(tt.params)
+		stmt, err := p.ParseStatement()
+		if errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.s, tt.err, err)
+		} else if tt.err == "" && stmt.String() != tt.stmt {
+			t.Errorf("%d. %q: statement mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.s, tt.stmt, stmt)
+		}
+	}
+}
@@ -23,7 +23,8 @@ const (
 
 // Parser represents an InfluxQL parser.
 type Parser struct {
-	s *bufScanner
+	s      *bufScanner
+	params map[string]interface{}
 }
 
 // NewParser returns a new instance of Parser.
@@ -31,6 +32,13 @@ func NewParser(r io.Reader) *Parser {
 	return &Parser{s: newBufScanner(r)}
 }
 
+// SetParams sets the values of the bound parameters used where the statement
+// requires an integer, such as LIMIT and OFFSET. Bound parameters used in
+// expressions are parsed as a BoundParameter and bound with BindParameters.
+func (p *Parser) SetParams(params map[string]interface{}) {
+	p.params = params
+}
+
 // ParseQuery parses a query string and returns its AST representation.
 func ParseQuery(s string) (*Query, error) { return NewParser(strings.NewReader(s)).ParseQuery() }
 
@@ -91,6 +99,8 @@ func (p *Parser) ParseStatement() (Statement, error) {
 		return p.parseSelectStatement(targetNotRequired)
 	case DELETE:
 		return p.parseDeleteStatement()
+	case EXPLAIN:
+		return p.parseExplainStatement()
 	case SHOW:
 		return p.parseShowStatement()
 	case CREATE:
@@ -105,9 +115,15 @@ func (p *Parser) ParseStatement() (Statement, error) {
 		return p.parseAlterStatement()
 	case SET:
 		return p.parseSetPasswordUserStatement()
-	default:
-		return nil, newParseError(tokstr(tok, lit), []string{"SELECT", "DELETE", "SHOW", "CREATE", "DROP", "GRANT", "REVOKE", "ALTER", "SET"}, pos)
+	case IDENT:
+		switch name := strings.ToLower(lit); name {
+		case "run":
+			return p.parseRunContinuousQueryStatement()
+		case "purge", "pause", "resume":
+			return p.parseHintedHandoffNodeStatement(name)
+		}
 	}
+	return nil, newParseError(tokstr(tok, lit), []string{"SELECT", "DELETE", "EXPLAIN", "SHOW", "CREATE", "DROP", "GRANT", "REVOKE", "ALTER", "SET", "RUN", "PURGE", "PAUSE", "RESUME"}, pos)
 }
 
 // parseShowStatement parses a string and returns a list statement.
@@ -163,6 +179,18 @@ func (p *Parser) parseShowStatement() (Statement, error) {
 		return p.parseShowUsersStatement()
 	case SUBSCRIPTIONS:
 		return p.parseShowSubscriptionsStatement()
+	case IDENT:
+		switch strings.ToLower(lit) {
+		case "downsamples":
+			return p.parseShowDownsamplesStatement()
+		case "tokens":
+			return p.parseShowTokensStatement()
+		case "hinted":
+			if err := p.parseIdentWords([]string{"handoff"}); err != nil {
+				return nil, err
+			}
+			return &ShowHintedHandoffStatement{}, nil
+		}
 	}
 
 	showQueryKeywords := []string{
@@ -170,14 +198,17 @@ func (p *Parser) parseShowStatement() (Statement, error) {
 		"DATABASES",
 		"FIELD",
 		"GRANTS",
+		"HINTED",
 		"MEASUREMENTS",
 		"RETENTION",
 		"SERIES",
 		"SERVERS",
 		"TAG",
+		"TOKENS",
 		"USERS",
 		"STATS",
 		"DIAGNOSTICS",
+		"DOWNSAMPLES",
 		"SHARD",
 		"SHARDS",
 		"SUBSCRIPTIONS",
@@ -205,9 +236,13 @@ func (p *Parser) parseCreateStatement() (Statement, error) {
 		return p.parseCreateRetentionPolicyStatement()
 	} else if tok == SUBSCRIPTION {
 		return p.parseCreateSubscriptionStatement()
+	} else if tok == IDENT && strings.ToLower(lit) == "downsample" {
+		return p.parseCreateDownsampleStatement()
+	} else if tok == IDENT && strings.ToLower(lit) == "token" {
+		return p.parseCreateTokenStatement()
 	}
 
-	return nil, newParseError(tokstr(tok, lit), []string{"CONTINUOUS", "DATABASE", "USER", "RETENTION", "SUBSCRIPTION"}, pos)
+	return nil, newParseError(tokstr(tok, lit), []string{"CONTINUOUS", "DATABASE", "USER", "RETENTION", "SUBSCRIPTION", "DOWNSAMPLE", "TOKEN"}, pos)
 }
 
 // parseDropStatement parses a string and returns a drop statement.
@@ -233,9 +268,13 @@ func (p *Parser) parseDropStatement() (Statement, error) {
 		return p.parseDropServerStatement(tok)
 	} else if tok == SUBSCRIPTION {
 		return p.parseDropSubscriptionStatement()
+	} else if tok == IDENT && strings.ToLower(lit) == "downsample" {
+		return p.parseDropDownsampleStatement()
+	} else if tok == IDENT && strings.ToLower(lit) == "token" {
+		return p.parseDropTokenStatement()
 	}
 
-	return nil, newParseError(tokstr(tok, lit), []string{"SERIES", "CONTINUOUS", "MEASUREMENT", "SERVER", "SUBSCRIPTION"}, pos)
+	return nil, newParseError(tokstr(tok, lit), []string{"SERIES", "CONTINUOUS", "MEASUREMENT", "SERVER", "SUBSCRIPTION", "DOWNSAMPLE", "TOKEN"}, pos)
 }
 
 // parseAlterStatement parses a string and returns an alter statement.
@@ -339,6 +378,112 @@ func (p *Parser) parseCreateSubscriptionStatement() (*CreateSubscriptionStatemen
 	return stmt, nil
 }
 
+// downsampleAggregates is the set of aggregate functions a downsample policy may apply.
+var downsampleAggregates = map[string]struct{}{
+	"count":   {},
+	"first":   {},
+	"last":    {},
+	"max":     {},
+	"mean":    {},
+	"median":  {},
+	"min":     {},
+	"spread":  {},
+	"stddev":  {},
+	"sum":     {},
+	"tdigest": {},
+}
+
+// parseCreateDownsampleStatement parses a string and returns a CreateDownsampleStatement.
+// This function assumes the "CREATE DOWNSAMPLE" tokens have already been consumed.
+func (p *Parser) parseCreateDownsampleStatement() (*CreateDownsampleStatement, error) {
+	stmt := &CreateDownsampleStatement{}
+
+	// Read the source and target retention policies.
+	var err error
+	stmt.Database, stmt.RetentionPolicy, stmt.TargetDatabase, stmt.TargetRetentionPolicy, err = p.parseDownsampleRetentionPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	// Expect an "EVERY" keyword followed by the bucket width.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != EVERY {
+		return nil, newParseError(tokstr(tok, lit), []string{"EVERY"}, pos)
+	}
+	_, pos, _ := p.scanIgnoreWhitespace()
+	p.unscan()
+	if stmt.Interval, err = p.parseDuration(); err != nil {
+		return nil, err
+	} else if stmt.Interval <= 0 {
+		return nil, &ParseError{Message: "downsample interval must be greater than zero", Pos: pos}
+	}
+
+	// Expect an "AGGREGATE" keyword followed by a list of functions.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != IDENT || strings.ToLower(lit) != "aggregate" {
+		return nil, newParseError(tokstr(tok, lit), []string{"AGGREGATE"}, pos)
+	}
+	_, pos, _ = p.scanIgnoreWhitespace()
+	p.unscan()
+	aggregates, err := p.parseIdentList()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{}, len(aggregates))
+	for _, name := range aggregates {
+		name = strings.ToLower(name)
+		if _, ok := downsampleAggregates[name]; !ok {
+			return nil, &ParseError{Message: fmt.Sprintf("invalid downsample aggregate: %s", name), Pos: pos}
+		} else if _, ok := seen[name]; ok {
+			return nil, &ParseError{Message: fmt.Sprintf("duplicate downsample aggregate: %s", name), Pos: pos}
+		}
+		seen[name] = struct{}{}
+		stmt.Aggregates = append(stmt.Aggregates, name)
+	}
+
+	return stmt, nil
+}
+
+// parseDownsampleRetentionPolicies parses "ON <db>.<rp> TO [<db>.]<rp>".
+// The target database defaults to the source database when omitted.
+func (p *Parser) parseDownsampleRetentionPolicies() (database, rp, targetDatabase, targetRP string, err error) {
+	// Expect an "ON" keyword.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != ON {
+		return "", "", "", "", newParseError(tokstr(tok, lit), []string{"ON"}, pos)
+	}
+
+	// Read the source database and retention policy.
+	if database, err = p.parseIdent(); err != nil {
+		return "", "", "", "", err
+	}
+	if tok, pos, lit := p.scan(); tok != DOT {
+		return "", "", "", "", newParseError(tokstr(tok, lit), []string{"."}, pos)
+	}
+	if rp, err = p.parseIdent(); err != nil {
+		return "", "", "", "", err
+	}
+
+	// Expect a "TO" keyword.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != TO {
+		return "", "", "", "", newParseError(tokstr(tok, lit), []string{"TO"}, pos)
+	}
+
+	// Read the target retention policy, optionally qualified by a database.
+	ident, err := p.parseIdent()
+	if err != nil {
+		return "", "", "", "", err
+	}
+	if tok, _, _ := p.scan(); tok == DOT {
+		targetDatabase = ident
+		if targetRP, err = p.parseIdent(); err != nil {
+			return "", "", "", "", err
+		}
+	} else {
+		p.unscan()
+		targetDatabase, targetRP = database, ident
+	}
+
+	return database, rp, targetDatabase, targetRP, nil
+}
+
 // parseCreateRetentionPolicyStatement parses a string and returns a create retention policy statement.
 // This function assumes the CREATE RETENTION POLICY tokens have already been consumed.
 func (p *Parser) parseCreateRetentionPolicyStatement() (*CreateRetentionPolicyStatement, error) {
@@ -684,13 +829,21 @@ func (p *Parser) parseRevokeStatement() (Statement, error) {
 func (p *Parser) parseRevokeOnStatement() (*RevokeStatement, error) {
 	stmt := &RevokeStatement{}
 
-	// Parse the name of the database.
+	// Parse the name of the database and, optionally, the measurement.
 	lit, err := p.parseIdent()
 	if err != nil {
 		return nil, err
 	}
 	stmt.On = lit
 
+	if tok, _, _ := p.scan(); tok == DOT {
+		if stmt.Measurement, err = p.parseIdent(); err != nil {
+			return nil, err
+		}
+	} else {
+		p.unscan()
+	}
+
 	// Parse FROM clause.
 	tok, pos, lit := p.scanIgnoreWhitespace()
 
@@ -763,13 +916,33 @@ func (p *Parser) parseGrantStatement() (Statement, error) {
 func (p *Parser) parseGrantOnStatement() (*GrantStatement, error) {
 	stmt := &GrantStatement{}
 
-	// Parse the name of the database.
+	// Parse the name of the database and, optionally, the measurement.
 	lit, err := p.parseIdent()
 	if err != nil {
 		return nil, err
 	}
 	stmt.On = lit
 
+	if tok, _, _ := p.scan(); tok == DOT {
+		if stmt.Measurement, err = p.parseIdent(); err != nil {
+			return nil, err
+		}
+
+		// Parse an optional condition restricting the grant to a set of series.
+		if tok, pos, _ := p.scanIgnoreWhitespace(); tok == WHERE {
+			if stmt.Condition, err = p.ParseExpr(); err != nil {
+				return nil, err
+			}
+			if !isGrantCondition(stmt.Condition) {
+				return nil, &ParseError{Message: "grant conditions may only compare tags to strings or regular expressions", Pos: pos}
+			}
+		} else {
+			p.unscan()
+		}
+	} else {
+		p.unscan()
+	}
+
 	// Parse TO clause.
 	tok, pos, lit := p.scanIgnoreWhitespace()
 
@@ -788,6 +961,32 @@ func (p *Parser) parseGrantOnStatement() (*GrantStatement, error) {
 	return stmt, nil
 }
 
+// isGrantCondition returns true if expr only compares tags to strings or
+// regular expressions, combined with AND and OR.
+func isGrantCondition(expr Expr) bool {
+	switch expr := expr.(type) {
+	case *ParenExpr:
+		return isGrantCondition(expr.Expr)
+	case *BinaryExpr:
+		switch expr.Op {
+		case AND, OR:
+			return isGrantCondition(expr.LHS) && isGrantCondition(expr.RHS)
+		case EQ, NEQ, EQREGEX, NEQREGEX:
+			ref, ok := expr.LHS.(*VarRef)
+			if !ok || strings.ToLower(ref.Val) == "time" {
+				return false
+			}
+			switch expr.RHS.(type) {
+			case *StringLiteral:
+				return expr.Op == EQ || expr.Op == NEQ
+			case *RegexLiteral:
+				return expr.Op == EQREGEX || expr.Op == NEQREGEX
+			}
+		}
+	}
+	return false
+}
+
 // parseGrantAdminStatement parses a string and returns a grant admin statement.
 // This function assumes the ALL [PRVILEGES] TO tokens have already been consumed.
 func (p *Parser) parseGrantAdminStatement() (*GrantAdminStatement, error) {
@@ -847,6 +1046,11 @@ func (p *Parser) parseSelectStatement(tr targetRequirement) (*SelectStatement, e
 		return nil, err
 	}
 
+	// Parse join: "JOIN <source> ON <tag>+".
+	if stmt.Sources, stmt.JoinOn, err = p.parseJoin(stmt.Sources); err != nil {
+		return nil, err
+	}
+
 	// Parse condition: "WHERE EXPR".
 	if stmt.Condition, err = p.parseCondition(); err != nil {
 		return nil, err
@@ -887,6 +1091,16 @@ func (p *Parser) parseSelectStatement(tr targetRequirement) (*SelectStatement, e
 		return nil, err
 	}
 
+	// Parse time zone: "tz('<name>')".
+	if stmt.Location, err = p.parseLocation(); err != nil {
+		return nil, err
+	}
+
+	// Aggregate the fields of a join over each window.
+	if len(stmt.JoinOn) > 0 {
+		stmt.rewriteJoinFields()
+	}
+
 	// Set if the query is a raw data query or one with an aggregate
 	stmt.IsRawQuery = true
 	WalkFunc(stmt.Fields, func(n Node) {
@@ -954,6 +1168,34 @@ func (p *Parser) parseTarget(tr targetRequirement) (*Target, error) {
 	return t, nil
 }
 
+// parseExplainStatement parses a string and returns an ExplainStatement.
+// This function assumes the EXPLAIN token has already been consumed.
+func (p *Parser) parseExplainStatement() (*ExplainStatement, error) {
+	stmt := &ExplainStatement{}
+
+	// Check for the optional ANALYZE keyword.
+	if tok, _, lit := p.scanIgnoreWhitespace(); tok == IDENT && strings.ToLower(lit) == "analyze" {
+		stmt.Analyze = true
+	} else {
+		p.unscan()
+	}
+
+	// Expect a SELECT statement that doesn't write its results.
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok != SELECT {
+		return nil, newParseError(tokstr(tok, lit), []string{"SELECT"}, pos)
+	}
+	sel, err := p.parseSelectStatement(targetNotRequired)
+	if err != nil {
+		return nil, err
+	} else if sel.Target != nil {
+		return nil, &ParseError{Message: "EXPLAIN does not support SELECT INTO", Pos: pos}
+	}
+	stmt.Statement = sel
+
+	return stmt, nil
+}
+
 // parseDeleteStatement parses a delete string and returns a DeleteStatement.
 // This function assumes the DELETE token has already been consumed.
 func (p *Parser) parseDeleteStatement() (*DeleteStatement, error) {
@@ -1231,6 +1473,12 @@ func (p *Parser) parseShowUsersStatement() (*ShowUsersStatement, error) {
 	return &ShowUsersStatement{}, nil
 }
 
+// parseShowTokensStatement parses a string and returns a ShowTokensStatement.
+// This function assumes the "SHOW TOKENS" tokens have been consumed.
+func (p *Parser) parseShowTokensStatement() (*ShowTokensStatement, error) {
+	return &ShowTokensStatement{}, nil
+}
+
 // parseShowSubscriptionsStatement parses a string and returns a ShowSubscriptionsStatement
 // This function assumes the "SHOW SUBSCRIPTIONS" tokens have been consumed.
 func (p *Parser) parseShowSubscriptionsStatement() (*ShowSubscriptionsStatement, error) {
@@ -1238,6 +1486,12 @@ func (p *Parser) parseShowSubscriptionsStatement() (*ShowSubscriptionsStatement,
 	return stmt, nil
 }
 
+// parseShowDownsamplesStatement parses a string and returns a ShowDownsamplesStatement.
+// This function assumes the "SHOW DOWNSAMPLES" tokens have been consumed.
+func (p *Parser) parseShowDownsamplesStatement() (*ShowDownsamplesStatement, error) {
+	return &ShowDownsamplesStatement{}, nil
+}
+
 // parseShowFieldKeysStatement parses a string and returns a ShowSeriesStatement.
 // This function assumes the "SHOW FIELD KEYS" tokens have already been consumed.
 func (p *Parser) parseShowFieldKeysStatement() (*ShowFieldKeysStatement, error) {
@@ -1339,6 +1593,35 @@ func (p *Parser) parseDropServerStatement(tok Token) (*DropServerStatement, erro
 	return s, nil
 }
 
+// parseHintedHandoffNodeStatement parses a string and returns a
+// PurgeHintedHandoffStatement, PauseHintedHandoffStatement or
+// ResumeHintedHandoffStatement.
+// This function assumes the PURGE, PAUSE or RESUME identifier has already been consumed.
+func (p *Parser) parseHintedHandoffNodeStatement(name string) (Statement, error) {
+	if err := p.parseIdentWords([]string{"hinted", "handoff"}); err != nil {
+		return nil, err
+	} else if tok, pos, lit := p.scanIgnoreWhitespace(); tok != FOR {
+		return nil, newParseError(tokstr(tok, lit), []string{"FOR"}, pos)
+	} else if err := p.parseIdentWords([]string{"node"}); err != nil {
+		return nil, err
+	}
+
+	// Parse the node's ID.
+	nodeID, err := p.parseUInt64()
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "purge":
+		return &PurgeHintedHandoffStatement{NodeID: nodeID}, nil
+	case "pause":
+		return &PauseHintedHandoffStatement{NodeID: nodeID}, nil
+	default:
+		return &ResumeHintedHandoffStatement{NodeID: nodeID}, nil
+	}
+}
+
 // parseShowContinuousQueriesStatement parses a string and returns a ShowContinuousQueriesStatement.
 // This function assumes the "SHOW CONTINUOUS" tokens have already been consumed.
 func (p *Parser) parseShowContinuousQueriesStatement() (*ShowContinuousQueriesStatement, error) {
@@ -1599,6 +1882,20 @@ func (p *Parser) parseDropSubscriptionStatement() (*DropSubscriptionStatement, e
 	return stmt, nil
 }
 
+// parseDropDownsampleStatement parses a string and returns a DropDownsampleStatement.
+// This function assumes the "DROP DOWNSAMPLE" tokens have already been consumed.
+func (p *Parser) parseDropDownsampleStatement() (*DropDownsampleStatement, error) {
+	stmt := &DropDownsampleStatement{}
+
+	var err error
+	stmt.Database, stmt.RetentionPolicy, stmt.TargetDatabase, stmt.TargetRetentionPolicy, err = p.parseDownsampleRetentionPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
 // parseDropRetentionPolicyStatement parses a string and returns a DropRetentionPolicyStatement.
 // This function assumes the DROP RETENTION POLICY tokens have been consumed.
 func (p *Parser) parseDropRetentionPolicyStatement() (*DropRetentionPolicyStatement, error) {
@@ -1678,6 +1975,72 @@ func (p *Parser) parseDropUserStatement() (*DropUserStatement, error) {
 	return stmt, nil
 }
 
+// parseCreateTokenStatement parses a string and returns a CreateTokenStatement.
+// This function assumes the "CREATE TOKEN" tokens have already been consumed.
+func (p *Parser) parseCreateTokenStatement() (*CreateTokenStatement, error) {
+	stmt := &CreateTokenStatement{}
+
+	// Parse name of the token to be created.
+	ident, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = ident
+
+	// Parse the user the token authenticates as.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != FOR {
+		return nil, newParseError(tokstr(tok, lit), []string{"FOR"}, pos)
+	}
+	if stmt.User, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+
+	// Check for optional WITH clause scoping the token's privileges.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok != WITH {
+		p.unscan()
+		return stmt, nil
+	}
+
+	for {
+		priv, err := p.parsePrivilege()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != ON {
+			return nil, newParseError(tokstr(tok, lit), []string{"ON"}, pos)
+		}
+
+		db, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Privileges = append(stmt.Privileges, TokenPrivilege{Database: db, Privilege: priv})
+
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok != COMMA {
+			p.unscan()
+			break
+		}
+	}
+
+	return stmt, nil
+}
+
+// parseDropTokenStatement parses a string and returns a DropTokenStatement.
+// This function assumes the DROP TOKEN tokens have already been consumed.
+func (p *Parser) parseDropTokenStatement() (*DropTokenStatement, error) {
+	stmt := &DropTokenStatement{}
+
+	// Parse the name of the token to be dropped.
+	lit, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = lit
+
+	return stmt, nil
+}
+
 // parseRetentionPolicy parses a string and returns a retention policy name.
 // This function assumes the "WITH" token has already been consumed.
 func (p *Parser) parseRetentionPolicy() (name string, dfault bool, err error) {
@@ -1781,6 +2144,45 @@ func (p *Parser) parseDropContinuousQueryStatement() (*DropContinuousQueryStatem
 	return stmt, nil
 }
 
+// parseRunContinuousQueryStatement parses a string and returns a RunContinuousQueryStatement.
+// This function assumes the "RUN" identifier has already been consumed.
+func (p *Parser) parseRunContinuousQueryStatement() (*RunContinuousQueryStatement, error) {
+	stmt := &RunContinuousQueryStatement{}
+
+	// Expect "CONTINUOUS QUERY" tokens.
+	if err := p.parseTokens([]Token{CONTINUOUS, QUERY}); err != nil {
+		return nil, err
+	}
+
+	// Read the id of the query to run.
+	ident, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = ident
+
+	// Expect an "ON" keyword.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != ON {
+		return nil, newParseError(tokstr(tok, lit), []string{"ON"}, pos)
+	}
+
+	// Read the name of the database the query belongs to.
+	if ident, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+	stmt.Database = ident
+
+	// Expect a "FOR" keyword followed by the time range to recompute.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != FOR {
+		return nil, newParseError(tokstr(tok, lit), []string{"FOR"}, pos)
+	}
+	if stmt.Condition, err = p.ParseExpr(); err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
 // parseFields parses a list of one or more fields.
 func (p *Parser) parseFields() (Fields, error) {
 	var fields Fields
@@ -1895,6 +2297,38 @@ func (p *Parser) parseSources() (Sources, error) {
 	return sources, nil
 }
 
+// parseJoin parses a "JOIN <source> ON <tag>+" clause, if it exists, and
+// returns the joined sources and the tags they are joined on.
+func (p *Parser) parseJoin(sources Sources) (Sources, []string, error) {
+	// Check if the clause exists.
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok != IDENT || strings.ToLower(lit) != "join" {
+		p.unscan()
+		return sources, nil, nil
+	}
+
+	// Only two measurements can be joined.
+	if len(sources) != 1 || sources[0].(*Measurement).Regex != nil {
+		return nil, nil, &ParseError{Message: "JOIN requires a single measurement on each side", Pos: pos}
+	}
+	src, err := p.parseSource()
+	if err != nil {
+		return nil, nil, err
+	} else if src.(*Measurement).Regex != nil {
+		return nil, nil, &ParseError{Message: "JOIN requires a single measurement on each side", Pos: pos}
+	}
+
+	// Parse the tags to join on.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != ON {
+		return nil, nil, newParseError(tokstr(tok, lit), []string{"ON"}, pos)
+	}
+	on, err := p.parseIdentList()
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(sources, src), on, nil
+}
+
 // peekRune returns the next rune that would be read by the scanner.
 func (p *Parser) peekRune() rune {
 	r, _, _ := p.s.s.r.ReadRune()
@@ -2022,6 +2456,13 @@ func (p *Parser) parseDimension() (*Dimension, error) {
 
 // parseFill parses the fill call and its options.
 func (p *Parser) parseFill() (FillOption, interface{}, error) {
+	// Check if the fill call exists, so a following clause isn't consumed.
+	if tok, _, lit := p.scanIgnoreWhitespace(); tok != IDENT || strings.ToLower(lit) != "fill" {
+		p.unscan()
+		return NullFill, nil, nil
+	}
+	p.unscan()
+
 	// Parse the expression first.
 	expr, err := p.ParseExpr()
 	if err != nil {
@@ -2038,7 +2479,7 @@ func (p *Parser) parseFill() (FillOption, interface{}, error) {
 		return NullFill, nil, nil
 	}
 	if len(lit.Args) != 1 {
-		return NullFill, nil, errors.New("fill requires an argument, e.g.: 0, null, none, previous")
+		return NullFill, nil, errors.New("fill requires an argument, e.g.: 0, null, none, previous, linear")
 	}
 	switch lit.Args[0].String() {
 	case "null":
@@ -2047,6 +2488,8 @@ func (p *Parser) parseFill() (FillOption, interface{}, error) {
 		return NoFill, nil, nil
 	case "previous":
 		return PreviousFill, nil, nil
+	case "linear":
+		return LinearFill, nil, nil
 	default:
 		num, ok := lit.Args[0].(*NumberLiteral)
 		if !ok {
@@ -2056,6 +2499,34 @@ func (p *Parser) parseFill() (FillOption, interface{}, error) {
 	}
 }
 
+// parseLocation parses the time zone of a "tz('<name>')" clause, if it exists.
+func (p *Parser) parseLocation() (*time.Location, error) {
+	// Check if the clause exists.
+	if tok, _, lit := p.scanIgnoreWhitespace(); tok != IDENT || strings.ToLower(lit) != "tz" {
+		p.unscan()
+		return nil, nil
+	}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != LPAREN {
+		return nil, newParseError(tokstr(tok, lit), []string{"("}, pos)
+	}
+
+	// Load the named time zone.
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok != STRING {
+		return nil, newParseError(tokstr(tok, lit), []string{"string"}, pos)
+	}
+	loc, err := time.LoadLocation(lit)
+	if err != nil {
+		return nil, &ParseError{Message: fmt.Sprintf("unable to find time zone %s", lit), Pos: pos}
+	}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != RPAREN {
+		return nil, newParseError(tokstr(tok, lit), []string{")"}, pos)
+	}
+	return loc, nil
+}
+
 // parseOptionalTokenAndInt parses the specified token followed
 // by an int, if it exists.
 func (p *Parser) parseOptionalTokenAndInt(t Token) (int, error) {
@@ -2067,7 +2538,16 @@ func (p *Parser) parseOptionalTokenAndInt(t Token) (int, error) {
 
 	// Scan the number.
 	tok, pos, lit := p.scanIgnoreWhitespace()
-	if tok != NUMBER {
+	if tok == BOUNDPARAM {
+		n, err := intParam(p.params, lit)
+		if err != nil {
+			return 0, &ParseError{Message: err.Error(), Pos: pos}
+		} else if n < 0 {
+			msg := fmt.Sprintf("%s must be >= 0", t.String())
+			return 0, &ParseError{Message: msg, Pos: pos}
+		}
+		return int(n), nil
+	} else if tok != NUMBER {
 		return 0, newParseError(tokstr(tok, lit), []string{"number"}, pos)
 	}
 
@@ -2222,13 +2702,19 @@ func (p *Parser) ParseExpr() (Expr, error) {
 		// Otherwise parse the next expression.
 		var rhs Expr
 		if IsRegexOp(op) {
-			// RHS of a regex operator must be a regular expression.
+			// RHS of a regex operator must be a regular expression
+			// or a bound parameter.
 			p.consumeWhitespace()
-			if rhs, err = p.parseRegex(); err != nil {
+			if p.peekRune() == '$' {
+				tok, pos, lit := p.scanIgnoreWhitespace()
+				if tok != BOUNDPARAM {
+					return nil, newParseError(tokstr(tok, lit), []string{"regex"}, pos)
+				}
+				rhs = &BoundParameter{Name: lit}
+			} else if rhs, err = p.parseRegex(); err != nil {
 				return nil, err
-			}
-			// parseRegex can return an empty type, but we need it to be present
-			if rhs.(*RegexLiteral) == nil {
+			} else if rhs.(*RegexLiteral) == nil {
+				// parseRegex can return an empty type, but we need it to be present
 				tok, pos, lit := p.scanIgnoreWhitespace()
 				return nil, newParseError(tokstr(tok, lit), []string{"regex"}, pos)
 			}
@@ -2342,6 +2828,8 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 			return nil, &ParseError{Message: err.Error(), Pos: pos}
 		}
 		return &RegexLiteral{Val: re}, nil
+	case BOUNDPARAM:
+		return &BoundParameter{Name: lit}, nil
 	default:
 		return nil, newParseError(tokstr(tok, lit), []string{"identifier", "string", "number", "bool"}, pos)
 	}
@@ -2558,6 +3046,18 @@ func (p *Parser) parseTokens(toks []Token) error {
 	return nil
 }
 
+// parseIdentWords consumes an expected sequence of identifiers. The words are
+// matched case insensitively so statements can use them without reserving them
+// as keywords.
+func (p *Parser) parseIdentWords(words []string) error {
+	for _, expected := range words {
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != IDENT || strings.ToLower(lit) != expected {
+			return newParseError(tokstr(tok, lit), []string{strings.ToUpper(expected)}, pos)
+		}
+	}
+	return nil
+}
+
 // parseTokenMaybe consumes the next token if it matches the expected one and
 // does nothing if the next token is not the next one.
 func (p *Parser) parseTokenMaybe(expected Token) bool {
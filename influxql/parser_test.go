@@ -239,6 +239,59 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// SELECT statement with a time zone
+		{
+			s: `SELECT mean(value) FROM cpu WHERE time > now() - 7d GROUP BY time(1d) tz('America/New_York')`,
+			stmt: &influxql.SelectStatement{
+				Fields: []*influxql.Field{{
+					Expr: &influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "value"}}},
+				}},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.GT,
+					LHS: &influxql.VarRef{Val: "time"},
+					RHS: &influxql.BinaryExpr{
+						Op:  influxql.SUB,
+						LHS: &influxql.Call{Name: "now"},
+						RHS: &influxql.DurationLiteral{Val: 7 * 24 * time.Hour},
+					},
+				},
+				Dimensions: []*influxql.Dimension{{
+					Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{&influxql.DurationLiteral{Val: 24 * time.Hour}}},
+				}},
+				Location: LoadLocation("America/New_York"),
+			},
+		},
+
+		// SELECT statement with a join
+		{
+			s: `SELECT mem.used / sum(disk.free) FROM mem JOIN disk ON host, region WHERE time > now() - 1h GROUP BY time(1m)`,
+			stmt: &influxql.SelectStatement{
+				Fields: []*influxql.Field{{
+					Expr: &influxql.BinaryExpr{
+						Op:  influxql.DIV,
+						LHS: &influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "mem.used"}}},
+						RHS: &influxql.Call{Name: "sum", Args: []influxql.Expr{&influxql.VarRef{Val: "disk.free"}}},
+					},
+					Alias: "mem.used_sum",
+				}},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "mem"}, &influxql.Measurement{Name: "disk"}},
+				JoinOn:  []string{"host", "region"},
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.GT,
+					LHS: &influxql.VarRef{Val: "time"},
+					RHS: &influxql.BinaryExpr{
+						Op:  influxql.SUB,
+						LHS: &influxql.Call{Name: "now"},
+						RHS: &influxql.DurationLiteral{Val: time.Hour},
+					},
+				},
+				Dimensions: []*influxql.Dimension{{
+					Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{&influxql.DurationLiteral{Val: time.Minute}}},
+				}},
+			},
+		},
+
 		// SELECT * FROM cpu WHERE host = 'serverC' AND region =~ /.*west.*/
 		{
 			s: `SELECT * FROM cpu WHERE host = 'serverC' AND region =~ /.*west.*/`,
@@ -622,6 +675,28 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// SELECT statement with linear fill and a time offset
+		{
+			s: fmt.Sprintf(`SELECT mean(value) FROM cpu where time < '%s' GROUP BY time(1h, 15m) FILL(linear)`, now.UTC().Format(time.RFC3339Nano)),
+			stmt: &influxql.SelectStatement{
+				Fields: []*influxql.Field{{
+					Expr: &influxql.Call{
+						Name: "mean",
+						Args: []influxql.Expr{&influxql.VarRef{Val: "value"}}}}},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.LT,
+					LHS: &influxql.VarRef{Val: "time"},
+					RHS: &influxql.TimeLiteral{Val: now.UTC()},
+				},
+				Dimensions: []*influxql.Dimension{{Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{
+					&influxql.DurationLiteral{Val: time.Hour},
+					&influxql.DurationLiteral{Val: 15 * time.Minute},
+				}}}},
+				Fill: influxql.LinearFill,
+			},
+		},
+
 		// See issues https://github.com/freetsdb/freetsdb/issues/1647
 		// and https://github.com/freetsdb/freetsdb/issues/4404
 		// DELETE statement
@@ -959,6 +1034,12 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.ShowUsersStatement{},
 		},
 
+		// SHOW TOKENS
+		{
+			s:    `SHOW TOKENS`,
+			stmt: &influxql.ShowTokensStatement{},
+		},
+
 		// SHOW FIELD KEYS
 		{
 			skip: true,
@@ -1021,6 +1102,42 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.DropServerStatement{NodeID: 123, Meta: false},
 		},
 
+		// SHOW HINTED HANDOFF statement
+		{
+			s:    `SHOW HINTED HANDOFF`,
+			stmt: &influxql.ShowHintedHandoffStatement{},
+		},
+
+		// PURGE, PAUSE and RESUME HINTED HANDOFF statements
+		{
+			s:    `PURGE HINTED HANDOFF FOR NODE 2`,
+			stmt: &influxql.PurgeHintedHandoffStatement{NodeID: 2},
+		},
+		{
+			s:    `PAUSE HINTED HANDOFF FOR NODE 2`,
+			stmt: &influxql.PauseHintedHandoffStatement{NodeID: 2},
+		},
+		{
+			s:    `RESUME HINTED HANDOFF FOR NODE 2`,
+			stmt: &influxql.ResumeHintedHandoffStatement{NodeID: 2},
+		},
+
+		// Hinted handoff words are not reserved
+		{
+			s: `SELECT node FROM cpu WHERE hinted = 'a' GROUP BY node`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: true,
+				Fields:     []*influxql.Field{{Expr: &influxql.VarRef{Val: "node"}}},
+				Sources:    []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.EQ,
+					LHS: &influxql.VarRef{Val: "hinted"},
+					RHS: &influxql.StringLiteral{Val: "a"},
+				},
+				Dimensions: []*influxql.Dimension{{Expr: &influxql.VarRef{Val: "node"}}},
+			},
+		},
+
 		// SHOW CONTINUOUS QUERIES statement
 		{
 			s:    `SHOW CONTINUOUS QUERIES`,
@@ -1342,6 +1459,54 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.DropContinuousQueryStatement{Name: "myquery", Database: "foo"},
 		},
 
+		// RUN CONTINUOUS QUERY statement
+		{
+			s: `RUN CONTINUOUS QUERY myquery ON foo FOR time >= '2000-01-01T00:00:00Z' AND time < '2000-01-02T00:00:00Z'`,
+			stmt: &influxql.RunContinuousQueryStatement{
+				Name:      "myquery",
+				Database:  "foo",
+				Condition: MustParseExpr(`time >= '2000-01-01T00:00:00Z' AND time < '2000-01-02T00:00:00Z'`),
+			},
+		},
+
+		// RUN is not reserved
+		{
+			s: `SELECT run FROM cpu WHERE run = 'a'`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: true,
+				Fields:     []*influxql.Field{{Expr: &influxql.VarRef{Val: "run"}}},
+				Sources:    []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.EQ,
+					LHS: &influxql.VarRef{Val: "run"},
+					RHS: &influxql.StringLiteral{Val: "a"},
+				},
+			},
+		},
+
+		// EXPLAIN statement
+		{
+			s: `EXPLAIN SELECT value FROM cpu`,
+			stmt: &influxql.ExplainStatement{
+				Statement: &influxql.SelectStatement{
+					IsRawQuery: true,
+					Fields:     []*influxql.Field{{Expr: &influxql.VarRef{Val: "value"}}},
+					Sources:    []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				},
+			},
+		},
+		{
+			s: `explain analyze SELECT value FROM cpu`,
+			stmt: &influxql.ExplainStatement{
+				Statement: &influxql.SelectStatement{
+					IsRawQuery: true,
+					Fields:     []*influxql.Field{{Expr: &influxql.VarRef{Val: "value"}}},
+					Sources:    []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				},
+				Analyze: true,
+			},
+		},
+
 		// DROP DATABASE statement
 		{
 			s: `DROP DATABASE testdb`,
@@ -1379,6 +1544,46 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.DropUserStatement{Name: "jdoe"},
 		},
 
+		// CREATE TOKEN statement
+		{
+			s:    `CREATE TOKEN grafana FOR jdoe`,
+			stmt: &influxql.CreateTokenStatement{Name: "grafana", User: "jdoe"},
+		},
+
+		// CREATE TOKEN statement with scoped privileges
+		{
+			s: `CREATE TOKEN telegraf FOR jdoe WITH WRITE ON db0, READ ON db1`,
+			stmt: &influxql.CreateTokenStatement{
+				Name: "telegraf",
+				User: "jdoe",
+				Privileges: []influxql.TokenPrivilege{
+					{Database: "db0", Privilege: influxql.WritePrivilege},
+					{Database: "db1", Privilege: influxql.ReadPrivilege},
+				},
+			},
+		},
+
+		// DROP TOKEN statement
+		{
+			s:    `DROP TOKEN grafana`,
+			stmt: &influxql.DropTokenStatement{Name: "grafana"},
+		},
+
+		// TOKEN and TOKENS are not reserved
+		{
+			s: `SELECT token FROM cpu WHERE tokens = 'a'`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: true,
+				Fields:     []*influxql.Field{{Expr: &influxql.VarRef{Val: "token"}}},
+				Sources:    []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.EQ,
+					LHS: &influxql.VarRef{Val: "tokens"},
+					RHS: &influxql.StringLiteral{Val: "a"},
+				},
+			},
+		},
+
 		// GRANT READ
 		{
 			s: `GRANT READ ON testdb TO jdoe`,
@@ -1419,6 +1624,29 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// GRANT READ on a measurement
+		{
+			s: `GRANT READ ON testdb.cpu TO jdoe`,
+			stmt: &influxql.GrantStatement{
+				Privilege:   influxql.ReadPrivilege,
+				On:          "testdb",
+				Measurement: "cpu",
+				User:        "jdoe",
+			},
+		},
+
+		// GRANT WRITE on a set of series
+		{
+			s: `GRANT WRITE ON testdb.cpu WHERE team = 'a' OR host =~ /^web/ TO jdoe`,
+			stmt: &influxql.GrantStatement{
+				Privilege:   influxql.WritePrivilege,
+				On:          "testdb",
+				Measurement: "cpu",
+				Condition:   MustParseExpr(`team = 'a' OR host =~ /^web/`),
+				User:        "jdoe",
+			},
+		},
+
 		// GRANT ALL admin privilege
 		{
 			s: `GRANT ALL TO jdoe`,
@@ -1445,6 +1673,17 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// REVOKE READ on a measurement
+		{
+			s: `REVOKE READ ON testdb.cpu FROM jdoe`,
+			stmt: &influxql.RevokeStatement{
+				Privilege:   influxql.ReadPrivilege,
+				On:          "testdb",
+				Measurement: "cpu",
+				User:        "jdoe",
+			},
+		},
+
 		// REVOKE WRITE
 		{
 			s: `REVOKE WRITE ON testdb FROM jdoe`,
@@ -1632,17 +1871,89 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.ShowSubscriptionsStatement{},
 		},
 
+		// CREATE DOWNSAMPLE
+		{
+			s: `CREATE DOWNSAMPLE ON "db"."rp" TO "db"."rp_5m" EVERY 5m AGGREGATE mean, MAX, count`,
+			stmt: &influxql.CreateDownsampleStatement{
+				Database:              "db",
+				RetentionPolicy:       "rp",
+				TargetDatabase:        "db",
+				TargetRetentionPolicy: "rp_5m",
+				Interval:              5 * time.Minute,
+				Aggregates:            []string{"mean", "max", "count"},
+			},
+		},
+
+		// CREATE DOWNSAMPLE with an unqualified target
+		{
+			s: `CREATE DOWNSAMPLE ON db.rp TO rp_1h EVERY 1h AGGREGATE max`,
+			stmt: &influxql.CreateDownsampleStatement{
+				Database:              "db",
+				RetentionPolicy:       "rp",
+				TargetDatabase:        "db",
+				TargetRetentionPolicy: "rp_1h",
+				Interval:              time.Hour,
+				Aggregates:            []string{"max"},
+			},
+		},
+
+		// DROP DOWNSAMPLE
+		{
+			s: `DROP DOWNSAMPLE ON "db"."rp" TO "other"."rp_5m"`,
+			stmt: &influxql.DropDownsampleStatement{
+				Database:              "db",
+				RetentionPolicy:       "rp",
+				TargetDatabase:        "other",
+				TargetRetentionPolicy: "rp_5m",
+			},
+		},
+
+		// SHOW DOWNSAMPLES
+		{
+			s:    `SHOW DOWNSAMPLES`,
+			stmt: &influxql.ShowDownsamplesStatement{},
+		},
+
+		// Downsample words are not reserved
+		{
+			s: `SELECT aggregate FROM downsample WHERE downsamples = 'a'`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: true,
+				Fields:     []*influxql.Field{{Expr: &influxql.VarRef{Val: "aggregate"}}},
+				Sources:    []influxql.Source{&influxql.Measurement{Name: "downsample"}},
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.EQ,
+					LHS: &influxql.VarRef{Val: "downsamples"},
+					RHS: &influxql.StringLiteral{Val: "a"},
+				},
+			},
+		},
+
 		// Errors
-		{s: ``, err: `found EOF, expected SELECT, DELETE, SHOW, CREATE, DROP, GRANT, REVOKE, ALTER, SET at line 1, char 1`},
+		{s: ``, err: `found EOF, expected SELECT, DELETE, EXPLAIN, SHOW, CREATE, DROP, GRANT, REVOKE, ALTER, SET, RUN, PURGE, PAUSE, RESUME at line 1, char 1`},
 		{s: `SELECT`, err: `found EOF, expected identifier, string, number, bool at line 1, char 8`},
 		{s: `SELECT time FROM myseries`, err: `at least 1 non-time field must be queried`},
-		{s: `blah blah`, err: `found blah, expected SELECT, DELETE, SHOW, CREATE, DROP, GRANT, REVOKE, ALTER, SET at line 1, char 1`},
+		{s: `blah blah`, err: `found blah, expected SELECT, DELETE, EXPLAIN, SHOW, CREATE, DROP, GRANT, REVOKE, ALTER, SET, RUN, PURGE, PAUSE, RESUME at line 1, char 1`},
 		{s: `SELECT field1 X`, err: `found X, expected FROM at line 1, char 15`},
 		{s: `SELECT field1 FROM "series" WHERE X +;`, err: `found ;, expected identifier, string, number, bool at line 1, char 38`},
 		{s: `SELECT field1 FROM myseries GROUP`, err: `found EOF, expected BY at line 1, char 35`},
 		{s: `SELECT field1 FROM myseries LIMIT`, err: `found EOF, expected number at line 1, char 35`},
 		{s: `SELECT field1 FROM myseries LIMIT 10.5`, err: `fractional parts not allowed in LIMIT at line 1, char 35`},
+		{s: `SELECT field1 FROM myseries tz`, err: `found EOF, expected ( at line 1, char 32`},
+		{s: `SELECT field1 FROM myseries tz(America)`, err: `found America, expected string at line 1, char 32`},
+		{s: `SELECT field1 FROM myseries tz('Nowhere/Land')`, err: `unable to find time zone Nowhere/Land at line 1, char 31`},
+		{s: `SELECT mem.used FROM mem JOIN disk ON host`, err: `JOIN requires GROUP BY time()`},
+		{s: `SELECT used FROM mem JOIN disk ON host WHERE time > now() - 1h GROUP BY time(1m)`, err: `field used must be qualified by a joined measurement`},
+		{s: `SELECT mem.used FROM mem, cpu JOIN disk ON host`, err: `JOIN requires a single measurement on each side at line 1, char 31`},
+		{s: `SELECT mem.used FROM mem JOIN /disk/ ON host`, err: `JOIN requires a single measurement on each side at line 1, char 26`},
+		{s: `SELECT mem.used FROM mem JOIN disk host`, err: `found host, expected ON at line 1, char 36`},
 		{s: `SELECT top() FROM myseries`, err: `invalid number of arguments for top, expected at least 2, got 0`},
+		{s: `SELECT percentile_approx(field1) FROM myseries`, err: `invalid number of arguments for percentile_approx, expected 2, got 1`},
+		{s: `SELECT percentile_approx(field1, foo) FROM myseries`, err: `expected float argument in percentile_approx()`},
+		{s: `SELECT histogram(field1, 10) FROM myseries`, err: `invalid number of arguments for histogram, expected at least 3, got 2`},
+		{s: `SELECT histogram(field1, 0, foo) FROM myseries`, err: `expected number as bucket boundary in histogram(), found foo`},
+		{s: `SELECT histogram(field1, 10, 5) FROM myseries`, err: `bucket boundaries in histogram() must be in ascending order`},
+		{s: `SELECT histogram(field1, 0, 10), mean(field1) FROM myseries`, err: `aggregate function histogram() can not be combined with other functions or fields`},
 		{s: `SELECT top(field1) FROM myseries`, err: `invalid number of arguments for top, expected at least 2, got 1`},
 		{s: `SELECT top(field1,foo) FROM myseries`, err: `expected integer as last argument in top(), found foo`},
 		{s: `SELECT top(field1,host,'server',foo) FROM myseries`, err: `expected integer as last argument in top(), found foo`},
@@ -1675,8 +1986,10 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SELECT count(value) FROM foo group by time(1s) where host = 'hosta.influxdb.org'`, err: `aggregate functions with GROUP BY time require a WHERE time clause`},
 		{s: `SELECT count(value) FROM foo group by time`, err: `time() is a function and expects at least one argument`},
 		{s: `SELECT count(value) FROM foo group by 'time'`, err: `only time and tag dimensions allowed`},
-		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time()`, err: `time dimension expected one argument`},
+		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time()`, err: `time dimension expected 1 or 2 arguments`},
 		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time(b)`, err: `time dimension must have one duration argument`},
+		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time(1h, b)`, err: `time dimension offset must be a duration`},
+		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time(1h, 15m, 5m)`, err: `time dimension expected 1 or 2 arguments`},
 		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time(1s), time(2s)`, err: `multiple time dimensions not allowed`},
 		{s: `SELECT field1 FROM 12`, err: `found 12, expected identifier at line 1, char 20`},
 		{s: `SELECT 1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000 FROM myseries`, err: `unable to parse number at line 1, char 8`},
@@ -1742,7 +2055,7 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SHOW RETENTION POLICIES mydb`, err: `found mydb, expected ON at line 1, char 25`},
 		{s: `SHOW RETENTION POLICIES ON`, err: `found EOF, expected identifier at line 1, char 28`},
 		{s: `SHOW SHARD`, err: `found EOF, expected GROUPS at line 1, char 12`},
-		{s: `SHOW FOO`, err: `found FOO, expected CONTINUOUS, DATABASES, DIAGNOSTICS, FIELD, GRANTS, MEASUREMENTS, RETENTION, SERIES, SERVERS, SHARD, SHARDS, STATS, SUBSCRIPTIONS, TAG, USERS at line 1, char 6`},
+		{s: `SHOW FOO`, err: `found FOO, expected CONTINUOUS, DATABASES, DIAGNOSTICS, DOWNSAMPLES, FIELD, GRANTS, HINTED, MEASUREMENTS, RETENTION, SERIES, SERVERS, SHARD, SHARDS, STATS, SUBSCRIPTIONS, TAG, TOKENS, USERS at line 1, char 6`},
 		{s: `SHOW STATS FOR`, err: `found EOF, expected string at line 1, char 16`},
 		{s: `SHOW DIAGNOSTICS FOR`, err: `found EOF, expected string at line 1, char 22`},
 		{s: `SHOW GRANTS`, err: `found EOF, expected FOR at line 1, char 13`},
@@ -1751,12 +2064,23 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `DROP CONTINUOUS QUERY`, err: `found EOF, expected identifier at line 1, char 23`},
 		{s: `DROP CONTINUOUS QUERY myquery`, err: `found EOF, expected ON at line 1, char 31`},
 		{s: `DROP CONTINUOUS QUERY myquery ON`, err: `found EOF, expected identifier at line 1, char 34`},
+		{s: `RUN`, err: `found EOF, expected CONTINUOUS at line 1, char 5`},
+		{s: `SHOW HINTED`, err: `found EOF, expected HANDOFF at line 1, char 13`},
+		{s: `PURGE HINTED HANDOFF`, err: `found EOF, expected FOR at line 1, char 22`},
+		{s: `PAUSE HINTED HANDOFF FOR NODE`, err: `found EOF, expected number at line 1, char 31`},
+		{s: `RESUME HINTED HANDOFF FOR node1`, err: `found node1, expected NODE at line 1, char 27`},
+		{s: `RUN CONTINUOUS QUERY myquery`, err: `found EOF, expected ON at line 1, char 30`},
+		{s: `RUN CONTINUOUS QUERY myquery ON foo`, err: `found EOF, expected FOR at line 1, char 37`},
+		{s: `RUN CONTINUOUS QUERY myquery ON foo FOR`, err: `found EOF, expected identifier, string, number, bool at line 1, char 41`},
+		{s: `EXPLAIN`, err: `found EOF, expected SELECT at line 1, char 9`},
+		{s: `EXPLAIN ANALYZE SHOW DATABASES`, err: `found SHOW, expected SELECT at line 1, char 17`},
+		{s: `EXPLAIN SELECT value INTO cpu_copy FROM cpu`, err: `EXPLAIN does not support SELECT INTO at line 1, char 9`},
 		{s: `CREATE CONTINUOUS`, err: `found EOF, expected QUERY at line 1, char 19`},
 		{s: `CREATE CONTINUOUS QUERY`, err: `found EOF, expected identifier at line 1, char 25`},
 		{s: `CREATE CONTINUOUS QUERY cq ON db RESAMPLE FOR 5s BEGIN SELECT mean(value) INTO cpu_mean FROM cpu GROUP BY time(10s) END`, err: `FOR duration must be >= GROUP BY time duration: must be a minimum of 10s, got 5s`},
 		{s: `CREATE CONTINUOUS QUERY cq ON db RESAMPLE EVERY 10s FOR 5s BEGIN SELECT mean(value) INTO cpu_mean FROM cpu GROUP BY time(5s) END`, err: `FOR duration must be >= GROUP BY time duration: must be a minimum of 10s, got 5s`},
-		{s: `DROP FOO`, err: `found FOO, expected SERIES, CONTINUOUS, MEASUREMENT, SERVER, SUBSCRIPTION at line 1, char 6`},
-		{s: `CREATE FOO`, err: `found FOO, expected CONTINUOUS, DATABASE, USER, RETENTION, SUBSCRIPTION at line 1, char 8`},
+		{s: `DROP FOO`, err: `found FOO, expected SERIES, CONTINUOUS, MEASUREMENT, SERVER, SUBSCRIPTION, DOWNSAMPLE, TOKEN at line 1, char 6`},
+		{s: `CREATE FOO`, err: `found FOO, expected CONTINUOUS, DATABASE, USER, RETENTION, SUBSCRIPTION, DOWNSAMPLE, TOKEN at line 1, char 8`},
 		{s: `CREATE DATABASE`, err: `found EOF, expected identifier at line 1, char 17`},
 		{s: `CREATE DATABASE "testdb" WITH`, err: `found EOF, expected DURATION, REPLICATION, NAME at line 1, char 31`},
 		{s: `CREATE DATABASE "testdb" WITH DURATION`, err: `found EOF, expected duration at line 1, char 40`},
@@ -1777,6 +2101,10 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `DROP RETENTION POLICY "1h.cpu"`, err: `found EOF, expected ON at line 1, char 31`},
 		{s: `DROP RETENTION POLICY "1h.cpu" ON`, err: `found EOF, expected identifier at line 1, char 35`},
 		{s: `DROP USER`, err: `found EOF, expected identifier at line 1, char 11`},
+		{s: `DROP TOKEN`, err: `found EOF, expected identifier at line 1, char 12`},
+		{s: `CREATE TOKEN grafana`, err: `found EOF, expected FOR at line 1, char 22`},
+		{s: `CREATE TOKEN grafana FOR jdoe WITH READ`, err: `found EOF, expected ON at line 1, char 41`},
+		{s: `CREATE TOKEN grafana FOR jdoe WITH READ ON db0,`, err: `found EOF, expected READ, WRITE, ALL [PRIVILEGES] at line 1, char 48`},
 		{s: `DROP SUBSCRIPTION`, err: `found EOF, expected identifier at line 1, char 19`},
 		{s: `DROP SUBSCRIPTION "name"`, err: `found EOF, expected ON at line 1, char 25`},
 		{s: `DROP SUBSCRIPTION "name" ON `, err: `found EOF, expected identifier at line 1, char 30`},
@@ -1795,6 +2123,16 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `CREATE SUBSCRIPTION "name" ON "db"."rp"`, err: `found EOF, expected DESTINATIONS at line 1, char 40`},
 		{s: `CREATE SUBSCRIPTION "name" ON "db"."rp" DESTINATIONS`, err: `found EOF, expected ALL, ANY at line 1, char 54`},
 		{s: `CREATE SUBSCRIPTION "name" ON "db"."rp" DESTINATIONS ALL `, err: `found EOF, expected string at line 1, char 59`},
+		{s: `CREATE DOWNSAMPLE`, err: `found EOF, expected ON at line 1, char 19`},
+		{s: `CREATE DOWNSAMPLE ON "db"`, err: `found EOF, expected . at line 1, char 26`},
+		{s: `CREATE DOWNSAMPLE ON "db"."rp"`, err: `found EOF, expected TO at line 1, char 31`},
+		{s: `CREATE DOWNSAMPLE ON "db"."rp" TO "rp_5m"`, err: `found EOF, expected EVERY at line 1, char 42`},
+		{s: `CREATE DOWNSAMPLE ON "db"."rp" TO "rp_5m" EVERY 0s AGGREGATE mean`, err: `downsample interval must be greater than zero at line 1, char 49`},
+		{s: `CREATE DOWNSAMPLE ON "db"."rp" TO "rp_5m" EVERY 5m`, err: `found EOF, expected AGGREGATE at line 1, char 51`},
+		{s: `CREATE DOWNSAMPLE ON "db"."rp" TO "rp_5m" EVERY 5m AGGREGATE`, err: `found EOF, expected identifier at line 1, char 62`},
+		{s: `CREATE DOWNSAMPLE ON "db"."rp" TO "rp_5m" EVERY 5m AGGREGATE top`, err: `invalid downsample aggregate: top at line 1, char 62`},
+		{s: `CREATE DOWNSAMPLE ON "db"."rp" TO "rp_5m" EVERY 5m AGGREGATE mean, mean`, err: `duplicate downsample aggregate: mean at line 1, char 62`},
+		{s: `DROP DOWNSAMPLE ON "db"."rp"`, err: `found EOF, expected TO at line 1, char 29`},
 		{s: `GRANT`, err: `found EOF, expected READ, WRITE, ALL [PRIVILEGES] at line 1, char 7`},
 		{s: `GRANT BOGUS`, err: `found BOGUS, expected READ, WRITE, ALL [PRIVILEGES] at line 1, char 7`},
 		{s: `GRANT READ`, err: `found EOF, expected ON at line 1, char 12`},
@@ -1804,6 +2142,9 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `GRANT READ ON testdb`, err: `found EOF, expected TO at line 1, char 22`},
 		{s: `GRANT READ ON testdb TO`, err: `found EOF, expected identifier at line 1, char 25`},
 		{s: `GRANT READ TO`, err: `found TO, expected ON at line 1, char 12`},
+		{s: `GRANT READ ON testdb.`, err: `found EOF, expected identifier at line 1, char 22`},
+		{s: `GRANT READ ON testdb.cpu WHERE time > now() TO jdoe`, err: `grant conditions may only compare tags to strings or regular expressions at line 1, char 26`},
+		{s: `GRANT READ ON testdb.cpu WHERE value > 1 TO jdoe`, err: `grant conditions may only compare tags to strings or regular expressions at line 1, char 26`},
 		{s: `GRANT WRITE`, err: `found EOF, expected ON at line 1, char 13`},
 		{s: `GRANT WRITE FROM`, err: `found FROM, expected ON at line 1, char 13`},
 		{s: `GRANT WRITE ON`, err: `found EOF, expected identifier at line 1, char 16`},
@@ -1929,6 +2270,7 @@ func TestParser_ParseExpr(t *testing.T) {
 		{s: `'2000-01-32 00:00:00'`, err: `unable to parse datetime at line 1, char 1`},
 		{s: `'2000-01-01'`, expr: &influxql.TimeLiteral{Val: mustParseTime("2000-01-01T00:00:00Z")}},
 		{s: `'2000-01-99'`, err: `unable to parse date at line 1, char 1`},
+		{s: `$host`, expr: &influxql.BoundParameter{Name: "host"}},
 
 		// Simple binary expression
 		{
@@ -2246,6 +2588,36 @@ func TestDropSeriesStatement_String(t *testing.T) {
 				},
 			},
 		},
+		{
+			s: `SELECT mean(value) FROM cpu GROUP BY time(1d) tz('Asia/Shanghai')`,
+			stmt: &influxql.SelectStatement{
+				Fields: []*influxql.Field{{
+					Expr: &influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "value"}}},
+				}},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				Dimensions: []*influxql.Dimension{{
+					Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{&influxql.DurationLiteral{Val: 24 * time.Hour}}},
+				}},
+				Location: LoadLocation("Asia/Shanghai"),
+			},
+		},
+		{
+			s: `SELECT mean("mem.used") / mean("disk.used") FROM mem JOIN disk ON host GROUP BY time(1m)`,
+			stmt: &influxql.SelectStatement{
+				Fields: []*influxql.Field{{
+					Expr: &influxql.BinaryExpr{
+						Op:  influxql.DIV,
+						LHS: &influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "mem.used"}}},
+						RHS: &influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "disk.used"}}},
+					},
+				}},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "mem"}, &influxql.Measurement{Name: "disk"}},
+				JoinOn:  []string{"host"},
+				Dimensions: []*influxql.Dimension{{
+					Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{&influxql.DurationLiteral{Val: time.Minute}}},
+				}},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -2287,6 +2659,15 @@ func MustParseExpr(s string) influxql.Expr {
 	return expr
 }
 
+// LoadLocation loads a time zone location. Panic on error.
+func LoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
 // errstring converts an error to its string representation.
 func errstring(err error) string {
 	if err != nil {
@@ -170,7 +170,8 @@ func (enc *FloatPointEncoder) EncodeFloatPoint(p *FloatPoint) error {
 
 // NewFloatPointDecoder decodes FloatPoint points from a reader.
 type FloatPointDecoder struct {
-	r io.Reader
+	r     io.Reader
+	stats *IteratorStats
 }
 
 // NewFloatPointDecoder returns a new instance of FloatPointDecoder that reads from r.
@@ -197,6 +198,14 @@ func (dec *FloatPointDecoder) DecodeFloatPoint(p *FloatPoint) error {
 	if err := proto.Unmarshal(buf, &pb); err != nil {
 		return err
 	}
+
+	// Statistics are sent after the points so read past them.
+	if pb.Stats != nil {
+		if dec.stats != nil {
+			dec.stats.Add(decodeIteratorStats(pb.Stats))
+		}
+		return dec.DecodeFloatPoint(p)
+	}
 	*p = *decodeFloatPoint(&pb)
 
 	return nil
@@ -358,7 +367,8 @@ func (enc *IntegerPointEncoder) EncodeIntegerPoint(p *IntegerPoint) error {
 
 // NewIntegerPointDecoder decodes IntegerPoint points from a reader.
 type IntegerPointDecoder struct {
-	r io.Reader
+	r     io.Reader
+	stats *IteratorStats
 }
 
 // NewIntegerPointDecoder returns a new instance of IntegerPointDecoder that reads from r.
@@ -385,6 +395,14 @@ func (dec *IntegerPointDecoder) DecodeIntegerPoint(p *IntegerPoint) error {
 	if err := proto.Unmarshal(buf, &pb); err != nil {
 		return err
 	}
+
+	// Statistics are sent after the points so read past them.
+	if pb.Stats != nil {
+		if dec.stats != nil {
+			dec.stats.Add(decodeIteratorStats(pb.Stats))
+		}
+		return dec.DecodeIntegerPoint(p)
+	}
 	*p = *decodeIntegerPoint(&pb)
 
 	return nil
@@ -546,7 +564,8 @@ func (enc *StringPointEncoder) EncodeStringPoint(p *StringPoint) error {
 
 // NewStringPointDecoder decodes StringPoint points from a reader.
 type StringPointDecoder struct {
-	r io.Reader
+	r     io.Reader
+	stats *IteratorStats
 }
 
 // NewStringPointDecoder returns a new instance of StringPointDecoder that reads from r.
@@ -573,6 +592,14 @@ func (dec *StringPointDecoder) DecodeStringPoint(p *StringPoint) error {
 	if err := proto.Unmarshal(buf, &pb); err != nil {
 		return err
 	}
+
+	// Statistics are sent after the points so read past them.
+	if pb.Stats != nil {
+		if dec.stats != nil {
+			dec.stats.Add(decodeIteratorStats(pb.Stats))
+		}
+		return dec.DecodeStringPoint(p)
+	}
 	*p = *decodeStringPoint(&pb)
 
 	return nil
@@ -734,7 +761,8 @@ func (enc *BooleanPointEncoder) EncodeBooleanPoint(p *BooleanPoint) error {
 
 // NewBooleanPointDecoder decodes BooleanPoint points from a reader.
 type BooleanPointDecoder struct {
-	r io.Reader
+	r     io.Reader
+	stats *IteratorStats
 }
 
 // NewBooleanPointDecoder returns a new instance of BooleanPointDecoder that reads from r.
@@ -761,6 +789,14 @@ func (dec *BooleanPointDecoder) DecodeBooleanPoint(p *BooleanPoint) error {
 	if err := proto.Unmarshal(buf, &pb); err != nil {
 		return err
 	}
+
+	// Statistics are sent after the points so read past them.
+	if pb.Stats != nil {
+		if dec.stats != nil {
+			dec.stats.Add(decodeIteratorStats(pb.Stats))
+		}
+		return dec.DecodeBooleanPoint(p)
+	}
 	*p = *decodeBooleanPoint(&pb)
 
 	return nil
@@ -252,7 +252,8 @@ func decodeAux(pb []*internal.Aux) []interface{} {
 
 // NewPointDecoder decodes generic points from a reader.
 type PointDecoder struct {
-	r io.Reader
+	r     io.Reader
+	stats *IteratorStats
 }
 
 // NewPointDecoder returns a new instance of PointDecoder that reads from r.
@@ -280,6 +281,14 @@ func (dec *PointDecoder) DecodePoint(p *Point) error {
 		return err
 	}
 
+	// Statistics are sent after the points so read past them.
+	if pb.Stats != nil {
+		if dec.stats != nil {
+			dec.stats.Add(decodeIteratorStats(pb.Stats))
+		}
+		return dec.DecodePoint(p)
+	}
+
 	if pb.IntegerValue != nil {
 		*p = decodeIntegerPoint(&pb)
 	} else if pb.StringValue != nil {
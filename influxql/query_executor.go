@@ -7,7 +7,24 @@ import (
 
 // QueryExecutor executes every statement in an Query.
 type QueryExecutor interface {
-	ExecuteQuery(query *Query, database string, chunkSize int, closing chan struct{}) <-chan *Result
+	ExecuteQuery(query *Query, opt ExecutionOptions, closing chan struct{}) <-chan *Result
+}
+
+// ExecutionOptions contains the options for executing a query.
+type ExecutionOptions struct {
+	// The default database for statements that don't specify one.
+	Database string
+
+	// The requested maximum number of points to return in each result.
+	ChunkSize int
+
+	// The user executing the query, if authenticated, and the address of
+	// the client. Used to attribute statements in the audit log.
+	User string
+	Addr string
+
+	// The values of the bound parameters in the query's statements.
+	Params map[string]interface{}
 }
 
 var (
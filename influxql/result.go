@@ -30,18 +30,24 @@ type Result struct {
 	StatementID int `json:"-"`
 	Series      models.Rows
 	Err         error
+
+	// Partial is set if the series were truncated by a limit on the size
+	// of the response.
+	Partial bool
 }
 
 // MarshalJSON encodes the result into JSON.
 func (r *Result) MarshalJSON() ([]byte, error) {
 	// Define a struct that outputs "error" as a string.
 	var o struct {
-		Series []*models.Row `json:"series,omitempty"`
-		Err    string        `json:"error,omitempty"`
+		Series  []*models.Row `json:"series,omitempty"`
+		Err     string        `json:"error,omitempty"`
+		Partial bool          `json:"partial,omitempty"`
 	}
 
 	// Copy fields to output struct.
 	o.Series = r.Series
+	o.Partial = r.Partial
 	if r.Err != nil {
 		o.Err = r.Err.Error()
 	}
@@ -52,8 +58,9 @@ func (r *Result) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON decodes the data into the Result struct
 func (r *Result) UnmarshalJSON(b []byte) error {
 	var o struct {
-		Series []*models.Row `json:"series,omitempty"`
-		Err    string        `json:"error,omitempty"`
+		Series  []*models.Row `json:"series,omitempty"`
+		Err     string        `json:"error,omitempty"`
+		Partial bool          `json:"partial,omitempty"`
 	}
 
 	err := json.Unmarshal(b, &o)
@@ -61,6 +68,7 @@ func (r *Result) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	r.Series = o.Series
+	r.Partial = o.Partial
 	if o.Err != "" {
 		r.Err = errors.New(o.Err)
 	}
@@ -97,6 +97,8 @@ func (s *Scanner) Scan() (tok Token, pos Pos, lit string) {
 		return SEMICOLON, pos, ""
 	case ':':
 		return COLON, pos, ""
+	case '$':
+		return s.scanBoundParam()
 	}
 
 	return ILLEGAL, pos, string(ch0)
@@ -159,6 +161,25 @@ func (s *Scanner) scanIdent() (tok Token, pos Pos, lit string) {
 	return IDENT, pos, lit
 }
 
+// scanBoundParam consumes a bound parameter. The name of the parameter is
+// either a bare or a double quoted identifier following the "$".
+func (s *Scanner) scanBoundParam() (tok Token, pos Pos, lit string) {
+	_, pos = s.r.curr()
+
+	ch, _ := s.r.read()
+	s.r.unread()
+	if ch == '"' {
+		tok, _, lit = s.scanIdent()
+		if tok != IDENT {
+			return tok, pos, lit
+		}
+		return BOUNDPARAM, pos, lit
+	} else if isIdentFirstChar(ch) {
+		return BOUNDPARAM, pos, ScanBareIdent(s.r)
+	}
+	return ILLEGAL, pos, "$"
+}
+
 // scanString consumes a contiguous string of non-quote characters.
 // Quote characters can be consumed if they're first escaped with a backslash.
 func (s *Scanner) scanString() (tok Token, pos Pos, lit string) {
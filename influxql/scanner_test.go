@@ -69,6 +69,11 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `test"`, tok: influxql.BADSTRING, lit: "", pos: influxql.Pos{Line: 0, Char: 3}},
 		{s: `"test`, tok: influxql.BADSTRING, lit: `test`},
 
+		// Bound parameters
+		{s: `$host`, tok: influxql.BOUNDPARAM, lit: `host`},
+		{s: `$"host name"`, tok: influxql.BOUNDPARAM, lit: `host name`},
+		{s: `$`, tok: influxql.ILLEGAL, lit: `$`},
+
 		{s: `true`, tok: influxql.TRUE},
 		{s: `false`, tok: influxql.FALSE},
 
@@ -27,6 +27,11 @@ func Select(stmt *SelectStatement, ic IteratorCreator, sopt *SelectOptions) ([]I
 		return nil, err
 	}
 
+	// Read the fields of joined measurements from their own measurement.
+	if len(stmt.JoinOn) > 0 {
+		ic = newJoinIteratorCreator(ic, stmt.Sources)
+	}
+
 	// Retrieve refs for each call and var ref.
 	info := newSelectInfo(stmt)
 	if len(info.calls) > 1 && len(info.refs) > 0 {
@@ -219,6 +224,17 @@ func buildExprIterator(expr Expr, ic IteratorCreator, opt IteratorOptions) (Iter
 				return nil, err
 			}
 			return NewIntervalIterator(input, opt), nil
+		case "histogram":
+			input, err := ic.CreateIterator(opt)
+			if err != nil {
+				return nil, err
+			}
+			itr, err := newHistogramCountIterator(input)
+			if err != nil {
+				input.Close()
+				return nil, err
+			}
+			return NewIntervalIterator(itr, opt), nil
 		case "derivative", "non_negative_derivative":
 			input, err := buildExprIterator(expr.Args[0], ic, opt)
 			if err != nil {
@@ -247,8 +263,19 @@ func buildExprIterator(expr Expr, ic IteratorCreator, opt IteratorOptions) (Iter
 						}
 					}
 					return ic.CreateIterator(opt)
-				case "min", "max", "sum", "first", "last", "mean":
+				case "min", "max", "sum", "first", "last", "mean", "tdigest":
 					return ic.CreateIterator(opt)
+				case "percentile_approx":
+					input, err := ic.CreateIterator(opt)
+					if err != nil {
+						return nil, err
+					}
+					itr, err := newPercentileApproxIterator(input, expr.Args[1].(*NumberLiteral).Val)
+					if err != nil {
+						input.Close()
+						return nil, err
+					}
+					return itr, nil
 				case "median":
 					input, err := buildExprIterator(expr.Args[0].(*VarRef), ic, opt)
 					if err != nil {
@@ -370,6 +397,13 @@ func buildExprIterator(expr Expr, ic IteratorCreator, opt IteratorOptions) (Iter
 			if err != nil {
 				return nil, err
 			}
+
+			// Match the points of joined measurements on their tags and time.
+			if _, ok := ic.(*joinIteratorCreator); ok {
+				if lhs, rhs, err = newJoinIterators(lhs, rhs, opt); err != nil {
+					return nil, err
+				}
+			}
 			return buildTransformIterator(lhs, rhs, expr.Op, ic, opt)
 		}
 	case *ParenExpr:
@@ -1,6 +1,7 @@
 package influxql_test
 
 import (
+	"bytes"
 	"reflect"
 	"testing"
 	"time"
@@ -411,6 +412,31 @@ func TestSelect_Top_NoTags_Float(t *testing.T) {
 }
 
 // Ensure a SELECT top() query can be executed.
+func TestSelect_Top_Fill_Float_Descending(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return &FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 25 * Second, Value: 4},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 23 * Second, Value: 7},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 21 * Second, Value: 5},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 5 * Second, Value: 3},
+		}}, nil
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT top(value, 2) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:30Z' GROUP BY time(10s) fill(null) ORDER BY time DESC`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "cpu", Time: 20 * Second, Value: 7}},
+		{&influxql.FloatPoint{Name: "cpu", Time: 20 * Second, Value: 5}},
+		{&influxql.FloatPoint{Name: "cpu", Time: 10 * Second, Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 3}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
 func TestSelect_Top_NoTags_Integer(t *testing.T) {
 	var ic IteratorCreator
 	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
@@ -945,6 +971,229 @@ func TestSelect_Fill_Previous_Float(t *testing.T) {
 	}
 }
 
+// Ensure a SELECT query with a fill(linear) statement can be executed.
+func TestSelect_Fill_Null_Float_Descending(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return influxql.NewCallIterator(&FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 12 * Second, Value: 2},
+		}}, opt)
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT mean(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:01:00Z' GROUP BY host, time(10s) fill(null) ORDER BY time DESC`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 50 * Second, Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 40 * Second, Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 30 * Second, Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 20 * Second, Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 2, Aggregated: 1}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Nil: true}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
+func TestSelect_Fill_Previous_Float_Descending(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return influxql.NewCallIterator(&FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 34 * Second, Value: 4},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 12 * Second, Value: 2},
+		}}, opt)
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT mean(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:01:00Z' GROUP BY host, time(10s) fill(previous) ORDER BY time DESC`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 50 * Second, Value: 4}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 40 * Second, Value: 4}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 30 * Second, Value: 4, Aggregated: 1}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 20 * Second, Value: 2}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 2, Aggregated: 1}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Nil: true}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
+func TestSelect_Fill_Linear_Float(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return influxql.NewCallIterator(&FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 12 * Second, Value: 2},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 42 * Second, Value: 8},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 22 * Second, Value: 5},
+		}}, opt)
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT mean(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:01:00Z' GROUP BY host, time(10s) fill(linear)`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 2, Aggregated: 1}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 20 * Second, Value: 4}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 30 * Second, Value: 6}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 40 * Second, Value: 8, Aggregated: 1}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 50 * Second, Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 0 * Second, Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 10 * Second, Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 20 * Second, Value: 5, Aggregated: 1}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 30 * Second, Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 40 * Second, Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 50 * Second, Nil: true}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure a SELECT query with a fill(linear) statement can be executed on integers.
+func TestSelect_Fill_Linear_Integer(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return influxql.NewCallIterator(&IntegerIterator{Points: []influxql.IntegerPoint{
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 12 * Second, Value: 1},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 42 * Second, Value: 10},
+		}}, opt)
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT max(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:50Z' GROUP BY host, time(10s) fill(linear)`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Nil: true}},
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 1, Aggregated: 1}},
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 20 * Second, Value: 4}},
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 30 * Second, Value: 7}},
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 40 * Second, Value: 10, Aggregated: 1}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure a SELECT query with a GROUP BY time offset shifts the windows.
+func TestSelect_GroupByOffset_Float(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return influxql.NewCallIterator(&FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 4 * Second, Value: 1},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 5 * Second, Value: 2},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 14 * Second, Value: 4},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 31 * Second, Value: 8},
+		}}, opt)
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT sum(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:35Z' GROUP BY time(10s, 5s)`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "cpu", Time: -5 * Second, Value: 1, Aggregated: 1}},
+		{&influxql.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 6, Aggregated: 2}},
+		{&influxql.FloatPoint{Name: "cpu", Time: 15 * Second, Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Time: 25 * Second, Value: 8, Aggregated: 1}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure a SELECT query can join the windows of two measurements on a tag.
+func TestSelect_Join_Float(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		if !reflect.DeepEqual(opt.Dimensions, []string{"host"}) {
+			t.Fatalf("unexpected dimensions: %v", opt.Dimensions)
+		} else if len(opt.Sources) != 1 {
+			t.Fatalf("unexpected sources: %s", opt.Sources)
+		}
+		switch source := opt.Sources[0].(*influxql.Measurement).Name; {
+		case source == "mem" && opt.Expr.String() == `mean(used)`:
+			return influxql.NewCallIterator(&FloatIterator{Points: []influxql.FloatPoint{
+				{Name: "mem", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 10},
+				{Name: "mem", Tags: ParseTags("host=A"), Time: 12 * Second, Value: 20},
+				{Name: "mem", Tags: ParseTags("host=B"), Time: 0 * Second, Value: 5},
+			}}, opt)
+		case source == "disk" && opt.Expr.String() == `sum(free)`:
+			return influxql.NewCallIterator(&IntegerIterator{Points: []influxql.IntegerPoint{
+				{Name: "disk", Tags: ParseTags("host=A"), Time: 1 * Second, Value: 2},
+				{Name: "disk", Tags: ParseTags("host=A"), Time: 15 * Second, Value: 4},
+				{Name: "disk", Tags: ParseTags("host=C"), Time: 0 * Second, Value: 1},
+			}}, opt)
+		default:
+			t.Fatalf("unexpected expr in %s: %s", source, opt.Expr)
+		}
+		return nil, nil
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT mem.used / sum(disk.free) FROM mem JOIN disk ON host WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:20Z' GROUP BY time(10s)`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "mem_disk", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 5, Aggregated: 1}},
+		{&influxql.FloatPoint{Name: "mem_disk", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 5, Aggregated: 1}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure a SELECT query joining two measurements only reads the windows it returns.
+func TestSelect_Join_Stream(t *testing.T) {
+	inputs := make(map[string]*FloatIterator)
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		name := opt.Sources[0].(*influxql.Measurement).Name
+		input := &FloatIterator{}
+		for i := int64(0); i < 4; i++ {
+			input.Points = append(input.Points, influxql.FloatPoint{Name: name, Tags: ParseTags("host=A"), Time: i * 10 * Second, Value: float64(i + 1)})
+		}
+		inputs[name] = input
+		return influxql.NewCallIterator(input, opt)
+	}
+
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT mem.used + disk.free FROM mem JOIN disk ON host WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:40Z' GROUP BY time(10s)`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer influxql.Iterators(itrs).Close()
+
+	if p := itrs[0].(influxql.FloatIterator).Next(); !deep.Equal(p, &influxql.FloatPoint{Name: "mem_disk", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 2, Aggregated: 1}) {
+		t.Fatalf("unexpected point: %s", spew.Sdump(p))
+	} else if len(inputs["mem"].Points) == 0 || len(inputs["disk"].Points) == 0 {
+		t.Fatal("expected the later windows to be unread")
+	}
+}
+
+// Ensure a SELECT query with a fill(null) statement and a time zone fills
+// the local days around a daylight saving time change.
+func TestSelect_Fill_Null_Float_Location(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return influxql.NewCallIterator(&FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: mustParseTime("2020-03-08T12:00:00-04:00").UnixNano(), Value: 2},
+		}}, opt)
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT mean(value) FROM cpu WHERE time >= '2020-03-07T05:00:00Z' AND time < '2020-03-10T04:00:00Z' GROUP BY host, time(1d) fill(null) tz('America/New_York')`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: mustParseTime("2020-03-07T00:00:00-05:00").UnixNano(), Nil: true}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: mustParseTime("2020-03-08T00:00:00-05:00").UnixNano(), Value: 2, Aggregated: 1}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: mustParseTime("2020-03-09T00:00:00-04:00").UnixNano(), Nil: true}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
 // Ensure a SELECT stddev() query can be executed.
 func TestSelect_Stddev_Float(t *testing.T) {
 	var ic IteratorCreator
@@ -1200,6 +1449,119 @@ func TestSelect_Percentile_Integer(t *testing.T) {
 	}
 }
 
+// Ensure a SELECT percentile_approx() query merges the digests of each shard.
+func TestSelect_PercentileApprox_Float(t *testing.T) {
+	var shard0, shard1 IteratorCreator
+	shard0.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return influxql.NewCallIterator(&FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 20},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 50 * Second, Value: 10},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 51 * Second, Value: 9},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 52 * Second, Value: 8},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 53 * Second, Value: 7},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 54 * Second, Value: 6},
+		}}, opt)
+	}
+	shard1.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return influxql.NewCallIterator(&IntegerIterator{Points: []influxql.IntegerPoint{
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 55 * Second, Value: 5},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 56 * Second, Value: 4},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 57 * Second, Value: 3},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 58 * Second, Value: 2},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 59 * Second, Value: 1},
+		}}, opt)
+	}
+
+	// An empty remote stream must not drop the digests of other shards.
+	var remote IteratorCreator
+	remote.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return influxql.NewReaderIterator(bytes.NewReader(nil), nil)
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT percentile_approx(value, 90) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:01:00Z' GROUP BY time(10s), host fill(none)`), influxql.IteratorCreators{&remote, &shard0, &shard1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 20, Aggregated: 1}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 50 * Second, Value: 9.5, Aggregated: 10}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure the digests of a SELECT tdigest() query can be re-aggregated
+// by percentile_approx().
+func TestSelect_TDigest_PercentileApprox(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		var points []influxql.FloatPoint
+		for i := 0; i < 10; i++ {
+			points = append(points, influxql.FloatPoint{Name: "cpu", Time: int64(i) * Second, Value: float64(i + 1)})
+		}
+		return influxql.NewCallIterator(&FloatIterator{Points: points}, opt)
+	}
+
+	// Store a digest for every 5 seconds.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT tdigest(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:10Z' GROUP BY time(5s)`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var digests []influxql.StringPoint
+	for _, a := range Iterators(itrs).ReadAll() {
+		digests = append(digests, *a[0].(*influxql.StringPoint))
+	}
+	if len(digests) != 2 {
+		t.Fatalf("unexpected digests: %s", spew.Sdump(digests))
+	}
+
+	// Combine the stored digests over the whole range.
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return influxql.NewCallIterator(&StringIterator{Points: digests}, opt)
+	}
+	itrs, err = influxql.Select(MustParseSelectStatement(`SELECT percentile_approx(digest, 50) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:10Z' GROUP BY time(10s)`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 5.5, Aggregated: 10}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure a SELECT histogram() query merges the counts of each shard.
+func TestSelect_Histogram_Float(t *testing.T) {
+	var shard0, shard1 IteratorCreator
+	shard0.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return influxql.NewCallIterator(&FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Time: 0 * Second, Value: -1},
+			{Name: "cpu", Time: 1 * Second, Value: 1},
+			{Name: "cpu", Time: 2 * Second, Value: 5},
+			{Name: "cpu", Time: 11 * Second, Value: 7.5},
+		}}, opt)
+	}
+	shard1.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return influxql.NewCallIterator(&FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Time: 3 * Second, Value: 4.5},
+			{Name: "cpu", Time: 4 * Second, Value: 9.9},
+			{Name: "cpu", Time: 5 * Second, Value: 10},
+		}}, opt)
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT histogram(value, 0, 5, 10) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:20Z' GROUP BY time(10s)`), influxql.IteratorCreators{&shard0, &shard1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.IntegerPoint{Name: "cpu", Time: 0 * Second, Value: 2}},
+		{&influxql.IntegerPoint{Name: "cpu", Time: 0 * Second, Value: 2}},
+		{&influxql.IntegerPoint{Name: "cpu", Time: 10 * Second, Value: 0}},
+		{&influxql.IntegerPoint{Name: "cpu", Time: 10 * Second, Value: 1}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
 // Ensure a simple raw SELECT statement can be executed.
 func TestSelect_Raw(t *testing.T) {
 	// Mock two iterators -- one for each value in the query.
@@ -1727,6 +2089,53 @@ func TestSelect_Derivative_Float(t *testing.T) {
 	}
 }
 
+func TestSelect_Derivative_Float_Descending(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return &FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Time: 12 * Second, Value: 3},
+			{Name: "cpu", Time: 8 * Second, Value: 19},
+			{Name: "cpu", Time: 4 * Second, Value: 10},
+			{Name: "cpu", Time: 0 * Second, Value: 20},
+		}}, nil
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT derivative(value, 1s) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:16Z' ORDER BY time DESC`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "cpu", Time: 12 * Second, Value: -4}},
+		{&influxql.FloatPoint{Name: "cpu", Time: 8 * Second, Value: 2.25}},
+		{&influxql.FloatPoint{Name: "cpu", Time: 4 * Second, Value: -2.5}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
+func TestSelect_Derivative_Float_GroupByTags(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		return &FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 20},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 4 * Second, Value: 10},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 0 * Second, Value: 1},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 2 * Second, Value: 5},
+		}}, nil
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT derivative(value, 1s) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:16Z' GROUP BY host`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a := Iterators(itrs).ReadAll(); !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 4 * Second, Value: -2.5}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 2 * Second, Value: 2}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
 func TestSelect_Derivative_Integer(t *testing.T) {
 	var ic IteratorCreator
 	ic.CreateIteratorFn = func(opt influxql.IteratorOptions) (influxql.Iterator, error) {
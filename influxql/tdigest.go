@@ -0,0 +1,319 @@
+package influxql
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+// DefaultTDigestCompression is the default compression of a t-digest.
+// Higher values keep more centroids and give more accurate quantiles.
+const DefaultTDigestCompression = 100
+
+// ErrInvalidTDigest is returned when an encoded t-digest can't be decoded.
+var ErrInvalidTDigest = errors.New("invalid t-digest")
+
+// ErrInvalidHistogram is returned when an encoded histogram can't be decoded.
+var ErrInvalidHistogram = errors.New("invalid histogram")
+
+// Encoding versions of the partial aggregate states.
+const (
+	tdigestVersion   = 1
+	histogramVersion = 1
+)
+
+// TDigest approximates the distribution of a set of values with a small
+// number of weighted centroids. Digests can be merged, so the digests of
+// each shard or node can be combined into the digest of all their values.
+type TDigest struct {
+	Compression float64
+
+	centroids []centroid // merged centroids, sorted by mean
+	unmerged  []centroid
+	total     float64
+	min, max  float64
+}
+
+// centroid represents the mean of a number of values.
+type centroid struct {
+	Mean   float64
+	Weight float64
+}
+
+type centroids []centroid
+
+func (a centroids) Len() int           { return len(a) }
+func (a centroids) Less(i, j int) bool { return a[i].Mean < a[j].Mean }
+func (a centroids) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// NewTDigest returns a new instance of TDigest with the default compression.
+func NewTDigest() *TDigest {
+	return &TDigest{
+		Compression: DefaultTDigestCompression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// Count returns the number of values added to the digest.
+func (d *TDigest) Count() float64 { return d.total }
+
+// Add adds the value x to the digest w times.
+func (d *TDigest) Add(x, w float64) {
+	if math.IsNaN(x) || math.IsInf(x, 0) || w <= 0 {
+		return
+	}
+	d.add(centroid{Mean: x, Weight: w})
+	if x < d.min {
+		d.min = x
+	}
+	if x > d.max {
+		d.max = x
+	}
+}
+
+// Merge adds the values of other to the digest.
+func (d *TDigest) Merge(other *TDigest) {
+	if other.total == 0 {
+		return
+	}
+	for _, c := range other.centroids {
+		d.add(c)
+	}
+	for _, c := range other.unmerged {
+		d.add(c)
+	}
+	d.min = math.Min(d.min, other.min)
+	d.max = math.Max(d.max, other.max)
+}
+
+func (d *TDigest) add(c centroid) {
+	d.unmerged = append(d.unmerged, c)
+	d.total += c.Weight
+	if len(d.unmerged) >= 5*int(d.Compression) {
+		d.compress()
+	}
+}
+
+// compress merges the unmerged centroids into the sorted centroids. Adjacent
+// centroids are combined while the combination covers no more than one unit
+// of the scale function, which keeps centroids near the tails small.
+func (d *TDigest) compress() {
+	if len(d.unmerged) == 0 {
+		return
+	}
+
+	a := make(centroids, 0, len(d.centroids)+len(d.unmerged))
+	a = append(a, d.centroids...)
+	a = append(a, d.unmerged...)
+	sort.Sort(a)
+
+	merged := make([]centroid, 0, len(d.centroids))
+	curr := a[0]
+	var weight float64
+	kLow := d.scale(0)
+	for _, c := range a[1:] {
+		if d.scale((weight+curr.Weight+c.Weight)/d.total)-kLow <= 1 {
+			curr.Weight += c.Weight
+			curr.Mean += (c.Mean - curr.Mean) * c.Weight / curr.Weight
+			continue
+		}
+		merged = append(merged, curr)
+		weight += curr.Weight
+		kLow = d.scale(weight / d.total)
+		curr = c
+	}
+	d.centroids = append(merged, curr)
+	d.unmerged = nil
+}
+
+// scale maps the quantile q to the scale of the digest.
+func (d *TDigest) scale(q float64) float64 {
+	return d.Compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// Quantile returns the approximate value at the quantile q, between 0 and 1.
+// Returns NaN if the digest is empty.
+func (d *TDigest) Quantile(q float64) float64 {
+	d.compress()
+	if len(d.centroids) == 0 {
+		return math.NaN()
+	} else if q <= 0 {
+		return d.min
+	} else if q >= 1 {
+		return d.max
+	}
+
+	// Each centroid is centered on the middle of its weight. Interpolate
+	// between the centers around the index, or the extremes at the ends.
+	index := q * d.total
+	first := d.centroids[0]
+	if index < first.Weight/2 {
+		return d.min + (first.Mean-d.min)*index/(first.Weight/2)
+	}
+
+	var weight float64
+	for i := 0; i < len(d.centroids)-1; i++ {
+		curr, next := d.centroids[i], d.centroids[i+1]
+		left := weight + curr.Weight/2
+		right := weight + curr.Weight + next.Weight/2
+		if index <= right {
+			return curr.Mean + (next.Mean-curr.Mean)*(index-left)/(right-left)
+		}
+		weight += curr.Weight
+	}
+
+	last := d.centroids[len(d.centroids)-1]
+	left := d.total - last.Weight/2
+	return last.Mean + (d.max-last.Mean)*(index-left)/(last.Weight/2)
+}
+
+// MarshalBinary encodes the digest into a binary format.
+func (d *TDigest) MarshalBinary() ([]byte, error) {
+	d.compress()
+
+	var buf bytes.Buffer
+	buf.WriteByte(tdigestVersion)
+	for _, v := range []float64{d.Compression, d.min, d.max} {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	binary.Write(&buf, binary.BigEndian, uint32(len(d.centroids)))
+	for _, c := range d.centroids {
+		binary.Write(&buf, binary.BigEndian, c.Mean)
+		binary.Write(&buf, binary.BigEndian, c.Weight)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the digest from a binary format.
+func (d *TDigest) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	if v, err := r.ReadByte(); err != nil || v != tdigestVersion {
+		return ErrInvalidTDigest
+	}
+
+	var hdr struct {
+		Compression, Min, Max float64
+		N                     uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &hdr); err != nil {
+		return ErrInvalidTDigest
+	} else if hdr.Compression <= 0 || int64(hdr.N)*16 != int64(r.Len()) {
+		return ErrInvalidTDigest
+	}
+
+	a := make([]centroid, hdr.N)
+	if err := binary.Read(r, binary.BigEndian, a); err != nil {
+		return ErrInvalidTDigest
+	}
+
+	var total float64
+	for _, c := range a {
+		if !(c.Weight > 0) || math.IsNaN(c.Mean) {
+			return ErrInvalidTDigest
+		}
+		total += c.Weight
+	}
+	*d = TDigest{Compression: hdr.Compression, centroids: a, total: total, min: hdr.Min, max: hdr.Max}
+	return nil
+}
+
+// encodeTDigest encodes the digest as a string so it can be passed between
+// iterators, nodes and stored as a field value.
+func encodeTDigest(d *TDigest) string {
+	buf, _ := d.MarshalBinary()
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// decodeTDigest decodes a digest encoded by encodeTDigest.
+func decodeTDigest(s string) (*TDigest, error) {
+	buf, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidTDigest
+	}
+	var d TDigest
+	if err := d.UnmarshalBinary(buf); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// histogram counts the values that fall into each bucket between a
+// list of ascending boundaries. Bucket i holds values in the range
+// [bounds[i], bounds[i+1]) and values outside of all buckets are ignored.
+type histogram struct {
+	bounds []float64
+	counts []uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)-1)}
+}
+
+// add counts the value x n times.
+func (h *histogram) add(x float64, n uint64) {
+	i := sort.SearchFloat64s(h.bounds, x)
+	if i < len(h.bounds) && h.bounds[i] == x {
+		i++
+	}
+	if i == 0 || i == len(h.bounds) {
+		return
+	}
+	h.counts[i-1] += n
+}
+
+// merge adds the counts of other, which must have the same boundaries.
+func (h *histogram) merge(other *histogram) error {
+	if len(other.bounds) != len(h.bounds) {
+		return ErrInvalidHistogram
+	}
+	for i, v := range other.bounds {
+		if h.bounds[i] != v {
+			return ErrInvalidHistogram
+		}
+	}
+	for i, n := range other.counts {
+		h.counts[i] += n
+	}
+	return nil
+}
+
+// encodeHistogram encodes the histogram as a string so it can be passed
+// between iterators and nodes.
+func encodeHistogram(h *histogram) string {
+	var buf bytes.Buffer
+	buf.WriteByte(histogramVersion)
+	binary.Write(&buf, binary.BigEndian, uint32(len(h.bounds)))
+	binary.Write(&buf, binary.BigEndian, h.bounds)
+	binary.Write(&buf, binary.BigEndian, h.counts)
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// decodeHistogram decodes a histogram encoded by encodeHistogram.
+func decodeHistogram(s string) (*histogram, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidHistogram
+	}
+
+	r := bytes.NewReader(data)
+	var n uint32
+	if v, err := r.ReadByte(); err != nil || v != histogramVersion {
+		return nil, ErrInvalidHistogram
+	} else if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, ErrInvalidHistogram
+	} else if n < 2 || int64(n)*16-8 != int64(r.Len()) {
+		return nil, ErrInvalidHistogram
+	}
+
+	h := newHistogram(make([]float64, n))
+	if err := binary.Read(r, binary.BigEndian, h.bounds); err != nil {
+		return nil, ErrInvalidHistogram
+	} else if err := binary.Read(r, binary.BigEndian, h.counts); err != nil {
+		return nil, ErrInvalidHistogram
+	}
+	return h, nil
+}
@@ -0,0 +1,82 @@
+package influxql_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/freetsdb/freetsdb/influxql"
+)
+
+// Ensure a t-digest approximates the quantiles of its values.
+func TestTDigest_Quantile(t *testing.T) {
+	d := influxql.NewTDigest()
+	for _, v := range rand.New(rand.NewSource(0)).Perm(10000) {
+		d.Add(float64(v), 1)
+	}
+
+	if n := d.Count(); n != 10000 {
+		t.Fatalf("unexpected count: %v", n)
+	}
+	for _, tt := range []struct {
+		q   float64
+		exp float64
+	}{
+		{q: 0, exp: 0},
+		{q: 0.01, exp: 100},
+		{q: 0.5, exp: 5000},
+		{q: 0.99, exp: 9900},
+		{q: 1, exp: 9999},
+	} {
+		if v := d.Quantile(tt.q); math.Abs(v-tt.exp) > 10000*0.005 {
+			t.Errorf("%v: unexpected quantile: %v", tt.q, v)
+		}
+	}
+}
+
+// Ensure merged t-digests approximate the quantiles of all of their values.
+func TestTDigest_Merge(t *testing.T) {
+	d := influxql.NewTDigest()
+	for i, v := range rand.New(rand.NewSource(0)).Perm(10000) {
+		if i%1000 == 0 {
+			other := influxql.NewTDigest()
+			d.Merge(other)
+		}
+		part := influxql.NewTDigest()
+		part.Add(float64(v), 1)
+		d.Merge(part)
+	}
+
+	if n := d.Count(); n != 10000 {
+		t.Fatalf("unexpected count: %v", n)
+	}
+	if v := d.Quantile(0.9); math.Abs(v-9000) > 10000*0.005 {
+		t.Fatalf("unexpected quantile: %v", v)
+	}
+}
+
+// Ensure a t-digest can be marshaled and unmarshaled.
+func TestTDigest_MarshalBinary(t *testing.T) {
+	d := influxql.NewTDigest()
+	for i := 0; i < 1000; i++ {
+		d.Add(float64(i), 1)
+	}
+
+	buf, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var other influxql.TDigest
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	} else if other.Count() != d.Count() {
+		t.Fatalf("unexpected count: %v", other.Count())
+	} else if a, b := other.Quantile(0.75), d.Quantile(0.75); a != b {
+		t.Fatalf("unexpected quantile: %v != %v", a, b)
+	}
+
+	if err := other.UnmarshalBinary(buf[:len(buf)-1]); err != influxql.ErrInvalidTDigest {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -26,6 +26,7 @@ const (
 	FALSE       // false
 	REGEX       // Regular expressions
 	BADREGEX    // `.*
+	BOUNDPARAM  // $param
 	literalEnd
 
 	operatorBeg
@@ -155,6 +156,7 @@ var tokens = [...]string{
 	TRUE:        "TRUE",
 	FALSE:       "FALSE",
 	REGEX:       "REGEX",
+	BOUNDPARAM:  "BOUNDPARAM",
 
 	ADD: "+",
 	SUB: "-",
@@ -0,0 +1,412 @@
+// Package msgpack implements the subset of MessagePack used to exchange query
+// results: nil, booleans, integers, floats, strings, binary, arrays and maps
+// with string keys. Times are encoded as RFC3339 strings, as they are in JSON.
+package msgpack // import "github.com/freetsdb/freetsdb/pkg/msgpack"
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Encoder writes MessagePack values to a writer.
+type Encoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewEncoder returns a new instance of Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes v to the underlying writer.
+func (e *Encoder) Encode(v interface{}) error {
+	b, err := Append(e.buf[:0], v)
+	if err != nil {
+		return err
+	}
+	e.buf = b
+	_, err = e.w.Write(b)
+	return err
+}
+
+// Append appends the encoding of v to b.
+func Append(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case int:
+		return appendInt(b, int64(v)), nil
+	case int8:
+		return appendInt(b, int64(v)), nil
+	case int16:
+		return appendInt(b, int64(v)), nil
+	case int32:
+		return appendInt(b, int64(v)), nil
+	case int64:
+		return appendInt(b, v), nil
+	case uint:
+		return appendUint(b, uint64(v)), nil
+	case uint8:
+		return appendUint(b, uint64(v)), nil
+	case uint16:
+		return appendUint(b, uint64(v)), nil
+	case uint32:
+		return appendUint(b, uint64(v)), nil
+	case uint64:
+		return appendUint(b, v), nil
+	case float32:
+		b = append(b, 0xca)
+		return appendUint32(b, math.Float32bits(v)), nil
+	case float64:
+		b = append(b, 0xcb)
+		return appendUint64(b, math.Float64bits(v)), nil
+	case string:
+		return appendString(b, v), nil
+	case []byte:
+		return appendBinary(b, v), nil
+	case time.Time:
+		return appendString(b, v.Format(time.RFC3339Nano)), nil
+	case []string:
+		b = appendArrayHeader(b, len(v))
+		for _, s := range v {
+			b = appendString(b, s)
+		}
+		return b, nil
+	case []interface{}:
+		b = appendArrayHeader(b, len(v))
+		for _, elem := range v {
+			var err error
+			if b, err = Append(b, elem); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case [][]interface{}:
+		b = appendArrayHeader(b, len(v))
+		for _, elem := range v {
+			var err error
+			if b, err = Append(b, elem); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]string:
+		b = appendMapHeader(b, len(v))
+		for _, k := range sortedKeys(v) {
+			b = appendString(b, k)
+			b = appendString(b, v[k])
+		}
+		return b, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b = appendMapHeader(b, len(v))
+		for _, k := range keys {
+			b = appendString(b, k)
+			var err error
+			if b, err = Append(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+}
+
+func appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		b = append(b, 0xd1)
+		return appendUint16(b, uint16(v))
+	case v >= math.MinInt32:
+		b = append(b, 0xd2)
+		return appendUint32(b, uint32(v))
+	default:
+		b = append(b, 0xd3)
+		return appendUint64(b, uint64(v))
+	}
+}
+
+func appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= 0x7f:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		b = append(b, 0xcd)
+		return appendUint16(b, uint16(v))
+	case v <= math.MaxUint32:
+		b = append(b, 0xce)
+		return appendUint32(b, uint32(v))
+	default:
+		b = append(b, 0xcf)
+		return appendUint64(b, v)
+	}
+}
+
+func appendString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda)
+		b = appendUint16(b, uint16(n))
+	default:
+		b = append(b, 0xdb)
+		b = appendUint32(b, uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendBinary(b []byte, v []byte) []byte {
+	switch n := len(v); {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xc5)
+		b = appendUint16(b, uint16(n))
+	default:
+		b = append(b, 0xc6)
+		b = appendUint32(b, uint32(n))
+	}
+	return append(b, v...)
+}
+
+func appendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xdc)
+		return appendUint16(b, uint16(n))
+	default:
+		b = append(b, 0xdd)
+		return appendUint32(b, uint32(n))
+	}
+}
+
+func appendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xde)
+		return appendUint16(b, uint16(n))
+	default:
+		b = append(b, 0xdf)
+		return appendUint32(b, uint32(n))
+	}
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return append(b, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Decoder reads MessagePack values from a reader.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder returns a new instance of Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next value. Integers are returned as int64, or uint64 if
+// they don't fit, floats as float64, arrays as []interface{} and maps as
+// map[string]interface{}. Returns io.EOF if there are no more values.
+func (d *Decoder) Decode() (interface{}, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := d.decode(c)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return v, err
+}
+
+func (d *Decoder) decode(c byte) (interface{}, error) {
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0:
+		return d.readString(int(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return d.readArray(int(c & 0x0f))
+	case c&0xf0 == 0x80:
+		return d.readMap(int(c & 0x0f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readLength(c - 0xc4)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(d.r, b)
+		return b, err
+	case 0xca:
+		v, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := d.readUint(8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := d.readUint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		} else if v > math.MaxInt64 {
+			return v, nil
+		}
+		return int64(v), nil
+	case 0xd0:
+		v, err := d.readUint(1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := d.readUint(2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := d.readUint(4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := d.readUint(8)
+		return int64(v), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readLength(c - 0xd9)
+		if err != nil {
+			return nil, err
+		}
+		return d.readString(n)
+	case 0xdc, 0xdd:
+		n, err := d.readLength(c - 0xdc + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.readArray(n)
+	case 0xde, 0xdf:
+		n, err := d.readLength(c - 0xde + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.readMap(n)
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type 0x%02x", c)
+	}
+}
+
+// readLength reads a length of 1, 2 or 4 bytes for a size of 0, 1 or 2.
+func (d *Decoder) readLength(size byte) (int, error) {
+	v, err := d.readUint(1 << size)
+	return int(v), err
+}
+
+// readUint reads a big endian unsigned integer of n bytes.
+func (d *Decoder) readUint(n int) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(d.r, buf[8-n:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+func (d *Decoder) readString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (d *Decoder) readArray(n int) ([]interface{}, error) {
+	a := make([]interface{}, n)
+	for i := range a {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if a[i], err = d.decode(c); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func (d *Decoder) readMap(n int) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		k, err := d.decode(c)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("msgpack: unsupported map key type %T", k)
+		}
+
+		if c, err = d.r.ReadByte(); err != nil {
+			return nil, err
+		}
+		if m[key], err = d.decode(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,79 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freetsdb/freetsdb/pkg/msgpack"
+)
+
+// Ensure values round trip through the encoder and decoder.
+func TestEncodeDecode(t *testing.T) {
+	for i, tt := range []struct {
+		in  interface{}
+		out interface{}
+	}{
+		{in: nil, out: nil},
+		{in: true, out: true},
+		{in: false, out: false},
+		{in: 0, out: int64(0)},
+		{in: 127, out: int64(127)},
+		{in: -32, out: int64(-32)},
+		{in: int64(-33), out: int64(-33)},
+		{in: int64(math.MinInt16), out: int64(math.MinInt16)},
+		{in: int64(math.MinInt64), out: int64(math.MinInt64)},
+		{in: int64(math.MaxInt64), out: int64(math.MaxInt64)},
+		{in: uint64(math.MaxUint64), out: uint64(math.MaxUint64)},
+		{in: 70000, out: int64(70000)},
+		{in: float32(1.5), out: float64(1.5)},
+		{in: 2.25, out: 2.25},
+		{in: "", out: ""},
+		{in: "cpu", out: "cpu"},
+		{in: strings.Repeat("x", 300), out: strings.Repeat("x", 300)},
+		{in: []byte{1, 2}, out: []byte{1, 2}},
+		{in: time.Unix(0, 10).UTC(), out: "1970-01-01T00:00:00.00000001Z"},
+		{in: []string{"a", "b"}, out: []interface{}{"a", "b"}},
+		{in: [][]interface{}{{1, "x"}, {nil}}, out: []interface{}{[]interface{}{int64(1), "x"}, []interface{}{nil}}},
+		{in: map[string]string{"host": "a"}, out: map[string]interface{}{"host": "a"}},
+		{in: map[string]interface{}{"values": make([]interface{}, 20)}, out: map[string]interface{}{"values": make([]interface{}, 20)}},
+	} {
+		var buf bytes.Buffer
+		if err := msgpack.NewEncoder(&buf).Encode(tt.in); err != nil {
+			t.Fatalf("%d. unexpected encode error: %s", i, err)
+		}
+
+		dec := msgpack.NewDecoder(&buf)
+		if v, err := dec.Decode(); err != nil {
+			t.Fatalf("%d. unexpected decode error: %s", i, err)
+		} else if !reflect.DeepEqual(v, tt.out) {
+			t.Fatalf("%d. unexpected value: %#v", i, v)
+		} else if _, err := dec.Decode(); err != io.EOF {
+			t.Fatalf("%d. expected EOF, got %v", i, err)
+		}
+	}
+}
+
+// Ensure a truncated value returns an error.
+func TestDecode_Truncated(t *testing.T) {
+	var buf bytes.Buffer
+	if err := msgpack.NewEncoder(&buf).Encode([]string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	if _, err := msgpack.NewDecoder(bytes.NewReader(b[:len(b)-1])).Decode(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure unsupported types can't be encoded.
+func TestEncode_Unsupported(t *testing.T) {
+	if err := msgpack.NewEncoder(&bytes.Buffer{}).Encode(struct{}{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/freetsdb/freetsdb/toml"
+)
+
+const (
+	// DefaultMaxSize is the size at which the audit log file is rotated.
+	DefaultMaxSize = 100 * 1024 * 1024
+
+	// DefaultMaxBackups is the number of rotated audit log files kept.
+	DefaultMaxBackups = 10
+
+	// DefaultStoreEnabled is whether audit records are written to a database.
+	DefaultStoreEnabled = true
+
+	// DefaultStoreDatabase is the name of the database audit records are written to.
+	DefaultStoreDatabase = "_audit"
+
+	// DefaultStoreDuration is how long audit records are kept in the database.
+	DefaultStoreDuration = 90 * 24 * time.Hour
+)
+
+// Config represents the configuration for the audit log.
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// Path of the audit log file. No file is written if empty.
+	Path       string    `toml:"path"`
+	MaxSize    toml.Size `toml:"max-size"`
+	MaxBackups int       `toml:"max-backups"`
+
+	StoreEnabled  bool          `toml:"store-enabled"`
+	StoreDatabase string        `toml:"store-database"`
+	StoreDuration toml.Duration `toml:"store-duration"`
+}
+
+// NewConfig returns an instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Enabled:       false,
+		MaxSize:       DefaultMaxSize,
+		MaxBackups:    DefaultMaxBackups,
+		StoreEnabled:  DefaultStoreEnabled,
+		StoreDatabase: DefaultStoreDatabase,
+		StoreDuration: toml.Duration(DefaultStoreDuration),
+	}
+}
@@ -0,0 +1,42 @@
+package audit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/freetsdb/freetsdb/services/audit"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	var c audit.Config
+	if _, err := toml.Decode(`
+enabled = true
+path = "/var/log/freetsdb/audit.log"
+max-size = "10m"
+max-backups = 3
+store-enabled = false
+store-database = "_compliance"
+store-duration = "720h"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.Enabled {
+		t.Fatalf("unexpected enabled: %v", c.Enabled)
+	} else if c.Path != "/var/log/freetsdb/audit.log" {
+		t.Fatalf("unexpected path: %s", c.Path)
+	} else if c.MaxSize != 10*1024*1024 {
+		t.Fatalf("unexpected max size: %d", c.MaxSize)
+	} else if c.MaxBackups != 3 {
+		t.Fatalf("unexpected max backups: %d", c.MaxBackups)
+	} else if c.StoreEnabled {
+		t.Fatalf("unexpected store enabled: %v", c.StoreEnabled)
+	} else if c.StoreDatabase != "_compliance" {
+		t.Fatalf("unexpected store database: %s", c.StoreDatabase)
+	} else if time.Duration(c.StoreDuration) != 720*time.Hour {
+		t.Fatalf("unexpected store duration: %s", c.StoreDuration)
+	}
+}
@@ -0,0 +1,315 @@
+package audit // import "github.com/freetsdb/freetsdb/services/audit"
+
+import (
+	"encoding/json"
+	"expvar"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/freetsdb/freetsdb"
+	"github.com/freetsdb/freetsdb/models"
+	"github.com/freetsdb/freetsdb/services/meta"
+)
+
+// Audit events.
+const (
+	// EventStatement is recorded for every non-SELECT statement executed.
+	EventStatement = "statement"
+
+	// EventAuthentication is recorded for every failed authentication.
+	EventAuthentication = "authentication"
+
+	// EventAuthorization is recorded for every query denied by authorization.
+	EventAuthorization = "authorization"
+)
+
+// Audit results.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+const (
+	// StoreRetentionPolicy is the retention policy audit records are written to.
+	StoreRetentionPolicy = "audit"
+
+	// storeMeasurement is the measurement audit records are written to.
+	storeMeasurement = "audit"
+
+	// storeBatchSize is the number of records buffered before writing them to the store.
+	storeBatchSize = 1000
+
+	// storeInterval is the maximum time a record is buffered before writing it to the store.
+	storeInterval = time.Second
+
+	// recordBufferSize is the number of records queued before Audit blocks.
+	recordBufferSize = 1024
+)
+
+// Statistics for the audit service.
+const (
+	statRecords        = "records"
+	statFileWriteFail  = "fileWriteFail"
+	statStoreWriteFail = "storeWriteFail"
+)
+
+// Record is a single entry in the audit log.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Node      uint64    `json:"node"`
+	User      string    `json:"user,omitempty"`
+	Addr      string    `json:"addr,omitempty"`
+	Database  string    `json:"database,omitempty"`
+	Statement string    `json:"statement,omitempty"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// NewRecord returns a record for an event, with the result derived from err.
+func NewRecord(event string, err error) *Record {
+	r := &Record{Event: event, Result: ResultSuccess}
+	if err != nil {
+		r.Result = ResultFailure
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// point returns the record as a point for the store.
+func (r *Record) point() (models.Point, error) {
+	tags := map[string]string{
+		"event":  r.Event,
+		"result": r.Result,
+		"node":   strconv.FormatUint(r.Node, 10),
+	}
+	if r.User != "" {
+		tags["user"] = r.User
+	}
+
+	fields := map[string]interface{}{"statement": r.Statement}
+	if r.Addr != "" {
+		fields["addr"] = r.Addr
+	}
+	if r.Database != "" {
+		fields["database"] = r.Database
+	}
+	if r.Error != "" {
+		fields["error"] = r.Error
+	}
+	return models.NewPoint(storeMeasurement, tags, fields, r.Time)
+}
+
+// Service records audit events to a rotating file and, optionally, to a
+// database in the cluster.
+type Service struct {
+	mu      sync.RWMutex
+	wg      sync.WaitGroup
+	records chan *Record
+
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *rotatingFile
+
+	storeEnabled  bool
+	storeDatabase string
+	storeDuration time.Duration
+	storeCreated  bool
+
+	// Node is used to tag records with the node they were recorded on.
+	Node *freetsdb.Node
+
+	MetaClient interface {
+		CreateDatabaseWithRetentionPolicy(name string, rpi *meta.RetentionPolicyInfo) (*meta.DatabaseInfo, error)
+	}
+
+	// Writer for storing records in the database.
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, points models.Points) error
+	}
+
+	Logger  *log.Logger
+	statMap *expvar.Map
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		path:          c.Path,
+		maxSize:       int64(c.MaxSize),
+		maxBackups:    c.MaxBackups,
+		storeEnabled:  c.StoreEnabled,
+		storeDatabase: c.StoreDatabase,
+		storeDuration: time.Duration(c.StoreDuration),
+		Logger:        log.New(os.Stderr, "[audit] ", log.LstdFlags),
+		statMap:       freetsdb.NewStatistics("audit", "audit", nil),
+	}
+}
+
+// SetLogger sets the internal logger to the logger passed in.
+func (s *Service) SetLogger(l *log.Logger) {
+	s.Logger = l
+}
+
+// Open starts recording audit events.
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.records != nil {
+		return nil
+	}
+
+	s.Logger.Println("Starting audit log")
+
+	if s.path != "" {
+		f, err := openRotatingFile(s.path, s.maxSize, s.maxBackups)
+		if err != nil {
+			return err
+		}
+		s.file = f
+		s.Logger.Printf("Writing audit log to %s", s.path)
+	}
+	if s.storeEnabled {
+		s.Logger.Printf("Storing audit log in database '%s' retention policy '%s'", s.storeDatabase, StoreRetentionPolicy)
+	}
+
+	s.records = make(chan *Record, recordBufferSize)
+	s.wg.Add(1)
+	go s.run(s.records)
+	return nil
+}
+
+// Close stops recording audit events, after flushing queued records.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	if s.records == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	close(s.records)
+	s.records = nil
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	if s.file != nil {
+		err := s.file.Close()
+		s.file = nil
+		return err
+	}
+	return nil
+}
+
+// Audit records an audit event. The time and node are set if not already
+// present. Audit blocks if records are being produced faster than they can
+// be written. Records are dropped if the service is not open.
+func (s *Service) Audit(r *Record) {
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+	if r.Node == 0 && s.Node != nil {
+		r.Node = s.Node.ID
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.records == nil {
+		return
+	}
+	s.records <- r
+}
+
+// run writes records until the records channel is closed.
+func (s *Service) run(records <-chan *Record) {
+	defer s.wg.Done()
+
+	var batch []*Record
+	tick := time.NewTicker(storeInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case r, ok := <-records:
+			if !ok {
+				s.store(batch)
+				return
+			}
+			s.statMap.Add(statRecords, 1)
+			s.write(r)
+
+			if s.storeEnabled {
+				batch = append(batch, r)
+				if len(batch) >= storeBatchSize {
+					s.store(batch)
+					batch = nil
+				}
+			}
+		case <-tick.C:
+			s.store(batch)
+			batch = nil
+		}
+	}
+}
+
+// write appends a record to the audit log file.
+func (s *Service) write(r *Record) {
+	if s.file == nil {
+		return
+	}
+
+	buf, err := json.Marshal(r)
+	if err != nil {
+		s.statMap.Add(statFileWriteFail, 1)
+		s.Logger.Printf("failed to encode audit record: %s", err)
+		return
+	}
+	if _, err := s.file.Write(append(buf, '\n')); err != nil {
+		s.statMap.Add(statFileWriteFail, 1)
+		s.Logger.Printf("failed to write audit log: %s", err)
+	}
+}
+
+// store writes a batch of records to the audit database.
+func (s *Service) store(batch []*Record) {
+	if len(batch) == 0 || !s.createStore() {
+		return
+	}
+
+	points := make(models.Points, 0, len(batch))
+	for _, r := range batch {
+		pt, err := r.point()
+		if err != nil {
+			s.Logger.Printf("dropping audit record: %s", err)
+			continue
+		}
+		points = append(points, pt)
+	}
+
+	if err := s.PointsWriter.WritePoints(s.storeDatabase, StoreRetentionPolicy, points); err != nil {
+		s.statMap.Add(statStoreWriteFail, int64(len(points)))
+		s.Logger.Printf("failed to store audit records: %s", err)
+	}
+}
+
+// createStore ensures the audit database has been created.
+func (s *Service) createStore() bool {
+	if s.storeCreated {
+		return true
+	}
+
+	rpi := meta.NewRetentionPolicyInfo(StoreRetentionPolicy)
+	rpi.Duration = s.storeDuration
+	// A conflicting retention policy means an operator has altered it.
+	if _, err := s.MetaClient.CreateDatabaseWithRetentionPolicy(s.storeDatabase, rpi); err != nil && err != meta.ErrRetentionPolicyConflict {
+		s.Logger.Printf("failed to create database '%s', failed to create audit storage: %s", s.storeDatabase, err)
+		return false
+	}
+
+	s.storeCreated = true
+	return true
+}
@@ -0,0 +1,183 @@
+package audit_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/freetsdb/freetsdb"
+	"github.com/freetsdb/freetsdb/models"
+	"github.com/freetsdb/freetsdb/services/audit"
+	"github.com/freetsdb/freetsdb/services/meta"
+)
+
+// Ensure records are written to the audit log file as JSON.
+func TestService_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := audit.NewConfig()
+	c.Path = filepath.Join(dir, "audit.log")
+	c.StoreEnabled = false
+
+	s := audit.NewService(c)
+	s.Node = &freetsdb.Node{ID: 2}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := audit.NewRecord(audit.EventStatement, nil)
+	r.User, r.Addr, r.Statement = "fred", "127.0.0.1", "DROP DATABASE db0"
+	s.Audit(r)
+	s.Audit(audit.NewRecord(audit.EventAuthentication, errors.New("authentication failed")))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := MustReadRecords(c.Path)
+	if len(records) != 2 {
+		t.Fatalf("unexpected records: %+v", records)
+	} else if r := records[0]; r.Event != audit.EventStatement || r.Result != audit.ResultSuccess || r.User != "fred" || r.Addr != "127.0.0.1" || r.Statement != "DROP DATABASE db0" || r.Node != 2 || r.Time.IsZero() {
+		t.Fatalf("unexpected record: %+v", r)
+	} else if r := records[1]; r.Event != audit.EventAuthentication || r.Result != audit.ResultFailure || r.Error != "authentication failed" {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+}
+
+// Ensure the audit log file is rotated once it reaches its maximum size.
+func TestService_File_Rotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := audit.NewConfig()
+	c.Path = filepath.Join(dir, "audit.log")
+	c.MaxSize = 200
+	c.MaxBackups = 2
+	c.StoreEnabled = false
+
+	s := audit.NewService(c)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		r := audit.NewRecord(audit.EventStatement, nil)
+		r.Statement = "CREATE DATABASE db0"
+		s.Audit(r)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the current file and two backups are kept.
+	for _, path := range []string{c.Path, c.Path + ".1", c.Path + ".2"} {
+		if fi, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		} else if fi.Size() > 200 {
+			t.Fatalf("file %s exceeds max size: %d", path, fi.Size())
+		}
+	}
+	if _, err := os.Stat(c.Path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("unexpected backup: %v", err)
+	}
+}
+
+// Ensure records are stored in the audit database.
+func TestService_Store(t *testing.T) {
+	s := audit.NewService(audit.NewConfig())
+
+	var created string
+	s.MetaClient = &MetaClient{
+		CreateDatabaseWithRetentionPolicyFn: func(name string, rpi *meta.RetentionPolicyInfo) (*meta.DatabaseInfo, error) {
+			if rpi.Name != audit.StoreRetentionPolicy {
+				t.Fatalf("unexpected retention policy: %s", rpi.Name)
+			}
+			created = name
+			return &meta.DatabaseInfo{Name: name}, nil
+		},
+	}
+
+	var mu sync.Mutex
+	var points models.Points
+	s.PointsWriter = &PointsWriter{
+		WritePointsFn: func(database, retentionPolicy string, a models.Points) error {
+			if database != audit.DefaultStoreDatabase || retentionPolicy != audit.StoreRetentionPolicy {
+				t.Fatalf("unexpected target: %s.%s", database, retentionPolicy)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			points = append(points, a...)
+			return nil
+		},
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	r := audit.NewRecord(audit.EventStatement, nil)
+	r.User, r.Statement = "fred", "DROP DATABASE db0"
+	s.Audit(r)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if created != audit.DefaultStoreDatabase {
+		t.Fatalf("unexpected database created: %q", created)
+	} else if len(points) != 1 {
+		t.Fatalf("unexpected points: %v", points)
+	} else if tags := points[0].Tags(); tags["user"] != "fred" || tags["event"] != audit.EventStatement || tags["result"] != audit.ResultSuccess {
+		t.Fatalf("unexpected tags: %v", tags)
+	} else if fields := points[0].Fields(); fields["statement"] != "DROP DATABASE db0" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+}
+
+// MetaClient is a mock implementation of Service.MetaClient.
+type MetaClient struct {
+	CreateDatabaseWithRetentionPolicyFn func(name string, rpi *meta.RetentionPolicyInfo) (*meta.DatabaseInfo, error)
+}
+
+func (c *MetaClient) CreateDatabaseWithRetentionPolicy(name string, rpi *meta.RetentionPolicyInfo) (*meta.DatabaseInfo, error) {
+	return c.CreateDatabaseWithRetentionPolicyFn(name, rpi)
+}
+
+// PointsWriter is a mock implementation of Service.PointsWriter.
+type PointsWriter struct {
+	WritePointsFn func(database, retentionPolicy string, points models.Points) error
+}
+
+func (w *PointsWriter) WritePoints(database, retentionPolicy string, points models.Points) error {
+	return w.WritePointsFn(database, retentionPolicy, points)
+}
+
+// MustReadRecords returns the records in an audit log file. Panic on error.
+func MustReadRecords(path string) []*audit.Record {
+	f, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	var records []*audit.Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r audit.Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			panic(err)
+		}
+		records = append(records, &r)
+	}
+	return records
+}
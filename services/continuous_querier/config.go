@@ -9,6 +9,17 @@ import (
 // Default values for aspects of interval computation.
 const (
 	DefaultRunInterval = time.Second
+
+	// DefaultBackfillChunkIntervals is the default number of GROUP BY
+	// intervals computed by each query issued during a backfill.
+	DefaultBackfillChunkIntervals = 60
+
+	// DefaultBackfillThrottle is the default pause between backfill queries.
+	DefaultBackfillThrottle = 100 * time.Millisecond
+
+	// DefaultDownsampleFieldsInterval is the default time the fields of a
+	// retention policy are reused by its downsample policies.
+	DefaultDownsampleFieldsInterval = time.Minute
 )
 
 // Config represents a configuration for the continuous query service.
@@ -24,6 +35,17 @@ type Config struct {
 	// every minute, this should be set to 1 minute. The default is set to '1s' so the interval
 	// is compatible with most aggregations.
 	RunInterval toml.Duration `toml:"run-interval"`
+
+	// Number of GROUP BY intervals recomputed by each query issued when backfilling
+	// a continuous query. Smaller chunks keep individual queries cheap.
+	BackfillChunkIntervals int `toml:"backfill-chunk-intervals"`
+
+	// Pause between the queries of a backfill so that it doesn't starve live queries.
+	BackfillThrottle toml.Duration `toml:"backfill-throttle"`
+
+	// How often the fields aggregated by downsample policies are read from the
+	// data nodes. New measurements are downsampled once they have been read.
+	DownsampleFieldsInterval toml.Duration `toml:"downsample-fields-interval"`
 }
 
 // NewConfig returns a new instance of Config with defaults.
@@ -32,5 +54,10 @@ func NewConfig() Config {
 		LogEnabled:  true,
 		Enabled:     true,
 		RunInterval: toml.Duration(DefaultRunInterval),
+
+		BackfillChunkIntervals: DefaultBackfillChunkIntervals,
+		BackfillThrottle:       toml.Duration(DefaultBackfillThrottle),
+
+		DownsampleFieldsInterval: toml.Duration(DefaultDownsampleFieldsInterval),
 	}
 }
@@ -14,6 +14,9 @@ func TestConfig_Parse(t *testing.T) {
 	if _, err := toml.Decode(`
 run-interval = "1m"
 enabled = true
+backfill-chunk-intervals = 24
+backfill-throttle = "1s"
+downsample-fields-interval = "5m"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -23,5 +26,11 @@ enabled = true
 		t.Fatalf("unexpected run interval: %v", c.RunInterval)
 	} else if c.Enabled != true {
 		t.Fatalf("unexpected enabled: %v", c.Enabled)
+	} else if c.BackfillChunkIntervals != 24 {
+		t.Fatalf("unexpected backfill chunk intervals: %d", c.BackfillChunkIntervals)
+	} else if time.Duration(c.BackfillThrottle) != time.Second {
+		t.Fatalf("unexpected backfill throttle: %v", c.BackfillThrottle)
+	} else if time.Duration(c.DownsampleFieldsInterval) != 5*time.Minute {
+		t.Fatalf("unexpected downsample fields interval: %v", c.DownsampleFieldsInterval)
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -27,12 +28,19 @@ const (
 	statQueryOK       = "queryOk"
 	statQueryFail     = "queryFail"
 	statPointsWritten = "pointsWritten"
+	statBackfillOK    = "backfillOk"
+	statBackfillFail  = "backfillFail"
 )
 
 // ContinuousQuerier represents a service that executes continuous queries.
 type ContinuousQuerier interface {
 	// Run executes the named query in the named database.  Blank database or name matches all.
 	Run(database, name string, t time.Time) error
+
+	// Backfill recomputes the named query in the named database over the time
+	// range [start, end). If progress is not nil, it is called after each chunk
+	// with the chunk's time range, the number of chunks completed and the total.
+	Backfill(database, name string, start, end time.Time, progress func(start, end time.Time, chunk, chunks int), closing <-chan struct{}) error
 }
 
 // metaClient is an internal interface to make testing easier.
@@ -43,6 +51,12 @@ type metaClient interface {
 	NodeID() uint64
 }
 
+// fieldStore is an internal interface for discovering the fields aggregated
+// by downsample policies across the cluster.
+type fieldStore interface {
+	NumericFields(database, retentionPolicy string) (map[string][]string, error)
+}
+
 // RunRequest is a request to run one or more CQs.
 type RunRequest struct {
 	// Now tells the CQ serivce what the current time is.
@@ -69,8 +83,11 @@ func (rr *RunRequest) matches(cq *meta.ContinuousQueryInfo) bool {
 type Service struct {
 	MetaClient    metaClient
 	QueryExecutor influxql.QueryExecutor
+	FieldStore    fieldStore
 	Config        *Config
 	RunInterval   time.Duration
+	// FieldsInterval is how long the fields read from FieldStore are reused.
+	FieldsInterval time.Duration
 	// RunCh can be used by clients to signal service to run CQs.
 	RunCh          chan *RunRequest
 	Logger         *log.Logger
@@ -81,6 +98,23 @@ type Service struct {
 	lastRuns map[string]time.Time
 	stop     chan struct{}
 	wg       *sync.WaitGroup
+
+	// fields caches the numeric fields of the retention policies with
+	// downsample policies.
+	fieldsMu sync.Mutex
+	fields   map[fieldsKey]*cachedFields
+}
+
+// fieldsKey identifies the retention policy of cached fields.
+type fieldsKey struct {
+	database        string
+	retentionPolicy string
+}
+
+// cachedFields holds the numeric fields of a retention policy.
+type cachedFields struct {
+	fields    map[string][]string
+	refreshed time.Time
 }
 
 // NewService returns a new instance of Service.
@@ -88,11 +122,13 @@ func NewService(c Config) *Service {
 	s := &Service{
 		Config:         &c,
 		RunInterval:    time.Duration(c.RunInterval),
+		FieldsInterval: time.Duration(c.DownsampleFieldsInterval),
 		RunCh:          make(chan *RunRequest),
 		loggingEnabled: c.LogEnabled,
 		statMap:        freetsdb.NewStatistics("cq", "cq", nil),
 		Logger:         log.New(os.Stderr, "[continuous_querier] ", log.LstdFlags),
 		lastRuns:       map[string]time.Time{},
+		fields:         make(map[fieldsKey]*cachedFields),
 	}
 
 	return s
@@ -109,7 +145,9 @@ func (s *Service) Open() error {
 	assert(s.MetaClient != nil, "MetaClient is nil")
 	assert(s.QueryExecutor != nil, "QueryExecutor is nil")
 
+	s.mu.Lock()
 	s.stop = make(chan struct{})
+	s.mu.Unlock()
 	s.wg = &sync.WaitGroup{}
 	s.wg.Add(1)
 	go s.backgroundLoop()
@@ -124,7 +162,9 @@ func (s *Service) Close() error {
 	close(s.stop)
 	s.wg.Wait()
 	s.wg = nil
+	s.mu.Lock()
 	s.stop = nil
+	s.mu.Unlock()
 	return nil
 }
 
@@ -160,7 +200,7 @@ func (s *Service) Run(database, name string, t time.Time) error {
 	defer s.mu.Unlock()
 	for _, db := range dbs {
 		// Loop through CQs in each DB executing the ones that match name.
-		for _, cq := range db.ContinuousQueries {
+		for _, cq := range s.continuousQueries(&db) {
 			if name == "" || cq.Name == name {
 				// Remove the last run time for the CQ
 				id := fmt.Sprintf("%s:%s", db.Name, cq.Name)
@@ -177,6 +217,113 @@ func (s *Service) Run(database, name string, t time.Time) error {
 	return nil
 }
 
+// Backfill recomputes a CQ over the time range [start, end). The range is
+// widened to whole GROUP BY intervals and processed oldest first in chunks
+// of Config.BackfillChunkIntervals intervals, pausing Config.BackfillThrottle
+// between chunks. It does not affect the regular schedule of the CQ. The
+// backfill stops before its next chunk once closing or the service is closed.
+func (s *Service) Backfill(database, name string, start, end time.Time, progress func(start, end time.Time, chunk, chunks int), closing <-chan struct{}) error {
+	if !start.Before(end) {
+		return errors.New("backfill start time must be before end time")
+	}
+
+	// Find the requested database.
+	dbi, err := s.MetaClient.Database(database)
+	if err != nil {
+		return err
+	} else if dbi == nil {
+		return influxql.ErrDatabaseNotFound(database)
+	}
+
+	// Find the requested CQ, which may have been generated by a downsample policy.
+	var cqi *meta.ContinuousQueryInfo
+	cqs := s.continuousQueries(dbi)
+	for i := range cqs {
+		if cqs[i].Name == name {
+			cqi = &cqs[i]
+			break
+		}
+	}
+	if cqi == nil {
+		return meta.ErrContinuousQueryNotFound
+	}
+
+	cq, err := NewContinuousQuery(dbi.Name, cqi)
+	if err != nil {
+		return err
+	}
+
+	// Set the retention policy to default if it wasn't specified in the query.
+	if cq.intoRP() == "" {
+		cq.setIntoRP(dbi.DefaultRetentionPolicy)
+	}
+
+	interval, err := cq.q.GroupByInterval()
+	if err != nil {
+		return err
+	} else if interval == 0 {
+		return errors.New("continuous queries must be aggregate queries")
+	}
+
+	chunkIntervals := s.Config.BackfillChunkIntervals
+	if chunkIntervals <= 0 {
+		chunkIntervals = DefaultBackfillChunkIntervals
+	}
+	chunkSize := interval * time.Duration(chunkIntervals)
+
+	// Align the range to whole intervals so no bucket is partially computed.
+	start = start.Truncate(interval)
+	if t := end.Truncate(interval); t.Before(end) {
+		end = t.Add(interval)
+	}
+	chunks := int((end.Sub(start) + chunkSize - 1) / chunkSize)
+
+	if s.loggingEnabled {
+		s.Logger.Printf("backfilling continuous query %s (%v to %v) in %d chunks", cq.Info.Name, start, end, chunks)
+	}
+
+	// The backfill is also interrupted when the service is closed.
+	s.mu.RLock()
+	stop := s.stop
+	s.mu.RUnlock()
+
+	for i, chunkStart := 0, start; chunkStart.Before(end); i, chunkStart = i+1, chunkStart.Add(chunkSize) {
+		// Pause between chunks so a large backfill doesn't starve other queries.
+		if i > 0 {
+			select {
+			case <-closing:
+				return errors.New("backfill interrupted")
+			case <-stop:
+				return errors.New("backfill interrupted")
+			case <-time.After(time.Duration(s.Config.BackfillThrottle)):
+			}
+		}
+
+		chunkEnd := chunkStart.Add(chunkSize)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+		if err := cq.q.SetTimeRange(chunkStart, chunkEnd); err != nil {
+			return err
+		}
+
+		if err := s.runContinuousQueryAndWriteResult(cq); err != nil {
+			s.Logger.Printf("error: %s. backfilling: %s\n", err, cq.q.String())
+			s.statMap.Add(statBackfillFail, 1)
+			return err
+		}
+		s.statMap.Add(statBackfillOK, 1)
+
+		if s.loggingEnabled {
+			s.Logger.Printf("backfilled continuous query %s (%v to %v), chunk %d of %d", cq.Info.Name, chunkStart, chunkEnd, i+1, chunks)
+		}
+		if progress != nil {
+			progress(chunkStart, chunkEnd, i+1, chunks)
+		}
+	}
+	return nil
+}
+
 // backgroundLoop runs on a go routine and periodically executes CQs.
 func (s *Service) backgroundLoop() {
 	leaseName := "continuous_querier"
@@ -218,10 +365,140 @@ func (s *Service) hasContinuousQueries() bool {
 		if len(db.ContinuousQueries) > 0 {
 			return true
 		}
+		for _, rp := range db.RetentionPolicies {
+			if len(rp.DownsamplePolicies) > 0 {
+				return true
+			}
+		}
 	}
 	return false
 }
 
+// continuousQueries returns the CQs stored in the database followed by the
+// queries generated for its downsample policies.
+func (s *Service) continuousQueries(dbi *meta.DatabaseInfo) []meta.ContinuousQueryInfo {
+	if s.FieldStore == nil {
+		return dbi.ContinuousQueries
+	}
+
+	// The database info may be shared with the meta client's cache, so the
+	// generated queries are appended to a copy of its CQs.
+	cqs := append([]meta.ContinuousQueryInfo(nil), dbi.ContinuousQueries...)
+
+	for _, rpi := range dbi.RetentionPolicies {
+		if len(rpi.DownsamplePolicies) == 0 {
+			continue
+		}
+
+		// Every numeric field of every measurement in the source retention
+		// policy is aggregated, so newly written measurements are picked up
+		// once the fields are refreshed without any new DDL.
+		fields := s.numericFields(dbi.Name, rpi.Name)
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for i := range rpi.DownsamplePolicies {
+			dpi := &rpi.DownsamplePolicies[i]
+			for _, name := range names {
+				stmt := &influxql.CreateContinuousQueryStatement{
+					Name:     fmt.Sprintf("downsample:%s:%s.%s:%s", rpi.Name, dpi.Database, dpi.RetentionPolicy, name),
+					Database: dbi.Name,
+					Source:   newDownsampleStatement(dbi.Name, rpi.Name, dpi, name, fields[name]),
+				}
+				cqs = append(cqs, meta.ContinuousQueryInfo{Name: stmt.Name, Query: stmt.String()})
+			}
+		}
+	}
+	return cqs
+}
+
+// numericFields returns the numeric fields of a retention policy. The fields
+// are read from FieldStore at most once every FieldsInterval. If a node can't
+// be read, the fields it returned before are kept.
+func (s *Service) numericFields(database, retentionPolicy string) map[string][]string {
+	s.fieldsMu.Lock()
+	defer s.fieldsMu.Unlock()
+
+	key := fieldsKey{database: database, retentionPolicy: retentionPolicy}
+	cached := s.fields[key]
+	if cached != nil && time.Since(cached.refreshed) < s.FieldsInterval {
+		return cached.fields
+	}
+
+	fields, err := s.FieldStore.NumericFields(database, retentionPolicy)
+	if err != nil {
+		s.Logger.Printf("error reading fields of %s.%s for downsampling: %s", database, retentionPolicy, err)
+		if cached != nil {
+			fields = mergeFields(fields, cached.fields)
+		}
+	}
+	s.fields[key] = &cachedFields{fields: fields, refreshed: time.Now()}
+	return fields
+}
+
+// mergeFields returns the sorted union of the fields of each measurement in a and b.
+func mergeFields(a, b map[string][]string) map[string][]string {
+	other := make(map[string][]string, len(a))
+	for name, fields := range a {
+		other[name] = fields
+	}
+	for name, fields := range b {
+		set := make(map[string]struct{})
+		for _, f := range other[name] {
+			set[f] = struct{}{}
+		}
+		for _, f := range fields {
+			set[f] = struct{}{}
+		}
+
+		merged := make([]string, 0, len(set))
+		for f := range set {
+			merged = append(merged, f)
+		}
+		sort.Strings(merged)
+		other[name] = merged
+	}
+	return other
+}
+
+// newDownsampleStatement returns a SELECT statement applying each aggregate of
+// a downsample policy to each field of a measurement. Results are written into
+// the measurement of the same name in the policy's target retention policy.
+func newDownsampleStatement(database, rp string, dpi *meta.DownsamplePolicyInfo, name string, fields []string) *influxql.SelectStatement {
+	stmt := &influxql.SelectStatement{
+		Target: &influxql.Target{
+			Measurement: &influxql.Measurement{
+				Database:        dpi.Database,
+				RetentionPolicy: dpi.RetentionPolicy,
+				Name:            name,
+				IsTarget:        true,
+			},
+		},
+		Sources: influxql.Sources{&influxql.Measurement{
+			Database:        database,
+			RetentionPolicy: rp,
+			Name:            name,
+		}},
+		Dimensions: influxql.Dimensions{
+			{Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{&influxql.DurationLiteral{Val: dpi.Interval}}}},
+			{Expr: &influxql.Wildcard{}},
+		},
+	}
+
+	for _, agg := range dpi.Aggregates {
+		for _, field := range fields {
+			stmt.Fields = append(stmt.Fields, &influxql.Field{
+				Expr:  &influxql.Call{Name: agg, Args: []influxql.Expr{&influxql.VarRef{Val: field}}},
+				Alias: agg + "_" + field,
+			})
+		}
+	}
+	return stmt
+}
+
 // runContinuousQueries gets CQs from the meta store and runs them.
 func (s *Service) runContinuousQueries(req *RunRequest) {
 	// Get list of all databases.
@@ -233,7 +510,7 @@ func (s *Service) runContinuousQueries(req *RunRequest) {
 	// Loop through all databases executing CQs.
 	for _, db := range dbs {
 		// TODO: distribute across nodes
-		for _, cq := range db.ContinuousQueries {
+		for _, cq := range s.continuousQueries(&db) {
 			if !req.matches(&cq) {
 				continue
 			}
@@ -347,7 +624,10 @@ func (s *Service) runContinuousQueryAndWriteResult(cq *ContinuousQuery) error {
 	defer close(closing)
 
 	// Execute the SELECT.
-	ch := s.QueryExecutor.ExecuteQuery(q, cq.Database, NoChunkingSize, closing)
+	ch := s.QueryExecutor.ExecuteQuery(q, influxql.ExecutionOptions{
+		Database:  cq.Database,
+		ChunkSize: NoChunkingSize,
+	}, closing)
 
 	// There is only one statement, so we will only ever receive one result
 	res, ok := <-ch
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/freetsdb/freetsdb/influxql"
 	"github.com/freetsdb/freetsdb/models"
 	"github.com/freetsdb/freetsdb/services/meta"
+	"github.com/freetsdb/freetsdb/toml"
 )
 
 var (
@@ -331,6 +333,153 @@ func TestExecuteContinuousQuery_QueryExecutor_Error(t *testing.T) {
 	}
 }
 
+// Test backfilling a CQ over a historic time range in chunks.
+func TestContinuousQueryService_Backfill(t *testing.T) {
+	s := NewTestService(t)
+	s.Config.BackfillChunkIntervals = 30
+	s.Config.BackfillThrottle = 0
+
+	// Record the time range of each query executed.
+	var ranges [][2]time.Time
+	qe := s.QueryExecutor.(*QueryExecutor)
+	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int, closing chan struct{}) <-chan *influxql.Result {
+		stmt := query.Statements[0].(*influxql.SelectStatement)
+		min, max := influxql.TimeRange(stmt.Condition)
+		ranges = append(ranges, [2]time.Time{min, max.Add(time.Nanosecond)})
+		ch := make(chan *influxql.Result, 1)
+		ch <- &influxql.Result{}
+		close(ch)
+		return ch
+	}
+
+	// The range is widened to whole intervals and split into 30m chunks.
+	start := time.Date(2000, 1, 1, 0, 0, 30, 0, time.UTC)
+	end := time.Date(2000, 1, 1, 1, 10, 0, 0, time.UTC)
+	var chunks []int
+	progress := func(start, end time.Time, chunk, n int) { chunks = append(chunks, chunk) }
+	if err := s.Backfill("db2", "cq2", start, end, progress, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][2]time.Time{
+		{time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 1, 0, 30, 0, 0, time.UTC)},
+		{time.Date(2000, 1, 1, 0, 30, 0, 0, time.UTC), time.Date(2000, 1, 1, 1, 0, 0, 0, time.UTC)},
+		{time.Date(2000, 1, 1, 1, 0, 0, 0, time.UTC), time.Date(2000, 1, 1, 1, 10, 0, 0, time.UTC)},
+	}
+	if len(ranges) != len(exp) {
+		t.Fatalf("exp %d queries, got %d", len(exp), len(ranges))
+	}
+	for i := range exp {
+		if !ranges[i][0].Equal(exp[i][0]) || !ranges[i][1].Equal(exp[i][1]) {
+			t.Errorf("query %d: exp %v, got %v", i, exp[i], ranges[i])
+		}
+	}
+	if len(chunks) != 3 || chunks[2] != 3 {
+		t.Errorf("unexpected progress: %v", chunks)
+	}
+
+	// Unknown CQs return an error.
+	if err := s.Backfill("db2", "foo", start, end, nil, nil); err != meta.ErrContinuousQueryNotFound {
+		t.Errorf("exp = %s, got = %v", meta.ErrContinuousQueryNotFound, err)
+	}
+}
+
+// Test interrupting a backfill between chunks.
+func TestContinuousQueryService_Backfill_Interrupted(t *testing.T) {
+	s := NewTestService(t)
+	s.Config.BackfillChunkIntervals = 30
+	s.Config.BackfillThrottle = toml.Duration(time.Hour)
+
+	// Close the channel while the first chunk is computed.
+	closing := make(chan struct{})
+	var n int
+	qe := s.QueryExecutor.(*QueryExecutor)
+	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int, c chan struct{}) <-chan *influxql.Result {
+		if n++; n == 1 {
+			close(closing)
+		}
+		ch := make(chan *influxql.Result, 1)
+		ch <- &influxql.Result{}
+		close(ch)
+		return ch
+	}
+
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2000, 1, 1, 2, 0, 0, 0, time.UTC)
+	if err := s.Backfill("db2", "cq2", start, end, nil, closing); err == nil || err.Error() != "backfill interrupted" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n != 1 {
+		t.Fatalf("exp 1 query, got %d", n)
+	}
+}
+
+// Test the fields of downsampled retention policies are only read once per interval.
+func TestService_NumericFields(t *testing.T) {
+	s := NewTestService(t)
+
+	var n int
+	var fields map[string][]string
+	var err error
+	s.FieldStore = FieldStoreFunc(func(database, retentionPolicy string) (map[string][]string, error) {
+		n++
+		return fields, err
+	})
+
+	// Fields are reused within the interval.
+	s.FieldsInterval = time.Hour
+	fields = map[string][]string{"cpu": {"idle", "user"}}
+	s.numericFields("db", "rp")
+	if a := s.numericFields("db", "rp"); n != 1 {
+		t.Fatalf("exp 1 read, got %d", n)
+	} else if !reflect.DeepEqual(a, fields) {
+		t.Fatalf("unexpected fields: %v", a)
+	}
+
+	// The fields of a node that can't be read are kept.
+	s.FieldsInterval = 0
+	fields, err = map[string][]string{"cpu": {"system"}, "mem": {"free"}}, errExpected
+	if a := s.numericFields("db", "rp"); n != 2 {
+		t.Fatalf("exp 2 reads, got %d", n)
+	} else if !reflect.DeepEqual(a, map[string][]string{"cpu": {"idle", "system", "user"}, "mem": {"free"}}) {
+		t.Fatalf("unexpected fields: %v", a)
+	}
+}
+
+// Test generated downsample queries don't modify the CQs of the database info.
+func TestService_ContinuousQueries_SharedInfo(t *testing.T) {
+	s := NewTestService(t)
+	s.FieldStore = FieldStoreFunc(func(database, retentionPolicy string) (map[string][]string, error) {
+		return map[string][]string{"cpu": {"idle"}}, nil
+	})
+
+	// The CQs have spare capacity, as they may in the meta client's cache.
+	cqs := make([]meta.ContinuousQueryInfo, 1, 2)
+	cqs[0] = meta.ContinuousQueryInfo{Name: "cq0"}
+	dbi := &meta.DatabaseInfo{
+		Name:              "db",
+		ContinuousQueries: cqs,
+		RetentionPolicies: []meta.RetentionPolicyInfo{{
+			Name:               "rp",
+			DownsamplePolicies: []meta.DownsamplePolicyInfo{{Database: "db", RetentionPolicy: "rp_1h", Interval: time.Hour, Aggregates: []string{"mean"}}},
+		}},
+	}
+
+	if a := s.continuousQueries(dbi); len(a) != 2 {
+		t.Fatalf("exp 2 queries, got %d", len(a))
+	} else if len(dbi.ContinuousQueries) != 1 {
+		t.Fatalf("unexpected database CQs: %v", dbi.ContinuousQueries)
+	} else if cqs[:2][1].Name != "" {
+		t.Fatalf("database CQs modified: %v", cqs[:2])
+	}
+}
+
+// FieldStoreFunc is a function that can be used as Service.FieldStore.
+type FieldStoreFunc func(database, retentionPolicy string) (map[string][]string, error)
+
+func (fn FieldStoreFunc) NumericFields(database, retentionPolicy string) (map[string][]string, error) {
+	return fn(database, retentionPolicy)
+}
+
 // NewTestService returns a new *Service with default mock object members.
 func NewTestService(t *testing.T) *Service {
 	s := NewService(NewConfig())
@@ -490,10 +639,10 @@ func NewQueryExecutor(t *testing.T) *QueryExecutor {
 }
 
 // ExecuteQuery returns a channel that the caller can read query results from.
-func (qe *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chunkSize int, closing chan struct{}) <-chan *influxql.Result {
+func (qe *QueryExecutor) ExecuteQuery(query *influxql.Query, opt influxql.ExecutionOptions, closing chan struct{}) <-chan *influxql.Result {
 	// If the test set a callback, call it.
 	if qe.ExecuteQueryFn != nil {
-		return qe.ExecuteQueryFn(query, database, chunkSize, make(chan struct{}))
+		return qe.ExecuteQueryFn(query, opt.Database, opt.ChunkSize, make(chan struct{}))
 	}
 
 	ch := make(chan *influxql.Result, 1)
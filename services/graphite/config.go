@@ -17,6 +17,8 @@ const (
 	DefaultDatabase = "graphite"
 
 	// DefaultProtocol is the default IP protocol used by the Graphite input.
+	// The input also accepts "udp", and "pickle" for the Carbon pickle
+	// protocol over TCP.
 	DefaultProtocol = "tcp"
 
 	// DefaultConsistencyLevel is the default write consistency for the Graphite input.
@@ -141,7 +143,18 @@ func (c *Config) validateTemplates() error {
 	filters := map[string]struct{}{}
 
 	for i, t := range c.Templates {
-		parts := strings.Fields(t)
+		// The separator can be set anywhere after the template.
+		var parts []string
+		for _, p := range strings.Fields(t) {
+			if strings.HasPrefix(p, separatorPrefix) {
+				if p == separatorPrefix {
+					return fmt.Errorf("missing separator in template: '%s'", t)
+				}
+				continue
+			}
+			parts = append(parts, p)
+		}
+
 		// Ensure template string is non-empty
 		if len(parts) == 0 {
 			return fmt.Errorf("missing template at position: %d", i)
@@ -154,7 +167,7 @@ func (c *Config) validateTemplates() error {
 			return fmt.Errorf("invalid template format: '%s'", t)
 		}
 
-		template := t
+		template := parts[0]
 		filter := ""
 		tags := ""
 		if len(parts) >= 2 {
@@ -173,8 +186,13 @@ func (c *Config) validateTemplates() error {
 			tags = parts[2]
 		}
 
-		// Validate the template has one and only one measurement
-		if err := c.validateTemplate(template); err != nil {
+		// Validate the template has one and only one measurement, unless it
+		// drops the metrics matching its filter.
+		if template == dropTemplate {
+			if filter == "" {
+				return fmt.Errorf("drop template requires a filter at position: %d", i)
+			}
+		} else if err := c.validateTemplate(template); err != nil {
 			return err
 		}
 
@@ -165,3 +165,30 @@ func TestConfigValidateFilterDuplicates(t *testing.T) {
 	}
 
 }
+
+func TestConfigValidateDropTemplate(t *testing.T) {
+	c := &graphite.Config{}
+	c.Templates = []string{"servers.*.debug drop", "measurement*"}
+	if err := c.Validate(); err != nil {
+		t.Errorf("config validate expected success, got %v", err)
+	}
+
+	// A drop template must have a filter.
+	c.Templates = []string{"drop"}
+	if err := c.Validate(); err == nil {
+		t.Errorf("config validate expected error. got nil")
+	}
+}
+
+func TestConfigValidateTemplateSeparator(t *testing.T) {
+	c := &graphite.Config{}
+	c.Templates = []string{"servers.* .host.measurement* separator:_ region=us-west"}
+	if err := c.Validate(); err != nil {
+		t.Errorf("config validate expected success, got %v", err)
+	}
+
+	c.Templates = []string{"servers.* .host.measurement* separator:"}
+	if err := c.Validate(); err == nil {
+		t.Errorf("config validate expected error. got nil")
+	}
+}
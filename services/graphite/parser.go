@@ -47,41 +47,102 @@ func NewParserWithOptions(options Options) (*Parser, error) {
 	matcher.AddDefaultTemplate(defaultTemplate)
 
 	for _, pattern := range options.Templates {
+		spec, err := parseTemplateSpec(pattern)
+		if err != nil {
+			return nil, err
+		} else if spec == nil {
+			continue
+		}
 
-		template := pattern
-		filter := ""
-		// Format is [filter] <template> [tag1=value1,tag2=value2]
-		parts := strings.Fields(pattern)
-		if len(parts) < 1 {
+		// Metrics matching a drop template's filter are discarded.
+		if spec.template == dropTemplate {
+			matcher.Add(spec.filter, &template{drop: true})
 			continue
-		} else if len(parts) >= 2 {
-			if strings.Contains(parts[1], "=") {
-				template = parts[0]
-			} else {
-				filter = parts[0]
-				template = parts[1]
-			}
 		}
 
-		// Parse out the default tags specific to this template
-		tags := models.Tags{}
-		if strings.Contains(parts[len(parts)-1], "=") {
-			tagStrs := strings.Split(parts[len(parts)-1], ",")
-			for _, kv := range tagStrs {
-				parts := strings.Split(kv, "=")
-				tags[parts[0]] = parts[1]
-			}
+		separator := options.Separator
+		if spec.separator != "" {
+			separator = spec.separator
 		}
 
-		tmpl, err := NewTemplate(template, tags, options.Separator)
+		tmpl, err := NewTemplate(spec.template, spec.tags, separator)
 		if err != nil {
 			return nil, err
 		}
-		matcher.Add(filter, tmpl)
+		matcher.Add(spec.filter, tmpl)
 	}
 	return &Parser{matcher: matcher, tags: options.DefaultTags}, nil
 }
 
+// dropTemplate is used in place of a template to discard the metrics
+// matching its filter.
+const dropTemplate = "drop"
+
+// separatorPrefix starts the part of a template overriding the separator used
+// to join the template's measurement, field and tag parts.
+const separatorPrefix = "separator:"
+
+// templateSpec is a parsed template setting.
+type templateSpec struct {
+	filter    string
+	template  string
+	separator string
+	tags      models.Tags
+}
+
+// parseTemplateSpec parses a template setting of the form
+// [filter] <template> [separator:<separator>] [tag1=value1,tag2=value2].
+// It returns nil for an empty setting.
+func parseTemplateSpec(pattern string) (*templateSpec, error) {
+	spec := &templateSpec{tags: models.Tags{}}
+
+	var parts []string
+	for _, part := range strings.Fields(pattern) {
+		if strings.HasPrefix(part, separatorPrefix) {
+			spec.separator = strings.TrimPrefix(part, separatorPrefix)
+			if spec.separator == "" {
+				return nil, fmt.Errorf("missing separator in template: '%s'", pattern)
+			}
+			continue
+		}
+		parts = append(parts, part)
+	}
+
+	switch len(parts) {
+	case 0:
+		return nil, nil
+	case 1:
+		spec.template = parts[0]
+	case 2, 3:
+		// We could have <filter> <template> or <template> <tags>. Equals is
+		// only allowed in the tags section.
+		if strings.Contains(parts[1], "=") {
+			spec.template = parts[0]
+		} else {
+			spec.filter = parts[0]
+			spec.template = parts[1]
+		}
+	default:
+		return nil, fmt.Errorf("invalid template format: '%s'", pattern)
+	}
+
+	// Parse out the default tags specific to this template
+	if last := parts[len(parts)-1]; len(parts) > 1 && strings.Contains(last, "=") {
+		for _, kv := range strings.Split(last, ",") {
+			parts := strings.Split(kv, "=")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return nil, fmt.Errorf("invalid template tags: '%s'", kv)
+			}
+			spec.tags[parts[0]] = parts[1]
+		}
+	}
+
+	if spec.template == dropTemplate && spec.filter == "" {
+		return nil, fmt.Errorf("drop template requires a filter: '%s'", pattern)
+	}
+	return spec, nil
+}
+
 // NewParser returns a GraphiteParser instance.
 func NewParser(templates []string, defaultTags models.Tags) (*Parser, error) {
 	return NewParserWithOptions(
@@ -92,7 +153,8 @@ func NewParser(templates []string, defaultTags models.Tags) (*Parser, error) {
 		})
 }
 
-// Parse performs Graphite parsing of a single line.
+// Parse performs Graphite parsing of a single line. It returns ErrMetricDropped
+// if the metric matches a drop template.
 func (p *Parser) Parse(line string) (models.Point, error) {
 	// Break into 3 fields (name, value, timestamp).
 	fields := strings.Fields(line)
@@ -101,17 +163,11 @@ func (p *Parser) Parse(line string) (models.Point, error) {
 	}
 
 	// decode the name and tags
-	template := p.matcher.Match(fields[0])
-	measurement, tags, field, err := template.Apply(fields[0])
+	measurement, tags, field, err := p.apply(fields[0])
 	if err != nil {
 		return nil, err
 	}
 
-	// Could not extract measurement, use the raw value
-	if measurement == "" {
-		measurement = fields[0]
-	}
-
 	// Parse value.
 	v, err := strconv.ParseFloat(fields[1], 64)
 	if err != nil {
@@ -122,13 +178,6 @@ func (p *Parser) Parse(line string) (models.Point, error) {
 		return nil, &UnsupportedValueError{Field: fields[0], Value: v}
 	}
 
-	fieldValues := map[string]interface{}{}
-	if field != "" {
-		fieldValues[field] = v
-	} else {
-		fieldValues["value"] = v
-	}
-
 	// If no 3rd field, use now as timestamp
 	timestamp := time.Now().UTC()
 
@@ -139,17 +188,58 @@ func (p *Parser) Parse(line string) (models.Point, error) {
 			return nil, fmt.Errorf(`field "%s" time: %s`, fields[0], err)
 		}
 
-		// -1 is a special value that gets converted to current UTC time
-		// See https://github.com/graphite-project/carbon/issues/54
-		if unixTime != float64(-1) {
-			// Check if we have fractional seconds
-			timestamp = time.Unix(int64(unixTime), int64((unixTime-math.Floor(unixTime))*float64(time.Second)))
-			if timestamp.Before(MinDate) || timestamp.After(MaxDate) {
-				return nil, fmt.Errorf("timestamp out of range")
-			}
+		if timestamp, err = parseTimestamp(unixTime); err != nil {
+			return nil, err
 		}
 	}
 
+	return p.point(measurement, tags, field, v, timestamp)
+}
+
+// ParseMetric returns the point for a metric that has already been split into
+// its name, value and timestamp, such as one received with the pickle protocol.
+// It returns ErrMetricDropped if the metric matches a drop template.
+func (p *Parser) ParseMetric(name string, v float64, timestamp time.Time) (models.Point, error) {
+	measurement, tags, field, err := p.apply(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, &UnsupportedValueError{Field: name, Value: v}
+	}
+
+	return p.point(measurement, tags, field, v, timestamp)
+}
+
+// apply returns the measurement, tags and field of a metric from the template
+// matching its name.
+func (p *Parser) apply(name string) (string, map[string]string, string, error) {
+	template := p.matcher.Match(name)
+	if template.drop {
+		return "", nil, "", ErrMetricDropped
+	}
+	measurement, tags, field, err := template.Apply(name)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	// Could not extract measurement, use the raw value
+	if measurement == "" {
+		measurement = name
+	}
+	return measurement, tags, field, nil
+}
+
+// point returns a point with the value in field, and the default tags.
+func (p *Parser) point(measurement string, tags map[string]string, field string, v float64, timestamp time.Time) (models.Point, error) {
+	fieldValues := map[string]interface{}{}
+	if field != "" {
+		fieldValues[field] = v
+	} else {
+		fieldValues["value"] = v
+	}
+
 	// Set the default tags on the point if they are not already set
 	for k, v := range p.tags {
 		if _, ok := tags[k]; !ok {
@@ -159,6 +249,23 @@ func (p *Parser) Parse(line string) (models.Point, error) {
 	return models.NewPoint(measurement, tags, fieldValues, timestamp)
 }
 
+// parseTimestamp converts a Graphite timestamp in seconds since the epoch to
+// a time.
+func parseTimestamp(unixTime float64) (time.Time, error) {
+	// -1 is a special value that gets converted to current UTC time
+	// See https://github.com/graphite-project/carbon/issues/54
+	if unixTime == float64(-1) {
+		return time.Now().UTC(), nil
+	}
+
+	// Check if we have fractional seconds
+	timestamp := time.Unix(int64(unixTime), int64((unixTime-math.Floor(unixTime))*float64(time.Second)))
+	if timestamp.Before(MinDate) || timestamp.After(MaxDate) {
+		return time.Time{}, fmt.Errorf("timestamp out of range")
+	}
+	return timestamp, nil
+}
+
 // ApplyTemplate extracts the template fields from the given line and
 // returns the measurement name and tags.
 func (p *Parser) ApplyTemplate(line string) (string, map[string]string, string, error) {
@@ -169,6 +276,9 @@ func (p *Parser) ApplyTemplate(line string) (string, map[string]string, string,
 	}
 	// decode the name and tags
 	template := p.matcher.Match(fields[0])
+	if template.drop {
+		return "", make(map[string]string), "", ErrMetricDropped
+	}
 	name, tags, field, err := template.Apply(fields[0])
 	// Set the default tags on the point if they are not already set
 	for k, v := range p.tags {
@@ -185,6 +295,9 @@ type template struct {
 	defaultTags       models.Tags
 	greedyMeasurement bool
 	separator         string
+
+	// drop is set on templates that discard the metrics they match.
+	drop bool
 }
 
 // NewTemplate returns a new template ensuring it has a measurement
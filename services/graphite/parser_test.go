@@ -707,3 +707,56 @@ func TestApplyTemplateFieldError(t *testing.T) {
 			"'field' can only be used once in each template: current.users.logged_in")
 	}
 }
+
+// Test that metrics matching a drop template are discarded.
+func TestParseDropTemplate(t *testing.T) {
+	p, err := graphite.NewParserWithOptions(graphite.Options{
+		Separator: "_",
+		Templates: []string{
+			"servers.*.debug.* drop",
+			"servers.* .host.measurement*",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating parser, got %v", err)
+	}
+
+	if _, err := p.Parse("servers.localhost.debug.gc 1 1435077219"); err != graphite.ErrMetricDropped {
+		t.Fatalf("expected metric to be dropped, got %v", err)
+	}
+
+	pt, err := p.Parse("servers.localhost.cpu.load 1 1435077219")
+	if err != nil {
+		t.Fatalf("unexpected error parsing, got %v", err)
+	} else if pt.Name() != "cpu_load" || pt.Tags()["host"] != "localhost" {
+		t.Fatalf("unexpected point: %s", pt.String())
+	}
+}
+
+// Test that a template's separator overrides the default separator.
+func TestParseTemplateSeparator(t *testing.T) {
+	p, err := graphite.NewParserWithOptions(graphite.Options{
+		Separator: "_",
+		Templates: []string{
+			"servers.* .host.measurement* separator:. region=us-west",
+			"measurement.measurement.field",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating parser, got %v", err)
+	}
+
+	pt, err := p.Parse("servers.localhost.cpu.load 1 1435077219")
+	if err != nil {
+		t.Fatalf("unexpected error parsing, got %v", err)
+	} else if pt.Name() != "cpu.load" || pt.Tags()["region"] != "us-west" {
+		t.Fatalf("unexpected point: %s", pt.String())
+	}
+
+	pt, err = p.Parse("cpu.load.idle 1 1435077219")
+	if err != nil {
+		t.Fatalf("unexpected error parsing, got %v", err)
+	} else if pt.Name() != "cpu_load" {
+		t.Fatalf("unexpected point: %s", pt.String())
+	}
+}
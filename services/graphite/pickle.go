@@ -0,0 +1,544 @@
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// maxPickleSize is the largest pickled message accepted, the same limit
+// Carbon uses.
+const maxPickleSize = 1 << 20
+
+// Pickle opcodes understood by the decoder. Carbon senders pickle plain
+// lists of tuples, so only the opcodes needed for those are supported.
+const (
+	opMark            = '('
+	opStop            = '.'
+	opPop             = '0'
+	opPopMark         = '1'
+	opDup             = '2'
+	opFloat           = 'F'
+	opInt             = 'I'
+	opBinInt          = 'J'
+	opBinInt1         = 'K'
+	opLong            = 'L'
+	opBinInt2         = 'M'
+	opNone            = 'N'
+	opString          = 'S'
+	opBinString       = 'T'
+	opShortBinString  = 'U'
+	opUnicode         = 'V'
+	opBinUnicode      = 'X'
+	opAppend          = 'a'
+	opGet             = 'g'
+	opBinGet          = 'h'
+	opLongBinGet      = 'j'
+	opList            = 'l'
+	opPut             = 'p'
+	opBinPut          = 'q'
+	opLongBinPut      = 'r'
+	opTuple           = 't'
+	opAppends         = 'e'
+	opEmptyList       = ']'
+	opEmptyTuple      = ')'
+	opBinFloat        = 'G'
+	opProto           = 0x80
+	opTuple1          = 0x85
+	opTuple2          = 0x86
+	opTuple3          = 0x87
+	opNewTrue         = 0x88
+	opNewFalse        = 0x89
+	opLong1           = 0x8a
+	opShortBinUnicode = 0x8c
+	opMemoize         = 0x94
+	opFrame           = 0x95
+)
+
+// pickleMark marks the start of a list or tuple on the unpickler's stack.
+type pickleMark struct{}
+
+// pickleTuple is an unpickled tuple.
+type pickleTuple []interface{}
+
+// pickleMetric is a single metric from a Carbon pickle message.
+type pickleMetric struct {
+	name      string
+	value     float64
+	timestamp time.Time
+}
+
+// readPickleMessage reads a length-prefixed pickle message from r.
+func readPickleMessage(r io.Reader) ([]byte, error) {
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, err
+	} else if n > maxPickleSize {
+		return nil, fmt.Errorf("pickle message too large: %d bytes, max %d", n, maxPickleSize)
+	}
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// decodePickleMetrics decodes a Carbon pickle message, a list of
+// (path, (timestamp, value)) tuples.
+func decodePickleMetrics(buf []byte) ([]pickleMetric, error) {
+	v, err := unpickle(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("pickle message is %T, not a list", v)
+	}
+
+	metrics := make([]pickleMetric, 0, len(list))
+	for _, item := range list {
+		metric, ok := item.(pickleTuple)
+		if !ok || len(metric) != 2 {
+			return nil, fmt.Errorf("invalid pickled metric: %v", item)
+		}
+		name, ok := metric[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid pickled metric name: %v", metric[0])
+		}
+		datapoint, ok := metric[1].(pickleTuple)
+		if !ok || len(datapoint) != 2 {
+			return nil, fmt.Errorf("invalid pickled datapoint for %s: %v", name, metric[1])
+		}
+
+		unixTime, err := pickleFloat(datapoint[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s time: %s", name, err)
+		}
+		timestamp, err := parseTimestamp(unixTime)
+		if err != nil {
+			return nil, err
+		}
+		value, err := pickleFloat(datapoint[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s value: %s", name, err)
+		}
+
+		metrics = append(metrics, pickleMetric{name: name, value: value, timestamp: timestamp})
+	}
+	return metrics, nil
+}
+
+// pickleFloat converts an unpickled number, or string holding one, to a float.
+func pickleFloat(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// unpickle decodes a single pickled value.
+func unpickle(buf []byte) (interface{}, error) {
+	r := bytes.NewReader(buf)
+	var stack []interface{}
+	memo := make(map[int]interface{})
+
+	pop := func() (interface{}, error) {
+		if len(stack) == 0 {
+			return nil, errors.New("pickle stack underflow")
+		}
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v, nil
+	}
+	// popMark pops everything down to the topmost mark.
+	popMark := func() ([]interface{}, error) {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if _, ok := stack[i].(pickleMark); ok {
+				items := append([]interface{}{}, stack[i+1:]...)
+				stack = stack[:i]
+				return items, nil
+			}
+		}
+		return nil, errors.New("pickle mark not found")
+	}
+	top := func() (interface{}, error) {
+		if len(stack) == 0 {
+			return nil, errors.New("pickle stack underflow")
+		}
+		return stack[len(stack)-1], nil
+	}
+	appendTo := func(items []interface{}) error {
+		v, err := pop()
+		if err != nil {
+			return err
+		}
+		list, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("cannot append to %T", v)
+		}
+		stack = append(stack, append(list, items...))
+		return nil
+	}
+	tuple := func(n int) error {
+		if len(stack) < n {
+			return errors.New("pickle stack underflow")
+		}
+		t := append(pickleTuple{}, stack[len(stack)-n:]...)
+		stack = append(stack[:len(stack)-n], t)
+		return nil
+	}
+
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("pickle: %s", err)
+		}
+
+		switch op {
+		case opProto:
+			if _, err := r.ReadByte(); err != nil {
+				return nil, err
+			}
+		case opFrame:
+			if _, err := readBytes(r, 8); err != nil {
+				return nil, err
+			}
+		case opStop:
+			return pop()
+
+		case opMark:
+			stack = append(stack, pickleMark{})
+		case opPop:
+			if _, err := pop(); err != nil {
+				return nil, err
+			}
+		case opPopMark:
+			if _, err := popMark(); err != nil {
+				return nil, err
+			}
+		case opDup:
+			v, err := top()
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, v)
+
+		case opNone:
+			stack = append(stack, nil)
+		case opNewTrue:
+			stack = append(stack, true)
+		case opNewFalse:
+			stack = append(stack, false)
+
+		case opInt:
+			line, err := readLine(r)
+			if err != nil {
+				return nil, err
+			}
+			switch line {
+			case "00":
+				stack = append(stack, false)
+			case "01":
+				stack = append(stack, true)
+			default:
+				n, err := strconv.ParseInt(line, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("pickle int: %s", err)
+				}
+				stack = append(stack, n)
+			}
+		case opBinInt:
+			b, err := readBytes(r, 4)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, int64(int32(binary.LittleEndian.Uint32(b))))
+		case opBinInt1:
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, int64(b))
+		case opBinInt2:
+			b, err := readBytes(r, 2)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, int64(binary.LittleEndian.Uint16(b)))
+		case opLong:
+			line, err := readLine(r)
+			if err != nil {
+				return nil, err
+			}
+			if len(line) > 0 && line[len(line)-1] == 'L' {
+				line = line[:len(line)-1]
+			}
+			n, ok := new(big.Int).SetString(line, 10)
+			if !ok {
+				return nil, fmt.Errorf("pickle long: invalid value %q", line)
+			}
+			stack = append(stack, pickleInt(n))
+		case opLong1:
+			n, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			b, err := readBytes(r, int(n))
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, pickleInt(decodeLong(b)))
+
+		case opFloat:
+			line, err := readLine(r)
+			if err != nil {
+				return nil, err
+			}
+			f, err := strconv.ParseFloat(line, 64)
+			if err != nil {
+				return nil, fmt.Errorf("pickle float: %s", err)
+			}
+			stack = append(stack, f)
+		case opBinFloat:
+			b, err := readBytes(r, 8)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, math.Float64frombits(binary.BigEndian.Uint64(b)))
+
+		case opString:
+			line, err := readLine(r)
+			if err != nil {
+				return nil, err
+			}
+			s, err := strconv.Unquote(pythonQuoted(line))
+			if err != nil {
+				return nil, fmt.Errorf("pickle string: %s", err)
+			}
+			stack = append(stack, s)
+		case opUnicode:
+			line, err := readLine(r)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, line)
+		case opBinString, opBinUnicode:
+			b, err := readBytes(r, 4)
+			if err != nil {
+				return nil, err
+			}
+			s, err := readBytes(r, int(binary.LittleEndian.Uint32(b)))
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, string(s))
+		case opShortBinString, opShortBinUnicode:
+			n, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			s, err := readBytes(r, int(n))
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, string(s))
+
+		case opEmptyList:
+			stack = append(stack, []interface{}{})
+		case opList:
+			items, err := popMark()
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, items)
+		case opAppend:
+			v, err := pop()
+			if err != nil {
+				return nil, err
+			}
+			if err := appendTo([]interface{}{v}); err != nil {
+				return nil, err
+			}
+		case opAppends:
+			items, err := popMark()
+			if err != nil {
+				return nil, err
+			}
+			if err := appendTo(items); err != nil {
+				return nil, err
+			}
+
+		case opEmptyTuple:
+			stack = append(stack, pickleTuple{})
+		case opTuple:
+			items, err := popMark()
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, pickleTuple(items))
+		case opTuple1, opTuple2, opTuple3:
+			if err := tuple(int(op-opTuple1) + 1); err != nil {
+				return nil, err
+			}
+
+		case opPut, opBinPut, opLongBinPut, opMemoize:
+			var id int
+			switch op {
+			case opPut:
+				line, err := readLine(r)
+				if err != nil {
+					return nil, err
+				}
+				if id, err = strconv.Atoi(line); err != nil {
+					return nil, fmt.Errorf("pickle put: %s", err)
+				}
+			case opBinPut:
+				b, err := r.ReadByte()
+				if err != nil {
+					return nil, err
+				}
+				id = int(b)
+			case opLongBinPut:
+				b, err := readBytes(r, 4)
+				if err != nil {
+					return nil, err
+				}
+				id = int(binary.LittleEndian.Uint32(b))
+			case opMemoize:
+				id = len(memo)
+			}
+			v, err := top()
+			if err != nil {
+				return nil, err
+			}
+			memo[id] = v
+		case opGet, opBinGet, opLongBinGet:
+			var id int
+			switch op {
+			case opGet:
+				line, err := readLine(r)
+				if err != nil {
+					return nil, err
+				}
+				if id, err = strconv.Atoi(line); err != nil {
+					return nil, fmt.Errorf("pickle get: %s", err)
+				}
+			case opBinGet:
+				b, err := r.ReadByte()
+				if err != nil {
+					return nil, err
+				}
+				id = int(b)
+			case opLongBinGet:
+				b, err := readBytes(r, 4)
+				if err != nil {
+					return nil, err
+				}
+				id = int(binary.LittleEndian.Uint32(b))
+			}
+			v, ok := memo[id]
+			if !ok {
+				return nil, fmt.Errorf("pickle memo %d not found", id)
+			}
+			stack = append(stack, v)
+
+		default:
+			return nil, fmt.Errorf("unsupported pickle opcode 0x%02x", op)
+		}
+	}
+}
+
+// readLine reads a newline terminated argument of a text opcode.
+func readLine(r *bytes.Reader) (string, error) {
+	var line []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		} else if c == '\n' {
+			return string(line), nil
+		}
+		line = append(line, c)
+	}
+}
+
+// readBytes reads n bytes from r. The length comes from the message so it is
+// checked against the bytes left before anything is allocated.
+func readBytes(r *bytes.Reader, n int) ([]byte, error) {
+	if n < 0 || n > r.Len() {
+		return nil, fmt.Errorf("pickle length %d exceeds the %d bytes left in the message", n, r.Len())
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// decodeLong decodes a little-endian two's complement integer.
+func decodeLong(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	n := new(big.Int).SetBytes(be)
+	if len(b) > 0 && b[len(b)-1]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return n
+}
+
+// pickleInt returns n as an int64 if it fits.
+func pickleInt(n *big.Int) interface{} {
+	if n.IsInt64() {
+		return n.Int64()
+	}
+	return n
+}
+
+// pythonQuoted converts a single-quoted Python string literal into a double
+// quoted one Go can unquote.
+func pythonQuoted(s string) string {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return s
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	body := s[1 : len(s)-1]
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '\\' && i+1 < len(body) && body[i+1] == '\'':
+			buf.WriteByte('\'')
+			i++
+		case c == '\\' && i+1 < len(body):
+			buf.WriteByte(c)
+			buf.WriteByte(body[i+1])
+			i++
+		case c == '"':
+			buf.WriteString(`\"`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
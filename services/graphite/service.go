@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"expvar"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"net"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/freetsdb/freetsdb"
 	"github.com/freetsdb/freetsdb/cluster"
+	"github.com/freetsdb/freetsdb/models"
 	"github.com/freetsdb/freetsdb/monitor/diagnostics"
 	"github.com/freetsdb/freetsdb/services/meta"
 	"github.com/freetsdb/freetsdb/tsdb"
@@ -36,6 +38,11 @@ const (
 	statBatchesTransmitFail = "batchesTxFail"
 	statConnectionsActive   = "connsActive"
 	statConnectionsHandled  = "connsHandled"
+	statPointsDropped       = "pointsDropped"
+	statPickleDecodeFail    = "pickleDecodeFail"
+	statUDPPacketsReceived  = "udpPacketsRx"
+	statBatchesSizeFlush    = "batchesSizeFlush"
+	statBatchesTimeoutFlush = "batchesTimeoutFlush"
 )
 
 type tcpConnection struct {
@@ -135,7 +142,7 @@ func (s *Service) Open() error {
 
 	// Configure expvar monitoring. It's OK to do this even if the service fails to open and
 	// should be done before any data could arrive for the service.
-	tags := map[string]string{"proto": s.protocol, "bind": s.bindAddress}
+	tags := map[string]string{"proto": s.protocol, "bind": s.bindAddress, "database": s.database}
 	s.statMap = freetsdb.NewStatistics(s.diagsKey, "graphite", tags)
 
 	// Register diagnostics if a Monitor service is available.
@@ -156,9 +163,9 @@ func (s *Service) Open() error {
 	go s.processBatches(s.batcher)
 
 	var err error
-	if strings.ToLower(s.protocol) == "tcp" {
+	if p := strings.ToLower(s.protocol); p == "tcp" || p == "pickle" {
 		s.addr, err = s.openTCPServer()
-	} else if strings.ToLower(s.protocol) == "udp" {
+	} else if p == "udp" {
 		s.addr, err = s.openUDPServer()
 	} else {
 		return fmt.Errorf("unrecognized Graphite input protocol %s", s.protocol)
@@ -257,6 +264,10 @@ func (s *Service) handleTCPConnection(conn net.Conn) {
 	s.trackConnection(conn)
 
 	reader := bufio.NewReader(conn)
+	if strings.ToLower(s.protocol) == "pickle" {
+		s.readPickleMessages(reader)
+		return
+	}
 
 	for {
 		// Read up to the next newline.
@@ -274,6 +285,35 @@ func (s *Service) handleTCPConnection(conn net.Conn) {
 	}
 }
 
+// readPickleMessages reads metrics sent with the Carbon pickle protocol until
+// the connection is closed.
+func (s *Service) readPickleMessages(r io.Reader) {
+	for {
+		buf, err := readPickleMessage(r)
+		if err != nil {
+			if err != io.EOF {
+				s.logger.Printf("unable to read pickle message: %s", err)
+			}
+			return
+		}
+		s.statMap.Add(statBytesReceived, int64(len(buf)+4))
+
+		// Messages are length-prefixed, so a bad one can be skipped.
+		metrics, err := decodePickleMetrics(buf)
+		if err != nil {
+			s.logger.Printf("unable to decode pickle message: %s", err)
+			s.statMap.Add(statPickleDecodeFail, 1)
+			continue
+		}
+
+		s.statMap.Add(statPointsReceived, int64(len(metrics)))
+		for _, m := range metrics {
+			point, err := s.parser.ParseMetric(m.name, m.value, m.timestamp)
+			s.handlePoint(m.name, point, err)
+		}
+	}
+}
+
 func (s *Service) trackConnection(c net.Conn) {
 	s.tcpConnectionsMu.Lock()
 	defer s.tcpConnectionsMu.Unlock()
@@ -323,6 +363,7 @@ func (s *Service) openUDPServer() (net.Addr, error) {
 			for _, line := range lines {
 				s.handleLine(line)
 			}
+			s.statMap.Add(statUDPPacketsReceived, 1)
 			s.statMap.Add(statPointsReceived, int64(len(lines)))
 			s.statMap.Add(statBytesReceived, int64(n))
 		}
@@ -337,6 +378,12 @@ func (s *Service) handleLine(line string) {
 
 	// Parse it.
 	point, err := s.parser.Parse(line)
+	s.handlePoint(line, point, err)
+}
+
+// handlePoint batches a parsed point, or records why it couldn't be parsed
+// from the metric.
+func (s *Service) handlePoint(metric string, point models.Point, err error) {
 	if err != nil {
 		switch err := err.(type) {
 		case *UnsupportedValueError:
@@ -346,7 +393,11 @@ func (s *Service) handleLine(line string) {
 				return
 			}
 		}
-		s.logger.Printf("unable to parse line: %s: %s", line, err)
+		if err == ErrMetricDropped {
+			s.statMap.Add(statPointsDropped, 1)
+			return
+		}
+		s.logger.Printf("unable to parse line: %s: %s", metric, err)
 		s.statMap.Add(statPointsParseFail, 1)
 		return
 	}
@@ -372,6 +423,7 @@ func (s *Service) processBatches(batcher *tsdb.PointBatcher) {
 				s.logger.Printf("failed to write point batch to database %q: %s", s.database, err)
 				s.statMap.Add(statBatchesTransmitFail, 1)
 			}
+			s.updateBatcherStats(batcher)
 
 		case <-s.done:
 			return
@@ -379,6 +431,19 @@ func (s *Service) processBatches(batcher *tsdb.PointBatcher) {
 	}
 }
 
+// updateBatcherStats records why the batcher has flushed its batches.
+func (s *Service) updateBatcherStats(batcher *tsdb.PointBatcher) {
+	stats := batcher.Stats()
+
+	sizeFlush := new(expvar.Int)
+	sizeFlush.Set(int64(stats.SizeTotal))
+	s.statMap.Set(statBatchesSizeFlush, sizeFlush)
+
+	timeoutFlush := new(expvar.Int)
+	timeoutFlush.Set(int64(stats.TimeoutTotal))
+	s.statMap.Set(statBatchesTimeoutFlush, timeoutFlush)
+}
+
 // Diagnostics returns diagnostics of the graphite service.
 func (s *Service) Diagnostics() (*diagnostics.Diagnostics, error) {
 	s.tcpConnectionsMu.Lock()
@@ -1,6 +1,7 @@
 package graphite_test
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
 	"sync"
@@ -159,6 +160,78 @@ func Test_ServerGraphiteUDP(t *testing.T) {
 	conn.Close()
 }
 
+func Test_ServerGraphitePickle(t *testing.T) {
+	t.Parallel()
+
+	config := graphite.Config{}
+	config.Database = "graphitedb"
+	config.BatchSize = 3
+	config.BatchTimeout = toml.Duration(time.Second)
+	config.BindAddress = ":0"
+	config.Protocol = "pickle"
+	config.Templates = []string{"servers.*.debug drop", "servers.* .host.measurement*"}
+
+	service, err := graphite.NewService(config)
+	if err != nil {
+		t.Fatalf("failed to create Graphite service: %s", err.Error())
+	}
+
+	// Allow test to wait until points are written.
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	pointsWriter := PointsWriter{
+		WritePointsFn: func(req *cluster.WritePointsRequest) error {
+			defer wg.Done()
+
+			exp := []string{
+				"cpu,host=a value=1.5 1234567890000000000",
+				"cpu,host=b value=2 1234567891500000000",
+				"mem,host=c value=3 1234567892000000000",
+			}
+			if len(req.Points) != len(exp) {
+				t.Fatalf("expected %d points, got %d", len(exp), len(req.Points))
+			}
+			for i, pt := range req.Points {
+				if pt.String() != exp[i] {
+					t.Fatalf("expected point %v, got %v", exp[i], pt.String())
+				}
+			}
+			return nil
+		},
+	}
+	service.PointsWriter = &pointsWriter
+	service.MetaClient = &DatabaseCreator{}
+
+	if err := service.Open(); err != nil {
+		t.Fatalf("failed to open Graphite service: %s", err.Error())
+	}
+	defer service.Close()
+
+	_, port, _ := net.SplitHostPort(service.Addr().String())
+	conn, err := net.Dial("tcp", "127.0.0.1:"+port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Messages pickled with protocol 2 by Python 3, and protocol 0 by Python 2.
+	// The first message claims a 4GB string and must be skipped.
+	for _, msg := range []string{
+		"\x80\x02X\xf0\xff\xff\xffservers.a.cpu.",
+		"\x80\x02]q\x00(X\r\x00\x00\x00servers.a.cpuq\x01J\xd2\x02\x96IG?\xf8\x00\x00\x00\x00\x00\x00\x86q\x02\x86q\x03X\r\x00\x00\x00servers.b.cpuq\x04GA\xd2e\x80\xb4\xe0\x00\x00K\x02\x86q\x05\x86q\x06e.",
+		"(lp0\n(S'servers.a.debug'\np1\n(I1234567890\nF1.0\ntp2\ntp3\na(S'servers.c.mem'\np4\n(I1234567892\nI3\ntp5\ntp6\na.",
+	} {
+		var buf [4]byte
+		binary.BigEndian.PutUint32(buf[:], uint32(len(msg)))
+		if _, err := conn.Write(append(buf[:], msg...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wg.Wait()
+}
+
 // PointsWriter represents a mock impl of PointsWriter.
 type PointsWriter struct {
 	WritePointsFn func(*cluster.WritePointsRequest) error
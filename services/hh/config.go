@@ -30,6 +30,15 @@ const (
 	// will ever be.
 	DefaultRetryMaxInterval = time.Minute
 
+	// DefaultBatchSize is the default maximum number of bytes of queued writes
+	// for a shard that are coalesced into a single write to the node.
+	DefaultBatchSize = 512 * 1024
+
+	// DefaultMaxConcurrentWrites is the default maximum number of writes in
+	// flight to a node while its queue is replayed. Writes to the same shard
+	// are always sent one at a time, in order.
+	DefaultMaxConcurrentWrites = 4
+
 	// DefaultPurgeInterval is the amount of time the system waits before attempting
 	// to purge hinted handoff data due to age or inactive nodes.
 	DefaultPurgeInterval = time.Hour
@@ -45,6 +54,9 @@ type Config struct {
 	RetryInterval    toml.Duration `toml:"retry-interval"`
 	RetryMaxInterval toml.Duration `toml:"retry-max-interval"`
 	PurgeInterval    toml.Duration `toml:"purge-interval"`
+
+	BatchSize           int64 `toml:"batch-size"`
+	MaxConcurrentWrites int   `toml:"max-concurrent-writes"`
 }
 
 // NewConfig returns a new Config.
@@ -57,6 +69,9 @@ func NewConfig() Config {
 		RetryInterval:    toml.Duration(DefaultRetryInterval),
 		RetryMaxInterval: toml.Duration(DefaultRetryMaxInterval),
 		PurgeInterval:    toml.Duration(DefaultPurgeInterval),
+
+		BatchSize:           DefaultBatchSize,
+		MaxConcurrentWrites: DefaultMaxConcurrentWrites,
 	}
 }
 
@@ -64,5 +79,11 @@ func (c *Config) Validate() error {
 	if c.Enabled && c.Dir == "" {
 		return errors.New("HintedHandoff.Dir must be specified")
 	}
+	if c.Enabled && c.BatchSize <= 0 {
+		return errors.New("HintedHandoff.BatchSize must be positive")
+	}
+	if c.Enabled && c.MaxConcurrentWrites <= 0 {
+		return errors.New("HintedHandoff.MaxConcurrentWrites must be positive")
+	}
 	return nil
 }
@@ -19,6 +19,8 @@ max-size=2048
 max-age="20m"
 retry-rate-limit=1000
 purge-interval = "1h"
+batch-size = 4096
+max-concurrent-writes = 8
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -52,6 +54,14 @@ purge-interval = "1h"
 		t.Fatalf("unexpected purge interval: got %v, exp %v", c.PurgeInterval, exp)
 	}
 
+	if exp := int64(4096); c.BatchSize != exp {
+		t.Fatalf("unexpected batch size: got %v, exp %v", c.BatchSize, exp)
+	}
+
+	if exp := 8; c.MaxConcurrentWrites != exp {
+		t.Fatalf("unexpected max concurrent writes: got %v, exp %v", c.MaxConcurrentWrites, exp)
+	}
+
 }
 
 func TestDefaultDisabled(t *testing.T) {
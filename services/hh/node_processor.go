@@ -15,6 +15,15 @@ import (
 	"github.com/freetsdb/freetsdb/models"
 )
 
+// Statistics describing the queue of a NodeProcessor.
+const (
+	statQueueBytes    = "queueBytes"
+	statQueueSegments = "queueSegments"
+	statQueueAge      = "queueOldestAgeNs"
+	statPaused        = "paused"
+	statReplayRate    = "replayBytesPerSec"
+)
+
 // NodeProcessor encapsulates a queue of hinted-handoff data for a node, and the
 // transmission of the data to the node.
 type NodeProcessor struct {
@@ -24,13 +33,22 @@ type NodeProcessor struct {
 	MaxSize          int64         // Maximum size an underlying queue can get.
 	MaxAge           time.Duration // Maximum age queue data can get before purging.
 	RetryRateLimit   int64         // Limits the rate data is sent to node.
-	nodeID           uint64
-	dir              string
+
+	BatchSize           int64 // Maximum bytes of queued writes sent to a shard in one request.
+	MaxConcurrentWrites int   // Maximum requests in flight to the node at once.
+	nodeID              uint64
+	dir                 string
 
 	mu   sync.RWMutex
 	wg   sync.WaitGroup
 	done chan struct{}
 
+	// paused stops data being sent to the node, and replayRate is the bytes
+	// per second sent during the last replay.
+	statusMu   sync.RWMutex
+	paused     bool
+	replayRate float64
+
 	queue  *queue
 	meta   metaClient
 	writer shardWriter
@@ -46,17 +64,19 @@ func NewNodeProcessor(nodeID uint64, dir string, w shardWriter, m metaClient) *N
 	tags := map[string]string{"node": fmt.Sprintf("%d", nodeID), "path": dir}
 
 	return &NodeProcessor{
-		PurgeInterval:    DefaultPurgeInterval,
-		RetryInterval:    DefaultRetryInterval,
-		RetryMaxInterval: DefaultRetryMaxInterval,
-		MaxSize:          DefaultMaxSize,
-		MaxAge:           DefaultMaxAge,
-		nodeID:           nodeID,
-		dir:              dir,
-		writer:           w,
-		meta:             m,
-		statMap:          freetsdb.NewStatistics(key, "hh_processor", tags),
-		Logger:           log.New(os.Stderr, "[handoff] ", log.LstdFlags),
+		PurgeInterval:       DefaultPurgeInterval,
+		RetryInterval:       DefaultRetryInterval,
+		RetryMaxInterval:    DefaultRetryMaxInterval,
+		MaxSize:             DefaultMaxSize,
+		MaxAge:              DefaultMaxAge,
+		BatchSize:           DefaultBatchSize,
+		MaxConcurrentWrites: DefaultMaxConcurrentWrites,
+		nodeID:              nodeID,
+		dir:                 dir,
+		writer:              w,
+		meta:                m,
+		statMap:             freetsdb.NewStatistics(key, "hh_processor", tags),
+		Logger:              log.New(os.Stderr, "[handoff] ", log.LstdFlags),
 	}
 }
 
@@ -142,6 +162,129 @@ func (n *NodeProcessor) WriteShard(shardID uint64, points []models.Point) error
 	return n.queue.Append(b)
 }
 
+// Pause stops sending hinted-handoff data to the node until Resume is called.
+// Data is still queued while paused.
+func (n *NodeProcessor) Pause() {
+	n.statusMu.Lock()
+	defer n.statusMu.Unlock()
+	n.paused = true
+}
+
+// Resume starts sending hinted-handoff data to the node again.
+func (n *NodeProcessor) Resume() {
+	n.statusMu.Lock()
+	defer n.statusMu.Unlock()
+	n.paused = false
+}
+
+// Paused returns whether sending data to the node is paused.
+func (n *NodeProcessor) Paused() bool {
+	n.statusMu.RLock()
+	defer n.statusMu.RUnlock()
+	return n.paused
+}
+
+// NodeStatus describes the hinted-handoff queue of a node.
+type NodeStatus struct {
+	NodeID uint64
+	Active bool
+	Paused bool
+
+	// Segments is the number of segment files in the queue, and Size the
+	// number of bytes in them not yet sent to the node.
+	Segments int
+	Size     int64
+
+	// Oldest is the time the oldest segment holding unsent data was last
+	// modified, and LastModified the time data was last queued.
+	Oldest       time.Time
+	LastModified time.Time
+
+	// Head and Tail are the segment positions data is read and written at.
+	Head string
+	Tail string
+
+	// ReplayRate is the bytes per second sent during the last replay.
+	ReplayRate float64
+}
+
+// Status returns the status of the NodeProcessor's queue.
+func (n *NodeProcessor) Status() (*NodeStatus, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.done == nil {
+		return nil, fmt.Errorf("node processor is closed")
+	}
+
+	qs, err := n.queue.Status()
+	if err != nil {
+		return nil, err
+	}
+	qp, err := n.queue.Position()
+	if err != nil {
+		return nil, err
+	}
+	lm, err := n.LastModified()
+	if err != nil {
+		return nil, err
+	}
+	active, err := n.Active()
+	if err != nil {
+		return nil, err
+	}
+
+	n.statusMu.RLock()
+	defer n.statusMu.RUnlock()
+	return &NodeStatus{
+		NodeID:       n.nodeID,
+		Active:       active,
+		Paused:       n.paused,
+		Segments:     qs.segments,
+		Size:         qs.pending,
+		Oldest:       qs.oldest.UTC(),
+		LastModified: lm,
+		Head:         qp.head,
+		Tail:         qp.tail,
+		ReplayRate:   n.replayRate,
+	}, nil
+}
+
+// updateStats updates the statistics describing the queue.
+func (n *NodeProcessor) updateStats() {
+	qs, err := n.queue.Status()
+	if err != nil {
+		n.Logger.Printf("failed to determine queue status for node %d: %s", n.nodeID, err.Error())
+		return
+	}
+
+	var age int64
+	if qs.pending > 0 {
+		age = int64(time.Since(qs.oldest))
+	}
+	var paused int64
+	if n.Paused() {
+		paused = 1
+	}
+	n.statusMu.RLock()
+	rate := n.replayRate
+	n.statusMu.RUnlock()
+
+	for k, v := range map[string]int64{
+		statQueueBytes:    qs.pending,
+		statQueueSegments: int64(qs.segments),
+		statQueueAge:      age,
+		statPaused:        paused,
+	} {
+		stat := new(expvar.Int)
+		stat.Set(v)
+		n.statMap.Set(k, stat)
+	}
+	rateStat := new(expvar.Float)
+	rateStat.Set(rate)
+	n.statMap.Set(statReplayRate, rateStat)
+}
+
 // LastModified returns the time the NodeProcessor last receieved hinted-handoff data.
 func (n *NodeProcessor) LastModified() (time.Time, error) {
 	t, err := n.queue.LastModified()
@@ -172,7 +315,13 @@ func (n *NodeProcessor) run() {
 			}
 
 		case <-time.After(currInterval):
+			n.updateStats()
+			if n.Paused() {
+				continue
+			}
+
 			limiter := NewRateLimiter(n.RetryRateLimit)
+			start, sent := time.Now(), 0
 			for {
 				c, err := n.SendWrite()
 				if err != nil {
@@ -193,17 +342,32 @@ func (n *NodeProcessor) run() {
 
 				// Update how many bytes we've sent
 				limiter.Update(c)
+				sent += c
+
+				// Stop replaying as soon as the node is paused.
+				if n.Paused() {
+					break
+				}
 
 				// Block to maintain the throughput rate
 				time.Sleep(limiter.Delay())
 			}
+
+			if sent > 0 {
+				n.statusMu.Lock()
+				n.replayRate = float64(sent) / time.Since(start).Seconds()
+				n.statusMu.Unlock()
+				n.updateStats()
+			}
 		}
 	}
 }
 
-// SendWrite attempts to sent the current block of hinted data to the target node. If successful,
-// it returns the number of bytes it sent and advances to the next block. Otherwise returns EOF
-// when there is no more data or the node is inactive.
+// SendWrite attempts to send the hinted data at the head of the queue to the target node.
+// Consecutive writes for the same shard are coalesced into batches of up to BatchSize bytes,
+// and batches for up to MaxConcurrentWrites shards are sent at once, each shard's in order.
+// It returns the number of bytes it sent and advances past them, along with the first error
+// if any batch failed. It returns EOF when there is no more data or the node is inactive.
 func (n *NodeProcessor) SendWrite() (int, error) {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
@@ -216,35 +380,109 @@ func (n *NodeProcessor) SendWrite() (int, error) {
 		return 0, io.EOF
 	}
 
-	// Get the current block from the queue
-	buf, err := n.queue.Current()
-	if err != nil {
-		return 0, err
+	concurrency := n.MaxConcurrentWrites
+	if concurrency < 1 {
+		concurrency = 1
 	}
 
-	// unmarshal the byte slice back to shard ID and points
-	shardID, points, err := unmarshalWrite(buf)
+	// Read enough blocks from the queue to give every concurrent write a full batch.
+	blocks, err := n.queue.Peek(n.BatchSize * int64(concurrency))
 	if err != nil {
-		n.Logger.Printf("unmarshal write failed: %v", err)
-		// Try to skip it.
-		if err := n.queue.Advance(); err != nil {
-			n.Logger.Printf("failed to advance queue for node %d: %s", n.nodeID, err.Error())
-		}
 		return 0, err
 	}
 
-	if err := n.writer.WriteShard(shardID, n.nodeID, points); err != nil {
-		n.statMap.Add(writeNodeReqFail, 1)
-		return 0, err
+	// Send the batches for each shard in order, stopping at the first failure.
+	batches := n.batchWrites(blocks)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed, failedErr := len(blocks), error(nil)
+	sem := make(chan struct{}, concurrency)
+	for _, shardBatches := range batches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(shardBatches []*writeBatch) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			for _, b := range shardBatches {
+				if err := n.sendBatch(b); err != nil {
+					mu.Lock()
+					if b.first < failed {
+						failed, failedErr = b.first, err
+					}
+					mu.Unlock()
+					return
+				}
+			}
+		}(shardBatches)
 	}
-	n.statMap.Add(writeNodeReq, 1)
-	n.statMap.Add(writeNodeReqPoints, int64(len(points)))
+	wg.Wait()
 
-	if err := n.queue.Advance(); err != nil {
+	// The queue can only advance past the blocks before the first failed batch.
+	// Batches sent after it are sent again on the next attempt.
+	var sent int
+	for _, b := range blocks[:failed] {
+		sent += len(b)
+	}
+	if err := n.queue.AdvanceN(failed); err != nil {
 		n.Logger.Printf("failed to advance queue for node %d: %s", n.nodeID, err.Error())
 	}
 
-	return len(buf), nil
+	return sent, failedErr
+}
+
+// writeBatch is a set of consecutive queued writes for a shard.
+type writeBatch struct {
+	shardID uint64
+	points  []models.Point
+	size    int64
+
+	// first is the index of the batch's first block in the blocks read.
+	first int
+}
+
+// batchWrites unmarshals blocks read from the queue and coalesces consecutive
+// writes for the same shard. The batches are returned grouped by shard, in
+// the order their shards first appear.
+func (n *NodeProcessor) batchWrites(blocks [][]byte) [][]*writeBatch {
+	var batches [][]*writeBatch
+	shards := make(map[uint64]int)
+
+	var cur *writeBatch
+	for i, buf := range blocks {
+		// unmarshal the byte slice back to shard ID and points
+		shardID, points, err := unmarshalWrite(buf)
+		if err != nil {
+			// Skip it, the queue advances past it with the batches around it.
+			n.Logger.Printf("unmarshal write failed: %v", err)
+			continue
+		}
+
+		if cur == nil || cur.shardID != shardID || cur.size+int64(len(buf)) > n.BatchSize {
+			cur = &writeBatch{shardID: shardID, first: i}
+			j, ok := shards[shardID]
+			if !ok {
+				j = len(batches)
+				shards[shardID] = j
+				batches = append(batches, nil)
+			}
+			batches[j] = append(batches[j], cur)
+		}
+		cur.points = append(cur.points, points...)
+		cur.size += int64(len(buf))
+	}
+	return batches
+}
+
+// sendBatch writes a batch to the target node.
+func (n *NodeProcessor) sendBatch(b *writeBatch) error {
+	if err := n.writer.WriteShard(b.shardID, n.nodeID, b.points); err != nil {
+		n.statMap.Add(writeNodeReqFail, 1)
+		return err
+	}
+	n.statMap.Add(writeNodeReq, 1)
+	n.statMap.Add(writeNodeReqPoints, int64(len(b.points)))
+	return nil
 }
 
 // Head returns the head of the processor's queue.
@@ -1,9 +1,12 @@
 package hh
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -153,3 +156,152 @@ func TestNodeProcessorSendBlock(t *testing.T) {
 		t.Fatalf("Node processor directory still present after purge")
 	}
 }
+
+func TestNodeProcessorPause(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node_processor_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	pt := models.MustNewPoint("cpu", models.Tags{"foo": "bar"}, models.Fields{"value": 1.0}, time.Unix(0, 0))
+
+	sent := make(chan struct{}, 1)
+	sh := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			sent <- struct{}{}
+			return nil
+		},
+	}
+	metastore := &fakeMetaStore{
+		NodeFn: func(nodeID uint64) (*meta.NodeInfo, error) {
+			return &meta.NodeInfo{}, nil
+		},
+	}
+
+	n := NewNodeProcessor(1, dir, sh, metastore)
+	n.RetryInterval = 10 * time.Millisecond
+	n.Pause()
+	if err := n.Open(); err != nil {
+		t.Fatalf("Failed to open node processor: %v", err)
+	}
+	defer n.Close()
+
+	if err := n.WriteShard(100, []models.Point{pt}); err != nil {
+		t.Fatalf("WriteShard() failed to write points: %v", err)
+	}
+
+	// Nothing should be sent while paused, and the write should stay queued.
+	select {
+	case <-sent:
+		t.Fatalf("write sent to paused node")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	st, err := n.Status()
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if !st.Paused || !st.Active || st.Segments != 1 {
+		t.Fatalf("Status() mismatch: %#v", st)
+	}
+	if exp := int64(8 + 8 + len(pt.String()) + 1); st.Size != exp {
+		t.Fatalf("Status() queue size mismatch: got %v, exp %v", st.Size, exp)
+	}
+	if st.Oldest.IsZero() {
+		t.Fatalf("Status() oldest not set")
+	}
+
+	// Resuming sends the write and empties the queue.
+	n.Resume()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatalf("write not sent to resumed node")
+	}
+
+	for i := 0; ; i++ {
+		if st, err = n.Status(); err != nil {
+			t.Fatalf("Status() failed: %v", err)
+		} else if st.Size == 0 && st.ReplayRate > 0 {
+			break
+		} else if i == 100 {
+			t.Fatalf("Status() mismatch after resume: %#v", st)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st.Paused || !st.Oldest.IsZero() {
+		t.Fatalf("Status() mismatch after resume: %#v", st)
+	}
+}
+
+func TestNodeProcessorSendWriteBatches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node_processor_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	pt := models.MustNewPoint("cpu", models.Tags{"foo": "bar"}, models.Fields{"value": 1.0}, time.Unix(0, 0))
+
+	var mu sync.Mutex
+	var fail bool
+	writes := make(map[uint64][]int)
+	sh := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if fail && shardID == 2 {
+				return fmt.Errorf("write failed")
+			}
+			writes[shardID] = append(writes[shardID], len(points))
+			return nil
+		},
+	}
+	metastore := &fakeMetaStore{
+		NodeFn: func(nodeID uint64) (*meta.NodeInfo, error) {
+			return &meta.NodeInfo{}, nil
+		},
+	}
+
+	n := NewNodeProcessor(1, dir, sh, metastore)
+	n.RetryInterval, n.RetryMaxInterval = time.Hour, time.Hour
+	if err := n.Open(); err != nil {
+		t.Fatalf("Failed to open node processor: %v", err)
+	}
+	defer n.Close()
+
+	for _, shardID := range []uint64{1, 1, 2, 1, 1} {
+		if err := n.WriteShard(shardID, []models.Point{pt}); err != nil {
+			t.Fatalf("WriteShard() failed to write points: %v", err)
+		}
+	}
+
+	// Shard 2 fails, so only the two writes queued before it are sent for good.
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	if _, err := n.SendWrite(); err == nil || err.Error() != "write failed" {
+		t.Fatalf("SendWrite() expected error: got %v", err)
+	}
+	if got, exp := writes[1], []int{2, 2}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("SendWrite() shard 1 batches mismatch: got %v, exp %v", got, exp)
+	}
+
+	// The failed write and the writes after it are sent again.
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	if _, err := n.SendWrite(); err != nil {
+		t.Fatalf("SendWrite() failed to write points: %v", err)
+	}
+	if got, exp := writes[1], []int{2, 2, 2}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("SendWrite() shard 1 batches mismatch: got %v, exp %v", got, exp)
+	} else if got, exp := writes[2], []int{1}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("SendWrite() shard 2 batches mismatch: got %v, exp %v", got, exp)
+	}
+
+	if _, err := n.SendWrite(); err != io.EOF {
+		t.Fatalf("SendWrite() expected io.EOF: got %v", err)
+	}
+}
@@ -78,6 +78,19 @@ type queuePos struct {
 	tail string
 }
 
+// queueStatus describes how much data is waiting in a queue.
+type queueStatus struct {
+	// segments is the number of segment files on disk.
+	segments int
+
+	// pending is the number of bytes in blocks the head hasn't advanced past.
+	pending int64
+
+	// oldest is the time the first segment holding pending blocks was last
+	// modified. It's the same time PurgeOlderThan ages data by.
+	oldest time.Time
+}
+
 type segments []*segment
 
 // newQueue create a queue that will store segments in dir and that will
@@ -233,6 +246,29 @@ func (l *queue) Position() (*queuePos, error) {
 	return qp, nil
 }
 
+// Status returns the number of segments and pending bytes in the queue.
+func (l *queue) Status() (*queueStatus, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	qs := &queueStatus{segments: len(l.segments)}
+	for _, s := range l.segments {
+		n := s.pending()
+		if n == 0 {
+			continue
+		}
+		if qs.pending == 0 {
+			mod, err := s.lastModified()
+			if err != nil {
+				return nil, err
+			}
+			qs.oldest = mod
+		}
+		qs.pending += n
+	}
+	return qs, nil
+}
+
 // diskUsage returns the total size on disk used by the queue
 func (l *queue) diskUsage() int64 {
 	var size int64
@@ -370,6 +406,68 @@ func (l *queue) Advance() error {
 	return nil
 }
 
+// Peek returns the byte slices from the head of the queue on, without
+// advancing the head, until maxBytes have been read. At least one byte
+// slice is returned unless the queue is empty, in which case io.EOF is
+// returned.
+func (l *queue) Peek(maxBytes int64) ([][]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.head == nil {
+		return nil, ErrNotOpen
+	}
+
+	var blocks [][]byte
+	var n int64
+	for _, s := range l.segments {
+		pos := s.position()
+		for n < maxBytes || len(blocks) == 0 {
+			b, next, err := s.peek(pos)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, b)
+			n += int64(len(b))
+			pos = next
+		}
+	}
+
+	if len(blocks) == 0 {
+		return nil, io.EOF
+	}
+	return blocks, nil
+}
+
+// AdvanceN moves the head pointer past the next n byte slices in the queue.
+func (l *queue) AdvanceN(n int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.head == nil {
+		return ErrNotOpen
+	}
+
+	for i := 0; i < n; i++ {
+		// Skip segments that were read to the end before the next was added.
+		for l.head.pending() == 0 && len(l.segments) > 1 {
+			if err := l.trimHead(); err != nil {
+				return err
+			}
+		}
+
+		if err := l.head.advance(); err == io.EOF {
+			if err := l.trimHead(); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (l *queue) trimHead() error {
 	if len(l.segments) > 1 {
 		l.segments = l.segments[1:]
@@ -560,6 +658,48 @@ func (l *segment) current() ([]byte, error) {
 	return b, nil
 }
 
+// peek returns the byte slice at pos in the segment and the position of the
+// byte slice following it. It returns io.EOF at the end of the segment.
+func (l *segment) peek(pos int64) ([]byte, int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil, 0, ErrNotOpen
+	}
+	if pos >= l.size-footerSize {
+		return nil, 0, io.EOF
+	}
+
+	if err := l.seek(pos); err != nil {
+		return nil, 0, err
+	}
+
+	// read the record size
+	sz, err := l.readUint64()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if int64(sz) > l.maxSize {
+		return nil, 0, fmt.Errorf("record size out of range: max %d: got %d", l.maxSize, sz)
+	}
+
+	b := make([]byte, sz)
+	if err := l.readBytes(b); err != nil {
+		return nil, 0, err
+	}
+
+	return b, pos + 8 + int64(sz), nil
+}
+
+// position returns the position of the current byte slice in the segment.
+func (l *segment) position() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.pos
+}
+
 // advance advances the current value pointer
 func (l *segment) advance() error {
 	l.mu.Lock()
@@ -634,6 +774,13 @@ func (l *segment) diskUsage() int64 {
 	return l.size
 }
 
+// pending returns the number of bytes in the blocks from the current block on.
+func (l *segment) pending() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.size - footerSize - l.pos
+}
+
 func (l *segment) SetMaxSegmentSize(size int64) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
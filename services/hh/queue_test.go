@@ -279,6 +279,80 @@ func TestQueueReopen(t *testing.T) {
 	}
 }
 
+func TestQueuePeekAdvanceN(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hh_queue")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// create the queue
+	q, err := newQueue(dir, 1024)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+
+	if err := q.Open(); err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+
+	if _, err := q.Peek(1024); err != io.EOF {
+		t.Fatalf("Queue.Peek expected io.EOF: got %v", err)
+	}
+
+	// Roll over to a new segment after every append.
+	if err := q.SetMaxSegmentSize(20); err != nil {
+		t.Fatalf("Queue.SetMaxSegmentSize failed: %v", err)
+	}
+	for _, v := range []string{"one", "two", "three", "four"} {
+		if err := q.Append([]byte(v)); err != nil {
+			t.Fatalf("Queue.Append failed: %v", err)
+		}
+	}
+	if got, exp := len(q.segments), 4; got != exp {
+		t.Fatalf("Queue segments mismatch: got %v, exp %v", got, exp)
+	}
+
+	blocks, err := q.Peek(6)
+	if err != nil {
+		t.Fatalf("Queue.Peek failed: %v", err)
+	}
+	if got, exp := len(blocks), 2; got != exp {
+		t.Fatalf("Queue.Peek length mismatch: got %v, exp %v", got, exp)
+	}
+	if got, exp := string(blocks[1]), "two"; got != exp {
+		t.Errorf("Queue.Peek mismatch: got %v, exp %v", got, exp)
+	}
+
+	// Peeking doesn't move the head.
+	cur, err := q.Current()
+	if err != nil {
+		t.Fatalf("Queue.Current failed: %v", err)
+	}
+	if exp := "one"; string(cur) != exp {
+		t.Errorf("Queue.Current mismatch: got %v, exp %v", string(cur), exp)
+	}
+
+	if err := q.AdvanceN(3); err != nil {
+		t.Fatalf("Queue.AdvanceN failed: %v", err)
+	}
+
+	blocks, err = q.Peek(1024)
+	if err != nil {
+		t.Fatalf("Queue.Peek failed: %v", err)
+	}
+	if len(blocks) != 1 || string(blocks[0]) != "four" {
+		t.Errorf("Queue.Peek mismatch after advance: got %q", blocks)
+	}
+
+	if err := q.AdvanceN(1); err != nil {
+		t.Fatalf("Queue.AdvanceN failed: %v", err)
+	}
+	if _, err := q.Peek(1024); err != io.EOF {
+		t.Fatalf("Queue.Peek expected io.EOF: got %v", err)
+	}
+}
+
 func TestPurgeQueue(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping purge queue")
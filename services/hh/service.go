@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,6 +23,9 @@ import (
 // disabled hinted handoff service.
 var ErrHintedHandoffDisabled = fmt.Errorf("hinted handoff disabled")
 
+// ErrNodeNotFound is returned when there is no hinted handoff queue for a node.
+var ErrNodeNotFound = fmt.Errorf("no hinted handoff queue for node")
+
 const (
 	writeShardReq       = "writeShardReq"
 	writeShardReqPoints = "writeShardReqPoints"
@@ -110,7 +114,7 @@ func (s *Service) Open() error {
 			continue
 		}
 
-		n := NewNodeProcessor(nodeID, s.pathforNode(nodeID), s.shardWriter, s.MetaClient)
+		n := s.newNodeProcessor(nodeID)
 		if err := n.Open(); err != nil {
 			return err
 		}
@@ -172,7 +176,7 @@ func (s *Service) WriteShard(shardID, ownerID uint64, points []models.Point) err
 
 			processor, ok = s.processors[ownerID]
 			if !ok {
-				processor = NewNodeProcessor(ownerID, s.pathforNode(ownerID), s.shardWriter, s.MetaClient)
+				processor = s.newNodeProcessor(ownerID)
 				if err := processor.Open(); err != nil {
 					return err
 				}
@@ -217,6 +221,92 @@ func (s *Service) Diagnostics() (*diagnostics.Diagnostics, error) {
 	return d, nil
 }
 
+// NodeStatuses returns the status of the hinted handoff queue of every node,
+// ordered by node ID.
+func (s *Service) NodeStatuses() ([]*NodeStatus, error) {
+	if !s.cfg.Enabled {
+		return nil, ErrHintedHandoffDisabled
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]*NodeStatus, 0, len(s.processors))
+	for _, p := range s.processors {
+		st, err := p.Status()
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, st)
+	}
+	sort.Sort(nodeStatuses(statuses))
+	return statuses, nil
+}
+
+// PurgeNode deletes all hinted handoff data queued for a node.
+func (s *Service) PurgeNode(nodeID uint64) error {
+	if !s.cfg.Enabled {
+		return ErrHintedHandoffDisabled
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.processors[nodeID]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	if err := p.Close(); err != nil {
+		return err
+	}
+	if err := p.Purge(); err != nil {
+		return err
+	}
+	delete(s.processors, nodeID)
+
+	s.Logger.Printf("purged hinted handoff queue for node %d", nodeID)
+	return nil
+}
+
+// PauseNode stops sending hinted handoff data to a node. Writes for the node
+// are still queued.
+func (s *Service) PauseNode(nodeID uint64) error {
+	p, err := s.processor(nodeID)
+	if err != nil {
+		return err
+	}
+	p.Pause()
+	s.Logger.Printf("paused hinted handoff for node %d", nodeID)
+	return nil
+}
+
+// ResumeNode starts sending hinted handoff data to a paused node again.
+func (s *Service) ResumeNode(nodeID uint64) error {
+	p, err := s.processor(nodeID)
+	if err != nil {
+		return err
+	}
+	p.Resume()
+	s.Logger.Printf("resumed hinted handoff for node %d", nodeID)
+	return nil
+}
+
+// processor returns the node processor for a node.
+func (s *Service) processor(nodeID uint64) (*NodeProcessor, error) {
+	if !s.cfg.Enabled {
+		return nil, ErrHintedHandoffDisabled
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.processors[nodeID]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	return p, nil
+}
+
 // purgeInactiveProcessors will cause the service to remove processors for inactive nodes.
 func (s *Service) purgeInactiveProcessors() {
 	defer s.wg.Done()
@@ -269,6 +359,28 @@ func (s *Service) purgeInactiveProcessors() {
 	}
 }
 
+// nodeStatuses sorts node statuses by node ID.
+type nodeStatuses []*NodeStatus
+
+func (a nodeStatuses) Len() int           { return len(a) }
+func (a nodeStatuses) Less(i, j int) bool { return a[i].NodeID < a[j].NodeID }
+func (a nodeStatuses) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// newNodeProcessor returns a node processor for a node, configured by the service's config.
+func (s *Service) newNodeProcessor(nodeID uint64) *NodeProcessor {
+	n := NewNodeProcessor(nodeID, s.pathforNode(nodeID), s.shardWriter, s.MetaClient)
+	n.PurgeInterval = time.Duration(s.cfg.PurgeInterval)
+	n.RetryInterval = time.Duration(s.cfg.RetryInterval)
+	n.RetryMaxInterval = time.Duration(s.cfg.RetryMaxInterval)
+	n.MaxSize = s.cfg.MaxSize
+	n.MaxAge = time.Duration(s.cfg.MaxAge)
+	n.RetryRateLimit = s.cfg.RetryRateLimit
+	n.BatchSize = s.cfg.BatchSize
+	n.MaxConcurrentWrites = s.cfg.MaxConcurrentWrites
+	n.Logger = s.Logger
+	return n
+}
+
 // pathforNode returns the directory for HH data, for the given node.
 func (s *Service) pathforNode(nodeID uint64) string {
 	return filepath.Join(s.cfg.Dir, fmt.Sprintf("%d", nodeID))
@@ -1,5 +1,10 @@
 package httpd
 
+const (
+	// DefaultMaxBodySize is the default maximum size of a write request body.
+	DefaultMaxBodySize = 25000000
+)
+
 // Config represents a configuration for a HTTP service.
 type Config struct {
 	Enabled          bool   `toml:"enabled"`
@@ -11,6 +16,13 @@ type Config struct {
 	HTTPSEnabled     bool   `toml:"https-enabled"`
 	HTTPSCertificate string `toml:"https-certificate"`
 	JSONWriteEnabled bool   `toml:"json-write-enabled"`
+	SharedSecret     string `toml:"shared-secret"`
+	MaxBodySize      int    `toml:"max-body-size"`
+
+	// MaxRowLimit is the maximum number of rows in the response to a query
+	// that isn't chunked. Larger responses are truncated and marked as
+	// partial. Zero is unlimited.
+	MaxRowLimit int `toml:"max-row-limit"`
 }
 
 // NewConfig returns a new Config with default settings.
@@ -22,5 +34,6 @@ func NewConfig() Config {
 		HTTPSEnabled:     false,
 		HTTPSCertificate: "/etc/ssl/freetsdb.pem",
 		JSONWriteEnabled: false,
+		MaxBodySize:      DefaultMaxBodySize,
 	}
 }
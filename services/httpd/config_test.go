@@ -19,6 +19,7 @@ write-tracing = true
 pprof-enabled = true
 https-enabled = true
 https-certificate = "/dev/null"
+shared-secret = "s3cret"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -40,6 +41,8 @@ https-certificate = "/dev/null"
 		t.Fatalf("unexpected https enabled: %v", c.HTTPSEnabled)
 	} else if c.HTTPSCertificate != "/dev/null" {
 		t.Fatalf("unexpected https certificate: %v", c.HTTPSCertificate)
+	} else if c.SharedSecret != "s3cret" {
+		t.Fatalf("unexpected shared secret: %v", c.SharedSecret)
 	}
 }
 
@@ -2,21 +2,29 @@ package httpd_test
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/freetsdb/freetsdb"
 	"github.com/freetsdb/freetsdb/client"
+	"github.com/freetsdb/freetsdb/cluster"
 	"github.com/freetsdb/freetsdb/influxql"
 	"github.com/freetsdb/freetsdb/models"
+	"github.com/freetsdb/freetsdb/pkg/msgpack"
+	"github.com/freetsdb/freetsdb/services/audit"
 	"github.com/freetsdb/freetsdb/services/httpd"
 	"github.com/freetsdb/freetsdb/services/meta"
 )
@@ -132,6 +140,70 @@ func TestBatchWrite_UnmarshalRFC(t *testing.T) {
 	}
 }
 
+// Ensure the handler truncates unchunked responses to the row limit.
+func TestHandler_Query_MaxRowLimit(t *testing.T) {
+	h := NewHandler(false)
+	h.MaxRowLimit = 3
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, closing chan struct{}) <-chan *influxql.Result {
+		results := make(chan *influxql.Result)
+		go func() {
+			defer close(results)
+			for i := 0; i < 3; i++ {
+				select {
+				case <-closing:
+					return
+				case results <- &influxql.Result{StatementID: 0, Series: models.Rows([]*models.Row{{
+					Name:    "cpu",
+					Columns: []string{"value"},
+					Values:  [][]interface{}{{float64(2 * i)}, {float64(2*i + 1)}},
+				}})}:
+				}
+			}
+		}()
+		return results
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+cpu", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"results":[{"series":[{"name":"cpu","columns":["value"],"values":[[0],[1],[2]]}],"partial":true}]}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler binds integer parameters and passes the rest to the executor.
+func TestHandler_Query_Params(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, closing chan struct{}) <-chan *influxql.Result {
+		if q.String() != `SELECT * FROM bar WHERE host = $host LIMIT 10` {
+			t.Fatalf("unexpected query: %s", q.String())
+		}
+		return NewResultChan(&influxql.Result{StatementID: 1, Series: models.Rows([]*models.Row{{Name: "series0"}})})
+	}
+
+	w := httptest.NewRecorder()
+	params := url.QueryEscape(`{"host":"serverA","limit":10}`)
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar+WHERE+host+%3D+$host+LIMIT+$limit&params="+params, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"results":[{"series":[{"name":"series0"}]}]}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler returns a status 400 if the query parameters are invalid.
+func TestHandler_Query_ErrInvalidParams(t *testing.T) {
+	h := NewHandler(false)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar+LIMIT+$limit&params=%7B", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if !strings.HasPrefix(w.Body.String(), `{"error":"error parsing query parameters: `) {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
 // Ensure the handler returns results from a query (including nil results).
 func TestHandler_Query(t *testing.T) {
 	h := NewHandler(false)
@@ -233,6 +305,132 @@ func TestHandler_Query_Chunked(t *testing.T) {
 	}
 }
 
+// Ensure the handler returns chunked results and errors as CSV when requested.
+func TestHandler_Query_CSV(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, closing chan struct{}) <-chan *influxql.Result {
+		return NewResultChan(
+			&influxql.Result{StatementID: 1, Series: models.Rows([]*models.Row{{
+				Name:    "cpu",
+				Tags:    map[string]string{"region": "west", "host": "a"},
+				Columns: []string{"time", "value"},
+				Values:  [][]interface{}{{time.Unix(0, 10).UTC(), 1.5}, {time.Unix(0, 20).UTC(), nil}},
+			}})},
+			&influxql.Result{StatementID: 1, Series: models.Rows([]*models.Row{{
+				Name:    "mem",
+				Columns: []string{"time", "free", "ok"},
+				Values:  [][]interface{}{{time.Unix(0, 10).UTC(), int64(100), true}},
+			}})},
+			&influxql.Result{StatementID: 2, Err: errors.New("measurement not found")},
+		)
+	}
+
+	w := httptest.NewRecorder()
+	r := MustNewRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar&chunked=true", nil)
+	r.Header.Set("Accept", "application/csv")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if ct := w.Header().Get("content-type"); ct != "application/csv" {
+		t.Fatalf("unexpected content type: %s", ct)
+	} else if exp := "name,tags,time,value\n" +
+		"cpu,\"host=a,region=west\",1970-01-01T00:00:00.00000001Z,1.5\n" +
+		"cpu,\"host=a,region=west\",1970-01-01T00:00:00.00000002Z,\n" +
+		"name,tags,time,free,ok\n" +
+		"mem,,1970-01-01T00:00:00.00000001Z,100,true\n" +
+		"error\n" +
+		"measurement not found\n"; w.Body.String() != exp {
+		t.Fatalf("unexpected body:\n%s", w.Body.String())
+	}
+}
+
+// Ensure the handler marks CSV results truncated by the row limit as partial.
+func TestHandler_Query_CSV_MaxRowLimit(t *testing.T) {
+	h := NewHandler(false)
+	h.MaxRowLimit = 2
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, closing chan struct{}) <-chan *influxql.Result {
+		return NewResultChan(&influxql.Result{StatementID: 0, Series: models.Rows([]*models.Row{{
+			Name:    "cpu",
+			Columns: []string{"value"},
+			Values:  [][]interface{}{{float64(0)}, {float64(1)}, {float64(2)}},
+		}})})
+	}
+
+	w := httptest.NewRecorder()
+	r := MustNewRequest("GET", "/query?db=foo&q=SELECT+*+FROM+cpu", nil)
+	r.Header.Set("Accept", "application/csv")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if exp := "name,tags,value\n" +
+		"cpu,,0\n" +
+		"cpu,,1\n" +
+		"partial\n" +
+		"true\n"; w.Body.String() != exp {
+		t.Fatalf("unexpected body:\n%s", w.Body.String())
+	}
+}
+
+// Ensure the handler returns results as MessagePack when requested.
+func TestHandler_Query_Msgpack(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, closing chan struct{}) <-chan *influxql.Result {
+		return NewResultChan(
+			&influxql.Result{StatementID: 1, Series: models.Rows([]*models.Row{{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "a"},
+				Columns: []string{"time", "value"},
+				Values:  [][]interface{}{{int64(10), 1.5}},
+			}})},
+			&influxql.Result{StatementID: 2, Err: errors.New("measurement not found")},
+		)
+	}
+
+	w := httptest.NewRecorder()
+	r := MustNewRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+	r.Header.Set("Accept", "application/x-msgpack, application/json;q=0.9")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if ct := w.Header().Get("content-type"); ct != "application/x-msgpack" {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+
+	v, err := msgpack.NewDecoder(w.Body).Decode()
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := map[string]interface{}{"results": []interface{}{
+		map[string]interface{}{"series": []interface{}{map[string]interface{}{
+			"name":    "cpu",
+			"tags":    map[string]interface{}{"host": "a"},
+			"columns": []interface{}{"time", "value"},
+			"values":  []interface{}{[]interface{}{int64(10), 1.5}},
+		}}},
+		map[string]interface{}{"error": "measurement not found"},
+	}}; !reflect.DeepEqual(v, exp) {
+		t.Fatalf("unexpected response: %#v", v)
+	}
+}
+
+// Ensure request errors are returned in the requested format.
+func TestHandler_Query_ErrQueryRequired_Msgpack(t *testing.T) {
+	h := NewHandler(false)
+	w := httptest.NewRecorder()
+	r := MustNewRequest("GET", "/query", nil)
+	r.Header.Set("Accept", "application/x-msgpack")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	v, err := msgpack.NewDecoder(w.Body).Decode()
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := map[string]interface{}{"error": `missing required parameter "q"`}; !reflect.DeepEqual(v, exp) {
+		t.Fatalf("unexpected response: %#v", v)
+	}
+}
+
 // Ensure the handler returns a status 400 if the query is not passed in.
 func TestHandler_Query_ErrQueryRequired(t *testing.T) {
 	h := NewHandler(false)
@@ -289,6 +487,167 @@ func TestHandler_Query_ErrResult(t *testing.T) {
 
 // Ensure the handler handles ping requests correctly.
 // TODO: This should be expanded to verify the MetaClient check in servePing is working correctly
+// Ensure the handler authenticates requests carrying a signed JSON Web Token.
+func TestHandler_Write_BearerJWT(t *testing.T) {
+	h := NewHandler(true)
+	h.SharedSecret = "s3cret"
+	h.MetaClient.UsersFn = func() []meta.UserInfo {
+		return []meta.UserInfo{{Name: "jdoe"}}
+	}
+	h.MetaClient.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return nil, nil
+	}
+
+	// Authenticated requests get as far as looking up the database.
+	for token, code := range map[string]int{
+		MustJWT("s3cret", "jdoe", time.Now().Add(time.Minute)):   http.StatusNotFound,
+		MustJWT("s3cret", "jdoe", time.Now().Add(-time.Minute)):  http.StatusUnauthorized,
+		MustJWT("other", "jdoe", time.Now().Add(time.Minute)):    http.StatusUnauthorized,
+		MustJWT("s3cret", "nobody", time.Now().Add(time.Minute)): http.StatusUnauthorized,
+	} {
+		w := httptest.NewRecorder()
+		r := MustNewRequest("POST", "/write?db=foo", bytes.NewBufferString("cpu value=1"))
+		r.Header.Set("Authorization", "Bearer "+token)
+		h.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+		}
+	}
+}
+
+// Ensure the handler authenticates requests carrying an API token.
+func TestHandler_Write_BearerAPIToken(t *testing.T) {
+	h := NewHandler(true)
+	h.MetaClient.UsersFn = func() []meta.UserInfo {
+		return []meta.UserInfo{{Name: "jdoe"}}
+	}
+	h.MetaClient.AuthenticateTokenFn = func(token string) (*meta.UserInfo, error) {
+		if token != "abc123" {
+			return nil, meta.ErrAuthenticate
+		}
+		return &meta.UserInfo{Name: "jdoe"}, nil
+	}
+	h.MetaClient.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return nil, nil
+	}
+
+	for token, code := range map[string]int{"abc123": http.StatusNotFound, "xyz": http.StatusUnauthorized} {
+		w := httptest.NewRecorder()
+		r := MustNewRequest("POST", "/write?db=foo", bytes.NewBufferString("cpu value=1"))
+		r.Header.Set("Authorization", "Bearer "+token)
+		h.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Fatalf("unexpected status for %q: %d: %s", token, w.Code, w.Body.String())
+		}
+	}
+}
+
+// Ensure the handler rejects writes over the write limits with a retry time.
+func TestHandler_Write_Throttled(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaClient.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+	h.Handler.PointsWriter = PointsWriterFunc(func(p *cluster.WritePointsRequest) error {
+		return &cluster.WriteThrottledError{Limit: "test", RetryAfter: 1500 * time.Millisecond}
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo", bytes.NewBufferString("cpu value=1")))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if v := w.Header().Get("Retry-After"); v != "2" {
+		t.Fatalf("unexpected Retry-After: %q", v)
+	}
+}
+
+// Ensure the handler rejects write bodies over the maximum size.
+func TestHandler_Write_MaxBodySize(t *testing.T) {
+	h := NewHandler(false)
+	h.MaxBodySize = 10
+	h.MetaClient.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+	h.Handler.PointsWriter = PointsWriterFunc(func(p *cluster.WritePointsRequest) error { return nil })
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo", bytes.NewBufferString("cpu value=100")))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo", bytes.NewBufferString("cpu v=1")))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// Ensure the handler records failed authentication attempts in the audit log.
+func TestHandler_AuthFailure_Audit(t *testing.T) {
+	h := NewHandler(true)
+	h.MetaClient.UsersFn = func() []meta.UserInfo {
+		return []meta.UserInfo{{Name: "jdoe"}}
+	}
+	h.MetaClient.AuthenticateFn = func(username, password string) (*meta.UserInfo, error) {
+		return nil, meta.ErrAuthenticate
+	}
+
+	var records []*audit.Record
+	h.Auditor = AuditorFunc(func(r *audit.Record) { records = append(records, r) })
+
+	w := httptest.NewRecorder()
+	r := MustNewRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar&u=jdoe&p=wrong", nil)
+	r.RemoteAddr = "10.0.0.1:51234"
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("unexpected records: %+v", records)
+	} else if r := records[0]; r.Event != audit.EventAuthentication || r.Result != audit.ResultFailure || r.User != "jdoe" || r.Addr != "10.0.0.1" || r.Error != meta.ErrAuthenticate.Error() {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+}
+
+// Ensure the handler records queries denied by authorization in the audit log.
+func TestHandler_AuthzFailure_Audit(t *testing.T) {
+	h := NewHandler(true)
+	h.MetaClient.UsersFn = func() []meta.UserInfo {
+		return []meta.UserInfo{{Name: "jdoe"}}
+	}
+	h.MetaClient.AuthenticateFn = func(username, password string) (*meta.UserInfo, error) {
+		return &meta.UserInfo{Name: username}, nil
+	}
+	h.QueryAuthorizer = QueryAuthorizerFunc(func(u *meta.UserInfo, query *influxql.Query, database string) error {
+		return &meta.ErrAuthorize{
+			Query:     query,
+			Statement: query.Statements[0],
+			User:      u.Name,
+			Database:  database,
+			Message:   "statement 'DROP DATABASE foo', requires admin privilege",
+		}
+	})
+
+	var records []*audit.Record
+	h.Auditor = AuditorFunc(func(r *audit.Record) { records = append(records, r) })
+
+	w := httptest.NewRecorder()
+	r := MustNewRequest("GET", "/query?db=foo&q=DROP+DATABASE+foo&u=jdoe&p=secret", nil)
+	r.RemoteAddr = "10.0.0.1:51234"
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("unexpected records: %+v", records)
+	} else if r := records[0]; r.Event != audit.EventAuthorization || r.Result != audit.ResultFailure || r.User != "jdoe" || r.Addr != "10.0.0.1" || r.Database != "foo" || r.Statement != "DROP DATABASE foo" || r.Error != "jdoe not authorized to execute statement 'DROP DATABASE foo', requires admin privilege" {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+}
+
 func TestHandler_Ping(t *testing.T) {
 	h := NewHandler(false)
 	w := httptest.NewRecorder()
@@ -477,10 +836,11 @@ func NewHandler(requireAuthentication bool) *Handler {
 
 // HandlerMetaStore is a mock implementation of Handler.MetaClient.
 type HandlerMetaStore struct {
-	PingFn         func(d time.Duration) error
-	DatabaseFn     func(name string) (*meta.DatabaseInfo, error)
-	AuthenticateFn func(username, password string) (ui *meta.UserInfo, err error)
-	UsersFn        func() []meta.UserInfo
+	PingFn              func(d time.Duration) error
+	DatabaseFn          func(name string) (*meta.DatabaseInfo, error)
+	AuthenticateFn      func(username, password string) (ui *meta.UserInfo, err error)
+	AuthenticateTokenFn func(token string) (ui *meta.UserInfo, err error)
+	UsersFn             func() []meta.UserInfo
 }
 
 func (s *HandlerMetaStore) Ping(b bool) error {
@@ -499,10 +859,31 @@ func (s *HandlerMetaStore) Authenticate(username, password string) (ui *meta.Use
 	return s.AuthenticateFn(username, password)
 }
 
+func (s *HandlerMetaStore) AuthenticateToken(token string) (ui *meta.UserInfo, err error) {
+	return s.AuthenticateTokenFn(token)
+}
+
 func (s *HandlerMetaStore) Users() []meta.UserInfo {
 	return s.UsersFn()
 }
 
+// QueryAuthorizerFunc is a function that can be used as Handler.QueryAuthorizer.
+type QueryAuthorizerFunc func(u *meta.UserInfo, query *influxql.Query, database string) error
+
+func (fn QueryAuthorizerFunc) AuthorizeQuery(u *meta.UserInfo, query *influxql.Query, database string) error {
+	return fn(u, query, database)
+}
+
+// AuditorFunc is a function that can be used as Handler.Auditor.
+type AuditorFunc func(r *audit.Record)
+
+func (fn AuditorFunc) Audit(r *audit.Record) { fn(r) }
+
+// PointsWriterFunc is a function that can be used as Handler.PointsWriter.
+type PointsWriterFunc func(p *cluster.WritePointsRequest) error
+
+func (fn PointsWriterFunc) WritePoints(p *cluster.WritePointsRequest) error { return fn(p) }
+
 // HandlerQueryExecutor is a mock implementation of Handler.QueryExecutor.
 type HandlerQueryExecutor struct {
 	AuthorizeFn    func(u *meta.UserInfo, q *influxql.Query, db string) error
@@ -513,8 +894,22 @@ func (e *HandlerQueryExecutor) Authorize(u *meta.UserInfo, q *influxql.Query, db
 	return e.AuthorizeFn(u, q, db)
 }
 
-func (e *HandlerQueryExecutor) ExecuteQuery(q *influxql.Query, db string, chunkSize int, closing chan struct{}) <-chan *influxql.Result {
-	return e.ExecuteQueryFn(q, db, chunkSize, closing)
+func (e *HandlerQueryExecutor) ExecuteQuery(q *influxql.Query, opt influxql.ExecutionOptions, closing chan struct{}) <-chan *influxql.Result {
+	return e.ExecuteQueryFn(q, opt.Database, opt.ChunkSize, closing)
+}
+
+// MustJWT returns an HS256 signed JSON Web Token for username. Panic on error.
+func MustJWT(secret, username string, exp time.Time) string {
+	enc := base64.RawURLEncoding
+	claims, err := json.Marshal(map[string]interface{}{"username": username, "exp": exp.Unix()})
+	if err != nil {
+		panic(err)
+	}
+	s := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claims)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(s))
+	return s + "." + enc.EncodeToString(mac.Sum(nil))
 }
 
 // MustNewRequest returns a new HTTP request. Panic on error.
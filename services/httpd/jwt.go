@@ -0,0 +1,102 @@
+package httpd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrTokenInvalid is returned when a JSON Web Token is malformed or its
+	// signature doesn't match.
+	ErrTokenInvalid = errors.New("invalid token")
+
+	// ErrTokenExpired is returned when a JSON Web Token has expired.
+	ErrTokenExpired = errors.New("token expired")
+
+	// ErrTokenUsernameRequired is returned when a JSON Web Token doesn't
+	// carry a username claim.
+	ErrTokenUsernameRequired = errors.New("token username required")
+
+	// ErrSharedSecretRequired is returned when a JSON Web Token is presented
+	// but no shared secret is configured.
+	ErrSharedSecretRequired = errors.New("token authentication requires a shared secret")
+)
+
+// jwtHeader is the decoded JOSE header of a JSON Web Token.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// jwtClaims are the claims of a JSON Web Token understood by the server.
+type jwtClaims struct {
+	Username string `json:"username"`
+	Exp      int64  `json:"exp"`
+}
+
+// isJWT returns true if token has the three part structure of a JSON Web Token.
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// parseJWT validates an HS256 signed JSON Web Token against secret and
+// returns the username it was issued for. The token must carry an "exp"
+// claim in the future and a non-empty "username" claim.
+func parseJWT(token, secret string, now time.Time) (string, error) {
+	if secret == "" {
+		return "", ErrSharedSecretRequired
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrTokenInvalid
+	}
+
+	// Only HS256 is accepted. Checking the algorithm before the signature
+	// rejects "none" and asymmetric algorithms.
+	var header jwtHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return "", err
+	} else if header.Alg != "HS256" {
+		return "", ErrTokenInvalid
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrTokenInvalid
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", ErrTokenInvalid
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return "", err
+	}
+
+	if claims.Exp == 0 || now.Unix() >= claims.Exp {
+		return "", ErrTokenExpired
+	} else if claims.Username == "" {
+		return "", ErrTokenUsernameRequired
+	}
+	return claims.Username, nil
+}
+
+// decodeJWTSegment decodes a base64url encoded JSON segment into v.
+func decodeJWTSegment(seg string, v interface{}) error {
+	buf, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrTokenInvalid
+	}
+	if err := json.Unmarshal(buf, v); err != nil {
+		return ErrTokenInvalid
+	}
+	return nil
+}
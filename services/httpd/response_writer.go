@@ -0,0 +1,249 @@
+package httpd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/freetsdb/freetsdb/influxql"
+	"github.com/freetsdb/freetsdb/pkg/msgpack"
+)
+
+// Media types of the query response formats.
+const (
+	contentTypeJSON    = "application/json"
+	contentTypeCSV     = "application/csv"
+	contentTypeMsgpack = "application/x-msgpack"
+)
+
+// ResponseWriter is an http.ResponseWriter that writes query responses in
+// the format requested by the client.
+type ResponseWriter interface {
+	// WriteResponse writes a response and returns the number of bytes written.
+	WriteResponse(resp Response) (int, error)
+
+	http.ResponseWriter
+}
+
+// NewResponseWriter returns a new ResponseWriter for the format named in the
+// Accept header of r. JSON is used if no supported format is accepted.
+func NewResponseWriter(w http.ResponseWriter, r *http.Request) ResponseWriter {
+	pretty := r.URL.Query().Get("pretty") == "true"
+	rw := &responseWriter{ResponseWriter: w}
+
+	switch contentType := acceptedContentType(r.Header.Get("Accept")); contentType {
+	case contentTypeCSV:
+		rw.formatter = &csvFormatter{}
+		w.Header().Add("content-type", contentTypeCSV)
+	case contentTypeMsgpack:
+		rw.formatter = &msgpackFormatter{}
+		w.Header().Add("content-type", contentTypeMsgpack)
+	default:
+		rw.formatter = &jsonFormatter{Pretty: pretty}
+		w.Header().Add("content-type", contentTypeJSON)
+	}
+	return rw
+}
+
+// acceptedContentType returns the first media type in accept with a supported
+// response format.
+func acceptedContentType(accept string) string {
+	for _, s := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(s))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case contentTypeJSON, contentTypeMsgpack:
+			return mediaType
+		case contentTypeCSV, "text/csv":
+			return contentTypeCSV
+		}
+	}
+	return contentTypeJSON
+}
+
+// formatter encodes responses in a format.
+type formatter interface {
+	WriteResponse(w io.Writer, resp Response) error
+}
+
+type responseWriter struct {
+	formatter formatter
+	http.ResponseWriter
+}
+
+// WriteResponse writes resp in the response writer's format.
+func (w *responseWriter) WriteResponse(resp Response) (int, error) {
+	cw := &countWriter{Writer: w.ResponseWriter}
+	err := w.formatter.WriteResponse(cw, resp)
+	return cw.n, err
+}
+
+// Flush flushes the underlying writer if it supports flushing.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify returns the close notification channel of the underlying writer.
+func (w *responseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return nil
+}
+
+// countWriter counts the bytes written to a writer.
+type countWriter struct {
+	io.Writer
+	n int
+}
+
+func (w *countWriter) Write(b []byte) (int, error) {
+	n, err := w.Writer.Write(b)
+	w.n += n
+	return n, err
+}
+
+type jsonFormatter struct {
+	Pretty bool
+}
+
+func (f *jsonFormatter) WriteResponse(w io.Writer, resp Response) error {
+	_, err := w.Write(MarshalJSON(resp, f.Pretty))
+	return err
+}
+
+// csvFormatter writes a header row of name, tags and the columns for each
+// series followed by its values. Errors are written as an error column and
+// a result truncated by a row limit is followed by a partial column.
+type csvFormatter struct{}
+
+func (f *csvFormatter) WriteResponse(w io.Writer, resp Response) error {
+	cw := csv.NewWriter(w)
+	if resp.Err != nil {
+		cw.Write([]string{"error"})
+		cw.Write([]string{resp.Err.Error()})
+		cw.Flush()
+		return cw.Error()
+	}
+
+	for _, result := range resp.Results {
+		if result.Err != nil {
+			cw.Write([]string{"error"})
+			cw.Write([]string{result.Err.Error()})
+			continue
+		}
+
+		for _, row := range result.Series {
+			record := make([]string, 0, len(row.Columns)+2)
+			record = append(record, "name", "tags")
+			record = append(record, row.Columns...)
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+
+			tags := make([]string, 0, len(row.Tags))
+			for k, v := range row.Tags {
+				tags = append(tags, k+"="+v)
+			}
+			sort.Strings(tags)
+
+			for _, values := range row.Values {
+				record = append(record[:0], row.Name, strings.Join(tags, ","))
+				for _, v := range values {
+					record = append(record, csvValue(v))
+				}
+				if err := cw.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+
+		if result.Partial {
+			cw.Write([]string{"partial"})
+			cw.Write([]string{"true"})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvValue returns the string representation of a value in a CSV record.
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// msgpackFormatter writes a response with the same structure as its JSON
+// encoding. Chunked responses are written as a sequence of responses.
+type msgpackFormatter struct{}
+
+func (f *msgpackFormatter) WriteResponse(w io.Writer, resp Response) error {
+	o := make(map[string]interface{}, 2)
+	if resp.Err != nil {
+		o["error"] = resp.Err.Error()
+	}
+	if len(resp.Results) > 0 {
+		results := make([]interface{}, len(resp.Results))
+		for i, result := range resp.Results {
+			results[i] = msgpackResult(result)
+		}
+		o["results"] = results
+	}
+	return msgpack.NewEncoder(w).Encode(o)
+}
+
+func msgpackResult(result *influxql.Result) map[string]interface{} {
+	o := make(map[string]interface{}, 3)
+	if result.Err != nil {
+		o["error"] = result.Err.Error()
+	}
+	if result.Partial {
+		o["partial"] = true
+	}
+	if len(result.Series) > 0 {
+		series := make([]interface{}, len(result.Series))
+		for i, row := range result.Series {
+			s := make(map[string]interface{}, 4)
+			if row.Name != "" {
+				s["name"] = row.Name
+			}
+			if len(row.Tags) > 0 {
+				s["tags"] = row.Tags
+			}
+			if len(row.Columns) > 0 {
+				s["columns"] = row.Columns
+			}
+			if len(row.Values) > 0 {
+				s["values"] = row.Values
+			}
+			series[i] = s
+		}
+		o["series"] = series
+	}
+	return o
+}
@@ -23,6 +23,7 @@ const (
 	statPingRequest                  = "pingReq"            // Number of ping requests served
 	statStatusRequest                = "statusReq"          // Number of status requests served
 	statWriteRequestBytesReceived    = "writeReqBytes"      // Sum of all bytes in write requests
+	statWriteRequestThrottled        = "writeReqThrottled"  // Number of write requests rejected by write limits
 	statQueryRequestBytesTransmitted = "queryRespBytes"     // Sum of all bytes returned in query reponses
 	statPointsWrittenOK              = "pointsWrittenOK"    // Number of points written OK
 	statPointsWrittenFail            = "pointsWrittenFail"  // Number of points that failed to be written
@@ -70,6 +71,9 @@ func NewService(c Config) *Service {
 		Logger: log.New(os.Stderr, "[httpd] ", log.LstdFlags),
 	}
 	s.Handler.Logger = s.Logger
+	s.Handler.SharedSecret = c.SharedSecret
+	s.Handler.MaxBodySize = c.MaxBodySize
+	s.Handler.MaxRowLimit = c.MaxRowLimit
 	return s
 }
 
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	crand "crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -588,6 +591,21 @@ func (c *Client) SetPrivilege(username, database string, p influxql.Privilege) e
 	)
 }
 
+// SetMeasurementPrivilege sets a privilege for a user on a measurement,
+// optionally restricted to the series matching a tag condition.
+func (c *Client) SetMeasurementPrivilege(username, database, measurement string, p influxql.Privilege, condition string) error {
+	cmd := &internal.SetMeasurementPrivilegeCommand{
+		Username:    proto.String(username),
+		Database:    proto.String(database),
+		Measurement: proto.String(measurement),
+		Privilege:   proto.Int32(int32(p)),
+	}
+	if condition != "" {
+		cmd.Condition = proto.String(condition)
+	}
+	return c.retryUntilExec(internal.Command_SetMeasurementPrivilegeCommand, internal.E_SetMeasurementPrivilegeCommand_Command, cmd)
+}
+
 func (c *Client) SetAdminPrivilege(username string, admin bool) error {
 	return c.retryUntilExec(internal.Command_SetAdminPrivilegeCommand, internal.E_SetAdminPrivilegeCommand_Command,
 		&internal.SetAdminPrivilegeCommand{
@@ -613,6 +631,21 @@ func (c *Client) UserPrivilege(username, database string) (*influxql.Privilege,
 	return p, nil
 }
 
+// UserMeasurementPrivilege returns the grant a user holds on a measurement,
+// or nil if there is none.
+func (c *Client) UserMeasurementPrivilege(username, database, measurement string) (*MeasurementPrivilege, error) {
+	ui := c.data().User(username)
+	if ui == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if mp := ui.MeasurementPrivilege(database, measurement); mp != nil {
+		other := *mp
+		return &other, nil
+	}
+	return nil, nil
+}
+
 func (c *Client) AdminUserExists() bool {
 	for _, u := range c.data().Users {
 		if u.Admin {
@@ -657,6 +690,92 @@ func (c *Client) Authenticate(username, password string) (*UserInfo, error) {
 	return userInfo, nil
 }
 
+// CreateToken creates a new API token for a user and returns its secret.
+// The secret is only available at creation time; the meta store keeps a hash.
+// If privileges is nil the token carries all of the user's privileges.
+func (c *Client) CreateToken(name, username string, privileges map[string]influxql.Privilege) (string, error) {
+	data := c.data()
+	if name == "" {
+		return "", ErrTokenNameRequired
+	} else if data.Token(name) != nil {
+		return "", ErrTokenExists
+	} else if data.User(username) == nil {
+		return "", ErrUserNotFound
+	}
+
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(crand.Reader, buf); err != nil {
+		return "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(buf)
+
+	ti := TokenInfo{
+		Name:       name,
+		Username:   username,
+		Hash:       hashToken(secret),
+		Scoped:     privileges != nil,
+		Privileges: privileges,
+	}
+
+	if err := c.retryUntilExec(internal.Command_CreateTokenCommand, internal.E_CreateTokenCommand_Command,
+		&internal.CreateTokenCommand{
+			Token: ti.marshal(),
+		},
+	); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// DropToken removes an API token.
+func (c *Client) DropToken(name string) error {
+	if c.data().Token(name) == nil {
+		return ErrTokenNotFound
+	}
+
+	return c.retryUntilExec(internal.Command_DropTokenCommand, internal.E_DropTokenCommand_Command,
+		&internal.DropTokenCommand{
+			Name: proto.String(name),
+		},
+	)
+}
+
+// Tokens returns the list of API tokens.
+func (c *Client) Tokens() []TokenInfo {
+	tokens := c.data().Tokens
+	if tokens == nil {
+		return []TokenInfo{}
+	}
+	return tokens
+}
+
+// AuthenticateToken returns the user an API token authenticates as, with
+// privileges narrowed to the scope of the token.
+func (c *Client) AuthenticateToken(token string) (*UserInfo, error) {
+	data := c.data()
+
+	hash := []byte(hashToken(token))
+	for i := range data.Tokens {
+		ti := &data.Tokens[i]
+		if subtle.ConstantTimeCompare([]byte(ti.Hash), hash) != 1 {
+			continue
+		}
+
+		ui := data.User(ti.Username)
+		if ui == nil {
+			return nil, ErrUserNotFound
+		}
+		return ti.Authorize(ui), nil
+	}
+	return nil, ErrAuthenticate
+}
+
+// hashToken returns the hex encoded SHA-256 hash of an API token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func (c *Client) UserCount() int {
 	return len(c.data().Users)
 }
@@ -951,6 +1070,27 @@ func (c *Client) DropSubscription(database, rp, name string) error {
 	)
 }
 
+func (c *Client) CreateDownsamplePolicy(database, rp string, dpi *DownsamplePolicyInfo) error {
+	return c.retryUntilExec(internal.Command_CreateDownsamplePolicyCommand, internal.E_CreateDownsamplePolicyCommand_Command,
+		&internal.CreateDownsamplePolicyCommand{
+			Database:        proto.String(database),
+			RetentionPolicy: proto.String(rp),
+			Policy:          dpi.marshal(),
+		},
+	)
+}
+
+func (c *Client) DropDownsamplePolicy(database, rp, targetDatabase, targetRP string) error {
+	return c.retryUntilExec(internal.Command_DropDownsamplePolicyCommand, internal.E_DropDownsamplePolicyCommand_Command,
+		&internal.DropDownsamplePolicyCommand{
+			Database:              proto.String(database),
+			RetentionPolicy:       proto.String(rp),
+			TargetDatabase:        proto.String(targetDatabase),
+			TargetRetentionPolicy: proto.String(targetRP),
+		},
+	)
+}
+
 func (c *Client) SetData(data *Data) error {
 	return c.retryUntilExec(internal.Command_SetDataCommand, internal.E_SetDataCommand_Command,
 		&internal.SetDataCommand{
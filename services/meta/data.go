@@ -33,6 +33,7 @@ type Data struct {
 	DataNodes []NodeInfo
 	Databases []DatabaseInfo
 	Users     []UserInfo
+	Tokens    []TokenInfo
 
 	MaxNodeID       uint64
 	MaxShardGroupID uint64
@@ -346,8 +347,9 @@ func (data *Data) CreateDatabase(name string) error {
 	return nil
 }
 
-// DropDatabase removes a database by name. It does not return an error
-// if the database cannot be found.
+// DropDatabase removes a database by name, along with the downsample
+// policies writing into it. It does not return an error if the database
+// cannot be found.
 func (data *Data) DropDatabase(name string) error {
 	for i := range data.Databases {
 		if data.Databases[i].Name == name {
@@ -355,6 +357,7 @@ func (data *Data) DropDatabase(name string) error {
 			break
 		}
 	}
+	data.dropDownsamplePolicies(name, "")
 	return nil
 }
 
@@ -423,6 +426,9 @@ func (data *Data) DropRetentionPolicy(database, name string) error {
 		}
 	}
 
+	// Remove the downsample policies writing into it.
+	data.dropDownsamplePolicies(database, name)
+
 	return nil
 }
 
@@ -721,6 +727,70 @@ func (data *Data) DropSubscription(database, rp, name string) error {
 	return ErrSubscriptionNotFound
 }
 
+// CreateDownsamplePolicy adds a downsample policy to a database and retention policy.
+func (data *Data) CreateDownsamplePolicy(database, rp string, dpi *DownsamplePolicyInfo) error {
+	rpi, err := data.RetentionPolicy(database, rp)
+	if err != nil {
+		return err
+	} else if rpi == nil {
+		return freetsdb.ErrRetentionPolicyNotFound(rp)
+	}
+
+	// Ensure the target exists and is not the source itself.
+	if trpi, err := data.RetentionPolicy(dpi.Database, dpi.RetentionPolicy); err != nil {
+		return err
+	} else if trpi == nil {
+		return freetsdb.ErrRetentionPolicyNotFound(dpi.RetentionPolicy)
+	} else if trpi == rpi {
+		return ErrDownsamplePolicyTarget
+	}
+
+	// Ensure the policy doesn't already exist.
+	if rpi.DownsamplePolicy(dpi.Database, dpi.RetentionPolicy) != nil {
+		return ErrDownsamplePolicyExists
+	}
+
+	rpi.DownsamplePolicies = append(rpi.DownsamplePolicies, dpi.clone())
+
+	return nil
+}
+
+// dropDownsamplePolicies removes the downsample policies writing into a
+// retention policy of database. An empty retention policy matches all of them.
+func (data *Data) dropDownsamplePolicies(database, rp string) {
+	for i := range data.Databases {
+		for j := range data.Databases[i].RetentionPolicies {
+			rpi := &data.Databases[i].RetentionPolicies[j]
+
+			var other []DownsamplePolicyInfo
+			for _, dpi := range rpi.DownsamplePolicies {
+				if dpi.Database != database || (rp != "" && dpi.RetentionPolicy != rp) {
+					other = append(other, dpi)
+				}
+			}
+			rpi.DownsamplePolicies = other
+		}
+	}
+}
+
+// DropDownsamplePolicy removes a downsample policy.
+func (data *Data) DropDownsamplePolicy(database, rp, targetDatabase, targetRP string) error {
+	rpi, err := data.RetentionPolicy(database, rp)
+	if err != nil {
+		return err
+	} else if rpi == nil {
+		return freetsdb.ErrRetentionPolicyNotFound(rp)
+	}
+
+	for i := range rpi.DownsamplePolicies {
+		if rpi.DownsamplePolicies[i].Database == targetDatabase && rpi.DownsamplePolicies[i].RetentionPolicy == targetRP {
+			rpi.DownsamplePolicies = append(rpi.DownsamplePolicies[:i], rpi.DownsamplePolicies[i+1:]...)
+			return nil
+		}
+	}
+	return ErrDownsamplePolicyNotFound
+}
+
 // User returns a user by username.
 func (data *Data) User(username string) *UserInfo {
 	for i := range data.Users {
@@ -755,12 +825,56 @@ func (data *Data) DropUser(name string) error {
 	for i := range data.Users {
 		if data.Users[i].Name == name {
 			data.Users = append(data.Users[:i], data.Users[i+1:]...)
+
+			// Remove the user's tokens.
+			var tokens []TokenInfo
+			for _, ti := range data.Tokens {
+				if ti.Username != name {
+					tokens = append(tokens, ti)
+				}
+			}
+			data.Tokens = tokens
 			return nil
 		}
 	}
 	return ErrUserNotFound
 }
 
+// Token returns a token by name.
+func (data *Data) Token(name string) *TokenInfo {
+	for i := range data.Tokens {
+		if data.Tokens[i].Name == name {
+			return &data.Tokens[i]
+		}
+	}
+	return nil
+}
+
+// CreateToken creates a new API token for an existing user.
+func (data *Data) CreateToken(ti *TokenInfo) error {
+	if ti.Name == "" {
+		return ErrTokenNameRequired
+	} else if data.Token(ti.Name) != nil {
+		return ErrTokenExists
+	} else if data.User(ti.Username) == nil {
+		return ErrUserNotFound
+	}
+
+	data.Tokens = append(data.Tokens, ti.clone())
+	return nil
+}
+
+// DropToken removes an existing token by name.
+func (data *Data) DropToken(name string) error {
+	for i := range data.Tokens {
+		if data.Tokens[i].Name == name {
+			data.Tokens = append(data.Tokens[:i], data.Tokens[i+1:]...)
+			return nil
+		}
+	}
+	return ErrTokenNotFound
+}
+
 // UpdateUser updates the password hash of an existing user.
 func (data *Data) UpdateUser(name, hash string) error {
 	for i := range data.Users {
@@ -787,6 +901,41 @@ func (data *Data) SetPrivilege(name, database string, p influxql.Privilege) erro
 	return nil
 }
 
+// SetMeasurementPrivilege sets a privilege for a user on a measurement. The
+// condition restricts the privilege to the series matching a tag expression.
+// Setting NoPrivileges removes the grant.
+func (data *Data) SetMeasurementPrivilege(name, database, measurement string, p influxql.Privilege, condition string) error {
+	ui := data.User(name)
+	if ui == nil {
+		return ErrUserNotFound
+	}
+
+	for i := range ui.MeasurementPrivileges {
+		mp := &ui.MeasurementPrivileges[i]
+		if mp.Database != database || mp.Measurement != measurement {
+			continue
+		}
+
+		if p == influxql.NoPrivileges {
+			ui.MeasurementPrivileges = append(ui.MeasurementPrivileges[:i], ui.MeasurementPrivileges[i+1:]...)
+			return nil
+		}
+		mp.Privilege, mp.Condition = p, condition
+		return nil
+	}
+
+	if p == influxql.NoPrivileges {
+		return nil
+	}
+	ui.MeasurementPrivileges = append(ui.MeasurementPrivileges, MeasurementPrivilege{
+		Database:    database,
+		Measurement: measurement,
+		Privilege:   p,
+		Condition:   condition,
+	})
+	return nil
+}
+
 // SetAdminPrivilege sets the admin privilege for a user.
 func (data *Data) SetAdminPrivilege(name string, admin bool) error {
 	ui := data.User(name)
@@ -860,6 +1009,14 @@ func (data *Data) Clone() *Data {
 		}
 	}
 
+	// Copy tokens.
+	if data.Tokens != nil {
+		other.Tokens = make([]TokenInfo, len(data.Tokens))
+		for i := range data.Tokens {
+			other.Tokens[i] = data.Tokens[i].clone()
+		}
+	}
+
 	return &other
 }
 
@@ -895,6 +1052,11 @@ func (data *Data) marshal() *internal.Data {
 		pb.Users[i] = data.Users[i].marshal()
 	}
 
+	pb.Tokens = make([]*internal.TokenInfo, len(data.Tokens))
+	for i := range data.Tokens {
+		pb.Tokens[i] = data.Tokens[i].marshal()
+	}
+
 	return pb
 }
 
@@ -935,6 +1097,11 @@ func (data *Data) unmarshal(pb *internal.Data) {
 	for i, x := range pb.GetUsers() {
 		data.Users[i].unmarshal(x)
 	}
+
+	data.Tokens = make([]TokenInfo, len(pb.GetTokens()))
+	for i, x := range pb.GetTokens() {
+		data.Tokens[i].unmarshal(x)
+	}
 }
 
 // MarshalBinary encodes the metadata to a binary format.
@@ -1096,6 +1263,7 @@ type RetentionPolicyInfo struct {
 	ShardGroupDuration time.Duration
 	ShardGroups        []ShardGroupInfo
 	Subscriptions      []SubscriptionInfo
+	DownsamplePolicies []DownsamplePolicyInfo
 }
 
 // NewRetentionPolicyInfo returns a new instance of RetentionPolicyInfo with defaults set.
@@ -1142,6 +1310,17 @@ func (rpi *RetentionPolicyInfo) DeletedShardGroups() []*ShardGroupInfo {
 	return groups
 }
 
+// DownsamplePolicy returns the downsample policy writing into the given
+// database and retention policy. Returns nil if no such policy exists.
+func (rpi *RetentionPolicyInfo) DownsamplePolicy(database, rp string) *DownsamplePolicyInfo {
+	for i := range rpi.DownsamplePolicies {
+		if rpi.DownsamplePolicies[i].Database == database && rpi.DownsamplePolicies[i].RetentionPolicy == rp {
+			return &rpi.DownsamplePolicies[i]
+		}
+	}
+	return nil
+}
+
 // marshal serializes to a protobuf representation.
 func (rpi *RetentionPolicyInfo) marshal() *internal.RetentionPolicyInfo {
 	pb := &internal.RetentionPolicyInfo{
@@ -1161,6 +1340,11 @@ func (rpi *RetentionPolicyInfo) marshal() *internal.RetentionPolicyInfo {
 		pb.Subscriptions[i] = sub.marshal()
 	}
 
+	pb.DownsamplePolicies = make([]*internal.DownsamplePolicyInfo, len(rpi.DownsamplePolicies))
+	for i, dpi := range rpi.DownsamplePolicies {
+		pb.DownsamplePolicies[i] = dpi.marshal()
+	}
+
 	return pb
 }
 
@@ -1183,6 +1367,12 @@ func (rpi *RetentionPolicyInfo) unmarshal(pb *internal.RetentionPolicyInfo) {
 			rpi.Subscriptions[i].unmarshal(x)
 		}
 	}
+	if len(pb.GetDownsamplePolicies()) > 0 {
+		rpi.DownsamplePolicies = make([]DownsamplePolicyInfo, len(pb.GetDownsamplePolicies()))
+		for i, x := range pb.GetDownsamplePolicies() {
+			rpi.DownsamplePolicies[i].unmarshal(x)
+		}
+	}
 }
 
 // clone returns a deep copy of rpi.
@@ -1196,6 +1386,13 @@ func (rpi RetentionPolicyInfo) clone() RetentionPolicyInfo {
 		}
 	}
 
+	if rpi.DownsamplePolicies != nil {
+		other.DownsamplePolicies = make([]DownsamplePolicyInfo, len(rpi.DownsamplePolicies))
+		for i := range rpi.DownsamplePolicies {
+			other.DownsamplePolicies[i] = rpi.DownsamplePolicies[i].clone()
+		}
+	}
+
 	return other
 }
 
@@ -1403,6 +1600,50 @@ func (si *SubscriptionInfo) unmarshal(pb *internal.SubscriptionInfo) {
 	}
 }
 
+// DownsamplePolicyInfo holds the information for aggregating every numeric
+// field of a retention policy into a target retention policy.
+type DownsamplePolicyInfo struct {
+	Database        string
+	RetentionPolicy string
+	Interval        time.Duration
+	Aggregates      []string
+}
+
+// clone returns a deep copy of dpi.
+func (dpi DownsamplePolicyInfo) clone() DownsamplePolicyInfo {
+	other := dpi
+	if dpi.Aggregates != nil {
+		other.Aggregates = make([]string, len(dpi.Aggregates))
+		copy(other.Aggregates, dpi.Aggregates)
+	}
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (dpi DownsamplePolicyInfo) marshal() *internal.DownsamplePolicyInfo {
+	pb := &internal.DownsamplePolicyInfo{
+		Database:        proto.String(dpi.Database),
+		RetentionPolicy: proto.String(dpi.RetentionPolicy),
+		Interval:        proto.Int64(int64(dpi.Interval)),
+	}
+
+	pb.Aggregates = make([]string, len(dpi.Aggregates))
+	copy(pb.Aggregates, dpi.Aggregates)
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (dpi *DownsamplePolicyInfo) unmarshal(pb *internal.DownsamplePolicyInfo) {
+	dpi.Database = pb.GetDatabase()
+	dpi.RetentionPolicy = pb.GetRetentionPolicy()
+	dpi.Interval = time.Duration(pb.GetInterval())
+
+	if len(pb.GetAggregates()) > 0 {
+		dpi.Aggregates = make([]string, len(pb.GetAggregates()))
+		copy(dpi.Aggregates, pb.GetAggregates())
+	}
+}
+
 // ShardOwner represents a node that owns a shard.
 type ShardOwner struct {
 	NodeID uint64
@@ -1454,6 +1695,9 @@ type UserInfo struct {
 	Hash       string
 	Admin      bool
 	Privileges map[string]influxql.Privilege
+
+	// Privileges granted on individual measurements.
+	MeasurementPrivileges []MeasurementPrivilege
 }
 
 // Authorize returns true if the user is authorized and false if not.
@@ -1465,6 +1709,73 @@ func (ui *UserInfo) Authorize(privilege influxql.Privilege, database string) boo
 	return ok && (p == privilege || p == influxql.AllPrivileges)
 }
 
+// AuthorizeMeasurements returns true if the user holds privilege on at least
+// one measurement of the database.
+func (ui *UserInfo) AuthorizeMeasurements(privilege influxql.Privilege, database string) bool {
+	for _, mp := range ui.MeasurementPrivileges {
+		if mp.Database == database && mp.authorize(privilege) {
+			return true
+		}
+	}
+	return false
+}
+
+// MeasurementPrivilege returns the user's grant on a measurement, or nil if
+// there is none.
+func (ui *UserInfo) MeasurementPrivilege(database, measurement string) *MeasurementPrivilege {
+	for i := range ui.MeasurementPrivileges {
+		if mp := &ui.MeasurementPrivileges[i]; mp.Database == database && mp.Measurement == measurement {
+			return mp
+		}
+	}
+	return nil
+}
+
+// AuthorizeSeriesRead returns true if the user may read the measurement. The
+// returned condition, if not nil, restricts which series may be read.
+func (ui *UserInfo) AuthorizeSeriesRead(database, measurement string) (bool, influxql.Expr) {
+	if ui.Authorize(influxql.ReadPrivilege, database) {
+		return true, nil
+	}
+
+	mp := ui.MeasurementPrivilege(database, measurement)
+	if mp == nil || !mp.authorize(influxql.ReadPrivilege) {
+		return false, nil
+	}
+
+	cond, err := mp.condition()
+	if err != nil {
+		return false, nil
+	}
+	return true, cond
+}
+
+// AuthorizeSeriesWrite returns true if the user may write a point with the
+// given measurement and tags to the database.
+func (ui *UserInfo) AuthorizeSeriesWrite(database, measurement string, tags map[string]string) bool {
+	if ui.Authorize(influxql.WritePrivilege, database) {
+		return true
+	}
+
+	mp := ui.MeasurementPrivilege(database, measurement)
+	if mp == nil || !mp.authorize(influxql.WritePrivilege) {
+		return false
+	}
+
+	cond, err := mp.condition()
+	if err != nil {
+		return false
+	} else if cond == nil {
+		return true
+	}
+
+	m := make(map[string]interface{}, len(tags))
+	for k, v := range tags {
+		m[k] = v
+	}
+	return influxql.EvalBool(cond, m)
+}
+
 // clone returns a deep copy of si.
 func (ui UserInfo) clone() UserInfo {
 	other := ui
@@ -1476,6 +1787,11 @@ func (ui UserInfo) clone() UserInfo {
 		}
 	}
 
+	if ui.MeasurementPrivileges != nil {
+		other.MeasurementPrivileges = make([]MeasurementPrivilege, len(ui.MeasurementPrivileges))
+		copy(other.MeasurementPrivileges, ui.MeasurementPrivileges)
+	}
+
 	return other
 }
 
@@ -1494,6 +1810,10 @@ func (ui UserInfo) marshal() *internal.UserInfo {
 		})
 	}
 
+	for _, mp := range ui.MeasurementPrivileges {
+		pb.MeasurementPrivileges = append(pb.MeasurementPrivileges, mp.marshal())
+	}
+
 	return pb
 }
 
@@ -1507,6 +1827,156 @@ func (ui *UserInfo) unmarshal(pb *internal.UserInfo) {
 	for _, p := range pb.GetPrivileges() {
 		ui.Privileges[p.GetDatabase()] = influxql.Privilege(p.GetPrivilege())
 	}
+
+	ui.MeasurementPrivileges = nil
+	for _, x := range pb.GetMeasurementPrivileges() {
+		var mp MeasurementPrivilege
+		mp.unmarshal(x)
+		ui.MeasurementPrivileges = append(ui.MeasurementPrivileges, mp)
+	}
+}
+
+// MeasurementPrivilege represents a privilege granted on a single measurement.
+type MeasurementPrivilege struct {
+	Database    string
+	Measurement string
+	Privilege   influxql.Privilege
+
+	// Tag expression restricting the series covered by the grant.
+	// Blank if the grant covers every series of the measurement.
+	Condition string
+}
+
+// authorize returns true if the grant includes privilege.
+func (mp *MeasurementPrivilege) authorize(privilege influxql.Privilege) bool {
+	return mp.Privilege == privilege || mp.Privilege == influxql.AllPrivileges
+}
+
+// condition returns the parsed condition of the grant, or nil if the grant is
+// not restricted to a set of series.
+func (mp *MeasurementPrivilege) condition() (influxql.Expr, error) {
+	if mp.Condition == "" {
+		return nil, nil
+	}
+	return influxql.ParseExpr(mp.Condition)
+}
+
+// marshal serializes to a protobuf representation.
+func (mp MeasurementPrivilege) marshal() *internal.MeasurementPrivilege {
+	pb := &internal.MeasurementPrivilege{
+		Database:    proto.String(mp.Database),
+		Measurement: proto.String(mp.Measurement),
+		Privilege:   proto.Int32(int32(mp.Privilege)),
+	}
+	if mp.Condition != "" {
+		pb.Condition = proto.String(mp.Condition)
+	}
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (mp *MeasurementPrivilege) unmarshal(pb *internal.MeasurementPrivilege) {
+	mp.Database = pb.GetDatabase()
+	mp.Measurement = pb.GetMeasurement()
+	mp.Privilege = influxql.Privilege(pb.GetPrivilege())
+	mp.Condition = pb.GetCondition()
+}
+
+// TokenInfo represents a long-lived API token that authenticates as a user.
+// Only a hash of the token is stored.
+type TokenInfo struct {
+	Name     string
+	Username string
+	Hash     string
+
+	// If Scoped is true the token is limited to Privileges, further
+	// restricted by the privileges of the user. Otherwise the token carries
+	// all of the user's privileges.
+	Scoped     bool
+	Privileges map[string]influxql.Privilege
+}
+
+// Authorize returns the user as seen through the token: the user's privileges
+// narrowed to the privileges of the token.
+func (ti *TokenInfo) Authorize(ui *UserInfo) *UserInfo {
+	other := ui.clone()
+	if !ti.Scoped {
+		return &other
+	}
+
+	// Scoped tokens never carry admin privilege.
+	other.Admin = false
+
+	other.Privileges = make(map[string]influxql.Privilege)
+	for database, p := range ti.Privileges {
+		var granted influxql.Privilege
+		if ui.Authorize(influxql.ReadPrivilege, database) {
+			granted |= influxql.ReadPrivilege
+		}
+		if ui.Authorize(influxql.WritePrivilege, database) {
+			granted |= influxql.WritePrivilege
+		}
+		if p&granted != influxql.NoPrivileges {
+			other.Privileges[database] = p & granted
+		}
+	}
+
+	other.MeasurementPrivileges = nil
+	for _, mp := range ui.MeasurementPrivileges {
+		if p, ok := ti.Privileges[mp.Database]; ok {
+			mp.Privilege &= p
+			if mp.Privilege != influxql.NoPrivileges {
+				other.MeasurementPrivileges = append(other.MeasurementPrivileges, mp)
+			}
+		}
+	}
+	return &other
+}
+
+// clone returns a deep copy of ti.
+func (ti TokenInfo) clone() TokenInfo {
+	other := ti
+
+	if ti.Privileges != nil {
+		other.Privileges = make(map[string]influxql.Privilege)
+		for k, v := range ti.Privileges {
+			other.Privileges[k] = v
+		}
+	}
+
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (ti TokenInfo) marshal() *internal.TokenInfo {
+	pb := &internal.TokenInfo{
+		Name:     proto.String(ti.Name),
+		Username: proto.String(ti.Username),
+		Hash:     proto.String(ti.Hash),
+		Scoped:   proto.Bool(ti.Scoped),
+	}
+
+	for database, privilege := range ti.Privileges {
+		pb.Privileges = append(pb.Privileges, &internal.UserPrivilege{
+			Database:  proto.String(database),
+			Privilege: proto.Int32(int32(privilege)),
+		})
+	}
+
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ti *TokenInfo) unmarshal(pb *internal.TokenInfo) {
+	ti.Name = pb.GetName()
+	ti.Username = pb.GetUsername()
+	ti.Hash = pb.GetHash()
+	ti.Scoped = pb.GetScoped()
+
+	ti.Privileges = make(map[string]influxql.Privilege)
+	for _, p := range pb.GetPrivileges() {
+		ti.Privileges[p.GetDatabase()] = influxql.Privilege(p.GetPrivilege())
+	}
 }
 
 // MarshalTime converts t to nanoseconds since epoch. A zero time returns 0.
@@ -4,6 +4,8 @@ import (
 	"reflect"
 
 	"testing"
+
+	"github.com/freetsdb/freetsdb/influxql"
 )
 
 func TestnewShardOwner(t *testing.T) {
@@ -29,3 +31,46 @@ func TestnewShardOwner(t *testing.T) {
 		t.Errorf("got owner frequencies %v, expected %v", got, exp)
 	}
 }
+
+func TestUserInfo_AuthorizeSeries(t *testing.T) {
+	data := &Data{}
+	if err := data.CreateUser("bob", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.SetMeasurementPrivilege("bob", "db0", "cpu", influxql.AllPrivileges, `team = 'a'`); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.SetMeasurementPrivilege("bob", "db0", "mem", influxql.ReadPrivilege, ""); err != nil {
+		t.Fatal(err)
+	}
+	ui := data.User("bob")
+
+	// Reads are restricted to the granted measurements and series.
+	if ok, cond := ui.AuthorizeSeriesRead("db0", "cpu"); !ok || cond == nil || cond.String() != `team = 'a'` {
+		t.Errorf("unexpected cpu read authorization: %v, %v", ok, cond)
+	}
+	if ok, cond := ui.AuthorizeSeriesRead("db0", "mem"); !ok || cond != nil {
+		t.Errorf("unexpected mem read authorization: %v, %v", ok, cond)
+	}
+	if ok, _ := ui.AuthorizeSeriesRead("db0", "disk"); ok {
+		t.Error("expected disk read to be unauthorized")
+	}
+
+	// Writes must match the grant's condition.
+	if !ui.AuthorizeSeriesWrite("db0", "cpu", map[string]string{"team": "a"}) {
+		t.Error("expected write to team a to be authorized")
+	}
+	if ui.AuthorizeSeriesWrite("db0", "cpu", map[string]string{"team": "b"}) {
+		t.Error("expected write to team b to be unauthorized")
+	}
+	if ui.AuthorizeSeriesWrite("db0", "mem", nil) {
+		t.Error("expected write to read-only measurement to be unauthorized")
+	}
+
+	// Setting no privileges removes the grant.
+	if err := data.SetMeasurementPrivilege("bob", "db0", "mem", influxql.NoPrivileges, ""); err != nil {
+		t.Fatal(err)
+	} else if mp := ui.MeasurementPrivilege("db0", "mem"); mp != nil {
+		t.Errorf("expected grant to be removed: %#v", mp)
+	}
+}
@@ -103,6 +103,18 @@ var (
 	ErrSubscriptionNotFound = errors.New("subscription not found")
 )
 
+var (
+	// ErrDownsamplePolicyExists is returned when creating an already existing downsample policy.
+	ErrDownsamplePolicyExists = errors.New("downsample policy already exists")
+
+	// ErrDownsamplePolicyNotFound is returned when removing a downsample policy that doesn't exist.
+	ErrDownsamplePolicyNotFound = errors.New("downsample policy not found")
+
+	// ErrDownsamplePolicyTarget is returned when a downsample policy would
+	// write into the retention policy it reads from.
+	ErrDownsamplePolicyTarget = errors.New("downsample policy cannot target its source retention policy")
+)
+
 var (
 	// ErrUserExists is returned when creating an already existing user.
 	ErrUserExists = errors.New("user already exists")
@@ -116,3 +128,14 @@ var (
 	// ErrAuthenticate is returned when authentication fails.
 	ErrAuthenticate = errors.New("authentication failed")
 )
+
+var (
+	// ErrTokenExists is returned when creating an already existing token.
+	ErrTokenExists = errors.New("token already exists")
+
+	// ErrTokenNotFound is returned when removing a token that doesn't exist.
+	ErrTokenNotFound = errors.New("token not found")
+
+	// ErrTokenNameRequired is returned when creating a token without a name.
+	ErrTokenNameRequired = errors.New("token name required")
+)
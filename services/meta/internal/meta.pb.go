@@ -16,10 +16,13 @@ It has these top-level messages:
 	ShardGroupInfo
 	ShardInfo
 	SubscriptionInfo
+	DownsamplePolicyInfo
 	ShardOwner
 	ContinuousQueryInfo
 	UserInfo
 	UserPrivilege
+	MeasurementPrivilege
+	TokenInfo
 	Command
 	CreateNodeCommand
 	DeleteNodeCommand
@@ -50,6 +53,11 @@ It has these top-level messages:
 	DeleteDataNodeCommand
 	Response
 	SetMetaNodeCommand
+	CreateDownsamplePolicyCommand
+	DropDownsamplePolicyCommand
+	SetMeasurementPrivilegeCommand
+	CreateTokenCommand
+	DropTokenCommand
 */
 package internal
 
@@ -93,6 +101,11 @@ const (
 	Command_DeleteMetaNodeCommand            Command_Type = 27
 	Command_DeleteDataNodeCommand            Command_Type = 28
 	Command_SetMetaNodeCommand               Command_Type = 29
+	Command_CreateDownsamplePolicyCommand    Command_Type = 30
+	Command_DropDownsamplePolicyCommand      Command_Type = 31
+	Command_SetMeasurementPrivilegeCommand   Command_Type = 32
+	Command_CreateTokenCommand               Command_Type = 33
+	Command_DropTokenCommand                 Command_Type = 34
 )
 
 var Command_Type_name = map[int32]string{
@@ -124,6 +137,11 @@ var Command_Type_name = map[int32]string{
 	27: "DeleteMetaNodeCommand",
 	28: "DeleteDataNodeCommand",
 	29: "SetMetaNodeCommand",
+	30: "CreateDownsamplePolicyCommand",
+	31: "DropDownsamplePolicyCommand",
+	32: "SetMeasurementPrivilegeCommand",
+	33: "CreateTokenCommand",
+	34: "DropTokenCommand",
 }
 var Command_Type_value = map[string]int32{
 	"CreateNodeCommand":                1,
@@ -154,6 +172,11 @@ var Command_Type_value = map[string]int32{
 	"DeleteMetaNodeCommand":            27,
 	"DeleteDataNodeCommand":            28,
 	"SetMetaNodeCommand":               29,
+	"CreateDownsamplePolicyCommand":    30,
+	"DropDownsamplePolicyCommand":      31,
+	"SetMeasurementPrivilegeCommand":   32,
+	"CreateTokenCommand":               33,
+	"DropTokenCommand":                 34,
 }
 
 func (x Command_Type) Enum() *Command_Type {
@@ -184,9 +207,10 @@ type Data struct {
 	MaxShardGroupID *uint64         `protobuf:"varint,8,req,name=MaxShardGroupID" json:"MaxShardGroupID,omitempty"`
 	MaxShardID      *uint64         `protobuf:"varint,9,req,name=MaxShardID" json:"MaxShardID,omitempty"`
 	// added for 0.10.0
-	DataNodes        []*NodeInfo `protobuf:"bytes,10,rep,name=DataNodes" json:"DataNodes,omitempty"`
-	MetaNodes        []*NodeInfo `protobuf:"bytes,11,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
-	XXX_unrecognized []byte      `json:"-"`
+	DataNodes        []*NodeInfo  `protobuf:"bytes,10,rep,name=DataNodes" json:"DataNodes,omitempty"`
+	MetaNodes        []*NodeInfo  `protobuf:"bytes,11,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
+	Tokens           []*TokenInfo `protobuf:"bytes,12,rep,name=Tokens" json:"Tokens,omitempty"`
+	XXX_unrecognized []byte       `json:"-"`
 }
 
 func (m *Data) Reset()         { *m = Data{} }
@@ -270,6 +294,13 @@ func (m *Data) GetMetaNodes() []*NodeInfo {
 	return nil
 }
 
+func (m *Data) GetTokens() []*TokenInfo {
+	if m != nil {
+		return m.Tokens
+	}
+	return nil
+}
+
 type NodeInfo struct {
 	ID               *uint64 `protobuf:"varint,1,req,name=ID" json:"ID,omitempty"`
 	Host             *string `protobuf:"bytes,2,req,name=Host" json:"Host,omitempty"`
@@ -343,13 +374,14 @@ func (m *DatabaseInfo) GetContinuousQueries() []*ContinuousQueryInfo {
 }
 
 type RetentionPolicyInfo struct {
-	Name               *string             `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
-	Duration           *int64              `protobuf:"varint,2,req,name=Duration" json:"Duration,omitempty"`
-	ShardGroupDuration *int64              `protobuf:"varint,3,req,name=ShardGroupDuration" json:"ShardGroupDuration,omitempty"`
-	ReplicaN           *uint32             `protobuf:"varint,4,req,name=ReplicaN" json:"ReplicaN,omitempty"`
-	ShardGroups        []*ShardGroupInfo   `protobuf:"bytes,5,rep,name=ShardGroups" json:"ShardGroups,omitempty"`
-	Subscriptions      []*SubscriptionInfo `protobuf:"bytes,6,rep,name=Subscriptions" json:"Subscriptions,omitempty"`
-	XXX_unrecognized   []byte              `json:"-"`
+	Name               *string                 `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	Duration           *int64                  `protobuf:"varint,2,req,name=Duration" json:"Duration,omitempty"`
+	ShardGroupDuration *int64                  `protobuf:"varint,3,req,name=ShardGroupDuration" json:"ShardGroupDuration,omitempty"`
+	ReplicaN           *uint32                 `protobuf:"varint,4,req,name=ReplicaN" json:"ReplicaN,omitempty"`
+	ShardGroups        []*ShardGroupInfo       `protobuf:"bytes,5,rep,name=ShardGroups" json:"ShardGroups,omitempty"`
+	Subscriptions      []*SubscriptionInfo     `protobuf:"bytes,6,rep,name=Subscriptions" json:"Subscriptions,omitempty"`
+	DownsamplePolicies []*DownsamplePolicyInfo `protobuf:"bytes,7,rep,name=DownsamplePolicies" json:"DownsamplePolicies,omitempty"`
+	XXX_unrecognized   []byte                  `json:"-"`
 }
 
 func (m *RetentionPolicyInfo) Reset()         { *m = RetentionPolicyInfo{} }
@@ -398,6 +430,13 @@ func (m *RetentionPolicyInfo) GetSubscriptions() []*SubscriptionInfo {
 	return nil
 }
 
+func (m *RetentionPolicyInfo) GetDownsamplePolicies() []*DownsamplePolicyInfo {
+	if m != nil {
+		return m.DownsamplePolicies
+	}
+	return nil
+}
+
 type ShardGroupInfo struct {
 	ID               *uint64      `protobuf:"varint,1,req,name=ID" json:"ID,omitempty"`
 	StartTime        *int64       `protobuf:"varint,2,req,name=StartTime" json:"StartTime,omitempty"`
@@ -510,6 +549,46 @@ func (m *SubscriptionInfo) GetDestinations() []string {
 	return nil
 }
 
+type DownsamplePolicyInfo struct {
+	Database         *string  `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	RetentionPolicy  *string  `protobuf:"bytes,2,req,name=RetentionPolicy" json:"RetentionPolicy,omitempty"`
+	Interval         *int64   `protobuf:"varint,3,req,name=Interval" json:"Interval,omitempty"`
+	Aggregates       []string `protobuf:"bytes,4,rep,name=Aggregates" json:"Aggregates,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *DownsamplePolicyInfo) Reset()         { *m = DownsamplePolicyInfo{} }
+func (m *DownsamplePolicyInfo) String() string { return proto.CompactTextString(m) }
+func (*DownsamplePolicyInfo) ProtoMessage()    {}
+
+func (m *DownsamplePolicyInfo) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *DownsamplePolicyInfo) GetRetentionPolicy() string {
+	if m != nil && m.RetentionPolicy != nil {
+		return *m.RetentionPolicy
+	}
+	return ""
+}
+
+func (m *DownsamplePolicyInfo) GetInterval() int64 {
+	if m != nil && m.Interval != nil {
+		return *m.Interval
+	}
+	return 0
+}
+
+func (m *DownsamplePolicyInfo) GetAggregates() []string {
+	if m != nil {
+		return m.Aggregates
+	}
+	return nil
+}
+
 type ShardOwner struct {
 	NodeID           *uint64 `protobuf:"varint,1,req,name=NodeID" json:"NodeID,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
@@ -551,11 +630,12 @@ func (m *ContinuousQueryInfo) GetQuery() string {
 }
 
 type UserInfo struct {
-	Name             *string          `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
-	Hash             *string          `protobuf:"bytes,2,req,name=Hash" json:"Hash,omitempty"`
-	Admin            *bool            `protobuf:"varint,3,req,name=Admin" json:"Admin,omitempty"`
-	Privileges       []*UserPrivilege `protobuf:"bytes,4,rep,name=Privileges" json:"Privileges,omitempty"`
-	XXX_unrecognized []byte           `json:"-"`
+	Name                  *string                 `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	Hash                  *string                 `protobuf:"bytes,2,req,name=Hash" json:"Hash,omitempty"`
+	Admin                 *bool                   `protobuf:"varint,3,req,name=Admin" json:"Admin,omitempty"`
+	Privileges            []*UserPrivilege        `protobuf:"bytes,4,rep,name=Privileges" json:"Privileges,omitempty"`
+	MeasurementPrivileges []*MeasurementPrivilege `protobuf:"bytes,5,rep,name=MeasurementPrivileges" json:"MeasurementPrivileges,omitempty"`
+	XXX_unrecognized      []byte                  `json:"-"`
 }
 
 func (m *UserInfo) Reset()         { *m = UserInfo{} }
@@ -590,6 +670,13 @@ func (m *UserInfo) GetPrivileges() []*UserPrivilege {
 	return nil
 }
 
+func (m *UserInfo) GetMeasurementPrivileges() []*MeasurementPrivilege {
+	if m != nil {
+		return m.MeasurementPrivileges
+	}
+	return nil
+}
+
 type UserPrivilege struct {
 	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
 	Privilege        *int32  `protobuf:"varint,2,req,name=Privilege" json:"Privilege,omitempty"`
@@ -614,6 +701,94 @@ func (m *UserPrivilege) GetPrivilege() int32 {
 	return 0
 }
 
+type MeasurementPrivilege struct {
+	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	Measurement      *string `protobuf:"bytes,2,req,name=Measurement" json:"Measurement,omitempty"`
+	Privilege        *int32  `protobuf:"varint,3,req,name=Privilege" json:"Privilege,omitempty"`
+	Condition        *string `protobuf:"bytes,4,opt,name=Condition" json:"Condition,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *MeasurementPrivilege) Reset()         { *m = MeasurementPrivilege{} }
+func (m *MeasurementPrivilege) String() string { return proto.CompactTextString(m) }
+func (*MeasurementPrivilege) ProtoMessage()    {}
+
+func (m *MeasurementPrivilege) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *MeasurementPrivilege) GetMeasurement() string {
+	if m != nil && m.Measurement != nil {
+		return *m.Measurement
+	}
+	return ""
+}
+
+func (m *MeasurementPrivilege) GetPrivilege() int32 {
+	if m != nil && m.Privilege != nil {
+		return *m.Privilege
+	}
+	return 0
+}
+
+func (m *MeasurementPrivilege) GetCondition() string {
+	if m != nil && m.Condition != nil {
+		return *m.Condition
+	}
+	return ""
+}
+
+type TokenInfo struct {
+	Name             *string          `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	Username         *string          `protobuf:"bytes,2,req,name=Username" json:"Username,omitempty"`
+	Hash             *string          `protobuf:"bytes,3,req,name=Hash" json:"Hash,omitempty"`
+	Privileges       []*UserPrivilege `protobuf:"bytes,4,rep,name=Privileges" json:"Privileges,omitempty"`
+	Scoped           *bool            `protobuf:"varint,5,req,name=Scoped" json:"Scoped,omitempty"`
+	XXX_unrecognized []byte           `json:"-"`
+}
+
+func (m *TokenInfo) Reset()         { *m = TokenInfo{} }
+func (m *TokenInfo) String() string { return proto.CompactTextString(m) }
+func (*TokenInfo) ProtoMessage()    {}
+
+func (m *TokenInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *TokenInfo) GetUsername() string {
+	if m != nil && m.Username != nil {
+		return *m.Username
+	}
+	return ""
+}
+
+func (m *TokenInfo) GetHash() string {
+	if m != nil && m.Hash != nil {
+		return *m.Hash
+	}
+	return ""
+}
+
+func (m *TokenInfo) GetPrivileges() []*UserPrivilege {
+	if m != nil {
+		return m.Privileges
+	}
+	return nil
+}
+
+func (m *TokenInfo) GetScoped() bool {
+	if m != nil && m.Scoped != nil {
+		return *m.Scoped
+	}
+	return false
+}
+
 type Command struct {
 	Type             *Command_Type             `protobuf:"varint,1,req,name=type,enum=internal.Command_Type" json:"type,omitempty"`
 	XXX_extensions   map[int32]proto.Extension `json:"-"`
@@ -1657,6 +1832,198 @@ var E_SetMetaNodeCommand_Command = &proto.ExtensionDesc{
 	Tag:           "bytes,129,opt,name=command",
 }
 
+type CreateDownsamplePolicyCommand struct {
+	Database         *string               `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	RetentionPolicy  *string               `protobuf:"bytes,2,req,name=RetentionPolicy" json:"RetentionPolicy,omitempty"`
+	Policy           *DownsamplePolicyInfo `protobuf:"bytes,3,req,name=Policy" json:"Policy,omitempty"`
+	XXX_unrecognized []byte                `json:"-"`
+}
+
+func (m *CreateDownsamplePolicyCommand) Reset()         { *m = CreateDownsamplePolicyCommand{} }
+func (m *CreateDownsamplePolicyCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateDownsamplePolicyCommand) ProtoMessage()    {}
+
+func (m *CreateDownsamplePolicyCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *CreateDownsamplePolicyCommand) GetRetentionPolicy() string {
+	if m != nil && m.RetentionPolicy != nil {
+		return *m.RetentionPolicy
+	}
+	return ""
+}
+
+func (m *CreateDownsamplePolicyCommand) GetPolicy() *DownsamplePolicyInfo {
+	if m != nil {
+		return m.Policy
+	}
+	return nil
+}
+
+var E_CreateDownsamplePolicyCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateDownsamplePolicyCommand)(nil),
+	Field:         130,
+	Name:          "internal.CreateDownsamplePolicyCommand.command",
+	Tag:           "bytes,130,opt,name=command",
+}
+
+type DropDownsamplePolicyCommand struct {
+	Database              *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	RetentionPolicy       *string `protobuf:"bytes,2,req,name=RetentionPolicy" json:"RetentionPolicy,omitempty"`
+	TargetDatabase        *string `protobuf:"bytes,3,req,name=TargetDatabase" json:"TargetDatabase,omitempty"`
+	TargetRetentionPolicy *string `protobuf:"bytes,4,req,name=TargetRetentionPolicy" json:"TargetRetentionPolicy,omitempty"`
+	XXX_unrecognized      []byte  `json:"-"`
+}
+
+func (m *DropDownsamplePolicyCommand) Reset()         { *m = DropDownsamplePolicyCommand{} }
+func (m *DropDownsamplePolicyCommand) String() string { return proto.CompactTextString(m) }
+func (*DropDownsamplePolicyCommand) ProtoMessage()    {}
+
+func (m *DropDownsamplePolicyCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *DropDownsamplePolicyCommand) GetRetentionPolicy() string {
+	if m != nil && m.RetentionPolicy != nil {
+		return *m.RetentionPolicy
+	}
+	return ""
+}
+
+func (m *DropDownsamplePolicyCommand) GetTargetDatabase() string {
+	if m != nil && m.TargetDatabase != nil {
+		return *m.TargetDatabase
+	}
+	return ""
+}
+
+func (m *DropDownsamplePolicyCommand) GetTargetRetentionPolicy() string {
+	if m != nil && m.TargetRetentionPolicy != nil {
+		return *m.TargetRetentionPolicy
+	}
+	return ""
+}
+
+var E_DropDownsamplePolicyCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DropDownsamplePolicyCommand)(nil),
+	Field:         131,
+	Name:          "internal.DropDownsamplePolicyCommand.command",
+	Tag:           "bytes,131,opt,name=command",
+}
+
+type SetMeasurementPrivilegeCommand struct {
+	Username         *string `protobuf:"bytes,1,req,name=Username" json:"Username,omitempty"`
+	Database         *string `protobuf:"bytes,2,req,name=Database" json:"Database,omitempty"`
+	Measurement      *string `protobuf:"bytes,3,req,name=Measurement" json:"Measurement,omitempty"`
+	Privilege        *int32  `protobuf:"varint,4,req,name=Privilege" json:"Privilege,omitempty"`
+	Condition        *string `protobuf:"bytes,5,opt,name=Condition" json:"Condition,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetMeasurementPrivilegeCommand) Reset()         { *m = SetMeasurementPrivilegeCommand{} }
+func (m *SetMeasurementPrivilegeCommand) String() string { return proto.CompactTextString(m) }
+func (*SetMeasurementPrivilegeCommand) ProtoMessage()    {}
+
+func (m *SetMeasurementPrivilegeCommand) GetUsername() string {
+	if m != nil && m.Username != nil {
+		return *m.Username
+	}
+	return ""
+}
+
+func (m *SetMeasurementPrivilegeCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *SetMeasurementPrivilegeCommand) GetMeasurement() string {
+	if m != nil && m.Measurement != nil {
+		return *m.Measurement
+	}
+	return ""
+}
+
+func (m *SetMeasurementPrivilegeCommand) GetPrivilege() int32 {
+	if m != nil && m.Privilege != nil {
+		return *m.Privilege
+	}
+	return 0
+}
+
+func (m *SetMeasurementPrivilegeCommand) GetCondition() string {
+	if m != nil && m.Condition != nil {
+		return *m.Condition
+	}
+	return ""
+}
+
+var E_SetMeasurementPrivilegeCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetMeasurementPrivilegeCommand)(nil),
+	Field:         132,
+	Name:          "internal.SetMeasurementPrivilegeCommand.command",
+	Tag:           "bytes,132,opt,name=command",
+}
+
+type CreateTokenCommand struct {
+	Token            *TokenInfo `protobuf:"bytes,1,req,name=Token" json:"Token,omitempty"`
+	XXX_unrecognized []byte     `json:"-"`
+}
+
+func (m *CreateTokenCommand) Reset()         { *m = CreateTokenCommand{} }
+func (m *CreateTokenCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateTokenCommand) ProtoMessage()    {}
+
+func (m *CreateTokenCommand) GetToken() *TokenInfo {
+	if m != nil {
+		return m.Token
+	}
+	return nil
+}
+
+var E_CreateTokenCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateTokenCommand)(nil),
+	Field:         133,
+	Name:          "internal.CreateTokenCommand.command",
+	Tag:           "bytes,133,opt,name=command",
+}
+
+type DropTokenCommand struct {
+	Name             *string `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DropTokenCommand) Reset()         { *m = DropTokenCommand{} }
+func (m *DropTokenCommand) String() string { return proto.CompactTextString(m) }
+func (*DropTokenCommand) ProtoMessage()    {}
+
+func (m *DropTokenCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+var E_DropTokenCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DropTokenCommand)(nil),
+	Field:         134,
+	Name:          "internal.DropTokenCommand.command",
+	Tag:           "bytes,134,opt,name=command",
+}
+
 func init() {
 	proto.RegisterType((*Data)(nil), "internal.Data")
 	proto.RegisterType((*NodeInfo)(nil), "internal.NodeInfo")
@@ -1665,10 +2032,13 @@ func init() {
 	proto.RegisterType((*ShardGroupInfo)(nil), "internal.ShardGroupInfo")
 	proto.RegisterType((*ShardInfo)(nil), "internal.ShardInfo")
 	proto.RegisterType((*SubscriptionInfo)(nil), "internal.SubscriptionInfo")
+	proto.RegisterType((*DownsamplePolicyInfo)(nil), "internal.DownsamplePolicyInfo")
 	proto.RegisterType((*ShardOwner)(nil), "internal.ShardOwner")
 	proto.RegisterType((*ContinuousQueryInfo)(nil), "internal.ContinuousQueryInfo")
 	proto.RegisterType((*UserInfo)(nil), "internal.UserInfo")
 	proto.RegisterType((*UserPrivilege)(nil), "internal.UserPrivilege")
+	proto.RegisterType((*MeasurementPrivilege)(nil), "internal.MeasurementPrivilege")
+	proto.RegisterType((*TokenInfo)(nil), "internal.TokenInfo")
 	proto.RegisterType((*Command)(nil), "internal.Command")
 	proto.RegisterType((*CreateNodeCommand)(nil), "internal.CreateNodeCommand")
 	proto.RegisterType((*DeleteNodeCommand)(nil), "internal.DeleteNodeCommand")
@@ -1699,6 +2069,11 @@ func init() {
 	proto.RegisterType((*DeleteDataNodeCommand)(nil), "internal.DeleteDataNodeCommand")
 	proto.RegisterType((*Response)(nil), "internal.Response")
 	proto.RegisterType((*SetMetaNodeCommand)(nil), "internal.SetMetaNodeCommand")
+	proto.RegisterType((*CreateDownsamplePolicyCommand)(nil), "internal.CreateDownsamplePolicyCommand")
+	proto.RegisterType((*DropDownsamplePolicyCommand)(nil), "internal.DropDownsamplePolicyCommand")
+	proto.RegisterType((*SetMeasurementPrivilegeCommand)(nil), "internal.SetMeasurementPrivilegeCommand")
+	proto.RegisterType((*CreateTokenCommand)(nil), "internal.CreateTokenCommand")
+	proto.RegisterType((*DropTokenCommand)(nil), "internal.DropTokenCommand")
 	proto.RegisterEnum("internal.Command_Type", Command_Type_name, Command_Type_value)
 	proto.RegisterExtension(E_CreateNodeCommand_Command)
 	proto.RegisterExtension(E_DeleteNodeCommand_Command)
@@ -1728,4 +2103,9 @@ func init() {
 	proto.RegisterExtension(E_DeleteMetaNodeCommand_Command)
 	proto.RegisterExtension(E_DeleteDataNodeCommand_Command)
 	proto.RegisterExtension(E_SetMetaNodeCommand_Command)
+	proto.RegisterExtension(E_CreateDownsamplePolicyCommand_Command)
+	proto.RegisterExtension(E_DropDownsamplePolicyCommand_Command)
+	proto.RegisterExtension(E_SetMeasurementPrivilegeCommand_Command)
+	proto.RegisterExtension(E_CreateTokenCommand_Command)
+	proto.RegisterExtension(E_DropTokenCommand_Command)
 }
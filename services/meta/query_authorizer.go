@@ -56,6 +56,16 @@ func (a *QueryAuthorizer) AuthorizeQuery(u *UserInfo, query *influxql.Query, dat
 		// Get the privileges required to execute the statement.
 		privs := stmt.RequiredPrivileges()
 
+		// Select statements are restricted to the measurements and series
+		// the user may read when they are executed.
+		sel, isSelect := stmt.(*influxql.SelectStatement)
+		if explain, ok := stmt.(*influxql.ExplainStatement); ok {
+			sel, isSelect = explain.Statement, true
+		}
+		if isSelect {
+			sel.SeriesAuthorizer = u
+		}
+
 		// Make sure the user has the privileges required to execute
 		// each statement.
 		for _, p := range privs {
@@ -63,10 +73,11 @@ func (a *QueryAuthorizer) AuthorizeQuery(u *UserInfo, query *influxql.Query, dat
 				// Admin privilege already checked so statement requiring admin
 				// privilege cannot be run.
 				return &ErrAuthorize{
-					Query:    query,
-					User:     u.Name,
-					Database: database,
-					Message:  fmt.Sprintf("statement '%s', requires admin privilege", stmt),
+					Query:     query,
+					Statement: stmt,
+					User:      u.Name,
+					Database:  database,
+					Message:   fmt.Sprintf("statement '%s', requires admin privilege", stmt),
 				}
 			}
 
@@ -77,12 +88,18 @@ func (a *QueryAuthorizer) AuthorizeQuery(u *UserInfo, query *influxql.Query, dat
 			if db == "" {
 				db = database
 			}
+			// Measurement grants let a user select from a database they
+			// cannot otherwise read.
+			if isSelect && p.Privilege == influxql.ReadPrivilege && u.AuthorizeMeasurements(p.Privilege, db) {
+				continue
+			}
 			if !u.Authorize(p.Privilege, db) {
 				return &ErrAuthorize{
-					Query:    query,
-					User:     u.Name,
-					Database: database,
-					Message:  fmt.Sprintf("statement '%s', requires %s on %s", stmt, p.Privilege.String(), db),
+					Query:     query,
+					Statement: stmt,
+					User:      u.Name,
+					Database:  database,
+					Message:   fmt.Sprintf("statement '%s', requires %s on %s", stmt, p.Privilege.String(), db),
 				}
 			}
 		}
@@ -92,10 +109,13 @@ func (a *QueryAuthorizer) AuthorizeQuery(u *UserInfo, query *influxql.Query, dat
 
 // ErrAuthorize represents an authorization error.
 type ErrAuthorize struct {
-	Query    *influxql.Query
-	User     string
-	Database string
-	Message  string
+	Query *influxql.Query
+	// Statement is the statement that was denied, if the query was denied
+	// because of a single statement.
+	Statement influxql.Statement
+	User      string
+	Database  string
+	Message   string
 }
 
 // Error returns the text of the error.
@@ -0,0 +1,57 @@
+package meta_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/freetsdb/freetsdb/influxql"
+	"github.com/freetsdb/freetsdb/services/meta"
+)
+
+// Ensure a continuous query can only be run by a user with write privilege
+// on the database of the continuous query.
+func TestQueryAuthorizer_RunContinuousQuery(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	if _, err := c.CreateUser("fred", "supersecure", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateUser("wilma", "password", false); err != nil {
+		t.Fatal(err)
+	}
+	for _, db := range []string{"db0", "db1"} {
+		if _, err := c.CreateDatabase(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.SetPrivilege("wilma", "db0", influxql.WritePrivilege); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := c.User("wilma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := meta.NewQueryAuthorizer(c)
+
+	q := &influxql.Query{Statements: influxql.Statements{influxql.MustParseStatement(`RUN CONTINUOUS QUERY cq0 ON db0 FOR time >= '2000-01-01T00:00:00Z' AND time < '2000-01-02T00:00:00Z'`)}}
+	if err := a.AuthorizeQuery(u, q, "db1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Write privilege on the request's database doesn't allow running a
+	// continuous query on another database.
+	q = &influxql.Query{Statements: influxql.Statements{influxql.MustParseStatement(`RUN CONTINUOUS QUERY cq0 ON db1 FOR time >= '2000-01-01T00:00:00Z' AND time < '2000-01-02T00:00:00Z'`)}}
+	if err := a.AuthorizeQuery(u, q, "db0"); err == nil {
+		t.Fatal("expected authorization error")
+	} else if e, ok := err.(*meta.ErrAuthorize); !ok {
+		t.Fatalf("unexpected error: %s", err)
+	} else if e.Statement != q.Statements[0] {
+		t.Fatalf("unexpected denied statement: %s", e.Statement)
+	}
+}
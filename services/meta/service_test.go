@@ -501,6 +501,75 @@ func TestMetaService_CreateUser(t *testing.T) {
 	}
 }
 
+func TestMetaService_Tokens(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	if _, err := c.CreateUser("fred", "supersecure", true); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unscoped token carries all of the user's privileges.
+	all, err := c.CreateToken("all", "fred", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, err := c.AuthenticateToken(all); err != nil {
+		t.Fatal(err)
+	} else if u.Name != "fred" || !u.Admin {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+
+	// A scoped token is limited to its privileges.
+	scoped, err := c.CreateToken("scoped", "fred", map[string]influxql.Privilege{"db0": influxql.ReadPrivilege})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, err := c.AuthenticateToken(scoped); err != nil {
+		t.Fatal(err)
+	} else if u.Admin {
+		t.Fatal("expected scoped token not to be admin")
+	} else if !u.Authorize(influxql.ReadPrivilege, "db0") || u.Authorize(influxql.WritePrivilege, "db0") || u.Authorize(influxql.ReadPrivilege, "db1") {
+		t.Fatalf("unexpected privileges: %v", u.Privileges)
+	}
+
+	// Token names are unique and tokens require a user.
+	if _, err := c.CreateToken("all", "fred", nil); err != meta.ErrTokenExists {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := c.CreateToken("other", "wilma", nil); err != meta.ErrUserNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only hashes of the tokens are stored.
+	if tokens := c.Tokens(); len(tokens) != 2 {
+		t.Fatalf("unexpected tokens: %+v", tokens)
+	} else if tokens[0].Hash == all || tokens[1].Hash == scoped {
+		t.Fatal("expected tokens to be stored hashed")
+	}
+
+	// Dropping a token revokes it without affecting the others.
+	if err := c.DropToken("scoped"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.AuthenticateToken(scoped); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := c.AuthenticateToken(all); err != nil {
+		t.Fatal(err)
+	} else if err := c.DropToken("scoped"); err != meta.ErrTokenNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Dropping the user drops the user's tokens.
+	if err := c.DropUser("fred"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.AuthenticateToken(all); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestMetaService_ContinuousQueries(t *testing.T) {
 	t.Parallel()
 
@@ -625,6 +694,120 @@ func TestMetaService_Subscriptions_Drop(t *testing.T) {
 	}
 }
 
+func TestMetaService_DownsamplePolicies(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	// Create a database with a target retention policy.
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{
+		Name:     "rp1",
+		Duration: 24 * time.Hour,
+		ReplicaN: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	dpi := &meta.DownsamplePolicyInfo{
+		Database:        "db0",
+		RetentionPolicy: "rp1",
+		Interval:        5 * time.Minute,
+		Aggregates:      []string{"mean", "max"},
+	}
+
+	// CREATE DOWNSAMPLE returns ErrRetentionPolicyNotFound when the
+	// target retention policy is unknown.
+	err := c.CreateDownsamplePolicy("db0", "default", &meta.DownsamplePolicyInfo{
+		Database:        "db0",
+		RetentionPolicy: "foo_policy",
+		Interval:        time.Minute,
+		Aggregates:      []string{"mean"},
+	})
+	if got, exp := err, freetsdb.ErrRetentionPolicyNotFound("foo_policy"); got == nil || got.Error() != exp.Error() {
+		t.Fatalf("got: %v, exp: %s", got, exp)
+	}
+
+	// CREATE DOWNSAMPLE cannot target its own retention policy.
+	err = c.CreateDownsamplePolicy("db0", "default", &meta.DownsamplePolicyInfo{
+		Database:        "db0",
+		RetentionPolicy: "default",
+		Interval:        time.Minute,
+		Aggregates:      []string{"mean"},
+	})
+	if got, exp := err, meta.ErrDownsamplePolicyTarget; got == nil || got.Error() != exp.Error() {
+		t.Fatalf("got: %v, exp: %s", got, exp)
+	}
+
+	if err := c.CreateDownsamplePolicy("db0", "default", dpi); err != nil {
+		t.Fatal(err)
+	}
+
+	// Creating the same policy twice is an error.
+	err = c.CreateDownsamplePolicy("db0", "default", dpi)
+	if got, exp := err, meta.ErrDownsamplePolicyExists; got == nil || got.Error() != exp.Error() {
+		t.Fatalf("got: %v, exp: %s", got, exp)
+	}
+
+	rpi, err := c.RetentionPolicy("db0", "default")
+	if err != nil {
+		t.Fatal(err)
+	} else if got := rpi.DownsamplePolicy("db0", "rp1"); !reflect.DeepEqual(got, dpi) {
+		t.Fatalf("unexpected policy: %#v", got)
+	}
+
+	if err := c.DropDownsamplePolicy("db0", "default", "db0", "rp1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// DROP DOWNSAMPLE returns ErrDownsamplePolicyNotFound when the policy
+	// is unknown.
+	err = c.DropDownsamplePolicy("db0", "default", "db0", "rp1")
+	if got, exp := err, meta.ErrDownsamplePolicyNotFound; got == nil || got.Error() != exp.Error() {
+		t.Fatalf("got: %v, exp: %s", got, exp)
+	}
+
+	// Dropping the target retention policy or database drops the policies
+	// writing into it.
+	if _, err := c.CreateDatabase("db1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateDownsamplePolicy("db0", "default", dpi); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateDownsamplePolicy("db0", "default", &meta.DownsamplePolicyInfo{
+		Database:        "db1",
+		RetentionPolicy: "default",
+		Interval:        time.Hour,
+		Aggregates:      []string{"mean"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.DropRetentionPolicy("db0", "rp1"); err != nil {
+		t.Fatal(err)
+	}
+	if rpi, err := c.RetentionPolicy("db0", "default"); err != nil {
+		t.Fatal(err)
+	} else if len(rpi.DownsamplePolicies) != 1 || rpi.DownsamplePolicy("db1", "default") == nil {
+		t.Fatalf("unexpected policies: %#v", rpi.DownsamplePolicies)
+	}
+
+	if err := c.DropDatabase("db1"); err != nil {
+		t.Fatal(err)
+	}
+	if rpi, err := c.RetentionPolicy("db0", "default"); err != nil {
+		t.Fatal(err)
+	} else if len(rpi.DownsamplePolicies) != 0 {
+		t.Fatalf("unexpected policies: %#v", rpi.DownsamplePolicies)
+	}
+}
+
 func TestMetaService_Shards(t *testing.T) {
 	t.Parallel()
 
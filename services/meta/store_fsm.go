@@ -64,6 +64,10 @@ func (fsm *storeFSM) Apply(l *raft.Log) interface{} {
 			return fsm.applyCreateSubscriptionCommand(&cmd)
 		case internal.Command_DropSubscriptionCommand:
 			return fsm.applyDropSubscriptionCommand(&cmd)
+		case internal.Command_CreateDownsamplePolicyCommand:
+			return fsm.applyCreateDownsamplePolicyCommand(&cmd)
+		case internal.Command_DropDownsamplePolicyCommand:
+			return fsm.applyDropDownsamplePolicyCommand(&cmd)
 		case internal.Command_CreateUserCommand:
 			return fsm.applyCreateUserCommand(&cmd)
 		case internal.Command_DropUserCommand:
@@ -72,8 +76,14 @@ func (fsm *storeFSM) Apply(l *raft.Log) interface{} {
 			return fsm.applyUpdateUserCommand(&cmd)
 		case internal.Command_SetPrivilegeCommand:
 			return fsm.applySetPrivilegeCommand(&cmd)
+		case internal.Command_SetMeasurementPrivilegeCommand:
+			return fsm.applySetMeasurementPrivilegeCommand(&cmd)
 		case internal.Command_SetAdminPrivilegeCommand:
 			return fsm.applySetAdminPrivilegeCommand(&cmd)
+		case internal.Command_CreateTokenCommand:
+			return fsm.applyCreateTokenCommand(&cmd)
+		case internal.Command_DropTokenCommand:
+			return fsm.applyDropTokenCommand(&cmd)
 		case internal.Command_SetDataCommand:
 			return fsm.applySetDataCommand(&cmd)
 		case internal.Command_UpdateNodeCommand:
@@ -426,6 +436,37 @@ func (fsm *storeFSM) applyDropSubscriptionCommand(cmd *internal.Command) interfa
 	return nil
 }
 
+func (fsm *storeFSM) applyCreateDownsamplePolicyCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateDownsamplePolicyCommand_Command)
+	v := ext.(*internal.CreateDownsamplePolicyCommand)
+
+	var dpi DownsamplePolicyInfo
+	dpi.unmarshal(v.GetPolicy())
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateDownsamplePolicy(v.GetDatabase(), v.GetRetentionPolicy(), &dpi); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyDropDownsamplePolicyCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DropDownsamplePolicyCommand_Command)
+	v := ext.(*internal.DropDownsamplePolicyCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DropDownsamplePolicy(v.GetDatabase(), v.GetRetentionPolicy(), v.GetTargetDatabase(), v.GetTargetRetentionPolicy()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
 func (fsm *storeFSM) applyCreateUserCommand(cmd *internal.Command) interface{} {
 	ext, _ := proto.GetExtension(cmd, internal.E_CreateUserCommand_Command)
 	v := ext.(*internal.CreateUserCommand)
@@ -479,6 +520,48 @@ func (fsm *storeFSM) applySetPrivilegeCommand(cmd *internal.Command) interface{}
 	return nil
 }
 
+func (fsm *storeFSM) applySetMeasurementPrivilegeCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetMeasurementPrivilegeCommand_Command)
+	v := ext.(*internal.SetMeasurementPrivilegeCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetMeasurementPrivilege(v.GetUsername(), v.GetDatabase(), v.GetMeasurement(), influxql.Privilege(v.GetPrivilege()), v.GetCondition()); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyCreateTokenCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateTokenCommand_Command)
+	v := ext.(*internal.CreateTokenCommand)
+
+	var ti TokenInfo
+	ti.unmarshal(v.GetToken())
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateToken(&ti); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyDropTokenCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DropTokenCommand_Command)
+	v := ext.(*internal.DropTokenCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DropToken(v.GetName()); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
 func (fsm *storeFSM) applySetAdminPrivilegeCommand(cmd *internal.Command) interface{} {
 	ext, _ := proto.GetExtension(cmd, internal.E_SetAdminPrivilegeCommand_Command)
 	v := ext.(*internal.SetAdminPrivilegeCommand)
@@ -42,6 +42,11 @@ type Config struct {
 	BatchPending     int           `toml:"batch-pending"`
 	BatchTimeout     toml.Duration `toml:"batch-timeout"`
 	LogPointErrors   bool          `toml:"log-point-errors"`
+
+	// QueryEnabled turns on the /api/query and /api/suggest read endpoints.
+	// They do not authenticate requests, so anyone who can reach the
+	// listener can read every measurement in the database.
+	QueryEnabled bool `toml:"query-enabled"`
 }
 
 // NewConfig returns a new config for the service.
@@ -10,10 +10,14 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/freetsdb/freetsdb"
 	"github.com/freetsdb/freetsdb/cluster"
+	"github.com/freetsdb/freetsdb/influxql"
 	"github.com/freetsdb/freetsdb/models"
 )
 
@@ -27,6 +31,10 @@ type Handler struct {
 		WritePoints(p *cluster.WritePointsRequest) error
 	}
 
+	// QueryExecutor serves /api/query and /api/suggest. Both endpoints
+	// return 404 when it is nil.
+	QueryExecutor influxql.QueryExecutor
+
 	Logger *log.Logger
 
 	statMap *expvar.Map
@@ -38,6 +46,18 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNoContent)
 	case "/api/put":
 		h.servePut(w, r)
+	case "/api/query":
+		if h.QueryExecutor == nil {
+			http.NotFound(w, r)
+			return
+		}
+		h.serveQuery(w, r)
+	case "/api/suggest":
+		if h.QueryExecutor == nil {
+			http.NotFound(w, r)
+			return
+		}
+		h.serveSuggest(w, r)
 	default:
 		http.NotFound(w, r)
 	}
@@ -141,6 +161,215 @@ func (h *Handler) servePut(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// serveQuery implements OpenTSDB's HTTP /api/query endpoint by translating
+// each metric query into an InfluxQL statement.
+func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	h.statMap.Add(statHTTPQueryRequests, 1)
+
+	var req *queryRequest
+	switch r.Method {
+	case "GET":
+		var err error
+		if req, err = parseQueryParams(r.URL.Query()); err != nil {
+			h.queryError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case "POST":
+		req = &queryRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			h.queryError(w, "json object decode error", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if len(req.Queries) == 0 {
+		h.queryError(w, "missing queries", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	start, err := parseQueryTime(req.Start, now)
+	if err != nil {
+		h.queryError(w, "start: "+err.Error(), http.StatusBadRequest)
+		return
+	} else if start.IsZero() {
+		h.queryError(w, "missing start time", http.StatusBadRequest)
+		return
+	}
+	end, err := parseQueryTime(req.End, now)
+	if err != nil {
+		h.queryError(w, "end: "+err.Error(), http.StatusBadRequest)
+		return
+	} else if end.IsZero() {
+		end = now
+	}
+
+	results := []queryResult{}
+	for i := range req.Queries {
+		q := &req.Queries[i]
+		stmt, err := q.statement(h.Database, h.RetentionPolicy, start, end)
+		if err != nil {
+			h.queryError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rows, err := h.execute(stmt)
+		if err != nil {
+			h.Logger.Println("query error: ", err)
+			h.queryError(w, "query error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		results = append(results, q.aggregate(seriesFromRows(rows), req.MsResolution)...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// serveSuggest implements OpenTSDB's HTTP /api/suggest endpoint, returning
+// the metrics, tag keys or tag values starting with a prefix.
+func (h *Handler) serveSuggest(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	h.statMap.Add(statHTTPSuggestRequests, 1)
+
+	var req struct {
+		Type string `json:"type"`
+		Q    string `json:"q"`
+		Max  int    `json:"max"`
+	}
+	switch r.Method {
+	case "GET":
+		params := r.URL.Query()
+		req.Type, req.Q = params.Get("type"), params.Get("q")
+		if s := params.Get("max"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				h.queryError(w, "invalid max: "+s, http.StatusBadRequest)
+				return
+			}
+			req.Max = n
+		}
+	case "POST":
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.queryError(w, "json object decode error", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if req.Max <= 0 {
+		req.Max = defaultSuggestMax
+	}
+
+	var stmt string
+	switch req.Type {
+	case "metrics":
+		stmt = "SHOW MEASUREMENTS"
+	case "tagk":
+		stmt = "SHOW TAG KEYS"
+	case "tagv":
+		// Tag values can only be shown for named keys.
+		rows, err := h.execute("SHOW TAG KEYS")
+		if err != nil {
+			h.queryError(w, "query error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		keys := suggestions(rows, "", 0)
+		if len(keys) == 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("[]\n"))
+			return
+		}
+		for i := range keys {
+			keys[i] = influxql.QuoteIdent(keys[i])
+		}
+		stmt = "SHOW TAG VALUES WITH KEY IN (" + strings.Join(keys, ", ") + ")"
+	default:
+		h.queryError(w, "invalid type: "+req.Type, http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.execute(stmt)
+	if err != nil {
+		h.Logger.Println("query error: ", err)
+		h.queryError(w, "query error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions(rows, req.Q, req.Max))
+}
+
+// execute runs a single InfluxQL statement against the database and returns
+// its rows.
+func (h *Handler) execute(stmt string) (models.Rows, error) {
+	q, err := influxql.ParseQuery(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	closing := make(chan struct{})
+	defer close(closing)
+
+	var rows models.Rows
+	for result := range h.QueryExecutor.ExecuteQuery(q, influxql.ExecutionOptions{Database: h.Database}, closing) {
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		rows = append(rows, result.Series...)
+	}
+	return rows, nil
+}
+
+// queryError writes an error in the format OpenTSDB clients expect.
+func (h *Handler) queryError(w http.ResponseWriter, message string, code int) {
+	h.statMap.Add(statHTTPQueryFail, 1)
+
+	var resp struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	resp.Error.Code, resp.Error.Message = code, message
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// suggestions returns up to max sorted, unique values from the last column of
+// rows that start with prefix. A max of zero is unlimited.
+func suggestions(rows models.Rows, prefix string, max int) []string {
+	set := make(map[string]struct{})
+	for _, row := range rows {
+		for _, values := range row.Values {
+			if len(values) == 0 {
+				continue
+			}
+			if s, ok := values[len(values)-1].(string); ok && strings.HasPrefix(s, prefix) {
+				set[s] = struct{}{}
+			}
+		}
+	}
+
+	a := make([]string, 0, len(set))
+	for s := range set {
+		a = append(a, s)
+	}
+	sort.Strings(a)
+
+	if max > 0 && len(a) > max {
+		a = a[:max]
+	}
+	return a
+}
+
 // chanListener represents a listener that receives connections through a channel.
 type chanListener struct {
 	addr net.Addr
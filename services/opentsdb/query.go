@@ -0,0 +1,639 @@
+package opentsdb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/freetsdb/freetsdb/influxql"
+	"github.com/freetsdb/freetsdb/models"
+)
+
+// queryRequest represents a request to OpenTSDB's /api/query endpoint.
+type queryRequest struct {
+	Start        interface{} `json:"start"`
+	End          interface{} `json:"end"`
+	Queries      []subQuery  `json:"queries"`
+	MsResolution bool        `json:"msResolution"`
+}
+
+// subQuery represents a single metric query in a queryRequest.
+type subQuery struct {
+	Aggregator  string            `json:"aggregator"`
+	Metric      string            `json:"metric"`
+	Downsample  string            `json:"downsample,omitempty"`
+	Rate        bool              `json:"rate"`
+	RateOptions rateOptions       `json:"rateOptions"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Filters     []tagFilter       `json:"filters,omitempty"`
+}
+
+// rateOptions control how a rate is calculated for counters.
+type rateOptions struct {
+	Counter    bool    `json:"counter"`
+	CounterMax float64 `json:"counterMax"`
+	ResetValue float64 `json:"resetValue"`
+	DropResets bool    `json:"dropResets"`
+}
+
+// tagFilter represents an OpenTSDB filter on the values of a tag.
+type tagFilter struct {
+	Type    string `json:"type"`
+	Tagk    string `json:"tagk"`
+	Filter  string `json:"filter"`
+	GroupBy bool   `json:"groupBy"`
+}
+
+// queryResult represents one aggregated series returned by /api/query.
+type queryResult struct {
+	Metric        string             `json:"metric"`
+	Tags          map[string]string  `json:"tags"`
+	AggregateTags []string           `json:"aggregateTags"`
+	Dps           map[string]float64 `json:"dps"`
+}
+
+// downsampleFuncs maps OpenTSDB downsample functions to the InfluxQL calls
+// used to select them.
+var downsampleFuncs = map[string]string{
+	"avg":    `mean("value")`,
+	"count":  `count("value")`,
+	"dev":    `stddev("value")`,
+	"first":  `first("value")`,
+	"last":   `last("value")`,
+	"max":    `max("value")`,
+	"median": `median("value")`,
+	"mimmax": `max("value")`,
+	"mimmin": `min("value")`,
+	"min":    `min("value")`,
+	"sum":    `sum("value")`,
+	"zimsum": `sum("value")`,
+	"p50":    `percentile("value", 50)`,
+	"p75":    `percentile("value", 75)`,
+	"p90":    `percentile("value", 90)`,
+	"p95":    `percentile("value", 95)`,
+	"p99":    `percentile("value", 99)`,
+	"p999":   `percentile("value", 99.9)`,
+}
+
+// aggregators are the OpenTSDB functions used to combine the values of several
+// series at the same time.
+var aggregators = map[string]func([]float64) float64{
+	"avg": func(a []float64) float64 {
+		var sum float64
+		for _, v := range a {
+			sum += v
+		}
+		return sum / float64(len(a))
+	},
+	"count": func(a []float64) float64 { return float64(len(a)) },
+	"dev": func(a []float64) float64 {
+		if len(a) < 2 {
+			return 0
+		}
+		var sum float64
+		for _, v := range a {
+			sum += v
+		}
+		mean := sum / float64(len(a))
+		var variance float64
+		for _, v := range a {
+			variance += (v - mean) * (v - mean)
+		}
+		return math.Sqrt(variance / float64(len(a)-1))
+	},
+	"max": func(a []float64) float64 {
+		max := a[0]
+		for _, v := range a[1:] {
+			max = math.Max(max, v)
+		}
+		return max
+	},
+	"min": func(a []float64) float64 {
+		min := a[0]
+		for _, v := range a[1:] {
+			min = math.Min(min, v)
+		}
+		return min
+	},
+	"sum": func(a []float64) float64 {
+		var sum float64
+		for _, v := range a {
+			sum += v
+		}
+		return sum
+	},
+}
+
+func init() {
+	// Values are not interpolated across series so the zero and max/min
+	// interpolating variants behave like their plain counterparts.
+	aggregators["zimsum"] = aggregators["sum"]
+	aggregators["mimmax"] = aggregators["max"]
+	aggregators["mimmin"] = aggregators["min"]
+	aggregators["median"] = percentile(50)
+	aggregators["p50"] = percentile(50)
+	aggregators["p75"] = percentile(75)
+	aggregators["p90"] = percentile(90)
+	aggregators["p95"] = percentile(95)
+	aggregators["p99"] = percentile(99)
+	aggregators["p999"] = percentile(99.9)
+}
+
+// percentile returns an aggregator returning the p-th percentile of its values.
+func percentile(p float64) func([]float64) float64 {
+	return func(a []float64) float64 {
+		sorted := make([]float64, len(a))
+		copy(sorted, a)
+		sort.Float64s(sorted)
+
+		i := int(math.Ceil(float64(len(sorted))*p/100)) - 1
+		if i < 0 {
+			i = 0
+		}
+		return sorted[i]
+	}
+}
+
+// parseQueryParams returns the query request for an /api/query GET request
+// in the form start=1h-ago&m=sum:1m-avg:rate:sys.cpu{host=*}.
+func parseQueryParams(params map[string][]string) (*queryRequest, error) {
+	req := &queryRequest{}
+	if v := params["start"]; len(v) > 0 {
+		req.Start = v[0]
+	}
+	if v := params["end"]; len(v) > 0 {
+		req.End = v[0]
+	}
+	if _, ok := params["ms"]; ok {
+		req.MsResolution = true
+	}
+
+	for _, m := range params["m"] {
+		q, err := parseMetricQuery(m)
+		if err != nil {
+			return nil, err
+		}
+		req.Queries = append(req.Queries, *q)
+	}
+	return req, nil
+}
+
+// parseMetricQuery parses an m parameter of the form
+// <aggregator>:[<downsample>:][rate[{counter[,max[,reset]]}]:]<metric>[{<tags>}].
+func parseMetricQuery(s string) (*subQuery, error) {
+	q := &subQuery{}
+
+	// Split off the tags following the metric name.
+	if i := strings.Index(s[strings.LastIndex(s, ":")+1:], "{"); i >= 0 {
+		i += strings.LastIndex(s, ":") + 1
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("invalid tags in metric query: %s", s)
+		}
+
+		q.Tags = make(map[string]string)
+		for _, kv := range strings.Split(s[i+1:len(s)-1], ",") {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return nil, fmt.Errorf("invalid tag in metric query: %s", kv)
+			}
+			q.Tags[parts[0]] = parts[1]
+		}
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid metric query: %s", s)
+	}
+	q.Aggregator, q.Metric = parts[0], parts[len(parts)-1]
+
+	for _, part := range parts[1 : len(parts)-1] {
+		if part == "rate" || strings.HasPrefix(part, "rate{") {
+			q.Rate = true
+			opts, err := parseRateOptions(strings.TrimPrefix(part, "rate"))
+			if err != nil {
+				return nil, err
+			}
+			q.RateOptions = opts
+		} else {
+			q.Downsample = part
+		}
+	}
+	return q, nil
+}
+
+// parseRateOptions parses rate options of the form {counter[,max[,reset]]}.
+func parseRateOptions(s string) (rateOptions, error) {
+	var opts rateOptions
+	if s == "" {
+		return opts, nil
+	} else if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return opts, fmt.Errorf("invalid rate options: %s", s)
+	}
+
+	parts := strings.Split(s[1:len(s)-1], ",")
+	opts.Counter = parts[0] == "counter"
+	for i, part := range parts[1:] {
+		if part == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return opts, fmt.Errorf("invalid rate options: %s", s)
+		}
+		if i == 0 {
+			opts.CounterMax = v
+		} else {
+			opts.ResetValue = v
+		}
+	}
+	return opts, nil
+}
+
+// parseQueryTime returns the time for an OpenTSDB start or end time. Times are
+// either relative, such as 1h-ago, or absolute in seconds or milliseconds
+// since the epoch.
+func parseQueryTime(v interface{}, now time.Time) (time.Time, error) {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return time.Time{}, nil
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		s = v
+	default:
+		return time.Time{}, fmt.Errorf("invalid time: %v", v)
+	}
+
+	if s == "" {
+		return time.Time{}, nil
+	} else if s == "now" {
+		return now, nil
+	} else if strings.HasSuffix(s, "-ago") {
+		d, err := parseInterval(strings.TrimSuffix(s, "-ago"))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-d), nil
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time: %s", s)
+	}
+	// Timestamps with more than 10 digits are in milliseconds.
+	if len(s) > 10 {
+		return time.Unix(n/1000, (n%1000)*int64(time.Millisecond)).UTC(), nil
+	}
+	return time.Unix(n, 0).UTC(), nil
+}
+
+// parseInterval returns the duration of an OpenTSDB interval such as 5m.
+// Month and year intervals are not supported.
+func parseInterval(s string) (time.Duration, error) {
+	d, err := influxql.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval: %s", s)
+	} else if d <= 0 {
+		return 0, fmt.Errorf("invalid interval: %s", s)
+	}
+	return d, nil
+}
+
+// filters returns the tag filters of q, including those given as tags.
+func (q *subQuery) filters() []tagFilter {
+	filters := make([]tagFilter, 0, len(q.Tags)+len(q.Filters))
+	keys := make([]string, 0, len(q.Tags))
+	for k := range q.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Tags are grouped by, and match their values literally unless they
+	// contain a wildcard.
+	for _, k := range keys {
+		typ := "literal_or"
+		if strings.Contains(q.Tags[k], "*") {
+			typ = "wildcard"
+		}
+		filters = append(filters, tagFilter{Type: typ, Tagk: k, Filter: q.Tags[k], GroupBy: true})
+	}
+	return append(filters, q.Filters...)
+}
+
+// statement returns the InfluxQL statement selecting the series for q from
+// the retention policy rp of database db between start and end. Every series
+// is returned separately so they can be aggregated by the handler.
+func (q *subQuery) statement(db, rp string, start, end time.Time) (string, error) {
+	if q.Metric == "" {
+		return "", errors.New("missing metric")
+	}
+	if _, ok := aggregators[q.Aggregator]; !ok && q.Aggregator != "none" {
+		return "", fmt.Errorf("unsupported aggregator: %s", q.Aggregator)
+	}
+
+	field := `"value"`
+	var groupBy, fill string
+	if q.Downsample != "" {
+		parts := strings.Split(q.Downsample, "-")
+		if len(parts) < 2 || len(parts) > 3 {
+			return "", fmt.Errorf("invalid downsample: %s", q.Downsample)
+		}
+
+		interval, err := parseInterval(parts[0])
+		if err != nil {
+			return "", err
+		}
+
+		if field = downsampleFuncs[parts[1]]; field == "" {
+			return "", fmt.Errorf("unsupported downsample function: %s", parts[1])
+		}
+
+		groupBy = fmt.Sprintf("time(%s), ", influxql.FormatDuration(interval))
+		fill = " fill(none)"
+		if len(parts) == 3 {
+			switch parts[2] {
+			case "zero":
+				fill = " fill(0)"
+			case "none", "nan", "null":
+			default:
+				return "", fmt.Errorf("unsupported fill policy: %s", parts[2])
+			}
+		}
+	}
+
+	cond := []string{
+		fmt.Sprintf("time >= %s", influxql.QuoteString(start.UTC().Format(time.RFC3339Nano))),
+		fmt.Sprintf("time <= %s", influxql.QuoteString(end.UTC().Format(time.RFC3339Nano))),
+	}
+	for _, f := range q.filters() {
+		expr, err := f.expr()
+		if err != nil {
+			return "", err
+		}
+		cond = append(cond, expr)
+	}
+
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s GROUP BY %s*%s",
+		field, influxql.QuoteIdent(db, rp, q.Metric), strings.Join(cond, " AND "), groupBy, fill), nil
+}
+
+// expr returns the InfluxQL condition for the filter.
+func (f *tagFilter) expr() (string, error) {
+	if f.Tagk == "" {
+		return "", errors.New("missing filter tag key")
+	}
+	key := influxql.QuoteIdent(f.Tagk)
+
+	switch f.Type {
+	case "literal_or", "iliteral_or", "not_literal_or", "not_iliteral_or":
+		// Case insensitive filters are matched with a regex.
+		if strings.HasPrefix(f.Type, "i") || strings.HasPrefix(f.Type, "not_i") {
+			a := strings.Split(f.Filter, "|")
+			for i := range a {
+				a[i] = regexp.QuoteMeta(a[i])
+			}
+			op := "=~"
+			if strings.HasPrefix(f.Type, "not_") {
+				op = "!~"
+			}
+			return fmt.Sprintf("%s %s %s", key, op, regexLiteral("(?i)^("+strings.Join(a, "|")+")$")), nil
+		}
+
+		op, join := "=", " OR "
+		if strings.HasPrefix(f.Type, "not_") {
+			op, join = "!=", " AND "
+		}
+		a := strings.Split(f.Filter, "|")
+		for i := range a {
+			a[i] = fmt.Sprintf("%s %s %s", key, op, influxql.QuoteString(a[i]))
+		}
+		return "(" + strings.Join(a, join) + ")", nil
+	case "wildcard", "iwildcard":
+		if f.Filter == "*" {
+			return fmt.Sprintf("%s =~ /.+/", key), nil
+		}
+		a := strings.Split(f.Filter, "*")
+		for i := range a {
+			a[i] = regexp.QuoteMeta(a[i])
+		}
+		re := "^" + strings.Join(a, ".*") + "$"
+		if f.Type == "iwildcard" {
+			re = "(?i)" + re
+		}
+		return fmt.Sprintf("%s =~ %s", key, regexLiteral(re)), nil
+	case "regexp":
+		if _, err := regexp.Compile(f.Filter); err != nil {
+			return "", fmt.Errorf("invalid regexp filter: %s", err)
+		}
+		return fmt.Sprintf("%s =~ %s", key, regexLiteral(f.Filter)), nil
+	default:
+		return "", fmt.Errorf("unsupported filter type: %s", f.Type)
+	}
+}
+
+// regexLiteral returns re as an InfluxQL regex literal.
+func regexLiteral(re string) string {
+	return "/" + strings.Replace(re, "/", `\/`, -1) + "/"
+}
+
+// series is a single series returned for a subQuery.
+type series struct {
+	tags   map[string]string
+	times  []int64
+	values []float64
+}
+
+// rate replaces the values of s with their rate of change per second.
+func (s *series) rate(opts rateOptions) {
+	var times []int64
+	var values []float64
+	for i := 1; i < len(s.values); i++ {
+		dt := float64(s.times[i]-s.times[i-1]) / float64(time.Second)
+		if dt <= 0 {
+			continue
+		}
+
+		delta := s.values[i] - s.values[i-1]
+		if opts.Counter && delta < 0 {
+			if opts.DropResets {
+				continue
+			}
+			max := opts.CounterMax
+			if max == 0 {
+				max = math.MaxInt64
+			}
+			delta = max - s.values[i-1] + s.values[i]
+		}
+
+		v := delta / dt
+		if opts.Counter && opts.ResetValue > 0 && v > opts.ResetValue {
+			v = 0
+		}
+		times = append(times, s.times[i])
+		values = append(values, v)
+	}
+	s.times, s.values = times, values
+}
+
+// seriesFromRows returns the series in rows. Rows for the same series are merged.
+func seriesFromRows(rows models.Rows) []*series {
+	var a []*series
+	index := make(map[string]*series)
+	for _, row := range rows {
+		key := tagsKey(row.Tags)
+		s := index[key]
+		if s == nil {
+			s = &series{tags: row.Tags}
+			index[key] = s
+			a = append(a, s)
+		}
+
+		for _, values := range row.Values {
+			if len(values) < 2 || values[1] == nil {
+				continue
+			}
+			t, ok := values[0].(time.Time)
+			if !ok {
+				continue
+			}
+
+			var v float64
+			switch value := values[1].(type) {
+			case float64:
+				v = value
+			case int64:
+				v = float64(value)
+			default:
+				continue
+			}
+			s.times = append(s.times, t.UnixNano())
+			s.values = append(s.values, v)
+		}
+	}
+	return a
+}
+
+// aggregate groups the series by the tags of the group by filters of q and
+// combines each group into a result with its aggregator. Series are combined
+// using only the values they have at each time, without interpolation.
+func (q *subQuery) aggregate(a []*series, msResolution bool) []queryResult {
+	var groupBy []string
+	for _, f := range q.filters() {
+		if f.GroupBy {
+			groupBy = append(groupBy, f.Tagk)
+		}
+	}
+	sort.Strings(groupBy)
+
+	// Group the series. A "none" aggregator returns every series.
+	var keys []string
+	groups := make(map[string][]*series)
+	for i, s := range a {
+		var key string
+		if q.Aggregator == "none" {
+			key = strconv.Itoa(i)
+		} else {
+			tags := make(map[string]string, len(groupBy))
+			for _, k := range groupBy {
+				tags[k] = s.tags[k]
+			}
+			key = tagsKey(tags)
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], s)
+	}
+	sort.Strings(keys)
+
+	results := make([]queryResult, 0, len(keys))
+	for _, key := range keys {
+		group := groups[key]
+
+		// Values at each time across the series in the group.
+		values := make(map[int64][]float64)
+		for _, s := range group {
+			if q.Rate {
+				s.rate(q.RateOptions)
+			}
+			for i, t := range s.times {
+				values[t] = append(values[t], s.values[i])
+			}
+		}
+
+		result := queryResult{
+			Metric:        q.Metric,
+			Tags:          make(map[string]string),
+			AggregateTags: []string{},
+			Dps:           make(map[string]float64, len(values)),
+		}
+
+		// Tags with the same value in every series are returned as tags,
+		// the rest as aggregate tags.
+		for k, v := range group[0].tags {
+			result.Tags[k] = v
+		}
+		for _, s := range group {
+			for k, v := range s.tags {
+				if tv, ok := result.Tags[k]; !ok || tv != v {
+					delete(result.Tags, k)
+				}
+			}
+		}
+		aggregateTags := make(map[string]struct{})
+		for _, s := range group {
+			for k := range s.tags {
+				if _, ok := result.Tags[k]; !ok {
+					aggregateTags[k] = struct{}{}
+				}
+			}
+		}
+		for k := range aggregateTags {
+			result.AggregateTags = append(result.AggregateTags, k)
+		}
+		sort.Strings(result.AggregateTags)
+
+		fn := aggregators[q.Aggregator]
+		for t, a := range values {
+			var ts string
+			if msResolution {
+				ts = strconv.FormatInt(t/int64(time.Millisecond), 10)
+			} else {
+				ts = strconv.FormatInt(t/int64(time.Second), 10)
+			}
+
+			if fn == nil {
+				result.Dps[ts] = a[0]
+			} else {
+				result.Dps[ts] = fn(a)
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// tagsKey returns a key uniquely identifying a set of tags.
+func tagsKey(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf []byte
+	for _, k := range keys {
+		buf = append(buf, k...)
+		buf = append(buf, '=')
+		buf = append(buf, tags[k]...)
+		buf = append(buf, ',')
+	}
+	return string(buf)
+}
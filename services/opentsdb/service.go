@@ -18,6 +18,7 @@ import (
 
 	"github.com/freetsdb/freetsdb"
 	"github.com/freetsdb/freetsdb/cluster"
+	"github.com/freetsdb/freetsdb/influxql"
 	"github.com/freetsdb/freetsdb/models"
 	"github.com/freetsdb/freetsdb/services/meta"
 	"github.com/freetsdb/freetsdb/tsdb"
@@ -43,8 +44,15 @@ const (
 	statConnectionsActive        = "connsActive"
 	statConnectionsHandled       = "connsHandled"
 	statDroppedPointsInvalid     = "droppedPointsInvalid"
+	statHTTPQueryRequests        = "httpQueryReq"
+	statHTTPSuggestRequests      = "httpSuggestReq"
+	statHTTPQueryFail            = "httpQueryFail"
 )
 
+// defaultSuggestMax is the number of suggestions returned by /api/suggest if
+// no maximum is requested.
+const defaultSuggestMax = 25
+
 // Service manages the listener and handler for an HTTP endpoint.
 type Service struct {
 	ln     net.Listener  // main listener
@@ -68,6 +76,8 @@ type Service struct {
 	MetaClient interface {
 		CreateDatabase(name string) (*meta.DatabaseInfo, error)
 	}
+	QueryExecutor influxql.QueryExecutor
+	QueryEnabled  bool
 
 	// Points received over the telnet protocol are batched.
 	batchSize    int
@@ -101,6 +111,7 @@ func NewService(c Config) (*Service, error) {
 		batchTimeout:     time.Duration(c.BatchTimeout),
 		Logger:           log.New(os.Stderr, "[opentsdb] ", log.LstdFlags),
 		LogPointErrors:   c.LogPointErrors,
+		QueryEnabled:     c.QueryEnabled,
 	}
 	return s, nil
 }
@@ -356,14 +367,22 @@ func (s *Service) handleTelnetConn(conn net.Conn) {
 
 // serveHTTP handles connections in HTTP format.
 func (s *Service) serveHTTP() {
-	srv := &http.Server{Handler: &Handler{
+	h := &Handler{
 		Database:         s.Database,
 		RetentionPolicy:  s.RetentionPolicy,
 		ConsistencyLevel: s.ConsistencyLevel,
 		PointsWriter:     s.PointsWriter,
 		Logger:           s.Logger,
 		statMap:          s.statMap,
-	}}
+	}
+
+	// The read endpoints are unauthenticated so they are only served when
+	// they have been explicitly enabled.
+	if s.QueryEnabled {
+		h.QueryExecutor = s.QueryExecutor
+	}
+
+	srv := &http.Server{Handler: h}
 	srv.Serve(s.httpln)
 }
 
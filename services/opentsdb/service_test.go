@@ -13,6 +13,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/freetsdb/freetsdb/cluster"
+	"github.com/freetsdb/freetsdb/influxql"
 	"github.com/freetsdb/freetsdb/models"
 	"github.com/freetsdb/freetsdb/services/meta"
 	"github.com/freetsdb/freetsdb/services/opentsdb"
@@ -132,9 +133,244 @@ func TestService_HTTP(t *testing.T) {
 	}
 }
 
+// Ensure a query is translated to InfluxQL and its series are aggregated.
+func TestService_HTTP_Query(t *testing.T) {
+	t.Parallel()
+
+	s := NewService("db0")
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Mock query executor returning a series for two hosts.
+	var stmt string
+	s.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, opt influxql.ExecutionOptions) ([]*influxql.Result, error) {
+		stmt = q.String()
+		if opt.Database != "db0" {
+			t.Fatalf("unexpected database: %s", opt.Database)
+		}
+		return []*influxql.Result{{Series: models.Rows{
+			{Name: "sys.cpu", Tags: map[string]string{"dc": "lga", "host": "web01"}, Columns: []string{"time", "mean"}, Values: [][]interface{}{
+				{time.Unix(60, 0).UTC(), 1.0},
+				{time.Unix(120, 0).UTC(), 2.0},
+			}},
+			{Name: "sys.cpu", Tags: map[string]string{"dc": "lga", "host": "web02"}, Columns: []string{"time", "mean"}, Values: [][]interface{}{
+				{time.Unix(60, 0).UTC(), 3.0},
+			}},
+		}}}, nil
+	}
+
+	resp, err := http.Get("http://" + s.Addr().String() + "/api/query?start=60&end=180&m=sum:1m-avg:sys.cpu{dc=lga}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %d: %s", resp.StatusCode, body)
+	} else if exp := `SELECT mean(value) FROM db0.."sys.cpu" WHERE time >= '1970-01-01T00:01:00Z' AND time <= '1970-01-01T00:03:00Z' AND (dc = 'lga') GROUP BY time(1m), * fill(none)`; stmt != exp {
+		t.Fatalf("unexpected statement:\n\nexp=%s\n\ngot=%s", exp, stmt)
+	} else if exp := `[{"metric":"sys.cpu","tags":{"dc":"lga"},"aggregateTags":["host"],"dps":{"120":2,"60":4}}]`; strings.TrimSpace(string(body)) != exp {
+		t.Fatalf("unexpected body:\n\nexp=%s\n\ngot=%s", exp, body)
+	}
+}
+
+// Ensure a query with filters and a rate can be posted.
+func TestService_HTTP_Query_Post(t *testing.T) {
+	t.Parallel()
+
+	s := NewService("db0")
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var stmt string
+	s.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, opt influxql.ExecutionOptions) ([]*influxql.Result, error) {
+		stmt = q.String()
+		return []*influxql.Result{{Series: models.Rows{
+			{Name: "if.bytes", Tags: map[string]string{"host": "web01"}, Columns: []string{"time", "value"}, Values: [][]interface{}{
+				{time.Unix(10, 0).UTC(), 100.0},
+				{time.Unix(20, 0).UTC(), 300.0},
+				{time.Unix(30, 0).UTC(), 50.0},
+			}},
+			{Name: "if.bytes", Tags: map[string]string{"host": "web02"}, Columns: []string{"time", "value"}, Values: [][]interface{}{
+				{time.Unix(10, 0).UTC(), 10.0},
+			}},
+		}}}, nil
+	}
+
+	resp, err := http.Post("http://"+s.Addr().String()+"/api/query", "application/json", strings.NewReader(`{
+		"start": 10, "end": 30, "msResolution": true,
+		"queries": [{
+			"aggregator": "none", "metric": "if.bytes", "rate": true,
+			"rateOptions": {"counter": true, "dropResets": true},
+			"filters": [{"type": "wildcard", "tagk": "host", "filter": "web*", "groupBy": false}]
+		}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %d: %s", resp.StatusCode, body)
+	} else if exp := `SELECT value FROM db0.."if.bytes" WHERE time >= '1970-01-01T00:00:10Z' AND time <= '1970-01-01T00:00:30Z' AND host =~ /^web.*$/ GROUP BY *`; stmt != exp {
+		t.Fatalf("unexpected statement:\n\nexp=%s\n\ngot=%s", exp, stmt)
+	} else if exp := `[{"metric":"if.bytes","tags":{"host":"web01"},"aggregateTags":[],"dps":{"20000":20}},{"metric":"if.bytes","tags":{"host":"web02"},"aggregateTags":[],"dps":{}}]`; strings.TrimSpace(string(body)) != exp {
+		t.Fatalf("unexpected body:\n\nexp=%s\n\ngot=%s", exp, body)
+	}
+}
+
+// Ensure queries read from the retention policy that points are written to.
+func TestService_HTTP_Query_RetentionPolicy(t *testing.T) {
+	t.Parallel()
+
+	s := NewService("db0")
+	s.RetentionPolicy = "rp0"
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var rp string
+	s.PointsWriter.WritePointsFn = func(req *cluster.WritePointsRequest) error {
+		rp = req.RetentionPolicy
+		return nil
+	}
+	var stmt string
+	s.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, opt influxql.ExecutionOptions) ([]*influxql.Result, error) {
+		stmt = q.String()
+		return []*influxql.Result{{}}, nil
+	}
+
+	resp, err := http.Post("http://"+s.Addr().String()+"/api/put", "application/json", strings.NewReader(`{"metric":"sys.cpu", "timestamp":60, "value":1, "tags":{"host":"web01"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	} else if rp != "rp0" {
+		t.Fatalf("unexpected retention policy: %s", rp)
+	}
+
+	resp, err = http.Get("http://" + s.Addr().String() + "/api/query?start=60&end=180&m=none:sys.cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %d: %s", resp.StatusCode, body)
+	} else if exp := `SELECT value FROM db0.rp0."sys.cpu" WHERE time >= '1970-01-01T00:01:00Z' AND time <= '1970-01-01T00:03:00Z' GROUP BY *`; stmt != exp {
+		t.Fatalf("unexpected statement:\n\nexp=%s\n\ngot=%s", exp, stmt)
+	}
+}
+
+// Ensure an invalid query returns an OpenTSDB error.
+func TestService_HTTP_Query_Invalid(t *testing.T) {
+	t.Parallel()
+
+	s := NewService("db0")
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	resp, err := http.Get("http://" + s.Addr().String() + "/api/query?start=1h-ago&m=foo:sys.cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	} else if exp := `{"error":{"code":400,"message":"unsupported aggregator: foo"}}`; strings.TrimSpace(string(body)) != exp {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure the read endpoints are not served unless queries are enabled.
+func TestService_HTTP_Query_Disabled(t *testing.T) {
+	t.Parallel()
+
+	s := NewService("db0")
+	s.QueryEnabled = false
+	s.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, opt influxql.ExecutionOptions) ([]*influxql.Result, error) {
+		t.Fatalf("unexpected query: %s", q)
+		return nil, nil
+	}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, path := range []string{"/api/query?start=60&m=sum:sys.cpu", "/api/suggest?type=metrics"} {
+		resp, err := http.Get("http://" + s.Addr().String() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("%s: unexpected status code: %d", path, resp.StatusCode)
+		}
+	}
+}
+
+// Ensure tag values are suggested from the series index.
+func TestService_HTTP_Suggest(t *testing.T) {
+	t.Parallel()
+
+	s := NewService("db0")
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var stmts []string
+	s.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, opt influxql.ExecutionOptions) ([]*influxql.Result, error) {
+		stmts = append(stmts, q.String())
+		switch len(stmts) {
+		case 1:
+			return []*influxql.Result{{Series: models.Rows{
+				{Name: "cpu", Columns: []string{"tagKey"}, Values: [][]interface{}{{"host"}}},
+				{Name: "mem", Columns: []string{"tagKey"}, Values: [][]interface{}{{"host"}, {"dc"}}},
+			}}}, nil
+		default:
+			return []*influxql.Result{{Series: models.Rows{
+				{Name: "cpu", Columns: []string{"key", "value"}, Values: [][]interface{}{{"host", "web02"}, {"host", "web01"}}},
+				{Name: "mem", Columns: []string{"key", "value"}, Values: [][]interface{}{{"host", "web01"}, {"dc", "lga"}, {"host", "web03"}}},
+			}}}, nil
+		}
+	}
+
+	resp, err := http.Get("http://" + s.Addr().String() + "/api/suggest?type=tagv&q=web&max=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %d: %s", resp.StatusCode, body)
+	} else if exp := []string{"SHOW TAG KEYS", "SHOW TAG VALUES WITH KEY IN (dc, host)"}; !reflect.DeepEqual(stmts, exp) {
+		t.Fatalf("unexpected statements: %q", stmts)
+	} else if exp := `["web01","web02"]`; strings.TrimSpace(string(body)) != exp {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
 type Service struct {
 	*opentsdb.Service
-	PointsWriter PointsWriter
+	PointsWriter  PointsWriter
+	QueryExecutor QueryExecutor
 }
 
 // NewService returns a new instance of Service.
@@ -143,9 +379,11 @@ func NewService(database string) *Service {
 		BindAddress:      "127.0.0.1:0",
 		Database:         database,
 		ConsistencyLevel: "one",
+		QueryEnabled:     true,
 	})
 	s := &Service{Service: srv}
 	s.Service.PointsWriter = &s.PointsWriter
+	s.Service.QueryExecutor = &s.QueryExecutor
 	s.Service.MetaClient = &DatabaseCreator{}
 
 	if !testing.Verbose() {
@@ -164,6 +402,28 @@ func (w *PointsWriter) WritePoints(p *cluster.WritePointsRequest) error {
 	return w.WritePointsFn(p)
 }
 
+// QueryExecutor represents a mock impl of influxql.QueryExecutor.
+type QueryExecutor struct {
+	ExecuteQueryFn func(q *influxql.Query, opt influxql.ExecutionOptions) ([]*influxql.Result, error)
+}
+
+func (e *QueryExecutor) ExecuteQuery(q *influxql.Query, opt influxql.ExecutionOptions, closing chan struct{}) <-chan *influxql.Result {
+	ch := make(chan *influxql.Result, 1)
+	results, err := e.ExecuteQueryFn(q, opt)
+	if err != nil {
+		ch <- &influxql.Result{Err: err}
+		close(ch)
+		return ch
+	}
+
+	ch = make(chan *influxql.Result, len(results))
+	for _, r := range results {
+		ch <- r
+	}
+	close(ch)
+	return ch
+}
+
 type DatabaseCreator struct {
 }
 
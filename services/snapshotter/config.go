@@ -0,0 +1,15 @@
+package snapshotter
+
+// Config represents the configuration for the snapshot service.
+type Config struct {
+	// Requests to the snapshot service aren't authenticated, so restores are
+	// only accepted from the local host unless remote restores are enabled.
+	// An online restore streams shards to every data node owning them, so
+	// it must be enabled on each node while restoring into a cluster.
+	RemoteRestoreEnabled bool `toml:"remote-restore-enabled"`
+}
+
+// NewConfig returns a new Config with defaults.
+func NewConfig() Config {
+	return Config{}
+}
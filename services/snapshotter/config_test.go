@@ -0,0 +1,28 @@
+package snapshotter_test
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/freetsdb/freetsdb/services/snapshotter"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Remote restores are disabled by default.
+	if c := snapshotter.NewConfig(); c.RemoteRestoreEnabled {
+		t.Fatal("remote restores enabled by default")
+	}
+
+	// Parse configuration.
+	var c snapshotter.Config
+	if _, err := toml.Decode(`
+remote-restore-enabled = true
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.RemoteRestoreEnabled {
+		t.Fatalf("unexpected remote restore enabled state: %v", c.RemoteRestoreEnabled)
+	}
+}
@@ -2,10 +2,14 @@ package snapshotter // import "github.com/freetsdb/freetsdb/services/snapshotter
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"os"
@@ -34,9 +38,16 @@ type Service struct {
 
 	Node *freetsdb.Node
 
+	// RemoteRestoreEnabled allows restore requests from other hosts.
+	RemoteRestoreEnabled bool
+
 	MetaClient interface {
 		encoding.BinaryMarshaler
 		Database(name string) (*meta.DatabaseInfo, error)
+		CreateDatabase(name string) (*meta.DatabaseInfo, error)
+		CreateRetentionPolicy(database string, rpi *meta.RetentionPolicyInfo) (*meta.RetentionPolicyInfo, error)
+		CreateShardGroup(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error)
+		DataNodes() ([]meta.NodeInfo, error)
 	}
 
 	TSDBStore *tsdb.Store
@@ -108,7 +119,7 @@ func (s *Service) serve() {
 
 // handleConn processes conn. This is run in a separate goroutine.
 func (s *Service) handleConn(conn net.Conn) error {
-	r, err := s.readRequest(conn)
+	r, body, err := s.readRequest(conn)
 	if err != nil {
 		return fmt.Errorf("read request: %s", err)
 	}
@@ -126,6 +137,10 @@ func (s *Service) handleConn(conn net.Conn) error {
 		return s.writeDatabaseInfo(conn, r.Database)
 	case RequestRetentionPolicyInfo:
 		return s.writeRetentionPolicyInfo(conn, r.Database, r.RetentionPolicy)
+	case RequestRetentionPolicyRestore:
+		return s.restoreRetentionPolicy(conn, r)
+	case RequestShardRestore:
+		return s.restoreShard(conn, r, io.LimitReader(body, r.Size))
 	default:
 		return fmt.Errorf("request type unknown: %v", r.Type)
 	}
@@ -255,13 +270,135 @@ func (s *Service) writeRetentionPolicyInfo(conn net.Conn, database, retentionPol
 	return nil
 }
 
-// readRequest Unmarshals a request object from the conn
-func (s *Service) readRequest(conn net.Conn) (Request, error) {
+// restoreRetentionPolicy creates the database, retention policy and shard
+// groups needed to restore a backed up retention policy, and writes the
+// shard groups and the data nodes owning them into the connection.
+func (s *Service) restoreRetentionPolicy(conn net.Conn, r Request) error {
+	var res Response
+	if err := s.allowRestore(conn); err != nil {
+		res.Err = err.Error()
+	} else if err := s.createShardGroups(r, &res); err != nil {
+		res.Err = err.Error()
+	}
+
+	if err := json.NewEncoder(conn).Encode(res); err != nil {
+		return fmt.Errorf("encode response: %s", err.Error())
+	}
+	return nil
+}
+
+// createShardGroups creates a shard group in the request's retention policy
+// for each shard group in the backed up retention policy.
+func (s *Service) createShardGroups(r Request, res *Response) error {
+	src := r.RetentionPolicyInfo
+	if src == nil {
+		return errors.New("retention policy required")
+	}
+
+	if _, err := s.MetaClient.CreateDatabase(r.Database); err != nil {
+		return err
+	}
+
+	rpi := meta.NewRetentionPolicyInfo(r.RetentionPolicy)
+	rpi.Duration = src.Duration
+	rpi.ShardGroupDuration = src.ShardGroupDuration
+	rpi.ReplicaN = src.ReplicaN
+	rp, err := s.MetaClient.CreateRetentionPolicy(r.Database, rpi)
+	if err != nil {
+		return err
+	} else if rp.ShardGroupDuration != src.ShardGroupDuration {
+		// Shards can only be restored into shard groups covering the same time range.
+		return fmt.Errorf("retention policy %s has shard group duration %s, backup has %s",
+			rp.Name, rp.ShardGroupDuration, src.ShardGroupDuration)
+	}
+
+	for _, sg := range src.ShardGroups {
+		if sg.Deleted() {
+			continue
+		}
+
+		g, err := s.MetaClient.CreateShardGroup(r.Database, r.RetentionPolicy, sg.StartTime)
+		if err != nil {
+			return err
+		}
+		res.ShardGroups = append(res.ShardGroups, *g)
+	}
+
+	res.Nodes, err = s.MetaClient.DataNodes()
+	return err
+}
+
+// restoreShard adds the backup archive read from r to a shard on this server,
+// and writes the result into the connection.
+func (s *Service) restoreShard(conn net.Conn, req Request, r io.Reader) error {
+	var res Response
+	if err := s.allowRestore(conn); err != nil {
+		res.Err = err.Error()
+	} else if err := s.restoreShardArchive(req, r); err != nil {
+		res.Err = err.Error()
+	}
+
+	// Consume the rest of the archive so the client isn't blocked writing it.
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(conn).Encode(res); err != nil {
+		return fmt.Errorf("encode response: %s", err.Error())
+	}
+	return nil
+}
+
+// allowRestore returns an error if restores aren't accepted from the client
+// of conn. Restore requests aren't authenticated, so only clients on the
+// local host may restore unless remote restores are enabled.
+func (s *Service) allowRestore(conn net.Conn) error {
+	if s.RemoteRestoreEnabled {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("restore from %s not allowed: remote restores are disabled", host)
+	}
+	return nil
+}
+
+// restoreShardArchive adds the archive read from r to the requested shard.
+func (s *Service) restoreShardArchive(req Request, r io.Reader) error {
+	if req.Compressed {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return s.TSDBStore.RestoreShard(req.Database, req.RetentionPolicy, req.ShardID, r)
+}
+
+// readRequest Unmarshals a request object from the conn. It also returns a
+// reader for any data sent after the request.
+func (s *Service) readRequest(conn net.Conn) (Request, io.Reader, error) {
 	var r Request
-	if err := json.NewDecoder(conn).Decode(&r); err != nil {
-		return r, err
+	dec := json.NewDecoder(conn)
+	if err := dec.Decode(&r); err != nil {
+		return r, nil, err
 	}
-	return r, nil
+
+	// Requests are written by a json.Encoder, which terminates them with a
+	// newline. It may not have been read from the connection yet.
+	body := io.MultiReader(dec.Buffered(), conn)
+	var nl [1]byte
+	if _, err := io.ReadFull(body, nl[:]); err != nil {
+		return r, nil, err
+	} else if nl[0] != '\n' {
+		return r, nil, errors.New("request not terminated by a newline")
+	}
+	return r, body, nil
 }
 
 type RequestType uint8
@@ -271,20 +408,40 @@ const (
 	RequestMetastoreBackup
 	RequestDatabaseInfo
 	RequestRetentionPolicyInfo
+	RequestRetentionPolicyRestore
+	RequestShardRestore
 )
 
 // Request represents a request for a specific backup or for information
-// about the shards on this server for a database or retention policy
+// about the shards on this server for a database or retention policy, or a
+// request to restore a backup into a database or retention policy
 type Request struct {
 	Type            RequestType
 	Database        string
 	RetentionPolicy string
 	ShardID         uint64
 	Since           time.Time
+
+	// RetentionPolicyInfo is the backed up retention policy being restored.
+	RetentionPolicyInfo *meta.RetentionPolicyInfo `json:",omitempty"`
+
+	// Size is the length of the shard backup archive following the request.
+	Size int64 `json:",omitempty"`
+
+	// Compressed is set if the archive following the request is gzipped.
+	Compressed bool `json:",omitempty"`
 }
 
 // Response contains the relative paths for all the shards on this server
-// that are in the requested database or retention policy
+// that are in the requested database or retention policy. For restores, it
+// contains the shard groups created for the backed up shard groups and the
+// data nodes that own them.
 type Response struct {
 	Paths []string
+
+	ShardGroups []meta.ShardGroupInfo `json:",omitempty"`
+	Nodes       []meta.NodeInfo       `json:",omitempty"`
+
+	// Err is set if a restore failed.
+	Err string `json:",omitempty"`
 }
@@ -1 +1,382 @@
 package snapshotter_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/freetsdb/freetsdb/models"
+	"github.com/freetsdb/freetsdb/services/meta"
+	"github.com/freetsdb/freetsdb/services/snapshotter"
+	"github.com/freetsdb/freetsdb/tcp"
+	"github.com/freetsdb/freetsdb/tsdb"
+	_ "github.com/freetsdb/freetsdb/tsdb/engine"
+)
+
+// Ensure a backed up shard can be restored into a new database and retention
+// policy while the service is running.
+func TestService_Restore(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	// Back up a shard from another store.
+	src := MustOpenStore()
+	defer src.Close()
+	if err := src.CreateShard("db0", "rp0", 1); err != nil {
+		t.Fatal(err)
+	}
+	pt, _ := models.NewPoint("cpu", map[string]string{"host": "A"}, map[string]interface{}{"value": 1.0}, time.Unix(10, 0))
+	if err := src.WriteToShard(1, []models.Point{pt}); err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	if err := src.BackupShard(1, time.Time{}, gz); err != nil {
+		t.Fatal(err)
+	} else if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var created *meta.RetentionPolicyInfo
+	s.MetaClient.CreateRetentionPolicyFn = func(database string, rpi *meta.RetentionPolicyInfo) (*meta.RetentionPolicyInfo, error) {
+		if database != "db1" {
+			t.Fatalf("unexpected database: %s", database)
+		}
+		created = rpi
+		return rpi, nil
+	}
+	s.MetaClient.CreateShardGroupFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		if policy != "rp1" || !timestamp.Equal(time.Unix(0, 0)) {
+			t.Fatalf("unexpected shard group: %s %s", policy, timestamp)
+		}
+		return &meta.ShardGroupInfo{ID: 2, StartTime: timestamp, Shards: []meta.ShardInfo{{ID: 20, Owners: []meta.ShardOwner{{NodeID: 1}}}}}, nil
+	}
+
+	// Restore the retention policy.
+	res := s.MustRequest(&snapshotter.Request{
+		Type:            snapshotter.RequestRetentionPolicyRestore,
+		Database:        "db1",
+		RetentionPolicy: "rp1",
+		RetentionPolicyInfo: &meta.RetentionPolicyInfo{
+			Name:               "rp0",
+			ReplicaN:           1,
+			ShardGroupDuration: time.Hour,
+			ShardGroups: []meta.ShardGroupInfo{
+				{ID: 1, StartTime: time.Unix(0, 0), Shards: []meta.ShardInfo{{ID: 1}}},
+			},
+		},
+	}, nil)
+	if res.Err != "" {
+		t.Fatal(res.Err)
+	} else if created == nil || created.Name != "rp1" || created.ShardGroupDuration != time.Hour {
+		t.Fatalf("unexpected retention policy: %#v", created)
+	} else if len(res.ShardGroups) != 1 || res.ShardGroups[0].Shards[0].ID != 20 {
+		t.Fatalf("unexpected shard groups: %#v", res.ShardGroups)
+	} else if len(res.Nodes) != 1 || res.Nodes[0].ID != 1 {
+		t.Fatalf("unexpected nodes: %#v", res.Nodes)
+	}
+
+	// Restore the compressed shard into the new shard.
+	res = s.MustRequest(&snapshotter.Request{
+		Type:            snapshotter.RequestShardRestore,
+		Database:        "db1",
+		RetentionPolicy: "rp1",
+		ShardID:         20,
+		Size:            int64(archive.Len()),
+		Compressed:      true,
+	}, archive.Bytes())
+	if res.Err != "" {
+		t.Fatal(res.Err)
+	}
+
+	if sh := s.TSDBStore.Shard(20); sh == nil {
+		t.Fatal("shard not created")
+	} else if idx := s.TSDBStore.DatabaseIndex("db1"); idx == nil || idx.Series("cpu,host=A") == nil {
+		t.Fatal("restored series not indexed")
+	}
+}
+
+// Ensure a retention policy restore fails if its shard groups can't line up
+// with the backed up shard groups.
+func TestService_Restore_ShardGroupDurationMismatch(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	s.MetaClient.CreateRetentionPolicyFn = func(database string, rpi *meta.RetentionPolicyInfo) (*meta.RetentionPolicyInfo, error) {
+		return &meta.RetentionPolicyInfo{Name: rpi.Name, ShardGroupDuration: 24 * time.Hour}, nil
+	}
+
+	res := s.MustRequest(&snapshotter.Request{
+		Type:                snapshotter.RequestRetentionPolicyRestore,
+		Database:            "db1",
+		RetentionPolicy:     "rp1",
+		RetentionPolicyInfo: &meta.RetentionPolicyInfo{Name: "rp0", ShardGroupDuration: time.Hour},
+	}, nil)
+	if exp := "retention policy rp1 has shard group duration 24h0m0s, backup has 1h0m0s"; res.Err != exp {
+		t.Fatalf("unexpected error: %q", res.Err)
+	}
+}
+
+// Ensure restores from other hosts are rejected unless remote restores are enabled.
+func TestService_Restore_Remote(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		func() {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			mux := tcp.NewMux()
+
+			s := NewService()
+			s.ln = ln
+			s.RemoteRestoreEnabled = enabled
+			s.Listener = &remoteListener{
+				Listener: mux.Listen(snapshotter.MuxHeader),
+				addr:     &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 51234},
+			}
+			go mux.Serve(ln)
+			if err := s.Open(); err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			res := s.MustRequest(&snapshotter.Request{
+				Type:            snapshotter.RequestShardRestore,
+				Database:        "db1",
+				RetentionPolicy: "rp1",
+				ShardID:         20,
+				Size:            int64(len("archive")),
+			}, []byte("archive"))
+			if exp := "restore from 10.0.0.1 not allowed: remote restores are disabled"; !enabled && res.Err != exp {
+				t.Fatalf("unexpected error: %q", res.Err)
+			} else if enabled && res.Err == exp {
+				t.Fatal("remote restore rejected while enabled")
+			}
+		}()
+	}
+}
+
+// Ensure the body of a request is read correctly when the newline ending the
+// request arrives in a separate write.
+func TestService_Restore_SplitRequest(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	// Back up a shard from another store.
+	src := MustOpenStore()
+	defer src.Close()
+	if err := src.CreateShard("db0", "rp0", 1); err != nil {
+		t.Fatal(err)
+	}
+	pt, _ := models.NewPoint("cpu", map[string]string{"host": "A"}, map[string]interface{}{"value": 1.0}, time.Unix(10, 0))
+	if err := src.WriteToShard(1, []models.Point{pt}); err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	if err := src.BackupShard(1, time.Time{}, gz); err != nil {
+		t.Fatal(err)
+	} else if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := json.Marshal(&snapshotter.Request{
+		Type:            snapshotter.RequestShardRestore,
+		Database:        "db1",
+		RetentionPolicy: "rp1",
+		ShardID:         20,
+		Size:            int64(archive.Len()),
+		Compressed:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := tcp.Dial("tcp", s.ln.Addr().String(), snapshotter.MuxHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Give the service time to decode the request before the newline is sent.
+	for _, b := range [][]byte{req, []byte("\n"), archive.Bytes()} {
+		if _, err := conn.Write(b); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var res snapshotter.Response
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		t.Fatal(err)
+	} else if res.Err != "" {
+		t.Fatal(res.Err)
+	}
+
+	if idx := s.TSDBStore.DatabaseIndex("db1"); idx == nil || idx.Series("cpu,host=A") == nil {
+		t.Fatal("restored series not indexed")
+	}
+}
+
+// Service represents a test wrapper for snapshotter.Service.
+type Service struct {
+	*snapshotter.Service
+
+	ln         net.Listener
+	MetaClient ServiceMetaClient
+	TSDBStore  *Store
+}
+
+// NewService returns a new instance of Service.
+func NewService() *Service {
+	s := &Service{
+		Service:   snapshotter.NewService(),
+		TSDBStore: MustOpenStore(),
+	}
+	s.Service.MetaClient = &s.MetaClient
+	s.Service.TSDBStore = s.TSDBStore.Store
+
+	if !testing.Verbose() {
+		s.SetLogger(log.New(ioutil.Discard, "", 0))
+	}
+	return s
+}
+
+// MustOpenService returns a new, opened service. Panic on error.
+func MustOpenService() *Service {
+	// Open randomly assigned port.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	// Start muxer.
+	mux := tcp.NewMux()
+
+	// Create new service and attach mux'd listener.
+	s := NewService()
+	s.ln = ln
+	s.Listener = mux.Listen(snapshotter.MuxHeader)
+	go mux.Serve(ln)
+
+	if err := s.Open(); err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// Close shuts down the service, the attached listener and the store.
+func (s *Service) Close() error {
+	s.ln.Close()
+	err := s.Service.Close()
+	s.TSDBStore.Close()
+	return err
+}
+
+// MustRequest sends a request, followed by body, to the service and returns
+// the response. Panic on error.
+func (s *Service) MustRequest(req *snapshotter.Request, body []byte) *snapshotter.Response {
+	conn, err := tcp.Dial("tcp", s.ln.Addr().String(), snapshotter.MuxHeader)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		panic(err)
+	}
+	if _, err := conn.Write(body); err != nil {
+		panic(err)
+	}
+
+	var res snapshotter.Response
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		panic(err)
+	}
+	return &res
+}
+
+// ServiceMetaClient is a mock that implements snapshotter.Service.MetaClient.
+type ServiceMetaClient struct {
+	CreateRetentionPolicyFn func(database string, rpi *meta.RetentionPolicyInfo) (*meta.RetentionPolicyInfo, error)
+	CreateShardGroupFn      func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error)
+}
+
+func (c *ServiceMetaClient) MarshalBinary() ([]byte, error) { return nil, nil }
+
+func (c *ServiceMetaClient) Database(name string) (*meta.DatabaseInfo, error) { return nil, nil }
+
+func (c *ServiceMetaClient) CreateDatabase(name string) (*meta.DatabaseInfo, error) {
+	return &meta.DatabaseInfo{Name: name}, nil
+}
+
+func (c *ServiceMetaClient) CreateRetentionPolicy(database string, rpi *meta.RetentionPolicyInfo) (*meta.RetentionPolicyInfo, error) {
+	return c.CreateRetentionPolicyFn(database, rpi)
+}
+
+func (c *ServiceMetaClient) CreateShardGroup(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+	return c.CreateShardGroupFn(database, policy, timestamp)
+}
+
+func (c *ServiceMetaClient) DataNodes() ([]meta.NodeInfo, error) {
+	return []meta.NodeInfo{{ID: 1, TCPHost: "localhost:8088"}}, nil
+}
+
+// Store is a test wrapper for tsdb.Store.
+type Store struct {
+	*tsdb.Store
+}
+
+// MustOpenStore returns a new, open Store at a temporary path.
+func MustOpenStore() *Store {
+	path, err := ioutil.TempDir("", "snapshotter-")
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Store{Store: tsdb.NewStore(path)}
+	s.EngineOptions.Config.WALDir = filepath.Join(path, "wal")
+	if !testing.Verbose() {
+		s.Logger = log.New(ioutil.Discard, "", 0)
+	}
+	if err := s.Open(); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Close closes the store and removes the underlying data.
+func (s *Store) Close() error {
+	defer os.RemoveAll(s.Path())
+	return s.Store.Close()
+}
+
+// remoteListener is a listener whose connections appear to come from addr.
+type remoteListener struct {
+	net.Listener
+	addr net.Addr
+}
+
+func (ln *remoteListener) Accept() (net.Conn, error) {
+	conn, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &remoteConn{Conn: conn, addr: ln.addr}, nil
+}
+
+// remoteConn is a connection with a fixed remote address.
+type remoteConn struct {
+	net.Conn
+	addr net.Addr
+}
+
+func (c *remoteConn) RemoteAddr() net.Addr { return c.addr }
@@ -160,7 +160,13 @@ func (ln *listener) Addr() net.Addr {
 
 // Dial connects to a remote mux listener with a given header byte.
 func Dial(network, address string, header byte) (net.Conn, error) {
-	conn, err := net.Dial(network, address)
+	return DialTimeout(network, address, header, 0)
+}
+
+// DialTimeout acts like Dial but takes a timeout for the connection to be
+// established. A timeout of zero means no timeout.
+func DialTimeout(network, address string, header byte, timeout time.Duration) (net.Conn, error) {
+	conn, err := net.DialTimeout(network, address, timeout)
 	if err != nil {
 		return nil, err
 	}
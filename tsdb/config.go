@@ -14,6 +14,13 @@ const (
 
 	// tsdb/engine/wal configuration options
 
+	// DefaultWALSyncMode is the default WAL sync mode.
+	DefaultWALSyncMode = WALSyncModeAlways
+
+	// DefaultWALFsyncDelay is the default time the WAL waits after a write
+	// before fsyncing it.
+	DefaultWALFsyncDelay = time.Duration(0)
+
 	// Default settings for TSM
 
 	// DefaultCacheMaxMemorySize is the maximum size a shard's cache can
@@ -38,6 +45,19 @@ const (
 	DefaultMaxPointsPerBlock = 1000
 )
 
+// WAL sync modes.
+const (
+	// WALSyncModeAlways fsyncs writes to the WAL before acknowledging them.
+	WALSyncModeAlways = "always"
+
+	// WALSyncModeInterval acknowledges writes to the WAL before fsyncing
+	// them in the background.
+	WALSyncModeInterval = "interval"
+
+	// WALSyncModeNone leaves flushing writes to the WAL to the OS.
+	WALSyncModeNone = "none"
+)
+
 // Config holds the configuration for the tsbd package.
 type Config struct {
 	Enabled bool   `toml:"enabled"`
@@ -48,6 +68,12 @@ type Config struct {
 	WALDir            string `toml:"wal-dir"`
 	WALLoggingEnabled bool   `toml:"wal-logging-enabled"`
 
+	// WALSyncMode is when writes to the WAL are fsynced. WALFsyncDelay is how
+	// long to wait after a write before fsyncing, so concurrent writes can
+	// share an fsync.
+	WALSyncMode   string        `toml:"wal-sync-mode"`
+	WALFsyncDelay toml.Duration `toml:"wal-fsync-delay"`
+
 	// Query logging
 	QueryLogEnabled bool `toml:"query-log-enabled"`
 
@@ -68,6 +94,8 @@ func NewConfig() Config {
 		Enabled: true, // data node enabled by default
 
 		WALLoggingEnabled: true,
+		WALSyncMode:       DefaultWALSyncMode,
+		WALFsyncDelay:     toml.Duration(DefaultWALFsyncDelay),
 
 		QueryLogEnabled: true,
 
@@ -99,5 +127,14 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("unrecognized engine %s", c.Engine)
 	}
 
+	switch c.WALSyncMode {
+	case "", WALSyncModeAlways, WALSyncModeInterval, WALSyncModeNone:
+	default:
+		return fmt.Errorf("unrecognized WAL sync mode %s", c.WALSyncMode)
+	}
+	if c.WALFsyncDelay < 0 {
+		return errors.New("Data.WALFsyncDelay must not be negative")
+	}
+
 	return nil
 }
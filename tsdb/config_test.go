@@ -2,6 +2,7 @@ package tsdb_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/freetsdb/freetsdb/tsdb"
@@ -12,6 +13,8 @@ func TestConfig_Parse(t *testing.T) {
 	var c tsdb.Config
 	if _, err := toml.Decode(`
 enabled = false
+wal-sync-mode = "interval"
+wal-fsync-delay = "10ms"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -19,6 +22,10 @@ enabled = false
 	// Validate configuration.
 	if c.Enabled == true {
 		t.Fatalf("unexpected enabled: %v", c.Enabled)
+	} else if c.WALSyncMode != tsdb.WALSyncModeInterval {
+		t.Fatalf("unexpected wal sync mode: %s", c.WALSyncMode)
+	} else if time.Duration(c.WALFsyncDelay) != 10*time.Millisecond {
+		t.Fatalf("unexpected wal fsync delay: %s", c.WALFsyncDelay)
 	}
 	// TODO: add remaining config tests
 }
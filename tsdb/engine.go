@@ -44,6 +44,8 @@ type Engine interface {
 	io.WriterTo
 
 	Backup(w io.Writer, basePath string, since time.Time) error
+	PrepareRestore(r io.Reader) ([]string, error)
+	Restore(paths []string) error
 }
 
 // EngineFormat represents the format for an engine.
@@ -66,6 +66,8 @@ type Engine struct {
 func NewEngine(path string, walPath string, opt tsdb.EngineOptions) tsdb.Engine {
 	w := NewWAL(walPath)
 	w.LoggingEnabled = opt.Config.WALLoggingEnabled
+	w.SyncMode = opt.Config.WALSyncMode
+	w.FsyncDelay = time.Duration(opt.Config.WALFsyncDelay)
 
 	fs := NewFileStore(path)
 	fs.traceLogging = opt.Config.DataLoggingEnabled
@@ -299,10 +301,141 @@ func (e *Engine) writeFileToBackup(f FileStat, shardRelativePath string, tw *tar
 	return err
 }
 
+// PrepareRestore reads a tar archive written by Backup and writes the TSM
+// files in it to the engine's directory as temporary files. Each file is
+// given a new generation so it can't replace an existing file. Other files
+// in the archive are ignored. The files aren't visible until they are added
+// with Restore, and are removed by cleanup if the engine is reopened first.
+func (e *Engine) PrepareRestore(r io.Reader) ([]string, error) {
+	var paths []string
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return paths, nil
+		} else if err != nil {
+			removeFiles(paths)
+			return nil, err
+		}
+
+		if filepath.Ext(hdr.Name) != "."+TSMFileExtension {
+			continue
+		}
+
+		path, err := e.writeRestoreFile(tr)
+		if err != nil {
+			removeFiles(paths)
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+}
+
+// writeRestoreFile writes the TSM file in r to a temporary file in the
+// engine's directory under the next generation and returns its path.
+func (e *Engine) writeRestoreFile(r io.Reader) (string, error) {
+	tmp := filepath.Join(e.path, fmt.Sprintf("%09d-%09d.%s.%s", e.FileStore.NextGeneration(), 1, TSMFileExtension, CompactionTempExtension))
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return tmp, nil
+}
+
+// Restore adds the temporary files written by PrepareRestore to the file
+// store, and their keys to the index. Files that aren't added are removed.
+func (e *Engine) Restore(paths []string) error {
+	for i, tmp := range paths {
+		if err := e.restoreFile(tmp); err != nil {
+			removeFiles(paths[i+1:])
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreFile moves a temporary file written by PrepareRestore into place,
+// and adds it to the file store and index.
+func (e *Engine) restoreFile(tmp string) error {
+	name := strings.TrimSuffix(tmp, "."+CompactionTempExtension)
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	fd, err := os.Open(name)
+	if err != nil {
+		os.Remove(name)
+		return err
+	}
+	tsm, err := NewTSMReaderWithOptions(TSMReaderOptions{MMAPFile: fd})
+	if err != nil {
+		fd.Close()
+		os.Remove(name)
+		return fmt.Errorf("error opening restored file %s: %v", name, err)
+	}
+
+	// Add the keys to the index before the data is visible.
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if err := e.addToIndexFromTSM(tsm); err != nil {
+		tsm.Close()
+		os.Remove(name)
+		return fmt.Errorf("error indexing restored file %s: %v", name, err)
+	}
+
+	e.FileStore.Add(tsm)
+	return nil
+}
+
+// removeFiles removes the files at paths, ignoring errors.
+func removeFiles(paths []string) {
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
+
+// addToIndexFromTSM adds the keys of a TSM file to the index, if loaded.
+func (e *Engine) addToIndexFromTSM(tsm *TSMReader) error {
+	if e.index == nil {
+		return nil
+	}
+
+	for _, k := range tsm.Keys() {
+		typ, err := tsm.Type(k)
+		if err != nil {
+			return err
+		}
+		fieldType, err := tsmFieldTypeToInfluxQLDataType(typ)
+		if err != nil {
+			return err
+		}
+		if err := e.addToIndexFromKey(k, fieldType, e.index, e.measurementFields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // addToIndexFromKey will pull the measurement name, series key, and field name from a composite key and add it to the
 // database index and measurement fields
 func (e *Engine) addToIndexFromKey(key string, fieldType influxql.DataType, index *tsdb.DatabaseIndex, measurementFields map[string]*tsdb.MeasurementFields) error {
-	seriesKey, field := seriesAndFieldFromCompositeKey(key)
+	seriesKey, field := SeriesAndFieldFromCompositeKey(key)
 	measurement := tsdb.MeasurementFromSeriesKey(seriesKey)
 
 	m := index.CreateMeasurementIndexIfNotExists(measurement)
@@ -371,7 +504,7 @@ func (e *Engine) DeleteSeries(seriesKeys []string) error {
 	var deleteKeys []string
 	// go through the keys in the file store
 	for _, k := range e.FileStore.Keys() {
-		seriesKey, _ := seriesAndFieldFromCompositeKey(k)
+		seriesKey, _ := SeriesAndFieldFromCompositeKey(k)
 		if _, ok := keyMap[seriesKey]; ok {
 			deleteKeys = append(deleteKeys, k)
 		}
@@ -385,7 +518,7 @@ func (e *Engine) DeleteSeries(seriesKeys []string) error {
 
 	s := e.Cache.Store()
 	for k, _ := range s {
-		seriesKey, _ := seriesAndFieldFromCompositeKey(k)
+		seriesKey, _ := SeriesAndFieldFromCompositeKey(k)
 		if _, ok := keyMap[seriesKey]; ok {
 			walKeys = append(walKeys, k)
 			delete(s, k)
@@ -806,6 +939,10 @@ func (e *Engine) createVarRefIterator(opt influxql.IteratorOptions) ([]influxql.
 						continue
 					}
 					itrs = append(itrs, itr)
+
+					if opt.Stats != nil {
+						opt.Stats.Add(influxql.IteratorStats{SeriesN: 1})
+					}
 				}
 			}
 		}
@@ -926,6 +1063,7 @@ func (e *Engine) buildCursor(measurement, seriesKey, field string, opt influxql.
 func (e *Engine) buildFloatCursor(measurement, seriesKey, field string, opt influxql.IteratorOptions) floatCursor {
 	cacheValues := e.Cache.Values(SeriesFieldKey(seriesKey, field))
 	keyCursor := e.KeyCursor(SeriesFieldKey(seriesKey, field), opt.SeekTime(), opt.Ascending)
+	keyCursor.stats = opt.Stats
 	return newFloatCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
 
@@ -933,6 +1071,7 @@ func (e *Engine) buildFloatCursor(measurement, seriesKey, field string, opt infl
 func (e *Engine) buildIntegerCursor(measurement, seriesKey, field string, opt influxql.IteratorOptions) integerCursor {
 	cacheValues := e.Cache.Values(SeriesFieldKey(seriesKey, field))
 	keyCursor := e.KeyCursor(SeriesFieldKey(seriesKey, field), opt.SeekTime(), opt.Ascending)
+	keyCursor.stats = opt.Stats
 	return newIntegerCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
 
@@ -940,6 +1079,7 @@ func (e *Engine) buildIntegerCursor(measurement, seriesKey, field string, opt in
 func (e *Engine) buildStringCursor(measurement, seriesKey, field string, opt influxql.IteratorOptions) stringCursor {
 	cacheValues := e.Cache.Values(SeriesFieldKey(seriesKey, field))
 	keyCursor := e.KeyCursor(SeriesFieldKey(seriesKey, field), opt.SeekTime(), opt.Ascending)
+	keyCursor.stats = opt.Stats
 	return newStringCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
 
@@ -947,6 +1087,7 @@ func (e *Engine) buildStringCursor(measurement, seriesKey, field string, opt inf
 func (e *Engine) buildBooleanCursor(measurement, seriesKey, field string, opt influxql.IteratorOptions) booleanCursor {
 	cacheValues := e.Cache.Values(SeriesFieldKey(seriesKey, field))
 	keyCursor := e.KeyCursor(SeriesFieldKey(seriesKey, field), opt.SeekTime(), opt.Ascending)
+	keyCursor.stats = opt.Stats
 	return newBooleanCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
 
@@ -970,7 +1111,9 @@ func tsmFieldTypeToInfluxQLDataType(typ byte) (influxql.DataType, error) {
 	}
 }
 
-func seriesAndFieldFromCompositeKey(key string) (string, string) {
+// SeriesAndFieldFromCompositeKey returns the series key and the field name of
+// a key combined by SeriesFieldKey.
+func SeriesAndFieldFromCompositeKey(key string) (string, string) {
 	parts := strings.Split(key, keyFieldSeparator)
 	if len(parts) != 0 {
 		return parts[0], strings.Join(parts[1:], keyFieldSeparator)
@@ -210,6 +210,106 @@ func TestEngine_Backup(t *testing.T) {
 	}
 }
 
+// Ensure engine can restore a backup alongside its existing data.
+func TestEngine_Restore(t *testing.T) {
+	src := MustOpenEngine()
+	defer src.Close()
+
+	if err := src.WritePointsString(`cpu,host=A value=1.1 1000000000`); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+	src.MustWriteSnapshot()
+
+	b := bytes.NewBuffer(nil)
+	if err := src.Backup(b, "db/rp/1", time.Unix(0, 0)); err != nil {
+		t.Fatalf("failed to backup: %s", err.Error())
+	}
+
+	e := MustOpenEngine()
+	defer e.Close()
+
+	if err := e.WritePointsString(`cpu,host=B value=1.2 2000000000`); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+	e.MustWriteSnapshot()
+
+	paths, err := e.PrepareRestore(b)
+	if err != nil {
+		t.Fatalf("failed to prepare restore: %s", err.Error())
+	} else if len(paths) != 1 {
+		t.Fatalf("prepared file count wrong: exp: %d, got: %d", 1, len(paths))
+	}
+
+	// Prepared files aren't visible until they are restored.
+	if n := e.FileStore.Count(); n != 1 {
+		t.Fatalf("file count wrong: exp: %d, got: %d", 1, n)
+	}
+
+	if err := e.Restore(paths); err != nil {
+		t.Fatalf("failed to restore: %s", err.Error())
+	}
+
+	// Both files should be present and the restored series indexed.
+	if n := e.FileStore.Count(); n != 2 {
+		t.Fatalf("file count wrong: exp: %d, got: %d", 2, n)
+	}
+	if s := e.Index().Series("cpu,host=A"); s == nil {
+		t.Fatal("restored series not indexed")
+	}
+	if values, err := e.FileStore.Read("cpu,host=A#!~#value", 1000000000); err != nil {
+		t.Fatal(err)
+	} else if len(values) != 1 || values[0].Value() != 1.1 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
+// Ensure engine removes a restored file it fails to index.
+func TestEngine_Restore_FieldTypeConflict(t *testing.T) {
+	src := MustOpenEngine()
+	defer src.Close()
+
+	if err := src.WritePointsString(`cpu,host=A value=1.1 1000000000`); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+	src.MustWriteSnapshot()
+
+	b := bytes.NewBuffer(nil)
+	if err := src.Backup(b, "db/rp/1", time.Unix(0, 0)); err != nil {
+		t.Fatalf("failed to backup: %s", err.Error())
+	}
+
+	e := MustOpenEngine()
+	defer e.Close()
+
+	if err := e.WritePointsString(`cpu,host=B value=1i 2000000000`); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+	e.MustWriteSnapshot()
+
+	// Index the existing integer field.
+	if err := e.LoadMetadataIndex(nil, tsdb.NewDatabaseIndex("db"), make(map[string]*tsdb.MeasurementFields)); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := e.PrepareRestore(b)
+	if err != nil {
+		t.Fatalf("failed to prepare restore: %s", err.Error())
+	}
+	if err := e.Restore(paths); err == nil {
+		t.Fatal("expected field type conflict")
+	}
+
+	// Only the existing file should be left.
+	if n := e.FileStore.Count(); n != 1 {
+		t.Fatalf("file count wrong: exp: %d, got: %d", 1, n)
+	}
+	if files, err := filepath.Glob(filepath.Join(e.Path(), "*."+tsm1.TSMFileExtension)); err != nil {
+		t.Fatal(err)
+	} else if len(files) != 1 {
+		t.Fatalf("unexpected files: %v", files)
+	}
+}
+
 // Ensure engine can create an ascending iterator for cached values.
 func TestEngine_CreateIterator_Cache_Ascending(t *testing.T) {
 	t.Parallel()
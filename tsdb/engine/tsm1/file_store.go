@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/freetsdb/freetsdb"
+	"github.com/freetsdb/freetsdb/influxql"
 	"github.com/freetsdb/freetsdb/tsdb"
 )
 
@@ -583,6 +584,9 @@ type KeyCursor struct {
 	// If this is true, we need to scan the duplicate blocks and dedup the points
 	// as query time until they are compacted.
 	duplicates bool
+
+	// stats counts the blocks decoded by the cursor, if not nil.
+	stats *influxql.IteratorStats
 }
 
 type location struct {
@@ -614,6 +618,13 @@ func (c *KeyCursor) Close() {
 	c.current = nil
 }
 
+// trackBlock counts a decoded block in the cursor's statistics.
+func (c *KeyCursor) trackBlock(entry *IndexEntry) {
+	if c.stats != nil {
+		c.stats.AddBlock(int64(entry.Size))
+	}
+}
+
 // hasOverlappingBlocks returns true if blocks have overlapping time ranges.
 // This result is computed once and stored as the "duplicates" field.
 func (c *KeyCursor) hasOverlappingBlocks() bool {
@@ -726,6 +737,13 @@ func (c *KeyCursor) nextAscending() {
 }
 
 func (c *KeyCursor) nextDescending() {
+	// If we have overlapping blocks, read the ones with the latest points
+	// together so we can dedup them.
+	if c.duplicates {
+		c.current = c.latestOverlappingBlocks()
+		return
+	}
+
 	for {
 		c.pos--
 		if c.pos < 0 {
@@ -737,22 +755,41 @@ func (c *KeyCursor) nextDescending() {
 
 	// Append the first matching block
 	c.current = []*location{c.seeks[c.pos]}
+}
 
-	// We're done if there are no overlapping blocks.
-	if !c.duplicates {
-		return
+// latestOverlappingBlocks returns the unread block with the latest points and
+// all unread blocks that overlap it, directly or through other blocks. All
+// other unread blocks only contain earlier points. Blocks are returned from
+// the newest file to the oldest so the newest values are kept when deduping.
+func (c *KeyCursor) latestOverlappingBlocks() []*location {
+	var latest *location
+	for _, e := range c.seeks {
+		if !e.read && (latest == nil || e.entry.MaxTime > latest.entry.MaxTime) {
+			latest = e
+		}
+	}
+	if latest == nil {
+		return nil
 	}
 
-	// If we have ovelapping blocks, append all their values so we can dedup
-	first := c.seeks[c.pos]
-	for i := c.pos; i >= 0; i-- {
-		if c.seeks[i].read {
-			continue
+	// Extend the time range until no other block overlaps it.
+	minTime := latest.entry.MinTime
+	for extended := true; extended; {
+		extended = false
+		for _, e := range c.seeks {
+			if !e.read && e.entry.MaxTime >= minTime && e.entry.MinTime < minTime {
+				minTime, extended = e.entry.MinTime, true
+			}
 		}
-		if c.seeks[i].entry.MaxTime >= first.entry.MinTime {
-			c.current = append(c.current, c.seeks[i])
+	}
+
+	var a []*location
+	for i := len(c.seeks) - 1; i >= 0; i-- {
+		if e := c.seeks[i]; !e.read && e.entry.MaxTime >= minTime {
+			a = append(a, e)
 		}
 	}
+	return a
 }
 
 // ReadFloatBlock reads the next block as a set of float values.
@@ -765,6 +802,7 @@ func (c *KeyCursor) ReadFloatBlock(buf []FloatValue) ([]FloatValue, error) {
 	// First block is the oldest block containing the points we're search for.
 	first := c.current[0]
 	values, err := first.r.ReadFloatBlockAt(first.entry, buf[:0])
+	c.trackBlock(first.entry)
 	first.read = true
 
 	// Only one block with this key and time range so return it
@@ -780,6 +818,7 @@ func (c *KeyCursor) ReadFloatBlock(buf []FloatValue) ([]FloatValue, error) {
 			cur.read = true
 			c.pos++
 			v, err := cur.r.ReadFloatBlockAt(cur.entry, nil)
+			c.trackBlock(cur.entry)
 			if err != nil {
 				return nil, err
 			}
@@ -789,6 +828,7 @@ func (c *KeyCursor) ReadFloatBlock(buf []FloatValue) ([]FloatValue, error) {
 			c.pos--
 
 			v, err := cur.r.ReadFloatBlockAt(cur.entry, nil)
+			c.trackBlock(cur.entry)
 			if err != nil {
 				return nil, err
 			}
@@ -809,6 +849,7 @@ func (c *KeyCursor) ReadIntegerBlock(buf []IntegerValue) ([]IntegerValue, error)
 	// First block is the oldest block containing the points we're search for.
 	first := c.current[0]
 	values, err := first.r.ReadIntegerBlockAt(first.entry, buf[:0])
+	c.trackBlock(first.entry)
 	first.read = true
 
 	// Only one block with this key and time range so return it
@@ -824,6 +865,7 @@ func (c *KeyCursor) ReadIntegerBlock(buf []IntegerValue) ([]IntegerValue, error)
 			cur.read = true
 			c.pos++
 			v, err := cur.r.ReadIntegerBlockAt(cur.entry, nil)
+			c.trackBlock(cur.entry)
 			if err != nil {
 				return nil, err
 			}
@@ -833,6 +875,7 @@ func (c *KeyCursor) ReadIntegerBlock(buf []IntegerValue) ([]IntegerValue, error)
 			c.pos--
 
 			v, err := cur.r.ReadIntegerBlockAt(cur.entry, nil)
+			c.trackBlock(cur.entry)
 			if err != nil {
 				return nil, err
 			}
@@ -853,6 +896,7 @@ func (c *KeyCursor) ReadStringBlock(buf []StringValue) ([]StringValue, error) {
 	// First block is the oldest block containing the points we're search for.
 	first := c.current[0]
 	values, err := first.r.ReadStringBlockAt(first.entry, buf[:0])
+	c.trackBlock(first.entry)
 	first.read = true
 
 	// Only one block with this key and time range so return it
@@ -868,6 +912,7 @@ func (c *KeyCursor) ReadStringBlock(buf []StringValue) ([]StringValue, error) {
 			cur.read = true
 			c.pos++
 			v, err := cur.r.ReadStringBlockAt(cur.entry, nil)
+			c.trackBlock(cur.entry)
 			if err != nil {
 				return nil, err
 			}
@@ -877,6 +922,7 @@ func (c *KeyCursor) ReadStringBlock(buf []StringValue) ([]StringValue, error) {
 			c.pos--
 
 			v, err := cur.r.ReadStringBlockAt(cur.entry, nil)
+			c.trackBlock(cur.entry)
 			if err != nil {
 				return nil, err
 			}
@@ -897,6 +943,7 @@ func (c *KeyCursor) ReadBooleanBlock(buf []BooleanValue) ([]BooleanValue, error)
 	// First block is the oldest block containing the points we're search for.
 	first := c.current[0]
 	values, err := first.r.ReadBooleanBlockAt(first.entry, buf[:0])
+	c.trackBlock(first.entry)
 	first.read = true
 
 	// Only one block with this key and time range so return it
@@ -912,6 +959,7 @@ func (c *KeyCursor) ReadBooleanBlock(buf []BooleanValue) ([]BooleanValue, error)
 			cur.read = true
 			c.pos++
 			v, err := cur.r.ReadBooleanBlockAt(cur.entry, nil)
+			c.trackBlock(cur.entry)
 			if err != nil {
 				return nil, err
 			}
@@ -921,6 +969,7 @@ func (c *KeyCursor) ReadBooleanBlock(buf []BooleanValue) ([]BooleanValue, error)
 			c.pos--
 
 			v, err := cur.r.ReadBooleanBlockAt(cur.entry, nil)
+			c.trackBlock(cur.entry)
 			if err != nil {
 				return nil, err
 			}
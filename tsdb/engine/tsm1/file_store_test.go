@@ -719,6 +719,58 @@ func TestFileStore_SeekToDesc_AfterEnd_OverlapString(t *testing.T) {
 	}
 }
 
+// Ensure a descending seek before the latest overlapping blocks still reads
+// the earlier overlapping blocks.
+func TestFileStore_SeekToDesc_BeforeLatestOverlap(t *testing.T) {
+	fs := tsm1.NewFileStore("")
+
+	// Setup 3 files
+	data := []keyValues{
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(0, 0.0), tsm1.NewValue(10, 10.0)}},
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(5, 5.0), tsm1.NewValue(6, 6.0)}},
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(100, 100.0), tsm1.NewValue(200, 200.0)}},
+	}
+
+	files, err := newFiles(data...)
+	if err != nil {
+		t.Fatalf("unexpected error creating files: %v", err)
+	}
+
+	fs.Add(files...)
+
+	buf := make(tsm1.FloatValues, 1000)
+	c := fs.KeyCursor("cpu", 50, false)
+	values, err := c.ReadFloatBlock(buf)
+	if err != nil {
+		t.Fatalf("unexpected error reading values: %v", err)
+	}
+
+	exp := []tsm1.Value{
+		data[0].values[0],
+		data[1].values[0],
+		data[1].values[1],
+		data[0].values[1],
+	}
+	if got, exp := len(values), len(exp); got != exp {
+		t.Fatalf("value length mismatch: got %v, exp %v", got, exp)
+	}
+
+	for i, v := range exp {
+		if got, exp := values[i].Value(), v.Value(); got != exp {
+			t.Fatalf("read value mismatch(%d): got %v, exp %v", i, got, exp)
+		}
+	}
+
+	// Check that no later blocks are read after the seek time.
+	c.Next()
+	values, err = c.ReadFloatBlock(buf)
+	if err != nil {
+		t.Fatalf("unexpected error reading values: %v", err)
+	} else if len(values) != 0 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
 func TestFileStore_SeekToDesc_Middle(t *testing.T) {
 	fs := tsm1.NewFileStore("")
 
@@ -59,8 +59,10 @@ var ErrWALClosed = fmt.Errorf("WAL closed")
 
 // Statistics gathered by the WAL.
 const (
-	statWALOldBytes     = "oldSegmentsDiskBytes"
-	statWALCurrentBytes = "currentSegmentDiskBytes"
+	statWALOldBytes      = "oldSegmentsDiskBytes"
+	statWALCurrentBytes  = "currentSegmentDiskBytes"
+	statWALFsync         = "fsync"           // Number of fsyncs of the current segment
+	statWALFsyncDuration = "fsyncDurationNs" // Number of (wall-time) nanoseconds spent fsyncing
 )
 
 type WAL struct {
@@ -86,6 +88,23 @@ type WAL struct {
 	// LoggingEnabled specifies if detailed logs should be output
 	LoggingEnabled bool
 
+	// SyncMode is when writes are fsynced, one of the tsdb.WALSyncMode values.
+	SyncMode string
+
+	// FsyncDelay is how long to wait after a write before fsyncing it. Writes
+	// made in the meantime are fsynced together.
+	FsyncDelay time.Duration
+
+	// syncing is set while a goroutine is waiting to fsync the current segment,
+	// dirty while there are writes that haven't been fsynced, and syncWaiters
+	// holds the writes waiting for the fsync.
+	syncing     bool
+	dirty       bool
+	syncWaiters []chan error
+
+	// openSegmentFile opens the file for a new segment.
+	openSegmentFile func(name string) (io.WriteCloser, error)
+
 	statMap *expvar.Map
 }
 
@@ -97,9 +116,14 @@ func NewWAL(path string) *WAL {
 		// these options should be overriden by any options in the config
 		LogOutput:   os.Stderr,
 		SegmentSize: DefaultSegmentSize,
+		SyncMode:    tsdb.DefaultWALSyncMode,
 		logger:      log.New(os.Stderr, "[tsm1wal] ", log.LstdFlags),
 		closing:     make(chan struct{}),
 
+		openSegmentFile: func(name string) (io.WriteCloser, error) {
+			return os.OpenFile(name, os.O_CREATE|os.O_RDWR, 0666)
+		},
+
 		statMap: freetsdb.NewStatistics(
 			"tsm1_wal:"+path,
 			"tsm1_wal",
@@ -272,24 +296,42 @@ func (l *WAL) writeToLog(entry WALEntry) (int, error) {
 	defer putBuf(encBuf)
 	compressed := snappy.Encode(encBuf, b)
 
+	id, synced, err := l.appendEntry(entry.Type(), compressed)
+	if err != nil {
+		return -1, err
+	}
+
+	// Wait for the entry to be fsynced, along with any entries written
+	// concurrently.
+	if synced != nil {
+		if err := <-synced; err != nil {
+			return -1, fmt.Errorf("error syncing WAL: %v", err)
+		}
+	}
+	return id, nil
+}
+
+// appendEntry writes a compressed entry to the current segment and schedules
+// an fsync according to the sync mode. If the write has to wait for the fsync,
+// the returned channel receives its result.
+func (l *WAL) appendEntry(entryType WalEntryType, compressed []byte) (int, chan error, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	// Make sure the log has not been closed
 	select {
 	case <-l.closing:
-		return -1, ErrWALClosed
+		return -1, nil, ErrWALClosed
 	default:
 	}
 
 	// roll the segment file if needed
 	if err := l.rollSegment(); err != nil {
-		return -1, fmt.Errorf("error rolling WAL segment: %v", err)
+		return -1, nil, fmt.Errorf("error rolling WAL segment: %v", err)
 	}
 
-	// write and sync
-	if err := l.currentSegmentWriter.Write(entry.Type(), compressed); err != nil {
-		return -1, fmt.Errorf("error writing WAL entry: %v", err)
+	if err := l.currentSegmentWriter.Write(entryType, compressed); err != nil {
+		return -1, nil, fmt.Errorf("error writing WAL entry: %v", err)
 	}
 
 	// Update stats for current segment size
@@ -299,7 +341,71 @@ func (l *WAL) writeToLog(entry WALEntry) (int, error) {
 
 	l.lastWriteTime = time.Now()
 
-	return l.currentSegmentID, l.currentSegmentWriter.sync()
+	var synced chan error
+	switch l.SyncMode {
+	case tsdb.WALSyncModeNone:
+	case tsdb.WALSyncModeInterval:
+		l.dirty = true
+		l.scheduleSync()
+	default:
+		l.dirty = true
+		synced = make(chan error, 1)
+		l.syncWaiters = append(l.syncWaiters, synced)
+		l.scheduleSync()
+	}
+
+	return l.currentSegmentID, synced, nil
+}
+
+// scheduleSync starts a goroutine that fsyncs the current segment after
+// FsyncDelay, unless one is already waiting to. It keeps fsyncing while
+// writes arrive, so concurrent writers share fsyncs. Must be called with
+// l.mu held.
+func (l *WAL) scheduleSync() {
+	if l.syncing {
+		return
+	}
+	l.syncing = true
+
+	closing := l.closing
+	go func() {
+		for {
+			if l.FsyncDelay > 0 {
+				select {
+				case <-closing:
+					return
+				case <-time.After(l.FsyncDelay):
+				}
+			}
+
+			l.mu.Lock()
+			if !l.dirty {
+				l.syncing = false
+				l.mu.Unlock()
+				return
+			}
+			l.sync()
+			l.mu.Unlock()
+		}
+	}()
+}
+
+// sync fsyncs the current segment and notifies the writes waiting for it.
+// Must be called with l.mu held.
+func (l *WAL) sync() {
+	var err error
+	if l.currentSegmentWriter != nil {
+		start := time.Now()
+		err = l.currentSegmentWriter.sync()
+		l.statMap.Add(statWALFsync, 1)
+		l.statMap.Add(statWALFsyncDuration, time.Since(start).Nanoseconds())
+	}
+
+	for _, ch := range l.syncWaiters {
+		ch <- err
+	}
+	l.syncWaiters = nil
+	l.dirty = false
 }
 
 // rollSegment closes the current segment and opens a new one if the current segment is over
@@ -356,6 +462,12 @@ func (l *WAL) Close() error {
 	// Close, but don't set to nil so future goroutines can still be signaled
 	close(l.closing)
 
+	// Fsync anything still waiting on it.
+	if l.dirty {
+		l.sync()
+	}
+	l.syncing = false
+
 	if l.currentSegmentWriter != nil {
 		l.currentSegmentWriter.close()
 		l.currentSegmentWriter = nil
@@ -378,6 +490,10 @@ func segmentFileNames(dir string) ([]string, error) {
 func (l *WAL) newSegmentFile() error {
 	l.currentSegmentID++
 	if l.currentSegmentWriter != nil {
+		// The writes waiting for an fsync are in the segment being closed.
+		if l.dirty {
+			l.sync()
+		}
 		if err := l.currentSegmentWriter.close(); err != nil {
 			return err
 		}
@@ -385,7 +501,7 @@ func (l *WAL) newSegmentFile() error {
 	}
 
 	fileName := filepath.Join(l.path, fmt.Sprintf("%s%05d.%s", WALFilePrefix, l.currentSegmentID, WALFileExtension))
-	fd, err := os.OpenFile(fileName, os.O_CREATE|os.O_RDWR, 0666)
+	fd, err := l.openSegmentFile(fileName)
 	if err != nil {
 		return err
 	}
@@ -680,7 +796,9 @@ func NewWALSegmentWriter(w io.WriteCloser) *WALSegmentWriter {
 }
 
 func (w *WALSegmentWriter) path() string {
-	if f, ok := w.w.(*os.File); ok {
+	if f, ok := w.w.(interface {
+		Name() string
+	}); ok {
 		return f.Name()
 	}
 	return ""
@@ -707,7 +825,9 @@ func (w *WALSegmentWriter) Write(entryType WalEntryType, compressed []byte) erro
 
 // Sync flushes the file systems in-memory copy of recently written data to disk.
 func (w *WALSegmentWriter) sync() error {
-	if f, ok := w.w.(*os.File); ok {
+	if f, ok := w.w.(interface {
+		Sync() error
+	}); ok {
 		return f.Sync()
 	}
 	return nil
@@ -0,0 +1,185 @@
+package tsm1
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/freetsdb/freetsdb/tsdb"
+)
+
+// Ensure writes acknowledged under the always sync mode survive a crash.
+func TestWAL_SyncModeAlways_Crash(t *testing.T) {
+	w, files := NewCrashWAL(t, tsdb.WALSyncModeAlways)
+	defer os.RemoveAll(w.path)
+
+	MustWriteWAL(t, w, 10)
+
+	// Crash without closing the WAL, losing everything that wasn't fsynced.
+	files.Crash()
+	if n := MustReadWALEntries(t, w.path); n != 10 {
+		t.Fatalf("unexpected entries after crash: got %d, exp %d", n, 10)
+	}
+}
+
+// Ensure writes under the none sync mode can be lost in a crash.
+func TestWAL_SyncModeNone_Crash(t *testing.T) {
+	w, files := NewCrashWAL(t, tsdb.WALSyncModeNone)
+	defer os.RemoveAll(w.path)
+
+	MustWriteWAL(t, w, 10)
+
+	files.Crash()
+	if n := MustReadWALEntries(t, w.path); n != 0 {
+		t.Fatalf("unexpected entries after crash: got %d, exp %d", n, 0)
+	}
+}
+
+// Ensure concurrent writes share fsyncs when the fsync is delayed.
+func TestWAL_GroupCommit(t *testing.T) {
+	w, _ := NewCrashWAL(t, tsdb.WALSyncModeAlways)
+	defer os.RemoveAll(w.path)
+	w.FsyncDelay = 20 * time.Millisecond
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			MustWriteWAL(t, w, 1)
+		}()
+	}
+	wg.Wait()
+
+	if n := w.statMap.Get(statWALFsync).(*expvar.Int).Value(); n == 0 || n >= 50 {
+		t.Fatalf("unexpected fsync count: %d", n)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := MustReadWALEntries(t, w.path); n != 50 {
+		t.Fatalf("unexpected entries: got %d, exp %d", n, 50)
+	}
+}
+
+// NewCrashWAL returns an open WAL in a temporary directory whose segment
+// files only persist data once it has been fsynced.
+func NewCrashWAL(t *testing.T, mode string) (*WAL, *crashFiles) {
+	dir, err := ioutil.TempDir("", "tsm1-wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := &crashFiles{}
+	w := NewWAL(dir)
+	w.SyncMode = mode
+	w.openSegmentFile = files.open
+	if err := w.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return w, files
+}
+
+// MustWriteWAL writes n entries to w.
+func MustWriteWAL(t *testing.T, w *WAL, n int) {
+	for i := 0; i < n; i++ {
+		values := map[string][]Value{"cpu,host=A#!~#value": {NewValue(int64(i), float64(i))}}
+		if _, err := w.WritePoints(values); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// MustReadWALEntries returns the number of entries in the segments in dir.
+func MustReadWALEntries(t *testing.T, dir string) int {
+	names, err := segmentFileNames(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := NewWALSegmentReader(f)
+		for r.Next() {
+			if _, err := r.Read(); err != nil {
+				t.Fatal(err)
+			}
+			n++
+		}
+		r.Close()
+	}
+	return n
+}
+
+// crashFiles opens segment files that hold writes in memory until they are
+// fsynced, so a crash can be simulated by dropping them.
+type crashFiles struct {
+	mu    sync.Mutex
+	files []*crashFile
+}
+
+func (c *crashFiles) open(name string) (io.WriteCloser, error) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cf := &crashFile{f: f}
+	c.files = append(c.files, cf)
+	return cf, nil
+}
+
+// Crash drops every write that hasn't been fsynced.
+func (c *crashFiles) Crash() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range c.files {
+		f.mu.Lock()
+		f.buf = nil
+		f.f.Close()
+		f.mu.Unlock()
+	}
+}
+
+type crashFile struct {
+	mu  sync.Mutex
+	f   *os.File
+	buf []byte
+}
+
+func (f *crashFile) Name() string { return f.f.Name() }
+
+func (f *crashFile) Write(b []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.buf = append(f.buf, b...)
+	return len(b), nil
+}
+
+func (f *crashFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.f.Write(f.buf); err != nil {
+		return fmt.Errorf("sync: %s", err)
+	}
+	f.buf = nil
+	return f.f.Sync()
+}
+
+func (f *crashFile) Close() error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.f.Close()
+}
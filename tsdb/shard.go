@@ -412,6 +412,22 @@ func (s *Shard) WriteTo(w io.Writer) (int64, error) {
 	return n, err
 }
 
+// Restore adds the data in a backup archive of a shard to the shard. The
+// archive is written to disk before the shard is locked, so writes and
+// queries are only blocked while the restored files are added.
+func (s *Shard) Restore(r io.Reader) error {
+	paths, err := s.engine.PrepareRestore(r)
+	if err != nil {
+		return err
+	}
+
+	s.index.mu.Lock()
+	s.mu.Lock()
+	defer s.index.mu.Unlock()
+	defer s.mu.Unlock()
+	return s.engine.Restore(paths)
+}
+
 // CreateIterator returns an iterator for the data in the shard.
 func (s *Shard) CreateIterator(opt influxql.IteratorOptions) (influxql.Iterator, error) {
 	if influxql.Sources(opt.Sources).HasSystemSource() {
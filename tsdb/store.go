@@ -475,6 +475,20 @@ func (s *Store) BackupShard(id uint64, since time.Time, w io.Writer) error {
 	return shard.engine.Backup(w, path, since)
 }
 
+// RestoreShard adds the data in a backup archive to a shard, creating the
+// shard if it doesn't exist. The shard remains online during the restore.
+func (s *Store) RestoreShard(database, retentionPolicy string, id uint64, r io.Reader) error {
+	if err := s.CreateShard(database, retentionPolicy, id); err != nil {
+		return err
+	}
+
+	shard := s.Shard(id)
+	if shard == nil {
+		return fmt.Errorf("shard %d doesn't exist on this server", id)
+	}
+	return shard.Restore(r)
+}
+
 // ShardRelativePath will return the relative path to the shard. i.e. <database>/<retention>/<id>
 func (s *Store) ShardRelativePath(id uint64) (string, error) {
 	shard := s.Shard(id)
@@ -693,6 +707,40 @@ func (s *Store) ExecuteShowFieldKeysStatement(stmt *influxql.ShowFieldKeysStatem
 	return rows, nil
 }
 
+// NumericFields returns the sorted float and integer field names of every
+// measurement held in the local shards of a retention policy.
+func (s *Store) NumericFields(database, retentionPolicy string) map[string][]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sets := make(map[string]stringSet)
+	for _, sh := range s.shards {
+		if sh.database != database || sh.retentionPolicy != retentionPolicy {
+			continue
+		}
+
+		sh.mu.RLock()
+		for name, mf := range sh.measurementFields {
+			for _, f := range mf.Fields {
+				if f.Type != influxql.Float && f.Type != influxql.Integer {
+					continue
+				}
+				if sets[name] == nil {
+					sets[name] = newStringSet()
+				}
+				sets[name].add(f.Name)
+			}
+		}
+		sh.mu.RUnlock()
+	}
+
+	fields := make(map[string][]string, len(sets))
+	for name, set := range sets {
+		fields[name] = set.list()
+	}
+	return fields
+}
+
 // filterShowSeriesResult will limit the number of series returned based on the limit and the offset.
 // Unlike limit and offset on SELECT statements, the limit and offset don't apply to the number of Rows, but
 // to the number of total Values returned, since each Value represents a unique series.